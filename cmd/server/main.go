@@ -1,22 +1,36 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	badger "github.com/dgraph-io/badger/v4"
 	"github.com/gofiber/fiber/v2"
 	"github.com/mrkaynak/rag/internal/config"
 	"github.com/mrkaynak/rag/internal/handler"
 	"github.com/mrkaynak/rag/internal/middleware"
+	"github.com/mrkaynak/rag/internal/service/answercache"
+	"github.com/mrkaynak/rag/internal/service/cleanup"
 	"github.com/mrkaynak/rag/internal/service/document"
 	"github.com/mrkaynak/rag/internal/service/embeddings"
 	"github.com/mrkaynak/rag/internal/service/llm"
+	"github.com/mrkaynak/rag/internal/service/maintenance"
+	"github.com/mrkaynak/rag/internal/service/querylog"
+	"github.com/mrkaynak/rag/internal/service/redact"
+	"github.com/mrkaynak/rag/internal/service/reindex"
+	"github.com/mrkaynak/rag/internal/service/rerank"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
 	"github.com/mrkaynak/rag/internal/service/settings"
+	"github.com/mrkaynak/rag/internal/service/smalltalk"
+	"github.com/mrkaynak/rag/internal/service/tracing"
+	"github.com/mrkaynak/rag/internal/service/usage"
 	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/pkg/errors"
 	"go.uber.org/zap"
 )
 
@@ -48,9 +62,24 @@ func run() error {
 		zap.String("port", cfg.Server.Port),
 	)
 
+	// Initialize tracing (no-op unless TRACING_ENABLED)
+	shutdownTracing, err := tracing.Init(context.Background(), cfg, version)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("failed to flush traces", zap.Error(err))
+		}
+	}()
+
 	// Initialize BadgerDB (single instance)
 	opts := badger.DefaultOptions(cfg.Storage.BadgerDBPath)
 	opts.Logger = nil // Disable badger logs
+	opts.ValueThreshold = int64(cfg.Storage.BadgerValueThreshold)
+	opts.NumCompactors = cfg.Storage.BadgerNumCompactors
 	db, err := badger.Open(opts)
 	if err != nil {
 		return fmt.Errorf("failed to open badger db: %w", err)
@@ -59,6 +88,11 @@ func run() error {
 
 	logger.Info("badger db initialized", zap.String("path", cfg.Storage.BadgerDBPath))
 
+	// Periodic value-log GC (stopped before the DB is closed)
+	badgerGC := maintenance.NewGC(db, logger, cfg.Storage.BadgerGCInterval, cfg.Storage.BadgerGCDiscardRatio)
+	badgerGC.Start()
+	defer badgerGC.Stop()
+
 	// Initialize settings service (uses existing db)
 	settingsSvc := settings.NewWithDB(db, cfg.Encryption.Key)
 
@@ -67,30 +101,175 @@ func run() error {
 		logger.Warn("failed to seed initial data", zap.Error(err))
 	}
 
+	// Initialize metadata store
+	metadataStore := document.NewMetadataStore(db)
+
 	// Initialize services
-	docService, err := document.New(cfg)
+	docService, err := document.New(cfg, logger, metadataStore)
 	if err != nil {
 		return fmt.Errorf("failed to initialize document service: %w", err)
 	}
 
-	embeddingsSvc := embeddings.New(cfg)
+	embeddingsSvc := embeddings.New(cfg, logger)
 
-	vectorStore, err := vector.New(cfg)
+	vectorStore, err := vector.New(cfg, db)
 	if err != nil {
 		return fmt.Errorf("failed to initialize vector store: %w", err)
 	}
 
-	// Initialize metadata store
-	metadataStore := document.NewMetadataStore(db)
+	// The store's dimension stamp reflects what is actually indexed; an explicit
+	// EMBEDDING_DIMENSIONS still wins so a model change can be reindexed
+	if stamped := vectorStore.Dimensions(); stamped > 0 {
+		if cfg.Embeddings.Dimensions == 0 {
+			embeddingsSvc.SetDimensions(stamped)
+		} else if cfg.Embeddings.Dimensions != stamped && !cfg.Storage.DimensionFallback {
+			logger.Warn("EMBEDDING_DIMENSIONS disagrees with the indexed vectors; uploads will fail until a reindex",
+				zap.Int("configured", cfg.Embeddings.Dimensions),
+				zap.Int("stamped", stamped),
+			)
+		}
+	}
 
-	openRouterClient := llm.NewOpenRouterClient(cfg)
-	bedrockClient := llm.NewBedrockClient(cfg)
+	// VECTOR_DIMENSION_FALLBACK keeps mismatched vectors searchable, but only approximately
+	if cfg.Storage.DimensionFallback {
+		legacy, stamped := vectorStore.LegacyChunks(), vectorStore.Dimensions()
+		if legacy > 0 || (stamped > 0 && cfg.Embeddings.Dimensions > 0 && cfg.Embeddings.Dimensions != stamped) {
+			logger.Warn("VECTOR_DIMENSION_FALLBACK is on: vectors of another dimension are padded or truncated and search results are DEGRADED until a reindex completes",
+				zap.Int("configured", cfg.Embeddings.Dimensions),
+				zap.Int("stamped", stamped),
+				zap.Int("legacy_chunks", legacy),
+			)
+		}
+	}
+
+	// With EMBEDDING_DIMENSIONS=auto and an empty store, discover the dimension in the
+	// background so an unreachable provider doesn't hold up startup
+	if embeddingsSvc.Dimensions() == 0 {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if dimension, err := embeddingsSvc.DetectDimension(ctx); err != nil {
+				logger.Warn("failed to detect embedding dimension; it will be set by the first embedding", zap.Error(err))
+			} else {
+				logger.Info("detected embedding dimension", zap.Int("dimensions", dimension))
+			}
+		}()
+	}
+
+	// Load the embedding model before the first request needs it (EMBEDDING_WARMUP)
+	if cfg.Embeddings.Warmup {
+		if elapsed, err := embeddingsSvc.Warmup(context.Background()); err != nil {
+			logger.Warn("embedding warmup failed", zap.Duration("latency", elapsed), zap.Error(err))
+		} else {
+			logger.Info("embedding model warmed up",
+				zap.String("provider", cfg.Embeddings.Provider),
+				zap.String("model", cfg.Embeddings.Model),
+				zap.Duration("latency", elapsed),
+			)
+		}
+	}
+
+	// Token usage and cost of all LLM calls, persisted across restarts with USAGE_PERSIST
+	var usageDB *badger.DB
+	if cfg.LLM.UsagePersist {
+		usageDB = db
+	}
+	usageTracker, err := usage.New(cfg.LLM.UsagePrices, usageDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize usage tracking: %w", err)
+	}
+
+	// Queries are embedded with the model the index was built with (EMBEDDING_QUERY_MODEL=stamp)
+	if stamp := vectorStore.Model(); !stamp.IsZero() && (stamp.Provider != cfg.Embeddings.Provider || stamp.Model != cfg.Embeddings.Model) {
+		logger.Warn("configured embedding model differs from the one the index was built with; reindex to switch models",
+			zap.String("indexed_provider", stamp.Provider),
+			zap.String("indexed_model", stamp.Model),
+			zap.String("configured_provider", cfg.Embeddings.Provider),
+			zap.String("configured_model", cfg.Embeddings.Model),
+			zap.String("query_model", cfg.Embeddings.QueryModel),
+		)
+	}
+
+	// Bounds concurrent calls across all LLM providers
+	llmLimiter := llm.NewLimiter(cfg.LLM.MaxConcurrency, cfg.LLM.QueueTimeout)
+	openRouterClient := llm.NewOpenRouterClient(cfg, logger, llmLimiter, usageTracker)
+	bedrockClient := llm.NewBedrockClient(cfg, logger, llmLimiter, usageTracker)
+
+	reindexSvc := reindex.New(logger, docService, embeddingsSvc, vectorStore, metadataStore)
+	reranker := rerank.New(cfg, logger)
+	if reranker.Enabled() {
+		logger.Info("reranking enabled",
+			zap.String("provider", cfg.RAG.RerankProvider),
+			zap.String("model", cfg.RAG.RerankModel),
+			zap.Int("candidates", cfg.RAG.RerankCandidates),
+		)
+	}
+
+	// Chat and search queries for evaluation exports (QUERY_LOG) and anonymized query
+	// embeddings (QUERY_LOGGING)
+	queryLog, err := querylog.New(cfg, db, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize query log: %w", err)
+	}
+	defer queryLog.Close()
+
+	retrievalSvc := retrieval.New(cfg, logger, embeddingsSvc, vectorStore, metadataStore, reranker, queryLog)
+
+	// Periodic purge of soft-deleted documents past DELETED_DOCUMENT_RETENTION
+	purger := maintenance.NewPurger(metadataStore, vectorStore, logger, cfg.Storage.DeletedRetention)
+	purger.Start()
+	defer purger.Stop()
+
+	compactor := maintenance.NewCompactor(metadataStore, vectorStore, logger, cfg.Storage.VectorCompactRatio)
+	compactor.Start()
+	defer compactor.Stop()
+
+	var smalltalkSvc *smalltalk.Classifier
+	if cfg.RAG.SmalltalkShortcut {
+		smalltalkSvc, err = smalltalk.New(cfg.RAG.SmalltalkRules)
+		if err != nil {
+			return fmt.Errorf("failed to initialize smalltalk classifier: %w", err)
+		}
+	}
+
+	var redactor *redact.Redactor
+	if cfg.RAG.AnswerRedaction {
+		redactor, err = redact.New(cfg.RAG.AnswerRedactionRules)
+		if err != nil {
+			return fmt.Errorf("failed to initialize answer redaction: %w", err)
+		}
+	}
+
+	var cleaner *cleanup.Cleaner
+	if cfg.RAG.ResponseCleanup {
+		cleaner, err = cleanup.New(cfg.RAG.ResponseCleanupPatterns)
+		if err != nil {
+			return fmt.Errorf("failed to initialize response cleanup: %w", err)
+		}
+	}
+
+	// Answers to repeated questions over unchanged context (ANSWER_CACHE)
+	var answerCache *answercache.Cache
+	if cfg.RAG.AnswerCache {
+		answerCache, err = answercache.New(db, cfg.RAG.AnswerCacheTTL)
+		if err != nil {
+			return fmt.Errorf("failed to initialize answer cache: %w", err)
+		}
+	}
 
 	// Initialize handlers
-	healthHandler := handler.NewHealthHandler(version, cfg)
-	uploadHandler := handler.NewUploadHandler(cfg, logger, docService, embeddingsSvc, vectorStore, metadataStore)
-	chatHandler := handler.NewChatHandler(cfg, logger, vectorStore, embeddingsSvc, openRouterClient, bedrockClient, settingsSvc)
+	healthHandler := handler.NewHealthHandler(version, cfg, vectorStore, embeddingsSvc)
+	uploadHandler := handler.NewUploadHandler(cfg, logger, docService, embeddingsSvc, vectorStore, metadataStore, reindexSvc, purger)
+	chatHandler := handler.NewChatHandler(cfg, logger, vectorStore, embeddingsSvc, openRouterClient, bedrockClient, settingsSvc, retrievalSvc, smalltalkSvc, redactor, cleaner, queryLog, answerCache)
 	settingsHandler := handler.NewSettingsHandler(logger, settingsSvc)
+	statsHandler := handler.NewStatsHandler(logger, vectorStore, metadataStore, embeddingsSvc)
+	usageHandler := handler.NewUsageHandler(usageTracker)
+	chunksHandler := handler.NewChunksHandler(logger, vectorStore)
+	adminHandler := handler.NewAdminHandler(logger, reindexSvc, badgerGC, compactor, vectorStore, queryLog)
+	searchHandler := handler.NewSearchHandler(cfg, logger, embeddingsSvc, retrievalSvc, queryLog)
+	evalHandler := handler.NewEvalHandler(logger, queryLog)
+	embedHandler := handler.NewEmbedHandler(cfg, logger, embeddingsSvc)
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
@@ -101,24 +280,55 @@ func run() error {
 
 	// Global middleware
 	app.Use(middleware.Recovery(logger))
+	if cfg.Tracing.Enabled {
+		app.Use(middleware.Tracing())
+	}
+	app.Use(middleware.Sample(cfg.Server.LogSampleRate))
 	app.Use(middleware.Logger(logger))
 	app.Use(middleware.CORS())
+	app.Use(middleware.Deadline(cfg.Server.RequestTimeout, cfg.Server.MaxRequestTimeout))
 
 	// Routes
 	api := app.Group("/api/v1")
 
+	// Uploads, retrieval and document and chunk access are bound to the caller's tenant when
+	// TENANT_HEADER is set
+	tenantScope := middleware.Tenant(cfg.Server.TenantHeader)
+
+	// Requests that embed text are rejected up front while the embeddings provider is down
+	// (EMBEDDING_HEALTH_PRECHECK)
+	embeddingsUp := middleware.RequireHealthy(cfg.Embeddings.HealthPrecheck, embeddingsSvc)
+	// Retrieval can still be served by the keyword fallback (EMBED_FALLBACK=keyword)
+	queryEmbeddingsUp := middleware.RequireHealthy(cfg.Embeddings.HealthPrecheck && cfg.RAG.EmbedFallback != "keyword", embeddingsSvc)
+
 	// Health & Info
 	api.Get("/health", healthHandler.Health)
+	api.Get("/ready", healthHandler.Ready)
 	api.Get("/system-prompt", healthHandler.GetSystemPrompt)
+	api.Get("/stats", statsHandler.Stats)
+	api.Get("/usage", usageHandler.Usage)
 
 	// Documents
-	api.Post("/upload", uploadHandler.Upload)
-	api.Get("/documents", uploadHandler.ListDocuments)
-	api.Delete("/documents/:id", uploadHandler.DeleteDocument)
+	api.Post("/upload", tenantScope, embeddingsUp, uploadHandler.Upload)
+	api.Post("/upload/stream", tenantScope, embeddingsUp, uploadHandler.UploadStream)
+	api.Post("/documents/text", tenantScope, embeddingsUp, uploadHandler.UploadText)
+	api.Get("/documents", tenantScope, uploadHandler.ListDocuments)
+	api.Get("/documents/:id", tenantScope, uploadHandler.GetDocument)
+	api.Delete("/documents/:id", tenantScope, uploadHandler.DeleteDocument)
+	api.Post("/documents/:id/restore", tenantScope, uploadHandler.RestoreDocument)
+	api.Get("/chunks", tenantScope, chunksHandler.ListChunks)
 
 	// Chat
-	api.Post("/chat", chatHandler.Chat)
-	api.Post("/chat/stream", chatHandler.ChatStream)
+	api.Post("/chat", tenantScope, queryEmbeddingsUp, chatHandler.Chat)
+	api.Post("/chat/stream", tenantScope, queryEmbeddingsUp, chatHandler.ChatStream)
+	api.Post("/chat/resume", tenantScope, queryEmbeddingsUp, chatHandler.ChatResume)
+	api.Post("/chat/debug", tenantScope, chatHandler.ChatDebug)
+
+	// Search
+	api.Post("/search", tenantScope, queryEmbeddingsUp, searchHandler.Search)
+
+	// Embeddings
+	api.Post("/embed", embeddingsUp, embedHandler.Embed)
 
 	// Settings - API Keys
 	api.Post("/settings/api-keys", settingsHandler.SaveAPIKeys)
@@ -128,12 +338,31 @@ func run() error {
 	api.Post("/settings/models", settingsHandler.SaveModel)
 	api.Get("/settings/models", settingsHandler.ListModels)
 	api.Delete("/settings/models/:id", settingsHandler.DeleteModel)
+	api.Put("/settings/providers/:provider/default-model", settingsHandler.SetDefaultModel)
+	api.Get("/settings/providers/:provider/default-model", settingsHandler.GetDefaultModel)
+	api.Delete("/settings/providers/:provider/default-model", settingsHandler.ClearDefaultModel)
 
 	// Settings - System Prompts
 	api.Post("/settings/system-prompts", settingsHandler.SaveSystemPrompt)
 	api.Get("/settings/system-prompts", settingsHandler.ListSystemPrompts)
 	api.Get("/settings/system-prompts/default", settingsHandler.GetDefaultSystemPrompt)
+	api.Post("/settings/system-prompts/test", tenantScope, chatHandler.TestSystemPrompt)
 	api.Delete("/settings/system-prompts/:id", settingsHandler.DeleteSystemPrompt)
+	api.Put("/settings/namespaces/:namespace/system-prompt", settingsHandler.SaveNamespacePrompt)
+	api.Get("/settings/namespaces/:namespace/system-prompt", settingsHandler.GetNamespacePrompt)
+	api.Delete("/settings/namespaces/:namespace/system-prompt", settingsHandler.DeleteNamespacePrompt)
+
+	// Admin (requires ADMIN_API_KEY)
+	admin := api.Group("/admin", middleware.AdminAuth(cfg.Admin.APIKey))
+	admin.Post("/reindex-all", adminHandler.ReindexAll)
+	admin.Get("/reindex-all", adminHandler.ReindexStatus)
+	admin.Post("/gc", adminHandler.RunGC)
+	admin.Post("/compact", adminHandler.Compact)
+	admin.Get("/compact", adminHandler.CompactionStats)
+	admin.Get("/export-chunks", tenantScope, adminHandler.ExportChunks)
+	admin.Get("/queries", adminHandler.ExportQueries)
+	api.Post("/reindex/stream", middleware.AdminAuth(cfg.Admin.APIKey), adminHandler.ReindexStream)
+	api.Get("/eval/export", middleware.AdminAuth(cfg.Admin.APIKey), evalHandler.Export)
 
 	// Start server in goroutine
 	go func() {
@@ -185,8 +414,9 @@ func customErrorHandler(logger *zap.Logger) fiber.ErrorHandler {
 		)
 
 		return c.Status(code).JSON(fiber.Map{
-			"error": err.Error(),
-			"code":  code,
+			"error":      err.Error(),
+			"code":       code,
+			"error_code": errors.DefaultCode(code),
 		})
 	}
 }
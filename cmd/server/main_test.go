@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startServer runs run() against an Ollama stub with the given extra environment, waits
+// until the server answers and returns the embedding prompts the stub received. The server
+// is stopped with SIGTERM when the test ends.
+func startServer(t *testing.T, env map[string]string) func() []string {
+	t.Helper()
+
+	var mu sync.Mutex
+	var prompts []string
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Prompt string `json:"prompt"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		prompts = append(prompts, body.Prompt)
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"embedding": []float64{0.1, 0.2, 0.3}})
+	}))
+	t.Cleanup(ollama.Close)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	dir := t.TempDir()
+	t.Setenv("PORT", fmt.Sprint(port))
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("EMBEDDING_PROVIDER", "ollama")
+	t.Setenv("OLLAMA_BASE_URL", ollama.URL)
+	t.Setenv("UPLOAD_DIR", dir+"/uploads")
+	t.Setenv("VECTOR_STORE_PATH", dir+"/vectors")
+	t.Setenv("BADGER_DB_PATH", dir+"/badger")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- run() }()
+
+	// run() only listens for SIGTERM once the server is up, so wait for it before stopping it
+	healthURL := fmt.Sprintf("http://127.0.0.1:%d/api/v1/health", port)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if resp, err := http.Get(healthURL); err == nil {
+			resp.Body.Close()
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("run() returned before the server was up: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("server did not come up")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Cleanup(func() {
+		syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("run() failed: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("server did not shut down")
+		}
+	})
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), prompts...)
+	}
+}
+
+func TestRunWarmsUpEmbeddingModel(t *testing.T) {
+	tests := []struct {
+		name   string
+		warmup string
+		want   int
+	}{
+		{name: "enabled", warmup: "true", want: 1},
+		{name: "disabled", warmup: "false", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompts := startServer(t, map[string]string{"EMBEDDING_WARMUP": tt.warmup})
+
+			warmups := 0
+			for _, prompt := range prompts() {
+				if prompt == "warmup" {
+					warmups++
+				}
+			}
+			if warmups != tt.want {
+				t.Errorf("warmup calls = %d, want %d (prompts %q)", warmups, tt.want, prompts())
+			}
+		})
+	}
+}
+
+func TestRunStartsWhenWarmupFails(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	// startServer fails the test unless the server comes up
+	startServer(t, map[string]string{"EMBEDDING_WARMUP": "true", "OLLAMA_BASE_URL": unreachable.URL})
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -18,18 +20,26 @@ type Config struct {
 	Storage    StorageConfig
 	Encryption EncryptionConfig
 	RAG        RAGConfig
+	Admin      AdminConfig
+	Tracing    TracingConfig
+	LLM        LLMConfig
 }
 
 // ServerConfig holds server-specific configuration
 type ServerConfig struct {
-	Port string
-	Env  string
+	Port              string
+	Env               string
+	RequestTimeout    time.Duration
+	MaxRequestTimeout time.Duration
+	TenantHeader      string
+	LogSampleRate     float64
 }
 
 // OpenRouterConfig holds OpenRouter API configuration
 type OpenRouterConfig struct {
-	APIKey string
-	Model  string
+	APIKey  string
+	Model   string
+	Headers map[string]string
 }
 
 // BedrockConfig holds AWS Bedrock configuration
@@ -37,13 +47,52 @@ type BedrockConfig struct {
 	APIKey  string
 	Region  string
 	ModelID string
+	Headers map[string]string
+}
+
+// LLMConfig holds settings shared by all LLM providers
+type LLMConfig struct {
+	MaxConcurrency int
+	QueueTimeout   time.Duration
+	DebugRaw       bool
+	UsagePrices    string
+	UsagePersist   bool
 }
 
 // EmbeddingsConfig holds embeddings configuration
 type EmbeddingsConfig struct {
-	Provider   string
-	Model      string
-	Dimensions int
+	Provider        string
+	Model           string
+	Dimensions      int
+	MaxDimensions   int
+	MaxInputTokens  int
+	QueryTruncation string
+	QueryModel      string
+	InvalidVectors  string
+	Normalize       bool
+	Warmup          bool
+	LogRequests     bool
+	LogContent      bool
+	HealthTTL       time.Duration
+	HealthPrecheck  bool
+	OpenRouterURL   string
+	BedrockBaseURL  string
+	OllamaPath      string
+
+	// Models used for uploads whose embedding_provider differs from EMBEDDING_PROVIDER
+	// (EMBEDDING_MODEL_OLLAMA, ..._OPENROUTER, ..._BEDROCK); EMBEDDING_PROVIDER uses Model
+	OllamaModel     string
+	OpenRouterModel string
+	BedrockModel    string
+
+	// Batching sends several texts per request to providers that accept it, within the
+	// provider's input count and token limits
+	Batching              bool
+	OpenRouterBatchInputs int
+	OpenRouterBatchTokens int
+
+	// Timeout bounds each embeddings HTTP call on its own (EMBEDDING_TIMEOUT, 0 = no limit)
+	Timeout time.Duration
 }
 
 // OllamaConfig holds Ollama configuration
@@ -53,9 +102,28 @@ type OllamaConfig struct {
 
 // StorageConfig holds storage paths configuration
 type StorageConfig struct {
-	UploadDir       string
-	VectorStorePath string
-	BadgerDBPath    string
+	UploadDir            string
+	VectorStorePath      string
+	BadgerDBPath         string
+	BadgerGCInterval     time.Duration
+	BadgerGCDiscardRatio float64
+	BadgerValueThreshold int
+	BadgerNumCompactors  int
+	MaxConcurrentUploads int
+	StoreDocumentContent bool
+	OnDuplicate          string
+	VectorQuantization   string
+	VectorCompactRatio   float64
+	CompressChunkText    bool
+	DeletedRetention     time.Duration
+
+	// DimensionFallback pads or truncates legacy vectors of another dimension instead of
+	// rejecting them, degrading results until a reindex (VECTOR_DIMENSION_FALLBACK)
+	DimensionFallback bool
+
+	// UploadsPerTenant bounds each tenant's (or, without tenants, each namespace's) share of
+	// the concurrent uploads (UPLOAD_CONCURRENCY_PER_TENANT, 0 = no per-tenant limit)
+	UploadsPerTenant int
 }
 
 // EncryptionConfig holds encryption configuration
@@ -63,12 +131,95 @@ type EncryptionConfig struct {
 	Key string
 }
 
+// AdminConfig holds admin API configuration
+type AdminConfig struct {
+	APIKey string
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration. The OTLP exporter itself is
+// configured through the standard OTEL_EXPORTER_OTLP_* environment variables.
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+	SampleRatio float64
+}
+
 // RAGConfig holds RAG-specific configuration
 type RAGConfig struct {
-	MaxContextChunks int
-	ChunkSize        int
-	ChunkOverlap     int
-	SystemPrompt     string
+	MaxContextChunks        int
+	ChunkSize               int
+	ChunkOverlap            int
+	ChunkUnit               string
+	SystemPrompt            string
+	TrackUsedSources        bool
+	StrictGrounding         bool
+	GroundingCheck          bool
+	ChunkContextualize      bool
+	EmbedFallback           string
+	ScoreNormalization      string
+	MultiQueryCount         int
+	MaxChunksPerDocument    int
+	MaxContextTokens        int
+	ContextOverflow         string
+	ContextSummaryModel     string
+	ChunkHeadings           string
+	NormalizeWhitespace     bool
+	DedupeChunks            bool
+	CodeIndexing            bool
+	CodeEmbedPath           bool
+	MessageSuffix           string
+	EmbedCodeBlocks         bool
+	SmalltalkShortcut       bool
+	SmalltalkRules          string
+	AnswerRedaction         bool
+	AnswerRedactionRules    string
+	ResponseCleanup         bool
+	RecencyBoost            float64
+	RecencyHalfLife         time.Duration
+	ResponseCleanupPatterns string
+	SearchMinScore          float64
+	SearchMinResults        int
+	SearchMaxResults        int
+
+	// ContextMinScore drops chat context chunks scoring below it (CONTEXT_MIN_SCORE, 0 = off).
+	// When that leaves none, the best MinResults chunks are kept anyway and flagged low
+	// confidence (MIN_RESULTS, 0 = off).
+	ContextMinScore float64
+	MinResults      int
+
+	// QueryLog records chat and search queries for GET /api/v1/eval/export (QUERY_LOG)
+	QueryLog bool
+
+	// QueryLogEmbeddings also records anonymized queries with their embeddings for
+	// GET /api/v1/admin/queries (QUERY_LOGGING, or its alias QUERY_LOG_EMBEDDINGS), kept for
+	// QueryLogEmbeddingsTTL (QUERY_LOG_EMBEDDINGS_TTL)
+	QueryLogEmbeddings    bool
+	QueryLogEmbeddingsTTL time.Duration
+
+	// StreamContextText includes the context texts in the chat stream's context event
+	// (STREAM_CONTEXT_TEXT); the sources event is always sent
+	StreamContextText bool
+
+	// StructuredChunking accepts .json/.yaml/.yml uploads and chunks them by keys and array
+	// elements (STRUCTURED_CHUNKING)
+	StructuredChunking bool
+
+	// SplitOversized hard-splits chunks whose embedded text exceeds EMBEDDING_MAX_INPUT_TOKENS
+	// (CHUNK_SPLIT_OVERSIZED)
+	SplitOversized bool
+
+	// RerankProvider reorders retrieved chunks by a model-assigned relevance score
+	// (RERANK_PROVIDER: none or ollama). RerankModel is the Ollama model prompted for scores
+	// (RERANK_MODEL) and RerankCandidates how many chunks are fetched and scored before
+	// keeping the top ones (RERANK_CANDIDATES).
+	RerankProvider   string
+	RerankModel      string
+	RerankCandidates int
+
+	// AnswerCache serves repeated questions over unchanged context from BadgerDB instead of
+	// calling the LLM (ANSWER_CACHE), for AnswerCacheTTL (ANSWER_CACHE_TTL)
+	AnswerCache    bool
+	AnswerCacheTTL time.Duration
 }
 
 // Load loads configuration from environment variables
@@ -78,39 +229,146 @@ func Load() (*Config, error) {
 
 	cfg := &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "3000"),
-			Env:  getEnv("ENV", "development"),
+			Port:              getEnv("PORT", "3000"),
+			Env:               getEnv("ENV", "development"),
+			RequestTimeout:    getEnvAsDuration("REQUEST_TIMEOUT", 0),
+			MaxRequestTimeout: getEnvAsDuration("REQUEST_TIMEOUT_MAX", 10*time.Minute),
+			TenantHeader:      getEnv("TENANT_HEADER", ""),
+			LogSampleRate:     getEnvAsFloat("LOG_SAMPLE_RATE", 0),
 		},
 		OpenRouter: OpenRouterConfig{
-			APIKey: getEnv("OPENROUTER_API_KEY", ""),
-			Model:  getEnv("OPENROUTER_MODEL", "anthropic/claude-3.5-sonnet"),
+			APIKey:  getEnv("OPENROUTER_API_KEY", ""),
+			Model:   getEnv("OPENROUTER_MODEL", "anthropic/claude-3.5-sonnet"),
+			Headers: openRouterHeaders(),
 		},
 		Bedrock: BedrockConfig{
 			APIKey:  getEnv("BEDROCK_API_KEY", ""),
 			Region:  getEnv("BEDROCK_REGION", "eu-north-1"),
 			ModelID: getEnv("BEDROCK_MODEL_ID", "openai.gpt-oss-20b-1:0"),
+			Headers: getEnvAsHeaders("BEDROCK_EXTRA_HEADERS"),
 		},
 		Ollama: OllamaConfig{
 			BaseURL: getEnv("OLLAMA_BASE_URL", "http://localhost:11434"),
 		},
 		Embeddings: EmbeddingsConfig{
-			Provider:   getEnv("EMBEDDING_PROVIDER", "ollama"),
-			Model:      getEnv("EMBEDDING_MODEL", "all-minilm:33m"),
-			Dimensions: getEnvAsInt("EMBEDDING_DIMENSIONS", 384),
+			Provider:        getEnv("EMBEDDING_PROVIDER", "ollama"),
+			Model:           getEnv("EMBEDDING_MODEL", "all-minilm:33m"),
+			Dimensions:      getEnvAsDimensions("EMBEDDING_DIMENSIONS", 0),
+			MaxDimensions:   getEnvAsInt("MAX_EMBEDDING_DIM", 8192),
+			MaxInputTokens:  getEnvAsInt("EMBEDDING_MAX_INPUT_TOKENS", 512),
+			QueryTruncation: getEnv("EMBEDDING_QUERY_TRUNCATION", "truncate_tail"),
+			QueryModel:      getEnv("EMBEDDING_QUERY_MODEL", "stamp"),
+			InvalidVectors:  getEnv("EMBEDDING_INVALID_VECTORS", "reject"),
+			Normalize:       getEnvAsBool("EMBEDDING_NORMALIZE", false),
+			Warmup:          getEnvAsBool("EMBEDDING_WARMUP", false),
+			LogRequests:     getEnvAsBool("EMBEDDING_LOG_REQUESTS", false),
+			LogContent:      getEnvAsBool("EMBEDDING_LOG_CONTENT", false),
+			HealthTTL:       getEnvAsDuration("PROVIDER_HEALTH_TTL", 5*time.Second),
+			HealthPrecheck:  getEnvAsBool("EMBEDDING_HEALTH_PRECHECK", false),
+			OpenRouterURL:   getEnv("OPENROUTER_EMBEDDINGS_URL", "https://openrouter.ai/api/v1/embeddings"),
+			BedrockBaseURL:  strings.TrimSuffix(getEnv("BEDROCK_EMBEDDINGS_BASE_URL", ""), "/"),
+			OllamaPath:      getEnv("OLLAMA_EMBEDDINGS_PATH", "/api/embeddings"),
+
+			OllamaModel:     getEnv("EMBEDDING_MODEL_OLLAMA", ""),
+			OpenRouterModel: getEnv("EMBEDDING_MODEL_OPENROUTER", ""),
+			BedrockModel:    getEnv("EMBEDDING_MODEL_BEDROCK", ""),
+
+			Batching:              getEnvAsBool("EMBEDDING_BATCHING", false),
+			OpenRouterBatchInputs: getEnvAsInt("OPENROUTER_EMBEDDING_BATCH_INPUTS", 2048),
+			OpenRouterBatchTokens: getEnvAsInt("OPENROUTER_EMBEDDING_BATCH_TOKENS", 300000),
+
+			Timeout: getEnvAsDuration("EMBEDDING_TIMEOUT", 0),
 		},
 		Storage: StorageConfig{
-			UploadDir:       getEnv("UPLOAD_DIR", "./data/uploads"),
-			VectorStorePath: getEnv("VECTOR_STORE_PATH", "./data/vectors"),
-			BadgerDBPath:    getEnv("BADGER_DB_PATH", "./data/badger"),
+			UploadDir:            getEnv("UPLOAD_DIR", "./data/uploads"),
+			VectorStorePath:      getEnv("VECTOR_STORE_PATH", "./data/vectors"),
+			BadgerDBPath:         getEnv("BADGER_DB_PATH", "./data/badger"),
+			BadgerGCInterval:     getEnvAsDuration("BADGER_GC_INTERVAL", 10*time.Minute),
+			BadgerGCDiscardRatio: getEnvAsFloat("BADGER_GC_DISCARD_RATIO", 0.5),
+			BadgerValueThreshold: getEnvAsInt("BADGER_VALUE_THRESHOLD", 1<<20),
+			BadgerNumCompactors:  getEnvAsInt("BADGER_NUM_COMPACTORS", 4),
+			MaxConcurrentUploads: getEnvAsInt("MAX_CONCURRENT_UPLOADS", 4),
+			StoreDocumentContent: getEnvAsBool("STORE_DOCUMENT_CONTENT", false),
+			OnDuplicate:          getEnv("ON_DUPLICATE", "allow"),
+			VectorQuantization:   getEnv("VECTOR_QUANTIZATION", "none"),
+			VectorCompactRatio:   getEnvAsFloat("VECTOR_COMPACT_RATIO", 0),
+			CompressChunkText:    getEnvAsBool("COMPRESS_CHUNK_TEXT", false),
+			DeletedRetention:     getEnvAsDuration("DELETED_DOCUMENT_RETENTION", 0),
+
+			DimensionFallback: getEnvAsBool("VECTOR_DIMENSION_FALLBACK", false),
+			UploadsPerTenant:  getEnvAsInt("UPLOAD_CONCURRENCY_PER_TENANT", 0),
 		},
 		Encryption: EncryptionConfig{
 			Key: getEnv("ENCRYPTION_KEY", ""),
 		},
 		RAG: RAGConfig{
-			MaxContextChunks: getEnvAsInt("MAX_CONTEXT_CHUNKS", 5),
-			ChunkSize:        getEnvAsInt("CHUNK_SIZE", 1000),
-			ChunkOverlap:     getEnvAsInt("CHUNK_OVERLAP", 200),
-			SystemPrompt:     getEnv("SYSTEM_PROMPT", "You are a helpful AI assistant. Answer questions based on the provided context."),
+			MaxContextChunks:        getEnvAsInt("MAX_CONTEXT_CHUNKS", 5),
+			ChunkSize:               getEnvAsInt("CHUNK_SIZE", 1000),
+			ChunkOverlap:            getEnvAsInt("CHUNK_OVERLAP", 200),
+			ChunkUnit:               getChunkUnit(),
+			SystemPrompt:            getEnv("SYSTEM_PROMPT", "You are a helpful AI assistant. Answer questions based on the provided context."),
+			TrackUsedSources:        getEnvAsBool("TRACK_USED_SOURCES", false),
+			StrictGrounding:         getEnvAsBool("STRICT_GROUNDING", false),
+			GroundingCheck:          getEnvAsBool("GROUNDING_CHECK", false),
+			ChunkContextualize:      getEnvAsBool("CHUNK_CONTEXTUALIZE", false),
+			EmbedFallback:           getEnv("EMBED_FALLBACK", "none"),
+			ScoreNormalization:      getEnv("SCORE_NORMALIZATION", "raw"),
+			MultiQueryCount:         getEnvAsInt("MULTI_QUERY_COUNT", 1),
+			MaxChunksPerDocument:    getEnvAsInt("MAX_CHUNKS_PER_DOCUMENT", 0),
+			MaxContextTokens:        getEnvAsInt("MAX_CONTEXT_TOKENS", 0),
+			ContextOverflow:         getEnv("CONTEXT_OVERFLOW", "drop"),
+			ContextSummaryModel:     getEnv("CONTEXT_SUMMARY_MODEL", ""),
+			ChunkHeadings:           getEnv("CHUNK_HEADINGS", "off"),
+			NormalizeWhitespace:     getEnvAsBool("CHUNK_NORMALIZE_WHITESPACE", false),
+			DedupeChunks:            getEnvAsBool("CHUNK_DEDUPE", true),
+			CodeIndexing:            getEnvAsBool("CODE_INDEXING", false),
+			CodeEmbedPath:           getEnvAsBool("CODE_EMBED_PATH", true),
+			MessageSuffix:           getEnv("MESSAGE_SUFFIX", ""),
+			EmbedCodeBlocks:         getEnvAsBool("EMBED_CODE_BLOCKS", true),
+			SmalltalkShortcut:       getEnvAsBool("SMALLTALK_SHORTCUT", false),
+			SmalltalkRules:          getEnv("SMALLTALK_RULES", ""),
+			AnswerRedaction:         getEnvAsBool("ANSWER_REDACTION", false),
+			AnswerRedactionRules:    getEnv("ANSWER_REDACTION_RULES", ""),
+			ResponseCleanup:         getEnvAsBool("RESPONSE_CLEANUP", false),
+			RecencyBoost:            getEnvAsFloat("RECENCY_BOOST", 0),
+			RecencyHalfLife:         getEnvAsDuration("RECENCY_HALFLIFE", 30*24*time.Hour),
+			ResponseCleanupPatterns: getEnv("RESPONSE_CLEANUP_PATTERNS", ""),
+			SearchMinScore:          getEnvAsFloat("SEARCH_MIN_SCORE", 0),
+			SearchMinResults:        getEnvAsInt("SEARCH_MIN_RESULTS", 0),
+			SearchMaxResults:        getEnvAsInt("SEARCH_MAX_RESULTS", 0),
+
+			ContextMinScore: getEnvAsFloat("CONTEXT_MIN_SCORE", 0),
+			MinResults:      getEnvAsInt("MIN_RESULTS", 0),
+			QueryLog:        getEnvAsBool("QUERY_LOG", false),
+
+			QueryLogEmbeddings:    getQueryLogging(),
+			QueryLogEmbeddingsTTL: getEnvAsDuration("QUERY_LOG_EMBEDDINGS_TTL", 30*24*time.Hour),
+
+			StreamContextText:  getEnvAsBool("STREAM_CONTEXT_TEXT", true),
+			StructuredChunking: getEnvAsBool("STRUCTURED_CHUNKING", false),
+			SplitOversized:     getEnvAsBool("CHUNK_SPLIT_OVERSIZED", false),
+
+			RerankProvider:   getEnv("RERANK_PROVIDER", "none"),
+			RerankModel:      getEnv("RERANK_MODEL", "llama3.2"),
+			RerankCandidates: getEnvAsInt("RERANK_CANDIDATES", 20),
+
+			AnswerCache:    getEnvAsBool("ANSWER_CACHE", false),
+			AnswerCacheTTL: getEnvAsDuration("ANSWER_CACHE_TTL", 24*time.Hour),
+		},
+		Admin: AdminConfig{
+			APIKey: getEnv("ADMIN_API_KEY", ""),
+		},
+		LLM: LLMConfig{
+			MaxConcurrency: getEnvAsInt("LLM_MAX_CONCURRENCY", 0),
+			QueueTimeout:   getEnvAsDuration("LLM_QUEUE_TIMEOUT", 30*time.Second),
+			DebugRaw:       getEnvAsBool("LLM_DEBUG_RAW", false),
+			UsagePrices:    getEnv("USAGE_PRICES", ""),
+			UsagePersist:   getEnvAsBool("USAGE_PERSIST", false),
+		},
+		Tracing: TracingConfig{
+			Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "go-rag"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
 		},
 	}
 
@@ -131,18 +389,209 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("EMBEDDING_PROVIDER must be 'ollama', 'openrouter', or 'bedrock'")
 	}
 
+	if c.Embeddings.Dimensions < 0 {
+		return fmt.Errorf("EMBEDDING_DIMENSIONS must be a positive number or 'auto'")
+	}
+
+	if c.Embeddings.MaxDimensions <= 0 {
+		return fmt.Errorf("MAX_EMBEDDING_DIM must be greater than 0")
+	}
+
+	if c.Embeddings.Dimensions > c.Embeddings.MaxDimensions {
+		return fmt.Errorf("EMBEDDING_DIMENSIONS (%d) exceeds MAX_EMBEDDING_DIM (%d)", c.Embeddings.Dimensions, c.Embeddings.MaxDimensions)
+	}
+
+	if c.Embeddings.OpenRouterBatchInputs <= 0 {
+		return fmt.Errorf("OPENROUTER_EMBEDDING_BATCH_INPUTS must be greater than 0")
+	}
+
+	if c.Embeddings.OpenRouterBatchTokens < 0 {
+		return fmt.Errorf("OPENROUTER_EMBEDDING_BATCH_TOKENS must be 0 (unbounded) or greater")
+	}
+
+	if c.Embeddings.Timeout < 0 {
+		return fmt.Errorf("EMBEDDING_TIMEOUT must be 0 (no limit) or greater")
+	}
+
+	if c.Embeddings.HealthTTL < 0 {
+		return fmt.Errorf("PROVIDER_HEALTH_TTL must be 0 (no caching) or greater")
+	}
+
+	if c.Embeddings.MaxInputTokens <= 0 {
+		return fmt.Errorf("EMBEDDING_MAX_INPUT_TOKENS must be greater than 0")
+	}
+
+	switch c.Embeddings.QueryTruncation {
+	case "truncate_head", "truncate_tail", "error":
+	default:
+		return fmt.Errorf("EMBEDDING_QUERY_TRUNCATION must be 'truncate_head', 'truncate_tail', or 'error'")
+	}
+
+	switch c.Embeddings.QueryModel {
+	case "stamp", "config":
+	default:
+		return fmt.Errorf("EMBEDDING_QUERY_MODEL must be 'stamp' or 'config'")
+	}
+
+	switch c.Embeddings.InvalidVectors {
+	case "reject", "skip":
+	default:
+		return fmt.Errorf("EMBEDDING_INVALID_VECTORS must be 'reject' or 'skip'")
+	}
+
 	if c.RAG.ChunkSize <= 0 {
 		return fmt.Errorf("CHUNK_SIZE must be greater than 0")
 	}
 
 	if c.RAG.ChunkOverlap < 0 || c.RAG.ChunkOverlap >= c.RAG.ChunkSize {
-		return fmt.Errorf("CHUNK_OVERLAP must be between 0 and CHUNK_SIZE")
+		return fmt.Errorf("CHUNK_OVERLAP must be between 0 and CHUNK_SIZE (both measured in CHUNK_UNIT)")
+	}
+
+	switch c.RAG.ChunkUnit {
+	case "chars", "tokens":
+	default:
+		return fmt.Errorf("CHUNK_UNIT must be 'chars' or 'tokens' (CHUNK_STRATEGY 'char' or 'token')")
+	}
+
+	if c.RAG.RecencyBoost < 0 || c.RAG.RecencyBoost > 1 {
+		return fmt.Errorf("RECENCY_BOOST must be between 0 (off) and 1")
+	}
+
+	if c.RAG.RecencyHalfLife <= 0 {
+		return fmt.Errorf("RECENCY_HALFLIFE must be greater than 0")
 	}
 
 	if c.RAG.MaxContextChunks <= 0 {
 		return fmt.Errorf("MAX_CONTEXT_CHUNKS must be greater than 0")
 	}
 
+	if c.RAG.SearchMinResults < 0 || c.RAG.SearchMaxResults < 0 {
+		return fmt.Errorf("SEARCH_MIN_RESULTS and SEARCH_MAX_RESULTS must be 0 or greater")
+	}
+
+	if c.RAG.SearchMaxResults > 0 && c.RAG.SearchMinResults > c.RAG.SearchMaxResults {
+		return fmt.Errorf("SEARCH_MIN_RESULTS must not exceed SEARCH_MAX_RESULTS")
+	}
+
+	if c.Storage.BadgerGCDiscardRatio <= 0 || c.Storage.BadgerGCDiscardRatio >= 1 {
+		return fmt.Errorf("BADGER_GC_DISCARD_RATIO must be between 0 and 1")
+	}
+
+	if c.Storage.BadgerValueThreshold <= 0 || c.Storage.BadgerValueThreshold > 1<<20 {
+		return fmt.Errorf("BADGER_VALUE_THRESHOLD must be between 1 and 1048576 bytes")
+	}
+
+	if c.Storage.BadgerNumCompactors == 1 || c.Storage.BadgerNumCompactors < 0 {
+		return fmt.Errorf("BADGER_NUM_COMPACTORS must be 0 or at least 2")
+	}
+
+	if c.Storage.MaxConcurrentUploads < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_UPLOADS must be 0 (unlimited) or greater")
+	}
+
+	if c.Storage.UploadsPerTenant < 0 {
+		return fmt.Errorf("UPLOAD_CONCURRENCY_PER_TENANT must be 0 (no per-tenant limit) or greater")
+	}
+
+	if c.Server.RequestTimeout < 0 || c.Server.MaxRequestTimeout < 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT and REQUEST_TIMEOUT_MAX must not be negative")
+	}
+
+	if c.Storage.DeletedRetention < 0 {
+		return fmt.Errorf("DELETED_DOCUMENT_RETENTION must be 0 (keep until purged) or greater")
+	}
+
+	if c.LLM.MaxConcurrency < 0 {
+		return fmt.Errorf("LLM_MAX_CONCURRENCY must be 0 (unlimited) or greater")
+	}
+
+	if c.Server.LogSampleRate < 0 || c.Server.LogSampleRate > 1 {
+		return fmt.Errorf("LOG_SAMPLE_RATE must be between 0 and 1")
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	switch c.Storage.VectorQuantization {
+	case "none", "float32", "int8":
+	default:
+		return fmt.Errorf("VECTOR_QUANTIZATION must be 'none', 'float32' or 'int8'")
+	}
+
+	if c.Storage.VectorCompactRatio < 0 || c.Storage.VectorCompactRatio >= 1 {
+		return fmt.Errorf("VECTOR_COMPACT_RATIO must be 0 (disabled) or between 0 and 1")
+	}
+
+	switch c.Storage.OnDuplicate {
+	case "allow", "skip", "replace", "error":
+	default:
+		return fmt.Errorf("ON_DUPLICATE must be 'allow', 'skip', 'replace' or 'error'")
+	}
+
+	if c.RAG.EmbedFallback != "none" && c.RAG.EmbedFallback != "keyword" {
+		return fmt.Errorf("EMBED_FALLBACK must be 'none' or 'keyword'")
+	}
+
+	switch c.RAG.ChunkHeadings {
+	case "off", "metadata", "embed":
+	default:
+		return fmt.Errorf("CHUNK_HEADINGS must be 'off', 'metadata', or 'embed'")
+	}
+
+	if c.RAG.MaxChunksPerDocument < 0 {
+		return fmt.Errorf("MAX_CHUNKS_PER_DOCUMENT must not be negative")
+	}
+
+	if c.RAG.MaxContextTokens < 0 {
+		return fmt.Errorf("MAX_CONTEXT_TOKENS must be 0 (unlimited) or greater")
+	}
+
+	if c.RAG.ContextMinScore < 0 {
+		return fmt.Errorf("CONTEXT_MIN_SCORE must be 0 (off) or greater")
+	}
+
+	if c.RAG.MinResults < 0 {
+		return fmt.Errorf("MIN_RESULTS must be 0 (off) or greater")
+	}
+
+	switch c.RAG.RerankProvider {
+	case "none":
+	case "ollama":
+		if c.RAG.RerankModel == "" {
+			return fmt.Errorf("RERANK_MODEL is required when RERANK_PROVIDER=ollama")
+		}
+		if c.RAG.RerankCandidates < 1 {
+			return fmt.Errorf("RERANK_CANDIDATES must be at least 1")
+		}
+	default:
+		return fmt.Errorf("RERANK_PROVIDER must be 'none' or 'ollama'")
+	}
+
+	if c.RAG.AnswerCache && c.RAG.AnswerCacheTTL <= 0 {
+		return fmt.Errorf("ANSWER_CACHE_TTL must be positive")
+	}
+
+	if c.RAG.QueryLogEmbeddings && c.RAG.QueryLogEmbeddingsTTL <= 0 {
+		return fmt.Errorf("QUERY_LOG_EMBEDDINGS_TTL must be positive")
+	}
+
+	switch c.RAG.ContextOverflow {
+	case "drop", "summarize":
+	default:
+		return fmt.Errorf("CONTEXT_OVERFLOW must be 'drop' or 'summarize'")
+	}
+
+	if c.RAG.MultiQueryCount < 1 {
+		return fmt.Errorf("MULTI_QUERY_COUNT must be at least 1")
+	}
+
+	switch c.RAG.ScoreNormalization {
+	case "raw", "minmax", "percent":
+	default:
+		return fmt.Errorf("SCORE_NORMALIZATION must be 'raw', 'minmax', or 'percent'")
+	}
+
 	return nil
 }
 
@@ -154,6 +603,32 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getChunkUnit reads CHUNK_UNIT, falling back to CHUNK_STRATEGY, an alias whose values are
+// 'char' and 'token'. CHUNK_UNIT wins when both are set.
+func getChunkUnit() string {
+	if unit := os.Getenv("CHUNK_UNIT"); unit != "" {
+		return unit
+	}
+
+	switch strategy := os.Getenv("CHUNK_STRATEGY"); strategy {
+	case "", "char":
+		return "chars"
+	case "token":
+		return "tokens"
+	default:
+		return strategy
+	}
+}
+
+// getQueryLogging reads QUERY_LOGGING (on/off), falling back to QUERY_LOG_EMBEDDINGS, an
+// alias for it. QUERY_LOGGING wins when both are set.
+func getQueryLogging() bool {
+	if os.Getenv("QUERY_LOGGING") != "" {
+		return getEnvAsBool("QUERY_LOGGING", false)
+	}
+	return getEnvAsBool("QUERY_LOG_EMBEDDINGS", false)
+}
+
 // getEnvAsInt gets an environment variable as an integer with a default value
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
@@ -163,3 +638,74 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsDimensions gets an embedding dimension, where "auto" yields 0 (detect from the provider)
+func getEnvAsDimensions(key string, defaultValue int) int {
+	if strings.EqualFold(os.Getenv(key), "auto") {
+		return 0
+	}
+	return getEnvAsInt(key, defaultValue)
+}
+
+// getEnvAsFloat gets an environment variable as a float with a default value
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsDuration gets an environment variable as a duration (e.g. "30s", "5m") with a default value
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsHeaders parses an environment variable of comma-separated name=value pairs
+// (e.g. "X-Proxy-Auth=abc,X-Team=search") into headers. Malformed pairs are ignored.
+func getEnvAsHeaders(key string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// openRouterHeaders builds the OpenRouter request headers. HTTP-Referer and X-Title attribute
+// traffic in OpenRouter's analytics; the referer is only sent when configured. Extra headers
+// may override either.
+func openRouterHeaders() map[string]string {
+	headers := map[string]string{
+		"X-Title": getEnv("OPENROUTER_APP_TITLE", "go-rag"),
+	}
+	if referer := getEnv("OPENROUTER_APP_URL", ""); referer != "" {
+		headers["HTTP-Referer"] = referer
+	}
+	for name, value := range getEnvAsHeaders("OPENROUTER_EXTRA_HEADERS") {
+		headers[name] = value
+	}
+	return headers
+}
+
+// getEnvAsBool gets an environment variable as a boolean with a default value.
+// Accepts true/false as well as on/off, yes/no and 1/0.
+func getEnvAsBool(key string, defaultValue bool) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "true", "on", "yes", "1":
+		return true
+	case "false", "off", "no", "0":
+		return false
+	}
+	return defaultValue
+}
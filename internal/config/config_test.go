@@ -0,0 +1,123 @@
+package config
+
+import "testing"
+
+func TestChunkStrategyAliasesChunkUnit(t *testing.T) {
+	tests := []struct {
+		unit, strategy string
+		want           string
+	}{
+		{"", "", "chars"},
+		{"", "token", "tokens"},
+		{"", "char", "chars"},
+		{"tokens", "", "tokens"},
+		{"chars", "token", "chars"},
+		{"", "sentence", "sentence"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("CHUNK_UNIT", tt.unit)
+		t.Setenv("CHUNK_STRATEGY", tt.strategy)
+		if got := getChunkUnit(); got != tt.want {
+			t.Errorf("CHUNK_UNIT=%q CHUNK_STRATEGY=%q: unit = %q, want %q", tt.unit, tt.strategy, got, tt.want)
+		}
+	}
+}
+
+func TestQueryLoggingAliases(t *testing.T) {
+	tests := []struct {
+		logging, embeddings string
+		want                bool
+	}{
+		{"", "", false},
+		{"on", "", true},
+		{"off", "", false},
+		{"", "true", true},
+		{"off", "true", false},
+		{"on", "false", true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("QUERY_LOGGING", tt.logging)
+		t.Setenv("QUERY_LOG_EMBEDDINGS", tt.embeddings)
+		if got := getQueryLogging(); got != tt.want {
+			t.Errorf("QUERY_LOGGING=%q QUERY_LOG_EMBEDDINGS=%q: enabled = %v, want %v", tt.logging, tt.embeddings, got, tt.want)
+		}
+	}
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("QUERY_LOGGING", "on")
+	t.Setenv("QUERY_LOG_EMBEDDINGS", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load with QUERY_LOGGING=on failed: %v", err)
+	}
+	if !cfg.RAG.QueryLogEmbeddings {
+		t.Error("QUERY_LOGGING=on did not enable query logging")
+	}
+}
+
+func TestChunkStrategyValidation(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("CHUNK_STRATEGY", "token")
+	t.Setenv("CHUNK_SIZE", "100")
+	t.Setenv("CHUNK_OVERLAP", "20")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load with CHUNK_STRATEGY=token failed: %v", err)
+	}
+	if cfg.RAG.ChunkUnit != "tokens" {
+		t.Errorf("ChunkUnit = %q, want tokens", cfg.RAG.ChunkUnit)
+	}
+
+	// The overlap must stay below the size in tokens
+	t.Setenv("CHUNK_OVERLAP", "100")
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted CHUNK_OVERLAP equal to CHUNK_SIZE")
+	}
+
+	t.Setenv("CHUNK_OVERLAP", "20")
+	t.Setenv("CHUNK_STRATEGY", "sentence")
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted CHUNK_STRATEGY=sentence")
+	}
+}
+
+func TestSplitOversizedDefaultsOff(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("CHUNK_SPLIT_OVERSIZED", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.RAG.SplitOversized {
+		t.Error("CHUNK_SPLIT_OVERSIZED defaults to on, want off so existing chunking is unchanged")
+	}
+}
+
+func TestBadgerTuningValidation(t *testing.T) {
+	tests := []struct {
+		threshold, compactors string
+		valid                 bool
+	}{
+		{"1048576", "4", true},
+		{"1024", "0", true},
+		{"0", "4", false},
+		{"2097152", "4", false},
+		{"1024", "1", false},
+		{"1024", "-2", false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("OPENROUTER_API_KEY", "test-key")
+		t.Setenv("BADGER_VALUE_THRESHOLD", tt.threshold)
+		t.Setenv("BADGER_NUM_COMPACTORS", tt.compactors)
+
+		_, err := Load()
+		if (err == nil) != tt.valid {
+			t.Errorf("BADGER_VALUE_THRESHOLD=%s BADGER_NUM_COMPACTORS=%s: err = %v, want valid = %v", tt.threshold, tt.compactors, err, tt.valid)
+		}
+	}
+}
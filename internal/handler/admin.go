@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/maintenance"
+	"github.com/mrkaynak/rag/internal/service/querylog"
+	"github.com/mrkaynak/rag/internal/service/reindex"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/internal/tenant"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// AdminHandler handles administrative maintenance requests
+type AdminHandler struct {
+	logger      *zap.Logger
+	reindexSvc  *reindex.Service
+	gc          *maintenance.GC
+	compactor   *maintenance.Compactor
+	vectorStore *vector.Store
+	queries     *querylog.Log
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(logger *zap.Logger, reindexSvc *reindex.Service, gc *maintenance.GC, compactor *maintenance.Compactor, vectorStore *vector.Store, queries *querylog.Log) *AdminHandler {
+	return &AdminHandler{
+		logger:      logger,
+		reindexSvc:  reindexSvc,
+		gc:          gc,
+		compactor:   compactor,
+		vectorStore: vectorStore,
+		queries:     queries,
+	}
+}
+
+// ReindexAll starts a background reindex of every document (POST /api/v1/admin/reindex-all)
+func (h *AdminHandler) ReindexAll(c *fiber.Ctx) error {
+	status, err := h.reindexSvc.Start()
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	h.logger.Info("reindex job started")
+
+	return c.Status(fiber.StatusAccepted).JSON(status)
+}
+
+// ReindexStatus returns the progress of the current or last reindex job (GET /api/v1/admin/reindex-all)
+func (h *AdminHandler) ReindexStatus(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(h.reindexSvc.Status())
+}
+
+// ReindexStream starts a reindex, or attaches to the running one, and streams its progress
+// as SSE until it finishes (POST /api/v1/reindex/stream)
+func (h *AdminHandler) ReindexStream(c *fiber.Ctx) error {
+	if _, err := h.reindexSvc.Start(); err != nil {
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != fiber.StatusConflict {
+			return h.sendError(c, err)
+		}
+		h.logger.Info("attaching to running reindex job")
+	} else {
+		h.logger.Info("reindex job started")
+	}
+
+	setSSEHeaders(c)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		for {
+			// Subscribe before reading the status so no update is missed
+			changes := h.reindexSvc.Changes()
+			status := h.reindexSvc.Status()
+
+			if status.State != reindex.StateRunning {
+				writeEvent(w, map[string]interface{}{
+					"type":   "done",
+					"status": status,
+				})
+				return
+			}
+
+			err := writeEvent(w, map[string]interface{}{
+				"type":                "progress",
+				"total_documents":     status.TotalDocuments,
+				"processed_documents": status.ProcessedDocuments,
+				"failed_documents":    status.FailedDocuments,
+				"chunks":              status.Chunks,
+				"errors":              status.Errors,
+			})
+			if err != nil {
+				// Client went away; the job keeps running in the background
+				return
+			}
+
+			<-changes
+		}
+	})
+
+	return nil
+}
+
+// RunGC triggers BadgerDB value-log garbage collection (POST /api/v1/admin/gc)
+func (h *AdminHandler) RunGC(c *fiber.Ctx) error {
+	result, err := h.gc.Run()
+	if err != nil {
+		h.logger.Error("badger GC failed", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to run garbage collection"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// Compact purges soft-deleted documents and rewrites the vector store file without stale
+// entries (POST /api/v1/admin/compact)
+func (h *AdminHandler) Compact(c *fiber.Ctx) error {
+	result, err := h.compactor.Compact()
+	if err != nil {
+		h.logger.Error("vector store compaction failed", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to compact vector store"))
+	}
+
+	h.logger.Info("vector store compacted",
+		zap.Int("chunks", result.Chunks),
+		zap.Int("purged_chunks", result.PurgedChunks),
+		zap.Int64("reclaimed_bytes", result.ReclaimedBytes),
+	)
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// CompactionStats reports the vector store file size, how much of it a compaction would
+// reclaim and the number of live and soft-deleted chunks (GET /api/v1/admin/compact)
+func (h *AdminHandler) CompactionStats(c *fiber.Ctx) error {
+	stats, err := h.vectorStore.CompactionStats()
+	if err != nil {
+		h.logger.Error("failed to measure vector store", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to measure vector store"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
+
+// ExportChunks streams every chunk of the caller's tenant as NDJSON, one chunk per line
+// (GET /api/v1/admin/export-chunks?include_embeddings=false)
+func (h *AdminHandler) ExportChunks(c *fiber.Ctx) error {
+	includeEmbeddings := c.QueryBool("include_embeddings")
+
+	var chunks []models.Chunk
+	for _, chunk := range h.vectorStore.GetAll() {
+		if tenant.Allows(c.UserContext(), chunk.TenantID) {
+			chunks = append(chunks, chunk)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		if chunks[i].DocID != chunks[j].DocID {
+			return chunks[i].DocID < chunks[j].DocID
+		}
+		return chunks[i].Index < chunks[j].Index
+	})
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="chunks.ndjson"`)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		enc := json.NewEncoder(w)
+		for _, chunk := range chunks {
+			line := models.ExportedChunk{
+				ID:        chunk.ID,
+				DocID:     chunk.DocID,
+				Index:     chunk.Index,
+				Heading:   chunk.Heading,
+				Namespace: chunk.Namespace,
+				Content:   chunk.Content,
+			}
+			if includeEmbeddings {
+				line.Embedding = chunk.Embedding
+			}
+
+			// Encode writes a trailing newline after each object
+			if err := enc.Encode(line); err != nil {
+				h.logger.Warn("chunk export aborted", zap.Error(err))
+				return
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			h.logger.Warn("chunk export aborted", zap.Error(err))
+			return
+		}
+
+		h.logger.Info("chunks exported", zap.Int("chunks", len(chunks)))
+	})
+
+	return nil
+}
+
+// ExportQueries streams the anonymized queries recorded with their embeddings
+// (QUERY_LOGGING) as NDJSON, one query per line, oldest first (GET /api/v1/admin/queries?since=&until=, RFC 3339 bounds)
+func (h *AdminHandler) ExportQueries(c *fiber.Ctx) error {
+	if !h.queries.EmbeddingsEnabled() {
+		return h.sendError(c, errors.NotFound("query embedding logging is disabled (QUERY_LOGGING)"))
+	}
+
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	until, err := parseTimeQuery(c, "until")
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="query-embeddings.ndjson"`)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		enc := json.NewEncoder(w)
+		exported := 0
+
+		err := h.queries.EachEmbedding(since, until, func(record models.QueryLogEmbedding) error {
+			exported++
+			// Encode writes a trailing newline after each object
+			return enc.Encode(record)
+		})
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			h.logger.Warn("query export aborted", zap.Int("exported", exported), zap.Error(err))
+			return
+		}
+
+		h.logger.Info("queries exported", zap.Int("queries", exported))
+	})
+
+	return nil
+}
+
+// sendError sends an error response
+func (h *AdminHandler) sendError(c *fiber.Ctx, err error) error {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.Internal("internal server error")
+	}
+
+	return c.Status(appErr.Code).JSON(models.ErrorResponse{
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
+	})
+}
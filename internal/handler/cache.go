@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/answercache"
+	"go.uber.org/zap"
+)
+
+// replayChunkWords is how many words each chunk event of a replayed cached answer holds
+const replayChunkWords = 8
+
+// answerCacheKey keys a prepared chat's answer on the user message sent to the LLM (with any
+// message suffix), prompt, context chunks, model and the store version it was retrieved at
+func answerCacheKey(req models.ChatRequest, pc *preparedChat) string {
+	chunkIDs := make([]string, len(pc.results))
+	for i, result := range pc.results {
+		chunkIDs[i] = result.Chunk.ID
+	}
+
+	return answercache.Key(answercache.KeyParts{
+		Query:        pc.userMessage,
+		SystemPrompt: pc.systemPrompt,
+		ChunkIDs:     chunkIDs,
+		Provider:     req.Provider,
+		Model:        req.Model,
+		MaxTokens:    pc.opts.MaxTokens,
+		Stop:         pc.opts.Stop,
+		StoreVersion: pc.storeVersion,
+	})
+}
+
+// cachedAnswer returns the cached answer to a prepared chat (ANSWER_CACHE). Cache failures
+// are logged and treated as a miss.
+func (h *ChatHandler) cachedAnswer(req models.ChatRequest, pc *preparedChat) (string, bool) {
+	if !h.answerCache.Enabled() {
+		return "", false
+	}
+
+	answer, ok, err := h.answerCache.Get(answerCacheKey(req, pc))
+	if err != nil {
+		h.logger.Warn("failed to read answer cache", zap.Error(err))
+		return "", false
+	}
+	if ok {
+		h.logger.Info("answered from cache", zap.String("provider", req.Provider), zap.String("model", req.Model))
+	}
+	return answer, ok
+}
+
+// cacheAnswer stores the filtered answer to a prepared chat (ANSWER_CACHE)
+func (h *ChatHandler) cacheAnswer(req models.ChatRequest, pc *preparedChat, answer string) {
+	if !h.answerCache.Enabled() || answer == "" {
+		return
+	}
+
+	if err := h.answerCache.Set(answerCacheKey(req, pc), answer); err != nil {
+		h.logger.Warn("failed to cache answer", zap.Error(err))
+	}
+}
+
+// writeReplay streams a cached answer as chunk events of a few words each, like a live answer
+func writeReplay(format streamFormat, w *bufio.Writer, answer string) {
+	words := strings.SplitAfter(answer, " ")
+	for i := 0; i < len(words); i += replayChunkWords {
+		chunk := strings.Join(words[i:min(i+replayChunkWords, len(words))], "")
+		if err := format.write(w, map[string]interface{}{
+			"type": "chunk",
+			"text": chunk,
+		}); err != nil {
+			return
+		}
+	}
+}
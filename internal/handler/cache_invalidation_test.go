@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/answercache"
+	"github.com/mrkaynak/rag/internal/service/reindex"
+)
+
+// corpusApp serves /chat with the answer cache on and /documents/text over an empty store
+func corpusApp(t *testing.T) (*fiber.App, *testEnv, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+
+	cache, err := answercache.New(env.db, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create answer cache: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, cache).Chat)
+	app.Post("/documents/text", env.uploadHandler().UploadText)
+
+	return app, env, stub
+}
+
+func TestChatAnswerCacheBypassedAfterUpload(t *testing.T) {
+	app, _, stub := corpusApp(t)
+	const question = `{"message": "What is RAG?", "provider": "openrouter"}`
+
+	// Answered and cached without any context, then served from the cache
+	if first := chat(t, app, question); first.Cached || len(first.Context) != 0 {
+		t.Fatalf("first request: cached = %v, context = %v; want a miss over an empty store", first.Cached, first.Context)
+	}
+	if second := chat(t, app, question); !second.Cached {
+		t.Fatal("repeated request over an unchanged store was not served from the cache")
+	}
+	calls := stub.calls()
+
+	if status, resp := uploadText(t, app, "rag.txt", "RAG combines retrieval with generation."); status != fiber.StatusCreated || resp.ChunkCount == 0 {
+		t.Fatalf("upload = %d %+v", status, resp)
+	}
+
+	resp := chat(t, app, question)
+	if resp.Cached || stub.calls() != calls+1 {
+		t.Fatalf("request after the upload: cached = %v, LLM calls = %d; want a fresh answer", resp.Cached, stub.calls()-calls)
+	}
+	if len(resp.Context) != 1 || !strings.Contains(resp.Context[0], "RAG combines retrieval") {
+		t.Fatalf("context after the upload = %v, want the new chunk", resp.Context)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if !strings.Contains(stub.lastSystemPrompt+stub.lastUserMessage, "RAG combines retrieval with generation.") {
+		t.Error("the new chunk was not sent to the model")
+	}
+}
+
+func TestChatAnswerCacheInvalidatedByReindex(t *testing.T) {
+	app, env, stub := corpusApp(t)
+	const question = `{"message": "What is RAG?", "provider": "openrouter"}`
+
+	// A chunk without document metadata is carried over by the reindex with its ID, so only
+	// the store version tells the cached answer apart
+	content := "RAG combines retrieval with generation."
+	if err := env.vectorStore.Add([]models.Chunk{{ID: "orphan-0", DocID: "orphan", Content: content, Embedding: stubEmbedding(content)}}); err != nil {
+		t.Fatalf("failed to add chunk: %v", err)
+	}
+
+	chat(t, app, question)
+	if resp := chat(t, app, question); !resp.Cached {
+		t.Fatal("repeated request was not served from the cache")
+	}
+	calls := stub.calls()
+
+	// The reindex swaps the store contents through Replace
+	if _, err := env.reindexSvc.Start(); err != nil {
+		t.Fatalf("failed to start reindex: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for env.reindexSvc.Running() {
+		if time.Now().After(deadline) {
+			t.Fatal("reindex did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := env.reindexSvc.Status(); status.State != reindex.StateCompleted {
+		t.Fatalf("reindex status = %+v, want completed", status)
+	}
+
+	resp := chat(t, app, question)
+	if resp.Cached || stub.calls() != calls+1 {
+		t.Fatalf("request after the reindex: cached = %v, LLM calls = %d; want a fresh answer", resp.Cached, stub.calls()-calls)
+	}
+	if len(resp.Context) != 1 || resp.Context[0] != content {
+		t.Errorf("context after the reindex = %v, want the carried-over chunk", resp.Context)
+	}
+}
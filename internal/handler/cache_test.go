@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/vector"
+)
+
+func TestAnswerCacheKeyUsesFinalUserMessage(t *testing.T) {
+	req := models.ChatRequest{Message: "What is RAG?", Provider: "openrouter", Model: "m"}
+	results := []vector.SimilarityResult{{Chunk: models.Chunk{ID: "c1"}}}
+
+	plain := &preparedChat{systemPrompt: "sys", userMessage: "What is RAG?", results: results}
+	suffixed := &preparedChat{systemPrompt: "sys", userMessage: "What is RAG?\n\nAnswer in one sentence.", results: results}
+
+	if answerCacheKey(req, plain) == answerCacheKey(req, suffixed) {
+		t.Error("requests differing only in message suffix share a cached answer")
+	}
+}
+
+func TestAnswerCacheKeyStoreVersion(t *testing.T) {
+	req := models.ChatRequest{Message: "q", Provider: "openrouter", Model: "m"}
+	before := &preparedChat{userMessage: "q", storeVersion: 1}
+	after := &preparedChat{userMessage: "q", storeVersion: 2}
+
+	if answerCacheKey(req, before) == answerCacheKey(req, after) {
+		t.Error("a store mutation did not change the cache key")
+	}
+}
+
+func TestWriteReplay(t *testing.T) {
+	answer := strings.Repeat("word ", 20) + "end."
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeReplay(formatNDJSON, w, answer)
+	w.Flush()
+
+	var replayed strings.Builder
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range lines {
+		var event map[string]string
+		decodeJSON(t, line, &event)
+		if event["type"] != "chunk" {
+			t.Fatalf("event type = %q, want chunk", event["type"])
+		}
+		replayed.WriteString(event["text"])
+	}
+
+	if replayed.String() != answer {
+		t.Errorf("replayed %q, want %q", replayed.String(), answer)
+	}
+	if len(lines) != 3 {
+		t.Errorf("replayed in %d chunks, want 3 of up to %d words", len(lines), replayChunkWords)
+	}
+}
@@ -2,16 +2,27 @@ package handler
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/middleware"
 	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/answercache"
+	"github.com/mrkaynak/rag/internal/service/cleanup"
 	"github.com/mrkaynak/rag/internal/service/embeddings"
 	"github.com/mrkaynak/rag/internal/service/llm"
+	"github.com/mrkaynak/rag/internal/service/querylog"
+	"github.com/mrkaynak/rag/internal/service/redact"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
 	"github.com/mrkaynak/rag/internal/service/settings"
+	"github.com/mrkaynak/rag/internal/service/smalltalk"
 	"github.com/mrkaynak/rag/internal/service/vector"
 	"github.com/mrkaynak/rag/pkg/errors"
 	"github.com/mrkaynak/rag/pkg/tokenizer"
@@ -20,13 +31,19 @@ import (
 
 // ChatHandler handles chat requests
 type ChatHandler struct {
-	cfg             *config.Config
-	logger          *zap.Logger
-	vectorStore     *vector.Store
-	embeddingsSvc   *embeddings.Service
+	cfg              *config.Config
+	logger           *zap.Logger
+	vectorStore      *vector.Store
+	embeddingsSvc    *embeddings.Service
 	openRouterClient *llm.OpenRouterClient
 	bedrockClient    *llm.BedrockClient
-	settingsSvc     *settings.Store
+	settingsSvc      *settings.Store
+	retrievalSvc     *retrieval.Service
+	smalltalkSvc     *smalltalk.Classifier
+	redactor         *redact.Redactor
+	cleaner          *cleanup.Cleaner
+	queryLog         *querylog.Log
+	answerCache      *answercache.Cache
 }
 
 // NewChatHandler creates a new chat handler
@@ -38,151 +55,424 @@ func NewChatHandler(
 	openRouterClient *llm.OpenRouterClient,
 	bedrockClient *llm.BedrockClient,
 	settingsSvc *settings.Store,
+	retrievalSvc *retrieval.Service,
+	smalltalkSvc *smalltalk.Classifier,
+	redactor *redact.Redactor,
+	cleaner *cleanup.Cleaner,
+	queryLog *querylog.Log,
+	answerCache *answercache.Cache,
 ) *ChatHandler {
 	return &ChatHandler{
-		cfg:             cfg,
-		logger:          logger,
-		vectorStore:     vectorStore,
-		embeddingsSvc:   embeddingsSvc,
+		cfg:              cfg,
+		logger:           logger,
+		vectorStore:      vectorStore,
+		embeddingsSvc:    embeddingsSvc,
 		openRouterClient: openRouterClient,
 		bedrockClient:    bedrockClient,
-		settingsSvc:     settingsSvc,
+		settingsSvc:      settingsSvc,
+		retrievalSvc:     retrievalSvc,
+		smalltalkSvc:     smalltalkSvc,
+		redactor:         redactor,
+		cleaner:          cleaner,
+		queryLog:         queryLog,
+		answerCache:      answerCache,
 	}
 }
 
 // Chat handles chat requests with RAG
 func (h *ChatHandler) Chat(c *fiber.Ctx) error {
 	var req models.ChatRequest
-	if err := c.BodyParser(&req); err != nil {
-		return h.sendError(c, errors.BadRequest("invalid request body"))
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
 	}
 
-	// Validate request
-	if req.Message == "" {
-		return h.sendError(c, errors.BadRequest("message is required"))
+	// Answer small talk directly, skipping embedding, retrieval and the LLM
+	if reply, ok := h.smalltalkSvc.Match(req.Message); ok && req.Mode != "retrieval" {
+		h.logger.Info("answered small talk without retrieval")
+		return c.Status(fiber.StatusOK).JSON(models.ChatResponse{Message: reply})
 	}
 
-	if req.Provider != "openrouter" && req.Provider != "bedrock" {
-		return h.sendError(c, errors.BadRequest("provider must be 'openrouter' or 'bedrock'"))
+	pc, err := h.prepare(c.UserContext(), &req, true)
+	if err != nil {
+		return h.sendError(c, err)
 	}
 
-	// Get API key from config based on provider
-	var apiKey string
-	switch req.Provider {
-	case "openrouter":
-		apiKey = h.cfg.OpenRouter.APIKey
-	case "bedrock":
-		apiKey = h.cfg.Bedrock.APIKey
+	// Retrieval-only: hand the assembled prompt back for the caller's own LLM
+	if req.Mode == "retrieval" {
+		h.logger.Info("retrieval-only chat request completed",
+			zap.Int("context_chunks", len(pc.results)),
+		)
+		h.recordChat(c.UserContext(), req, pc, "")
+
+		return c.Status(fiber.StatusOK).JSON(models.ChatRetrievalResponse{
+			SystemPrompt:      pc.systemPrompt,
+			UserMessage:       pc.userMessage,
+			Context:           pc.contextTexts,
+			Sources:           h.retrievalSvc.ToRetrievedChunks(pc.results, c.QueryBool("include_embeddings"), c.QueryBool("debug")),
+			UsedContext:       len(pc.contextTexts) > 0,
+			ContextChunkCount: len(pc.contextTexts),
+			Degraded:          retrieval.Degraded(pc.results),
+			LowConfidence:     retrieval.LowConfidence(pc.results),
+		})
 	}
 
-	if apiKey == "" {
-		return h.sendError(c, errors.Unauthorized("API key is not configured for provider: "+req.Provider))
+	// Call LLM, unless the same question was answered over the same context before
+	response, cached := h.cachedAnswer(req, pc)
+	if !cached {
+		response, err = h.complete(c.UserContext(), req.Provider, pc.apiKey, req.Model, pc.systemPrompt, pc.userMessage, pc.opts)
+		if err != nil {
+			h.logger.Error("LLM request failed", zap.Error(err), zap.String("provider", req.Provider))
+			return h.sendError(c, err)
+		}
+
+		response = h.postProcess(response)
+		h.cacheAnswer(req, pc, response)
 	}
+	h.checkGrounding(pc, response)
 
-	h.logger.Info("processing chat request",
+	if middleware.Sampled(c) {
+		h.logSampled(req, pc, response)
+	}
+
+	// Optionally determine which context chunks the answer drew from
+	var usedSources []int
+	if h.cfg.RAG.TrackUsedSources && len(pc.contextTexts) > 0 {
+		usedSources = h.detectUsedSources(c.UserContext(), req.Provider, pc.apiKey, req.Model, pc.contextTexts, response)
+	}
+
+	// Optionally verify the answer's claims against the context with another LLM call
+	var grounding *models.GroundingVerdict
+	if h.cfg.RAG.GroundingCheck && len(pc.contextTexts) > 0 {
+		grounding = h.verifyGrounding(c.UserContext(), req.Provider, pc.apiKey, req.Model, pc.contextTexts, response)
+	}
+
+	// Calculate token metrics
+	inputTokens := tokenizer.CountTokensForMessages(pc.systemPrompt, pc.userMessage, pc.context)
+	outputTokens := tokenizer.EstimateTokens(response)
+	totalTokens := inputTokens + outputTokens
+
+	h.logger.Info("chat request completed",
 		zap.String("provider", req.Provider),
-		zap.String("message", req.Message),
+		zap.Int("context_chunks", len(pc.results)),
+		zap.Int("input_tokens", inputTokens),
+		zap.Int("output_tokens", outputTokens),
+		zap.Int("total_tokens", totalTokens),
 	)
 
-	// Generate embedding for the query
-	queryChunk := models.Chunk{Content: req.Message}
-	chunks, err := h.embeddingsSvc.GenerateEmbeddings([]models.Chunk{queryChunk}, apiKey)
+	h.recordChat(c.UserContext(), req, pc, response)
+
+	return c.Status(fiber.StatusOK).JSON(models.ChatResponse{
+		Message:           response,
+		Context:           pc.contextTexts,
+		Sources:           h.retrievalSvc.ToRetrievedChunks(pc.results, c.QueryBool("include_embeddings"), c.QueryBool("debug")),
+		UsedSources:       usedSources,
+		Grounding:         grounding,
+		UsedContext:       len(pc.contextTexts) > 0,
+		ContextChunkCount: len(pc.contextTexts),
+		TokenMetrics: models.TokenMetrics{
+			InputTokens:  inputTokens,
+			OutputTokens: outputTokens,
+			TotalTokens:  totalTokens,
+		},
+		Degraded:      retrieval.Degraded(pc.results),
+		LowConfidence: retrieval.LowConfidence(pc.results),
+		Cached:        cached,
+	})
+}
+
+// ChatStream handles streaming chat requests with RAG. Events are sent as SSE, or as
+// newline-delimited JSON with ?format=ndjson.
+func (h *ChatHandler) ChatStream(c *fiber.Ctx) error {
+	format, err := parseStreamFormat(c.Query("format"))
 	if err != nil {
-		h.logger.Error("failed to generate query embedding", zap.Error(err))
-		return h.sendError(c, errors.InternalWrap(err, "failed to generate query embedding"))
+		return h.sendError(c, err)
 	}
 
-	queryEmbedding := chunks[0].Embedding
+	var req models.ChatRequest
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
+	}
 
-	// Search for similar chunks
-	results, err := h.vectorStore.Search(queryEmbedding, h.cfg.RAG.MaxContextChunks)
+	if reply, ok := h.smalltalkSvc.Match(req.Message); ok {
+		format.setHeaders(c)
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			format.write(w, map[string]interface{}{
+				"type": "chunk",
+				"text": reply,
+			})
+			format.write(w, map[string]interface{}{
+				"type": "done",
+			})
+		})
+		return nil
+	}
+
+	pc, err := h.prepare(c.UserContext(), &req, true)
 	if err != nil {
-		h.logger.Error("failed to search vector store", zap.Error(err))
-		return h.sendError(c, errors.InternalWrap(err, "failed to search context"))
+		return h.sendError(c, err)
 	}
 
-	// Build context from results
-	var contextParts []string
-	var contextTexts []string
+	h.streamAnswer(c, format, req, pc, "", map[string]interface{}{
+		"type":           "context",
+		"context":        pc.contextTexts,
+		"degraded":       retrieval.Degraded(pc.results),
+		"low_confidence": retrieval.LowConfidence(pc.results),
+	})
 
-	for _, result := range results {
-		// Just append the content without "Context X" labels
-		contextParts = append(contextParts, result.Chunk.Content)
-		contextTexts = append(contextTexts, result.Chunk.Content)
+	return nil
+}
+
+// streamAnswer streams the answer to a prepared chat in format, starting with a sources event
+// and contextEvent, whose context texts are left out unless STREAM_CONTEXT_TEXT. If the
+// stream fails midway, the error event carries the partial answer so far (prior plus what
+// was generated) and a resume token for POST /api/v1/chat/resume. A cached answer
+// (ANSWER_CACHE) is replayed as chunk events instead of calling the LLM.
+func (h *ChatHandler) streamAnswer(c *fiber.Ctx, format streamFormat, req models.ChatRequest, pc *preparedChat, prior string, contextEvent map[string]interface{}) {
+	format.setHeaders(c)
+
+	// The fiber context is recycled once the handler returns, so capture the trace context
+	ctx := c.UserContext()
+	sampled := middleware.Sampled(c)
+
+	sources := h.retrievalSvc.ToSources(pc.results)
+	if !h.cfg.RAG.StreamContextText {
+		delete(contextEvent, "context")
 	}
 
-	context := strings.Join(contextParts, "\n\n---\n\n")
+	// Resumed answers are never cached, since they continue a partial answer
+	var final string
+	var cached bool
+	if prior == "" {
+		final, cached = h.cachedAnswer(req, pc)
+	}
 
-	// Build system prompt (use custom if provided, otherwise try DB, then config default)
-	basePrompt := req.SystemPrompt
-	if basePrompt == "" {
-		// Try to get from DB first
-		if dbPrompt, err := h.settingsSvc.GetDefaultSystemPrompt(); err == nil && dbPrompt.Prompt != "" {
-			basePrompt = dbPrompt.Prompt
-			h.logger.Debug("using system prompt from DB")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// Send sources and context first
+		format.write(w, map[string]interface{}{
+			"type":    "sources",
+			"sources": sources,
+		})
+		format.write(w, contextEvent)
+
+		if cached {
+			writeReplay(format, w, final)
 		} else {
-			// Fallback to config
-			basePrompt = h.cfg.RAG.SystemPrompt
-			h.logger.Debug("using system prompt from config")
+			// With redaction or cleanup on, the answer is buffered and filtered as a whole (a match
+			// can span chunks) and flushed as a single chunk before the done event
+			buffered := h.redactor.Enabled() || h.cleaner.Enabled()
+			var answer strings.Builder
+
+			// Stream LLM response
+			var err error
+			switch req.Provider {
+			case "bedrock":
+				err = h.bedrockClient.ChatStream(ctx, pc.apiKey, req.Model, pc.systemPrompt, pc.userMessage, pc.opts, func(chunk string) error {
+					answer.WriteString(chunk)
+					if buffered {
+						return nil
+					}
+					return format.write(w, map[string]interface{}{
+						"type": "chunk",
+						"text": chunk,
+					})
+				})
+			default:
+				// OpenRouter streaming not implemented yet
+				format.write(w, map[string]interface{}{
+					"type":  "error",
+					"error": "streaming not supported for this provider",
+				})
+				return
+			}
+
+			if err != nil {
+				h.logger.Error("streaming failed", zap.Error(err), zap.Int("partial_length", answer.Len()))
+				message := err.Error()
+				errorCode := errors.CodeInternal
+				if errors.IsDeadlineExceeded(err) {
+					message = errors.ErrDeadlineExceeded.Message
+					errorCode = errors.ErrDeadlineExceeded.ErrorCode
+				} else if appErr, ok := err.(*errors.AppError); ok {
+					errorCode = appErr.ErrorCode
+				}
+
+				event := map[string]interface{}{
+					"type":       "error",
+					"error":      message,
+					"error_code": errorCode,
+				}
+				if partial := prior + answer.String(); partial != "" {
+					// Buffered text was never shown, so it still has to pass the answer filters
+					if buffered {
+						partial = prior + h.postProcess(answer.String())
+					}
+					event["partial"] = partial
+					event["resume_token"] = encodeResumeToken(req, pc.contextTexts)
+				}
+				format.write(w, event)
+				return
+			}
+
+			final = answer.String()
+			if buffered {
+				final = h.postProcess(final)
+				format.write(w, map[string]interface{}{
+					"type": "chunk",
+					"text": final,
+				})
+			}
+
+			if prior == "" {
+				h.cacheAnswer(req, pc, final)
+			}
+		}
+
+		// The grounding verdict covers the whole answer, including a resumed answer's prefix
+		if h.cfg.RAG.GroundingCheck && len(pc.contextTexts) > 0 {
+			if verdict := h.verifyGrounding(ctx, req.Provider, pc.apiKey, req.Model, pc.contextTexts, prior+final); verdict != nil {
+				format.write(w, map[string]interface{}{
+					"type":        "grounding",
+					"grounded":    verdict.Grounded,
+					"unsupported": verdict.Unsupported,
+				})
+			}
 		}
+
+		// Send done event
+		done := map[string]interface{}{
+			"type": "done",
+		}
+		if cached {
+			done["cached"] = true
+		}
+		format.write(w, done)
+
+		h.logger.Info("streaming chat request completed",
+			zap.String("provider", req.Provider),
+			zap.Int("context_chunks", len(pc.results)),
+		)
+
+		h.checkGrounding(pc, prior+final)
+		h.recordChat(ctx, req, pc, prior+final)
+
+		if sampled {
+			h.logSampled(req, pc, final)
+		}
+	})
+}
+
+// ChatDebug returns the assembled prompt without calling the LLM (POST /api/v1/chat/debug)
+func (h *ChatHandler) ChatDebug(c *fiber.Ctx) error {
+	var req models.ChatRequest
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
 	}
-	systemPrompt := h.buildSystemPrompt(basePrompt, context)
 
-	// Call LLM
-	var response string
-	switch req.Provider {
-	case "openrouter":
-		response, err = h.openRouterClient.Chat(apiKey, req.Model, systemPrompt, req.Message)
-	case "bedrock":
-		response, err = h.bedrockClient.Chat(apiKey, req.Model, systemPrompt, req.Message)
-	default:
-		return h.sendError(c, errors.BadRequest("unsupported provider"))
+	pc, err := h.prepare(c.UserContext(), &req, true)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.ChatDebugResponse{
+		SystemPrompt:    pc.systemPrompt,
+		UserMessage:     pc.userMessage,
+		Chunks:          h.retrievalSvc.ToRetrievedChunks(pc.results, c.QueryBool("include_embeddings"), c.QueryBool("debug")),
+		EstimatedTokens: tokenizer.CountTokensForMessages(pc.systemPrompt, pc.userMessage, ""),
+	})
+}
+
+// TestSystemPrompt runs a chat with a candidate system prompt without saving it
+// (POST /api/v1/settings/system-prompts/test)
+func (h *ChatHandler) TestSystemPrompt(c *fiber.Ctx) error {
+	var req models.PromptTestRequest
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
+	}
+
+	prompt := req.Prompt
+	if prompt == "" && req.PromptID != "" {
+		saved, err := h.settingsSvc.GetSystemPrompt(req.PromptID)
+		if err != nil {
+			return h.sendError(c, errors.NotFound("system prompt not found: "+req.PromptID).WithCode(errors.CodePromptNotFound))
+		}
+		prompt = saved.Prompt
+	}
+
+	if prompt == "" {
+		return h.sendError(c, errors.BadRequest("prompt or prompt_id is required"))
 	}
 
+	chatReq := models.ChatRequest{
+		Message:      req.Message,
+		Provider:     req.Provider,
+		Model:        req.Model,
+		SystemPrompt: prompt,
+		Namespaces:   req.Namespaces,
+	}
+
+	useRAG := len(req.Context) == 0 && (req.UseRAG == nil || *req.UseRAG)
+
+	pc, err := h.prepare(c.UserContext(), &chatReq, useRAG)
 	if err != nil {
-		h.logger.Error("LLM request failed", zap.Error(err), zap.String("provider", req.Provider))
 		return h.sendError(c, err)
 	}
 
-	// Calculate token metrics
-	inputTokens := tokenizer.CountTokensForMessages(systemPrompt, req.Message, context)
+	// Sample context stands in for retrieval
+	if len(req.Context) > 0 {
+		pc.contextTexts = req.Context
+		pc.context = h.joinContext(req.Context)
+		pc.systemPrompt = h.buildSystemPrompt(prompt, pc.context)
+	}
+
+	response, err := h.complete(c.UserContext(), chatReq.Provider, pc.apiKey, chatReq.Model, pc.systemPrompt, pc.userMessage, pc.opts)
+	if err != nil {
+		h.logger.Error("LLM request failed", zap.Error(err), zap.String("provider", chatReq.Provider))
+		return h.sendError(c, err)
+	}
+
+	response = h.postProcess(response)
+
+	inputTokens := tokenizer.CountTokensForMessages(pc.systemPrompt, pc.userMessage, pc.context)
 	outputTokens := tokenizer.EstimateTokens(response)
-	totalTokens := inputTokens + outputTokens
 
-	h.logger.Info("chat request completed",
-		zap.String("provider", req.Provider),
-		zap.Int("context_chunks", len(results)),
-		zap.Int("input_tokens", inputTokens),
-		zap.Int("output_tokens", outputTokens),
-		zap.Int("total_tokens", totalTokens),
+	h.logger.Info("system prompt test completed",
+		zap.String("provider", chatReq.Provider),
+		zap.Bool("use_rag", useRAG),
+		zap.Int("context_chunks", len(pc.results)),
 	)
 
-	return c.Status(fiber.StatusOK).JSON(models.ChatResponse{
-		Message: response,
-		Context: contextTexts,
+	return c.Status(fiber.StatusOK).JSON(models.PromptTestResponse{
+		Message:           response,
+		SystemPrompt:      pc.systemPrompt,
+		Context:           pc.contextTexts,
+		ContextChunkCount: len(pc.contextTexts),
 		TokenMetrics: models.TokenMetrics{
 			InputTokens:  inputTokens,
 			OutputTokens: outputTokens,
-			TotalTokens:  totalTokens,
+			TotalTokens:  inputTokens + outputTokens,
 		},
 	})
 }
 
-// ChatStream handles streaming chat requests with RAG
-func (h *ChatHandler) ChatStream(c *fiber.Ctx) error {
-	var req models.ChatRequest
-	if err := c.BodyParser(&req); err != nil {
-		return h.sendError(c, errors.BadRequest("invalid request body"))
-	}
+// preparedChat holds everything assembled for a chat request before the LLM is called
+type preparedChat struct {
+	apiKey       string
+	results      []vector.SimilarityResult
+	contextTexts []string
+	context      string
+	systemPrompt string
+	// userMessage is the message sent to the LLM, including any configured suffix
+	userMessage string
+	opts        llm.Options
+	// storeVersion is the vector store version read before retrieval, for answer cache keys
+	storeVersion uint64
+}
 
-	// Validate request
-	if req.Message == "" {
-		return h.sendError(c, errors.BadRequest("message is required"))
-	}
-
-	if req.Provider != "openrouter" && req.Provider != "bedrock" {
-		return h.sendError(c, errors.BadRequest("provider must be 'openrouter' or 'bedrock'"))
-	}
+// prepare retrieves context (unless retrieve is false) and builds the final system prompt for
+// a request already validated by parseBody
+func (h *ChatHandler) prepare(ctx context.Context, req *models.ChatRequest, retrieve bool) (*preparedChat, error) {
+	// Read before searching, so a mutation during retrieval invalidates the cached answer
+	storeVersion := h.vectorStore.Version()
 
 	// Get API key from config based on provider
 	var apiKey string
@@ -194,45 +484,62 @@ func (h *ChatHandler) ChatStream(c *fiber.Ctx) error {
 	}
 
 	if apiKey == "" {
-		return h.sendError(c, errors.Unauthorized("API key is not configured for provider: "+req.Provider))
+		return nil, errors.Unauthorized("API key is not configured for provider: " + req.Provider).WithCode(errors.CodeProviderNotConfigured)
 	}
 
-	h.logger.Info("processing streaming chat request",
-		zap.String("provider", req.Provider),
-		zap.String("message", req.Message),
-	)
+	model, err := h.resolveModel(req.Provider, req.Model)
+	if err != nil {
+		return nil, err
+	}
+	req.Model = model
 
-	// Generate embedding for the query
-	queryChunk := models.Chunk{Content: req.Message}
-	chunks, err := h.embeddingsSvc.GenerateEmbeddings([]models.Chunk{queryChunk}, apiKey)
+	scope, err := parseScope(req.Namespaces, req.UploadedAfter, req.UploadedBefore)
 	if err != nil {
-		h.logger.Error("failed to generate query embedding", zap.Error(err))
-		return h.sendError(c, errors.InternalWrap(err, "failed to generate query embedding"))
+		return nil, err
 	}
 
-	queryEmbedding := chunks[0].Embedding
+	h.logger.Info("processing chat request",
+		zap.String("provider", req.Provider),
+		zap.String("message", req.Message),
+	)
+
+	// Retrieve relevant chunks (optionally over LLM-generated query variants)
+	var results []vector.SimilarityResult
+	if retrieve {
+		queries := []string{req.Message}
+		if h.cfg.RAG.MultiQueryCount > 1 {
+			queries = append(queries, h.expandQuery(ctx, req.Provider, apiKey, req.Model, req.Message, h.cfg.RAG.MultiQueryCount-1)...)
+		}
 
-	// Search for similar chunks
-	results, err := h.vectorStore.Search(queryEmbedding, h.cfg.RAG.MaxContextChunks)
-	if err != nil {
-		h.logger.Error("failed to search vector store", zap.Error(err))
-		return h.sendError(c, errors.InternalWrap(err, "failed to search context"))
+		results, err = h.retrievalSvc.RetrieveContext(ctx, queries, apiKey, h.cfg.RAG.MaxContextChunks, scope)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	// Enforce MAX_CONTEXT_TOKENS; a summary of what did not fit only goes into the prompt,
+	// since it is not a source of its own
+	results, overflowSummary := h.fitContext(ctx, req, apiKey, results)
+
 	// Build context from results
-	var contextParts []string
 	var contextTexts []string
-
 	for _, result := range results {
-		// Just append the content without "Context X" labels
-		contextParts = append(contextParts, result.Chunk.Content)
 		contextTexts = append(contextTexts, result.Chunk.Content)
 	}
 
-	context := strings.Join(contextParts, "\n\n---\n\n")
+	context := h.joinContext(contextTexts)
+	if overflowSummary != "" {
+		context += contextSeparator + overflowSummary
+	}
 
-	// Build system prompt (use custom if provided, otherwise try DB, then config default)
+	// Build system prompt (use custom if provided, otherwise the namespace default, the DB default, then config)
 	basePrompt := req.SystemPrompt
+	if basePrompt == "" && len(scope.Namespaces) == 1 {
+		if nsPrompt, err := h.settingsSvc.GetNamespacePrompt(scope.Namespaces[0]); err == nil && nsPrompt.Prompt != "" {
+			basePrompt = nsPrompt.Prompt
+			h.logger.Debug("using namespace system prompt", zap.String("namespace", nsPrompt.Namespace))
+		}
+	}
 	if basePrompt == "" {
 		// Try to get from DB first
 		if dbPrompt, err := h.settingsSvc.GetDefaultSystemPrompt(); err == nil && dbPrompt.Prompt != "" {
@@ -244,95 +551,302 @@ func (h *ChatHandler) ChatStream(c *fiber.Ctx) error {
 			h.logger.Debug("using system prompt from config")
 		}
 	}
-	systemPrompt := h.buildSystemPrompt(basePrompt, context)
 
-	// Set SSE headers
-	c.Set("Content-Type", "text/event-stream")
-	c.Set("Cache-Control", "no-cache")
-	c.Set("Connection", "keep-alive")
-	c.Set("Transfer-Encoding", "chunked")
+	systemPrompt, maxTokens := applyVerbosity(h.buildSystemPrompt(basePrompt, context), req.Verbosity)
+
+	return &preparedChat{
+		apiKey:       apiKey,
+		results:      results,
+		contextTexts: contextTexts,
+		context:      context,
+		systemPrompt: systemPrompt,
+		userMessage:  h.buildUserMessage(req),
+		opts: llm.Options{
+			Stop:      h.resolveStop(req.Provider, req.Model, req.Stop),
+			MaxTokens: maxTokens,
+		},
+		storeVersion: storeVersion,
+	}, nil
+}
 
-	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		// Send context first
-		contextJSON, _ := json.Marshal(map[string]interface{}{
-			"type":    "context",
-			"context": contextTexts,
-		})
-		fmt.Fprintf(w, "data: %s\n\n", contextJSON)
-		w.Flush()
-
-		// Stream LLM response
-		switch req.Provider {
-		case "bedrock":
-			err = h.bedrockClient.ChatStream(apiKey, req.Model, systemPrompt, req.Message, func(chunk string) error {
-				eventData, _ := json.Marshal(map[string]interface{}{
-					"type": "chunk",
-					"text": chunk,
-				})
-				fmt.Fprintf(w, "data: %s\n\n", eventData)
-				return w.Flush()
-			})
-		default:
-			// OpenRouter streaming not implemented yet
-			eventData, _ := json.Marshal(map[string]interface{}{
-				"type":  "error",
-				"error": "streaming not supported for this provider",
-			})
-			fmt.Fprintf(w, "data: %s\n\n", eventData)
-			w.Flush()
-			return
-		}
+// queryExpansionPrompt instructs the LLM to rephrase a question for retrieval
+const queryExpansionPrompt = `You rewrite search queries. Given a question, produce %d alternative phrasings
+that could match relevant documents. Reply with one phrasing per line and nothing else.`
 
-		if err != nil {
-			h.logger.Error("streaming failed", zap.Error(err))
-			eventData, _ := json.Marshal(map[string]interface{}{
-				"type":  "error",
-				"error": err.Error(),
-			})
-			fmt.Fprintf(w, "data: %s\n\n", eventData)
-			w.Flush()
-			return
+// expandQuery asks the LLM for alternative phrasings of the message.
+// Failures are logged and yield no extra queries.
+func (h *ChatHandler) expandQuery(ctx context.Context, provider, apiKey, model, message string, count int) []string {
+	reply, err := h.complete(ctx, provider, apiKey, model, fmt.Sprintf(queryExpansionPrompt, count), message, llm.Options{})
+	if err != nil {
+		h.logger.Warn("query expansion failed", zap.Error(err))
+		return nil
+	}
+
+	var queries []string
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "-*0123456789. "))
+		if line == "" || line == message {
+			continue
 		}
+		queries = append(queries, line)
+		if len(queries) == count {
+			break
+		}
+	}
 
-		// Send done event
-		doneData, _ := json.Marshal(map[string]interface{}{
-			"type": "done",
-		})
-		fmt.Fprintf(w, "data: %s\n\n", doneData)
-		w.Flush()
+	return queries
+}
 
-		h.logger.Info("streaming chat request completed",
-			zap.String("provider", req.Provider),
-			zap.Int("context_chunks", len(results)),
-		)
-	})
+// contextSeparator joins context chunks in the system prompt
+const contextSeparator = "\n\n---\n\n"
 
-	return nil
+// joinContext joins context chunk texts for the system prompt. With TRACK_USED_SOURCES each
+// chunk is numbered "[n]" so that citation markers in the answer, read back by
+// detectUsedSources, refer to it; otherwise chunks are joined without labels.
+func (h *ChatHandler) joinContext(texts []string) string {
+	if !h.cfg.RAG.TrackUsedSources {
+		return strings.Join(texts, contextSeparator)
+	}
+
+	numbered := make([]string, len(texts))
+	for i, text := range texts {
+		numbered[i] = fmt.Sprintf("[%d] %s", i+1, text)
+	}
+	return strings.Join(numbered, contextSeparator)
 }
 
-// buildSystemPrompt builds the system prompt with context
+// buildSystemPrompt builds the system prompt with context. Under STRICT_GROUNDING the model
+// is told to answer only from the context, or to say it doesn't know when there is none.
 func (h *ChatHandler) buildSystemPrompt(basePrompt, context string) string {
 	if context == "" {
+		if h.cfg.RAG.StrictGrounding {
+			return basePrompt + "\n\n" + groundingNoContext
+		}
 		return basePrompt
 	}
 
+	instruction := "Use this knowledge to answer questions naturally."
+	if h.cfg.RAG.StrictGrounding {
+		instruction = groundingInstruction
+	}
+
 	return fmt.Sprintf(`%s
 
 KNOWLEDGE BASE:
 %s
 
-Use this knowledge to answer questions naturally.`, basePrompt, context)
+%s`, basePrompt, context, instruction)
+}
+
+// buildUserMessage appends the request's message suffix (or the configured default) to the
+// user message. Retrieval always uses the bare message.
+func (h *ChatHandler) buildUserMessage(req *models.ChatRequest) string {
+	suffix := req.MessageSuffix
+	if suffix == "" {
+		suffix = h.cfg.RAG.MessageSuffix
+	}
+
+	suffix = strings.TrimSpace(suffix)
+	if suffix == "" {
+		return req.Message
+	}
+
+	return req.Message + "\n\n" + suffix
+}
+
+// resolveModel picks the model for a request: the requested one, else the provider's default
+// model, else the first saved model for the provider, else the configured default
+func (h *ChatHandler) resolveModel(provider, model string) (string, error) {
+	if model != "" {
+		return model, nil
+	}
+
+	if def, err := h.settingsSvc.GetDefaultModel(provider); err != nil {
+		h.logger.Warn("failed to get default model", zap.String("provider", provider), zap.Error(err))
+	} else if def.ModelID != "" {
+		return def.ModelID, nil
+	}
+
+	saved, err := h.settingsSvc.ListModels(provider)
+	if err != nil {
+		h.logger.Warn("failed to list saved models", zap.String("provider", provider), zap.Error(err))
+	}
+	for _, m := range saved {
+		if m.ModelID != "" {
+			return m.ModelID, nil
+		}
+	}
+
+	switch provider {
+	case "openrouter":
+		model = h.cfg.OpenRouter.Model
+	case "bedrock":
+		model = h.cfg.Bedrock.ModelID
+	}
+
+	if model == "" {
+		return "", errors.BadRequest("no model specified and no default model is configured for provider: " + provider).WithCode(errors.CodeInvalidModel)
+	}
+
+	return model, nil
+}
+
+// resolveStop returns the request's stop sequences, falling back to those saved with the
+// model's settings
+func (h *ChatHandler) resolveStop(provider, model string, stop []string) []string {
+	if len(stop) > 0 {
+		return stop
+	}
+
+	saved, err := h.settingsSvc.ListModels(provider)
+	if err != nil {
+		h.logger.Warn("failed to list saved models", zap.String("provider", provider), zap.Error(err))
+	}
+	for _, m := range saved {
+		if m.ModelID == model {
+			return m.Stop
+		}
+	}
+
+	return nil
+}
+
+// logSampled logs the assembled prompt and the filtered answer of a request sampled for
+// verbose logging (LOG_SAMPLE_RATE)
+func (h *ChatHandler) logSampled(req models.ChatRequest, pc *preparedChat, answer string) {
+	h.logger.Info("sampled chat details",
+		zap.String("provider", req.Provider),
+		zap.String("model", req.Model),
+		zap.String("system_prompt", pc.systemPrompt),
+		zap.String("user_message", pc.userMessage),
+		zap.Int("context_chunks", len(pc.results)),
+		zap.String("answer", answer),
+	)
+}
+
+// recordChat adds a chat request, its context and answer to the query log (QUERY_LOG)
+func (h *ChatHandler) recordChat(ctx context.Context, req models.ChatRequest, pc *preparedChat, answer string) {
+	recordQuery(ctx, h.logger, h.queryLog, models.QueryLogEntry{
+		Endpoint:   "chat",
+		Query:      req.Message,
+		Namespaces: req.Namespaces,
+		Provider:   req.Provider,
+		Model:      req.Model,
+		Answer:     answer,
+		Degraded:   retrieval.Degraded(pc.results),
+	}, pc.results)
+}
+
+// postProcess applies the configured answer filters: citation artifact cleanup, then
+// redaction. Redactions are logged as warnings.
+func (h *ChatHandler) postProcess(answer string) string {
+	answer, cleaned := h.cleaner.Clean(answer)
+	if cleaned > 0 {
+		h.logger.Debug("removed citation artifacts from answer", zap.Int("matches", cleaned))
+	}
+
+	redacted, count := h.redactor.Redact(answer)
+	if count > 0 {
+		h.logger.Warn("redacted sensitive content from answer", zap.Int("matches", count))
+	}
+	return redacted
+}
+
+// complete sends a single non-streaming request to the given provider
+func (h *ChatHandler) complete(ctx context.Context, provider, apiKey, model, systemPrompt, message string, opts llm.Options) (string, error) {
+	switch provider {
+	case "openrouter":
+		return h.openRouterClient.Chat(ctx, apiKey, model, systemPrompt, message, opts)
+	case "bedrock":
+		return h.bedrockClient.Chat(ctx, apiKey, model, systemPrompt, message, opts)
+	default:
+		return "", errors.BadRequest("unsupported provider").WithCode(errors.CodeInvalidProvider)
+	}
+}
+
+// citationMarkerPattern matches inline citation markers such as [1] or [12]
+var citationMarkerPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// usedSourcesPrompt instructs the LLM to report which passages an answer relies on
+const usedSourcesPrompt = `You identify which numbered passages an answer was derived from.
+Reply with a JSON array of passage numbers only, for example [1, 3].
+Reply with [] if the answer does not rely on any passage.`
+
+// detectUsedSources returns the (0-based) indices of the context chunks the answer used.
+// Inline citation markers are preferred; otherwise the LLM is asked directly.
+// Failures are logged and result in no sources being reported.
+func (h *ChatHandler) detectUsedSources(ctx context.Context, provider, apiKey, model string, contextTexts []string, answer string) []int {
+	var markers []int
+	for _, match := range citationMarkerPattern.FindAllStringSubmatch(answer, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			markers = append(markers, n)
+		}
+	}
+	if indices := toSourceIndices(markers, len(contextTexts)); len(indices) > 0 {
+		return indices
+	}
+
+	var builder strings.Builder
+	builder.WriteString("PASSAGES:\n")
+	for i, text := range contextTexts {
+		fmt.Fprintf(&builder, "[%d] %s\n\n", i+1, text)
+	}
+	builder.WriteString("ANSWER:\n")
+	builder.WriteString(answer)
+
+	reply, err := h.complete(ctx, provider, apiKey, model, usedSourcesPrompt, builder.String(), llm.Options{})
+	if err != nil {
+		h.logger.Warn("failed to detect used sources", zap.Error(err))
+		return nil
+	}
+
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		h.logger.Warn("unexpected used sources reply", zap.String("reply", reply))
+		return nil
+	}
+
+	var numbers []int
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &numbers); err != nil {
+		h.logger.Warn("failed to parse used sources reply", zap.Error(err), zap.String("reply", reply))
+		return nil
+	}
+
+	return toSourceIndices(numbers, len(contextTexts))
+}
+
+// toSourceIndices converts 1-based passage numbers into sorted, unique 0-based indices
+func toSourceIndices(numbers []int, count int) []int {
+	seen := make(map[int]bool)
+	var indices []int
+
+	for _, n := range numbers {
+		if n < 1 || n > count || seen[n-1] {
+			continue
+		}
+		seen[n-1] = true
+		indices = append(indices, n-1)
+	}
+
+	sort.Ints(indices)
+	return indices
 }
 
 // sendError sends an error response
 func (h *ChatHandler) sendError(c *fiber.Ctx, err error) error {
+	if errors.IsDeadlineExceeded(err) {
+		err = errors.ErrDeadlineExceeded
+	}
+
 	appErr, ok := err.(*errors.AppError)
 	if !ok {
 		appErr = errors.Internal("internal server error")
 	}
 
 	return c.Status(appErr.Code).JSON(models.ErrorResponse{
-		Error: appErr.Message,
-		Code:  appErr.Code,
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
 	})
 }
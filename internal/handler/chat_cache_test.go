@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/answercache"
+)
+
+// cachedChatApp serves /chat with the answer cache on over one indexed chunk
+func cachedChatApp(t *testing.T) (*fiber.App, *testEnv, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	cache, err := answercache.New(env.db, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create answer cache: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, cache).Chat)
+
+	return app, env, stub
+}
+
+func chat(t *testing.T, app *fiber.App, body string) models.ChatResponse {
+	t.Helper()
+
+	status, resp := doRequest(t, app, http.MethodPost, "/chat", body, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("chat status = %d, body %s", status, resp)
+	}
+
+	var out models.ChatResponse
+	decodeJSON(t, resp, &out)
+	return out
+}
+
+func TestChatAnswerCacheHit(t *testing.T) {
+	app, _, stub := cachedChatApp(t)
+
+	first := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+	if first.Cached || stub.calls() != 1 {
+		t.Fatalf("first request: cached = %v, LLM calls = %d; want a miss", first.Cached, stub.calls())
+	}
+
+	second := chat(t, app, `{"message": "  what is   RAG? ", "provider": "openrouter"}`)
+	if !second.Cached || stub.calls() != 1 {
+		t.Fatalf("repeated request: cached = %v, LLM calls = %d; want a hit", second.Cached, stub.calls())
+	}
+	if second.Message != first.Message {
+		t.Errorf("cached answer = %q, want %q", second.Message, first.Message)
+	}
+}
+
+func TestChatAnswerCacheMissOnSuffix(t *testing.T) {
+	app, _, stub := cachedChatApp(t)
+
+	chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+	resp := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter", "message_suffix": "Answer in French."}`)
+
+	if resp.Cached || stub.calls() != 2 {
+		t.Fatalf("request with a different suffix: cached = %v, LLM calls = %d; want a miss", resp.Cached, stub.calls())
+	}
+}
+
+func TestChatAnswerCacheInvalidatedByUpload(t *testing.T) {
+	app, env, stub := cachedChatApp(t)
+
+	chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+
+	// Any store mutation, even one not retrieved for this question, invalidates the entry
+	env.addChunk(t, "c2", "doc2", "Unrelated content.")
+
+	resp := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+	if resp.Cached || stub.calls() != 2 {
+		t.Fatalf("request after an upload: cached = %v, LLM calls = %d; want a miss", resp.Cached, stub.calls())
+	}
+}
+
+func TestChatAnswerCacheSkipsFailures(t *testing.T) {
+	app, _, stub := cachedChatApp(t)
+
+	stub.reply = func(string, string) (int, string) { return http.StatusInternalServerError, "boom" }
+	if status, _ := doRequest(t, app, http.MethodPost, "/chat", `{"message": "What is RAG?", "provider": "openrouter"}`, nil); status == fiber.StatusOK {
+		t.Fatal("failing LLM call succeeded")
+	}
+
+	stub.reply = nil
+	resp := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+	if resp.Cached {
+		t.Fatal("a failed answer was cached")
+	}
+}
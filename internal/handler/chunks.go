@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/internal/tenant"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultChunkListLimit is the page size used when no limit is given
+	defaultChunkListLimit = 50
+	// maxChunkListLimit caps the page size of chunk listings
+	maxChunkListLimit = 500
+	// chunkSnippetLength is the number of characters of content returned per chunk
+	chunkSnippetLength = 200
+)
+
+// ChunksHandler handles chunk exploration requests
+type ChunksHandler struct {
+	logger      *zap.Logger
+	vectorStore *vector.Store
+}
+
+// NewChunksHandler creates a new chunks handler
+func NewChunksHandler(logger *zap.Logger, vectorStore *vector.Store) *ChunksHandler {
+	return &ChunksHandler{
+		logger:      logger,
+		vectorStore: vectorStore,
+	}
+}
+
+// ListChunks returns indexed chunk metadata filtered by document and content, limited to
+// the caller's tenant (GET /api/v1/chunks)
+func (h *ChunksHandler) ListChunks(c *fiber.Ctx) error {
+	docID := c.Query("doc_id")
+	contains := strings.ToLower(c.Query("contains"))
+
+	limit := c.QueryInt("limit", defaultChunkListLimit)
+	if limit <= 0 || limit > maxChunkListLimit {
+		return h.sendError(c, errors.BadRequest("limit must be between 1 and 500"))
+	}
+
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		return h.sendError(c, errors.BadRequest("offset must not be negative"))
+	}
+
+	var matched []models.Chunk
+	for _, chunk := range h.vectorStore.GetAll() {
+		if !tenant.Allows(c.UserContext(), chunk.TenantID) {
+			continue
+		}
+		if docID != "" && chunk.DocID != docID {
+			continue
+		}
+		if contains != "" && !strings.Contains(strings.ToLower(chunk.Content), contains) {
+			continue
+		}
+		matched = append(matched, chunk)
+	}
+
+	// Stable order so pagination is consistent between requests
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].DocID != matched[j].DocID {
+			return matched[i].DocID < matched[j].DocID
+		}
+		return matched[i].Index < matched[j].Index
+	})
+
+	resp := models.ChunkListResponse{
+		Chunks: []models.ChunkInfo{},
+		Total:  len(matched),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	if offset < len(matched) {
+		end := offset + limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+
+		for _, chunk := range matched[offset:end] {
+			resp.Chunks = append(resp.Chunks, models.ChunkInfo{
+				ID:            chunk.ID,
+				DocID:         chunk.DocID,
+				Index:         chunk.Index,
+				Heading:       chunk.Heading,
+				Snippet:       snippet(chunk.Content, chunkSnippetLength),
+				ContentLength: len([]rune(chunk.Content)),
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// snippet returns the first n characters of text
+func snippet(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+
+	return string(runes[:n]) + "..."
+}
+
+// sendError sends an error response
+func (h *ChunksHandler) sendError(c *fiber.Ctx, err error) error {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.Internal("internal server error")
+	}
+
+	return c.Status(appErr.Code).JSON(models.ErrorResponse{
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
+	})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/service/cleanup"
+)
+
+const artifactAnswer = "Based on the provided context, RAG retrieves documents (Context-1) before answering [1]."
+
+func TestResponseCleanup(t *testing.T) {
+	stub := stubProviders(t)
+	stub.reply = func(_, _ string) (int, string) { return http.StatusOK, artifactAnswer }
+
+	cfg := testConfig(t)
+	cfg.Bedrock.APIKey = "test-key"
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	cleaner, err := cleanup.New("")
+	if err != nil {
+		t.Fatalf("failed to create cleaner: %v", err)
+	}
+	handler := env.chatHandler(t, nil)
+	handler.cleaner = cleaner
+
+	app := fiber.New()
+	app.Post("/chat", handler.Chat)
+	app.Post("/chat/stream", handler.ChatStream)
+
+	const want = "RAG retrieves documents before answering [1]."
+
+	if got := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`).Message; got != want {
+		t.Errorf("chat answer = %q, want %q", got, want)
+	}
+
+	status, body := doRequest(t, app, http.MethodPost, "/chat/stream", `{"message": "What is RAG?", "provider": "bedrock"}`, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("stream status = %d: %s", status, body)
+	}
+	var streamed strings.Builder
+	for _, event := range sseEvents(t, body) {
+		if event["type"] == "chunk" {
+			streamed.WriteString(event["text"].(string))
+		}
+	}
+	if streamed.String() != want {
+		t.Errorf("streamed answer = %q, want %q", streamed.String(), want)
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestChunkContextualizeEmbedsTitleOnly(t *testing.T) {
+	stub := stubProviders(t)
+	var mu sync.Mutex
+	var embedded []string
+	stub.embedding = func(text string) []float64 {
+		mu.Lock()
+		embedded = append(embedded, text)
+		mu.Unlock()
+		return stubEmbedding(text)
+	}
+
+	cfg := testConfig(t)
+	cfg.RAG.ChunkContextualize = true
+	env := newTestEnv(t, cfg)
+
+	app := fiber.New()
+	app.Post("/documents/text", env.uploadHandler().UploadText)
+
+	status, resp := uploadText(t, app, "deployment-guide.md", "Run the installer and restart the service.")
+	if status != fiber.StatusCreated {
+		t.Fatalf("upload status = %d", status)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(embedded) == 0 || !strings.HasPrefix(embedded[0], "Document: deployment-guide\n\n") {
+		t.Errorf("embedded texts = %q, want the document title prepended", embedded)
+	}
+
+	chunks := documentChunks(env, resp.DocumentID)
+	if len(chunks) != 1 {
+		t.Fatalf("chunks = %d, want 1", len(chunks))
+	}
+	if chunks[0].Content != "Run the installer and restart the service." {
+		t.Errorf("stored content = %q, want it without the title", chunks[0].Content)
+	}
+}
+
+func TestChunkContextualizeOff(t *testing.T) {
+	stub := stubProviders(t)
+	var mu sync.Mutex
+	var embedded []string
+	stub.embedding = func(text string) []float64 {
+		mu.Lock()
+		embedded = append(embedded, text)
+		mu.Unlock()
+		return stubEmbedding(text)
+	}
+
+	env := newTestEnv(t, testConfig(t))
+	app := fiber.New()
+	app.Post("/documents/text", env.uploadHandler().UploadText)
+
+	uploadText(t, app, "deployment-guide.md", "Run the installer and restart the service.")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, text := range embedded {
+		if strings.Contains(text, "Document:") {
+			t.Errorf("embedded text %q carries the title with CHUNK_CONTEXTUALIZE off", text)
+		}
+	}
+}
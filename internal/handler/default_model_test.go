@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/settings"
+)
+
+// defaultModelApp serves chat and the default model settings over one indexed chunk
+func defaultModelApp(t *testing.T) (*fiber.App, *settings.Store, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	cfg.OpenRouter.Model = "config/model"
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	store := settings.NewWithDB(env.db, "")
+	settingsHandler := NewSettingsHandler(env.logger, store)
+
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, nil).Chat)
+	app.Delete("/settings/models/:id", settingsHandler.DeleteModel)
+	app.Put("/settings/providers/:provider/default-model", settingsHandler.SetDefaultModel)
+	app.Get("/settings/providers/:provider/default-model", settingsHandler.GetDefaultModel)
+	app.Delete("/settings/providers/:provider/default-model", settingsHandler.ClearDefaultModel)
+
+	return app, store, stub
+}
+
+// saveModel saves a model and returns its settings ID
+func saveModel(t *testing.T, store *settings.Store, provider, modelID string) string {
+	t.Helper()
+
+	id := provider + "-" + strings.ReplaceAll(modelID, "/", "-")
+	model := settings.ModelConfig{ID: id, Provider: provider, ModelID: modelID, DisplayName: modelID}
+	if err := store.SaveModel(model); err != nil {
+		t.Fatalf("failed to save model: %v", err)
+	}
+	return id
+}
+
+// chatModel sends a chat without a model and returns the model the provider was called with
+func chatModel(t *testing.T, app *fiber.App, stub *providerStub) string {
+	t.Helper()
+
+	chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	return stub.lastModel
+}
+
+func TestSetDefaultModel(t *testing.T) {
+	app, store, _ := defaultModelApp(t)
+	id := saveModel(t, store, "openrouter", "anthropic/claude-3.5-sonnet")
+	bedrockID := saveModel(t, store, "bedrock", "openai.gpt-oss-20b-1:0")
+
+	const path = "/settings/providers/openrouter/default-model"
+	if status, body := doRequest(t, app, http.MethodGet, path, "", nil); status != fiber.StatusNotFound {
+		t.Fatalf("default before any is set = %d, want 404: %s", status, body)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		status    int
+		errorCode string
+	}{
+		{name: "saved model", path: path, body: `{"id": "` + id + `"}`, status: fiber.StatusOK},
+		{name: "unknown model", path: path, body: `{"id": "missing"}`, status: fiber.StatusNotFound, errorCode: "MODEL_NOT_FOUND"},
+		{name: "other provider's model", path: path, body: `{"id": "` + bedrockID + `"}`, status: fiber.StatusBadRequest, errorCode: "INVALID_MODEL"},
+		{name: "unknown provider", path: "/settings/providers/acme/default-model", body: `{"id": "` + id + `"}`, status: fiber.StatusBadRequest, errorCode: "INVALID_PROVIDER"},
+		{name: "missing id", path: path, body: `{}`, status: fiber.StatusBadRequest, errorCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doRequest(t, app, http.MethodPut, tt.path, tt.body, nil)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %s", status, tt.status, body)
+			}
+			if tt.errorCode != "" {
+				var errResp models.ErrorResponse
+				decodeJSON(t, body, &errResp)
+				if errResp.ErrorCode != tt.errorCode {
+					t.Errorf("error code = %q, want %q", errResp.ErrorCode, tt.errorCode)
+				}
+			}
+		})
+	}
+
+	// Rejected requests leave the selection alone
+	status, body := doRequest(t, app, http.MethodGet, path, "", nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("get default = %d: %s", status, body)
+	}
+	var got settings.ModelConfig
+	decodeJSON(t, body, &got)
+	if got.ID != id {
+		t.Errorf("default model = %q, want %q", got.ID, id)
+	}
+
+	if status, body := doRequest(t, app, http.MethodGet, "/settings/providers/bedrock/default-model", "", nil); status != fiber.StatusNotFound {
+		t.Errorf("bedrock default = %d, want 404 as defaults are per provider: %s", status, body)
+	}
+}
+
+func TestChatResolvesDefaultModel(t *testing.T) {
+	app, store, stub := defaultModelApp(t)
+	first := saveModel(t, store, "openrouter", "first/model")
+	chosen := saveModel(t, store, "openrouter", "chosen/model")
+	saveModel(t, store, "bedrock", "bedrock/model")
+
+	if status, body := doRequest(t, app, http.MethodPut, "/settings/providers/openrouter/default-model", `{"id": "`+chosen+`"}`, nil); status != fiber.StatusOK {
+		t.Fatalf("set default = %d: %s", status, body)
+	}
+	if got := chatModel(t, app, stub); got != "chosen/model" {
+		t.Errorf("chat model = %q, want the provider's default", got)
+	}
+
+	// A requested model still wins
+	chat(t, app, `{"message": "What is RAG?", "provider": "openrouter", "model": "requested/model"}`)
+	stub.mu.Lock()
+	requested := stub.lastModel
+	stub.mu.Unlock()
+	if requested != "requested/model" {
+		t.Errorf("chat model = %q, want the requested one", requested)
+	}
+
+	// Deleting the default's model falls back to the remaining saved model
+	if status, body := doRequest(t, app, http.MethodDelete, "/settings/models/"+chosen, "", nil); status != fiber.StatusOK {
+		t.Fatalf("delete model = %d: %s", status, body)
+	}
+	if got := chatModel(t, app, stub); got != "first/model" {
+		t.Errorf("chat model after deleting the default = %q, want the saved model", got)
+	}
+
+	// Without saved models the configured default is used
+	if status, body := doRequest(t, app, http.MethodDelete, "/settings/models/"+first, "", nil); status != fiber.StatusOK {
+		t.Fatalf("delete model = %d: %s", status, body)
+	}
+	if got := chatModel(t, app, stub); got != "config/model" {
+		t.Errorf("chat model without saved models = %q, want the configured default", got)
+	}
+}
+
+func TestClearDefaultModel(t *testing.T) {
+	app, store, stub := defaultModelApp(t)
+	id := saveModel(t, store, "openrouter", "chosen/model")
+	saveModel(t, store, "openrouter", "other/model")
+
+	const path = "/settings/providers/openrouter/default-model"
+	doRequest(t, app, http.MethodPut, path, `{"id": "`+id+`"}`, nil)
+
+	if status, body := doRequest(t, app, http.MethodDelete, path, "", nil); status != fiber.StatusOK {
+		t.Fatalf("clear default = %d: %s", status, body)
+	}
+	if status, body := doRequest(t, app, http.MethodGet, path, "", nil); status != fiber.StatusNotFound {
+		t.Errorf("default after clearing = %d, want 404: %s", status, body)
+	}
+
+	// Saved models are listed in ID order, so the first saved model is chosen/model again
+	if got := chatModel(t, app, stub); got != "chosen/model" {
+		t.Errorf("chat model after clearing = %q, want the first saved model", got)
+	}
+}
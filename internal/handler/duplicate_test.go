@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+)
+
+const duplicateContent = "Retrieval-augmented generation grounds answers in documents."
+
+// duplicateApp serves text uploads with the given ON_DUPLICATE policy
+func duplicateApp(t *testing.T, policy string) (*fiber.App, *testEnv, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	cfg.Storage.OnDuplicate = policy
+	env := newTestEnv(t, cfg)
+
+	app := fiber.New()
+	app.Post("/documents/text", env.uploadHandler().UploadText)
+
+	return app, env, stub
+}
+
+func uploadText(t *testing.T, app *fiber.App, fileName, content string) (int, models.UploadResponse) {
+	t.Helper()
+
+	body := `{"filename": "` + fileName + `", "content": "` + content + `"}`
+	status, resp := doRequest(t, app, http.MethodPost, "/documents/text", body, nil)
+
+	var out models.UploadResponse
+	if status < 300 {
+		decodeJSON(t, resp, &out)
+	}
+	return status, out
+}
+
+func documentChunks(env *testEnv, docID string) []models.Chunk {
+	var chunks []models.Chunk
+	for _, chunk := range env.vectorStore.GetAllIncludingDeleted() {
+		if chunk.DocID == docID {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+func TestDuplicateAllow(t *testing.T) {
+	app, env, _ := duplicateApp(t, "allow")
+
+	_, first := uploadText(t, app, "a.txt", duplicateContent)
+	status, second := uploadText(t, app, "a.txt", duplicateContent)
+
+	if status != fiber.StatusCreated || second.Action != models.UploadCreated || second.DocumentID == first.DocumentID {
+		t.Fatalf("second upload = %d %+v, want a new document", status, second)
+	}
+	if docs, _ := env.metadataStore.List(); len(docs) != 2 {
+		t.Errorf("%d documents stored, want 2", len(docs))
+	}
+}
+
+func TestDuplicateSkip(t *testing.T) {
+	app, env, _ := duplicateApp(t, "skip")
+
+	_, first := uploadText(t, app, "a.txt", duplicateContent)
+	status, second := uploadText(t, app, "b.txt", duplicateContent)
+
+	if status != fiber.StatusOK || second.Action != models.UploadSkipped || second.DocumentID != first.DocumentID {
+		t.Fatalf("second upload = %d %+v, want the first document skipped", status, second)
+	}
+	if second.FileName != "a.txt" || second.ChunkCount != first.ChunkCount {
+		t.Errorf("skipped upload reports %+v, want the existing document", second)
+	}
+	if docs, _ := env.metadataStore.List(); len(docs) != 1 {
+		t.Errorf("%d documents stored, want 1", len(docs))
+	}
+}
+
+func TestDuplicateReplace(t *testing.T) {
+	app, env, _ := duplicateApp(t, "replace")
+
+	_, first := uploadText(t, app, "a.txt", duplicateContent)
+	before := documentChunks(env, first.DocumentID)
+
+	status, second := uploadText(t, app, "b.txt", duplicateContent)
+	if status != fiber.StatusOK || second.Action != models.UploadReplaced || second.DocumentID != first.DocumentID {
+		t.Fatalf("second upload = %d %+v, want the first document replaced", status, second)
+	}
+
+	if after := documentChunks(env, first.DocumentID); len(after) != len(before) {
+		t.Errorf("%d chunks after replacing, want %d", len(after), len(before))
+	}
+	if docs, _ := env.metadataStore.List(); len(docs) != 1 || docs[0].FileName != "b.txt" {
+		t.Errorf("documents after replacing = %+v, want one named b.txt", docs)
+	}
+
+	// The original is stored under the new name only
+	uploads := env.cfg.Storage.UploadDir
+	if _, err := os.Stat(filepath.Join(uploads, first.DocumentID+"_b.txt")); err != nil {
+		t.Errorf("replaced original missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(uploads, first.DocumentID+"_a.txt")); !os.IsNotExist(err) {
+		t.Errorf("previous original still stored (err %v)", err)
+	}
+}
+
+func TestDuplicateError(t *testing.T) {
+	app, env, _ := duplicateApp(t, "error")
+
+	_, first := uploadText(t, app, "a.txt", duplicateContent)
+	status, body := doRequest(t, app, http.MethodPost, "/documents/text", `{"filename": "b.txt", "content": "`+duplicateContent+`"}`, nil)
+	if status != fiber.StatusConflict {
+		t.Fatalf("second upload = %d: %s, want 409", status, body)
+	}
+
+	var errResp models.ErrorResponse
+	decodeJSON(t, body, &errResp)
+	if errResp.ErrorCode != "DUPLICATE_DOCUMENT" || !strings.Contains(errResp.Error, first.DocumentID) {
+		t.Errorf("error = %+v, want DUPLICATE_DOCUMENT naming %s", errResp, first.DocumentID)
+	}
+	if docs, _ := env.metadataStore.List(); len(docs) != 1 || docs[0].FileName != "a.txt" {
+		t.Errorf("documents = %+v, want only a.txt", docs)
+	}
+	if chunks := documentChunks(env, first.DocumentID); len(chunks) != first.ChunkCount {
+		t.Errorf("%d chunks after the rejected upload, want %d", len(chunks), first.ChunkCount)
+	}
+
+	// Different content is not a duplicate
+	if status, _ := uploadText(t, app, "c.txt", "Something else entirely."); status != fiber.StatusCreated {
+		t.Errorf("upload of new content = %d, want 201", status)
+	}
+}
+
+func TestDuplicateReplaceFailureKeepsDocument(t *testing.T) {
+	app, env, stub := duplicateApp(t, "replace")
+
+	_, first := uploadText(t, app, "a.txt", duplicateContent)
+	before := documentChunks(env, first.DocumentID)
+
+	// Embeddings of the wrong dimension fail the replacement after processing
+	stub.embedding = func(string) []float64 { return []float64{1, 2, 3, 4} }
+	if status, _ := uploadText(t, app, "b.txt", duplicateContent); status < 400 {
+		t.Fatalf("replacement with failing embeddings returned %d", status)
+	}
+
+	if after := documentChunks(env, first.DocumentID); len(after) != len(before) || after[0].ID != before[0].ID {
+		t.Errorf("chunks after a failed replacement = %d, want the %d original ones", len(after), len(before))
+	}
+	original, err := os.ReadFile(filepath.Join(env.cfg.Storage.UploadDir, first.DocumentID+"_a.txt"))
+	if err != nil || string(original) != duplicateContent {
+		t.Errorf("original after a failed replacement = %q, %v; want it kept", original, err)
+	}
+	if docs, _ := env.metadataStore.List(); len(docs) != 1 || docs[0].FileName != "a.txt" {
+		t.Errorf("documents after a failed replacement = %+v, want a.txt unchanged", docs)
+	}
+}
+
+func TestDuplicateMatchesLegacyNamespace(t *testing.T) {
+	app, env, _ := duplicateApp(t, "skip")
+
+	_, first := uploadText(t, app, "a.txt", duplicateContent)
+
+	// Documents stored before namespaces existed have none
+	if _, err := env.metadataStore.Update(first.DocumentID, func(doc *document.DocumentMetadata) { doc.Namespace = "" }); err != nil {
+		t.Fatalf("failed to clear namespace: %v", err)
+	}
+
+	if _, second := uploadText(t, app, "a.txt", duplicateContent); second.Action != models.UploadSkipped {
+		t.Errorf("upload to the default namespace = %+v, want the legacy document skipped", second)
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+	"go.uber.org/zap"
+)
+
+// EmbedHandler handles requests for raw embedding vectors
+type EmbedHandler struct {
+	cfg           *config.Config
+	logger        *zap.Logger
+	embeddingsSvc *embeddings.Service
+}
+
+// NewEmbedHandler creates a new embed handler
+func NewEmbedHandler(cfg *config.Config, logger *zap.Logger, embeddingsSvc *embeddings.Service) *EmbedHandler {
+	return &EmbedHandler{
+		cfg:           cfg,
+		logger:        logger,
+		embeddingsSvc: embeddingsSvc,
+	}
+}
+
+// Embed returns embeddings for arbitrary texts using the configured provider and model
+// (POST /api/v1/embed). Texts over EMBEDDING_MAX_INPUT_TOKENS are cut to the limit, as
+// uploads are, and listed in the response's truncated field.
+func (h *EmbedHandler) Embed(c *fiber.Ctx) error {
+	var req models.EmbedRequest
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
+	}
+
+	limit := h.cfg.Embeddings.MaxInputTokens
+	var truncated []int
+	chunks := make([]models.Chunk, len(req.Texts))
+	for i, text := range req.Texts {
+		if tokenizer.EstimateTokens(text) > limit {
+			text = tokenizer.TruncateTokens(text, limit, false)
+			truncated = append(truncated, i)
+		}
+		chunks[i] = models.Chunk{Content: text, Index: i}
+	}
+	if len(truncated) > 0 {
+		h.logger.Info("truncated texts for embedding", zap.Ints("texts", truncated), zap.Int("limit", limit))
+	}
+
+	embedded, err := h.embeddingsSvc.GenerateEmbeddings(c.UserContext(), chunks, h.embeddingsSvc.APIKey())
+	if err != nil {
+		h.logger.Error("failed to embed texts", zap.Error(err))
+		return h.sendError(c, err)
+	}
+
+	// With EMBEDDING_INVALID_VECTORS=skip unusable vectors are dropped, which would
+	// misalign the response with the input
+	if len(embedded) != len(chunks) {
+		missing := len(embedded)
+		for i, chunk := range embedded {
+			if chunk.Index != i {
+				missing = i
+				break
+			}
+		}
+		return h.sendError(c, errors.Internal(fmt.Sprintf("provider returned an unusable embedding for texts[%d]", missing)).WithCode(errors.CodeEmbeddingFailed))
+	}
+
+	resp := models.EmbedResponse{
+		Provider:   h.embeddingsSvc.Provider(),
+		Model:      h.embeddingsSvc.Model(),
+		Embeddings: make([][]float64, len(embedded)),
+		Truncated:  truncated,
+	}
+	for i, chunk := range embedded {
+		resp.Embeddings[i] = chunk.Embedding
+	}
+	if len(resp.Embeddings) > 0 {
+		resp.Dimensions = len(resp.Embeddings[0])
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// sendError sends an error response
+func (h *EmbedHandler) sendError(c *fiber.Ctx, err error) error {
+	if errors.IsDeadlineExceeded(err) {
+		err = errors.ErrDeadlineExceeded
+	}
+
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.Internal("internal server error")
+	}
+
+	return c.Status(appErr.Code).JSON(models.ErrorResponse{
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
+	})
+}
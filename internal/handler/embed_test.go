@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+)
+
+func TestEmbedTruncatesToInputLimit(t *testing.T) {
+	stub := stubProviders(t)
+	var mu sync.Mutex
+	var embedded []string
+	stub.embedding = func(text string) []float64 {
+		mu.Lock()
+		embedded = append(embedded, text)
+		mu.Unlock()
+		return stubEmbedding(text)
+	}
+
+	cfg := testConfig(t)
+	cfg.Embeddings.MaxInputTokens = 20
+	env := newTestEnv(t, cfg)
+
+	app := fiber.New()
+	app.Post("/embed", NewEmbedHandler(cfg, env.logger, env.embeddingsSvc).Embed)
+
+	long := strings.Repeat("retrieval augmented generation ", 40)
+	body, _ := json.Marshal(models.EmbedRequest{Texts: []string{"What is RAG?", long}})
+	status, resp := doRequest(t, app, http.MethodPost, "/embed", string(body), nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("embed status = %d: %s", status, resp)
+	}
+
+	var out models.EmbedResponse
+	decodeJSON(t, resp, &out)
+	if len(out.Embeddings) != 2 {
+		t.Fatalf("embeddings = %d, want one per text", len(out.Embeddings))
+	}
+	if !reflect.DeepEqual(out.Truncated, []int{1}) {
+		t.Errorf("truncated = %v, want [1]", out.Truncated)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(embedded) != 2 {
+		t.Fatalf("provider embedded %d texts, want 2", len(embedded))
+	}
+	for _, text := range embedded {
+		if tokens := tokenizer.EstimateTokens(text); tokens > cfg.Embeddings.MaxInputTokens {
+			t.Errorf("provider embedded ~%d tokens, want at most %d", tokens, cfg.Embeddings.MaxInputTokens)
+		}
+		if text != "What is RAG?" && !strings.HasPrefix(long, text) {
+			t.Errorf("embedded %q, want the beginning of the long text", text)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// overrideApp serves text uploads over an index stamped with the default Ollama model
+func overrideApp(t *testing.T) (*fiber.App, *testEnv) {
+	t.Helper()
+
+	stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+
+	app := fiber.New()
+	app.Post("/documents/text", env.uploadHandler().UploadText)
+
+	if status, _ := uploadText(t, app, "first.txt", "The first document stamps the index."); status != fiber.StatusCreated {
+		t.Fatalf("first upload status = %d", status)
+	}
+
+	return app, env
+}
+
+func uploadWithProvider(t *testing.T, app *fiber.App, provider string) (int, models.ErrorResponse) {
+	t.Helper()
+
+	body := `{"filename": "second.txt", "content": "Embedded with an override.", "embedding_provider": "` + provider + `"}`
+	status, resp := doRequest(t, app, http.MethodPost, "/documents/text", body, nil)
+
+	var out models.ErrorResponse
+	if status >= 400 {
+		decodeJSON(t, resp, &out)
+	}
+	return status, out
+}
+
+func TestEmbeddingProviderOverrideRejectsOtherModel(t *testing.T) {
+	app, env := overrideApp(t)
+	env.cfg.Embeddings.OpenRouterModel = "openai/text-embedding-3-small"
+
+	status, resp := uploadWithProvider(t, app, "openrouter")
+	if status != fiber.StatusBadRequest || resp.ErrorCode != errors.CodeModelMismatch {
+		t.Fatalf("override with another model = %d %+v, want 400 %s", status, resp, errors.CodeModelMismatch)
+	}
+}
+
+func TestEmbeddingProviderOverrideRequiresModel(t *testing.T) {
+	app, _ := overrideApp(t)
+
+	status, resp := uploadWithProvider(t, app, "openrouter")
+	if status != fiber.StatusBadRequest || resp.ErrorCode != errors.CodeInvalidModel {
+		t.Fatalf("override without EMBEDDING_MODEL_OPENROUTER = %d %+v, want 400 %s", status, resp, errors.CodeInvalidModel)
+	}
+}
+
+func TestEmbeddingProviderOverrideMatchingStamp(t *testing.T) {
+	app, env := overrideApp(t)
+	stamp := env.vectorStore.Model()
+
+	// After switching EMBEDDING_PROVIDER, the override keeps indexing with the stamped model
+	env.cfg.Embeddings.Provider = "openrouter"
+	env.cfg.Embeddings.Model = "openai/text-embedding-3-small"
+	env.cfg.Embeddings.OllamaModel = stamp.Model
+
+	if status, resp := uploadWithProvider(t, app, "ollama"); status != fiber.StatusCreated {
+		t.Fatalf("override matching the index = %d %+v, want 201", status, resp)
+	}
+	if env.vectorStore.Model() != stamp {
+		t.Errorf("index stamp changed to %+v", env.vectorStore.Model())
+	}
+}
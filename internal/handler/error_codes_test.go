@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+func TestErrorCodes(t *testing.T) {
+	long := strings.Repeat("word ", 200)
+
+	stub := stubProviders(t)
+	stub.reply = func(_, userMessage string) (int, string) {
+		switch {
+		case strings.Contains(userMessage, "rate limit me"):
+			return http.StatusTooManyRequests, `{"error": "slow down"}`
+		case strings.Contains(userMessage, long):
+			// Query variants that are too long as well, so every sub-query fails
+			return http.StatusOK, long + "\n" + long
+		}
+		return http.StatusOK, "answer"
+	}
+
+	cfg := testConfig(t)
+	cfg.Bedrock.APIKey = ""
+	cfg.Embeddings.MaxInputTokens = 50
+	cfg.Embeddings.QueryTruncation = "error"
+	cfg.RAG.MultiQueryCount = 2
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	app := fiber.New()
+	chatHandler := env.chatHandler(t, nil)
+	uploadHandler := env.uploadHandler()
+	app.Post("/chat", chatHandler.Chat)
+	app.Post("/search", NewSearchHandler(cfg, env.logger, env.embeddingsSvc, chatHandler.retrievalSvc, nil).Search)
+	app.Get("/documents/:id", uploadHandler.GetDocument)
+	app.Delete("/documents/:id", uploadHandler.DeleteDocument)
+
+	tests := []struct {
+		name, method, target, body string
+		status                     int
+		code                       string
+	}{
+		{"unknown provider", http.MethodPost, "/chat", `{"message": "hi", "provider": "nope"}`, 400, errors.CodeValidationFailed},
+		{"malformed body", http.MethodPost, "/chat", `{`, 400, errors.CodeInvalidRequest},
+		{"provider without key", http.MethodPost, "/chat", `{"message": "hi", "provider": "bedrock"}`, 401, errors.CodeProviderNotConfigured},
+		{"provider rate limited", http.MethodPost, "/chat", `{"message": "rate limit me", "provider": "openrouter"}`, 429, errors.CodeProviderRateLimited},
+		{"chat message too long to embed", http.MethodPost, "/chat", `{"message": "` + long + `", "provider": "openrouter"}`, 400, errors.CodeInvalidRequest},
+		{"search query too long to embed", http.MethodPost, "/search", `{"query": "` + long + `"}`, 400, errors.CodeInvalidRequest},
+		{"unknown document", http.MethodGet, "/documents/missing", "", 404, errors.CodeDocumentNotFound},
+		{"delete unknown document", http.MethodDelete, "/documents/missing", "", 404, errors.CodeDocumentNotFound},
+	}
+
+	for _, tt := range tests {
+		status, body := doRequest(t, app, tt.method, tt.target, tt.body, nil)
+
+		var resp models.ErrorResponse
+		decodeJSON(t, body, &resp)
+		if status != tt.status || resp.ErrorCode != tt.code {
+			t.Errorf("%s: %d %s (%s), want %d %s", tt.name, status, resp.ErrorCode, resp.Error, tt.status, tt.code)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/service/document"
+)
+
+// listDocuments requests the document listing and returns the status and ETag
+func listDocuments(t *testing.T, app *fiber.App, ifNoneMatch string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, "/documents", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("GET /documents failed: %v", err)
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, resp.Header.Get(fiber.HeaderETag)
+}
+
+func TestListDocumentsETag(t *testing.T) {
+	stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	handler := env.uploadHandler()
+
+	app := fiber.New()
+	app.Get("/documents", handler.ListDocuments)
+	app.Post("/documents/text", handler.UploadText)
+	app.Delete("/documents/:id", handler.DeleteDocument)
+
+	uploadText(t, app, "a.txt", "The first document.")
+	status, etag := listDocuments(t, app, "")
+	if status != fiber.StatusOK || etag == "" {
+		t.Fatalf("listing = %d with ETag %q, want 200 with an ETag", status, etag)
+	}
+
+	if status, got := listDocuments(t, app, etag); status != fiber.StatusNotModified || got != etag {
+		t.Errorf("matching If-None-Match = %d with ETag %q, want 304 with %q", status, got, etag)
+	}
+	if status, _ := listDocuments(t, app, `"other", W/`+etag); status != fiber.StatusNotModified {
+		t.Errorf("weak ETag in a list = %d, want 304", status)
+	}
+
+	// Adding a document changes the ETag, so the old one gets a fresh listing
+	_, added := uploadText(t, app, "b.txt", "The second document.")
+	status, afterAdd := listDocuments(t, app, etag)
+	if status != fiber.StatusOK || afterAdd == etag {
+		t.Fatalf("listing after an upload = %d with ETag %q, want 200 with a new ETag", status, afterAdd)
+	}
+
+	// Updating metadata changes it too
+	if _, err := env.metadataStore.Update(added.DocumentID, func(doc *document.DocumentMetadata) {
+		doc.Tags = []string{"updated"}
+	}); err != nil {
+		t.Fatalf("failed to update document: %v", err)
+	}
+	status, afterUpdate := listDocuments(t, app, afterAdd)
+	if status != fiber.StatusOK || afterUpdate == afterAdd {
+		t.Fatalf("listing after an update = %d with ETag %q, want 200 with a new ETag", status, afterUpdate)
+	}
+
+	// And so does deleting one
+	if status, body := doRequest(t, app, http.MethodDelete, "/documents/"+added.DocumentID, "", nil); status != fiber.StatusOK {
+		t.Fatalf("delete = %d: %s", status, body)
+	}
+	if status, afterDelete := listDocuments(t, app, afterUpdate); status != fiber.StatusOK || afterDelete == afterUpdate {
+		t.Errorf("listing after a delete = %d with ETag %q, want 200 with a new ETag", status, afterDelete)
+	}
+}
+
+func TestDocumentsETagIgnoresOrder(t *testing.T) {
+	a := document.DocumentMetadata{ID: "a", FileName: "a.txt"}
+	b := document.DocumentMetadata{ID: "b", FileName: "b.txt"}
+
+	first, err := documentsETag([]document.DocumentMetadata{a, b})
+	if err != nil {
+		t.Fatalf("documentsETag failed: %v", err)
+	}
+	second, err := documentsETag([]document.DocumentMetadata{b, a})
+	if err != nil {
+		t.Fatalf("documentsETag failed: %v", err)
+	}
+	if first != second {
+		t.Errorf("ETags differ by order: %s and %s", first, second)
+	}
+}
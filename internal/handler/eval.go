@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/querylog"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/internal/tenant"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// EvalHandler exports recorded queries for building evaluation datasets
+type EvalHandler struct {
+	logger   *zap.Logger
+	queryLog *querylog.Log
+}
+
+// NewEvalHandler creates a new eval handler
+func NewEvalHandler(logger *zap.Logger, queryLog *querylog.Log) *EvalHandler {
+	return &EvalHandler{
+		logger:   logger,
+		queryLog: queryLog,
+	}
+}
+
+// Export streams the query log as NDJSON, one query per line, oldest first
+// (GET /api/v1/eval/export?since=&until=, RFC 3339 bounds)
+func (h *EvalHandler) Export(c *fiber.Ctx) error {
+	if !h.queryLog.Enabled() {
+		return h.sendError(c, errors.NotFound("query logging is disabled (QUERY_LOG)"))
+	}
+
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	until, err := parseTimeQuery(c, "until")
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="queries.ndjson"`)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		enc := json.NewEncoder(w)
+		exported := 0
+
+		err := h.queryLog.Each(since, until, func(entry models.QueryLogEntry) error {
+			exported++
+			// Encode writes a trailing newline after each object
+			return enc.Encode(entry)
+		})
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			h.logger.Warn("query log export aborted", zap.Int("exported", exported), zap.Error(err))
+			return
+		}
+
+		h.logger.Info("query log exported", zap.Int("queries", exported))
+	})
+
+	return nil
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter
+func parseTimeQuery(c *fiber.Ctx, name string) (time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, errors.BadRequest(name + " must be an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+// sendError sends an error response
+func (h *EvalHandler) sendError(c *fiber.Ctx, err error) error {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.Internal("internal server error")
+	}
+
+	return c.Status(appErr.Code).JSON(models.ErrorResponse{
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
+	})
+}
+
+// recordQuery adds a query and the results it retrieved to the query log. Failures are only
+// logged, since the query itself succeeded.
+func recordQuery(ctx context.Context, logger *zap.Logger, queryLog *querylog.Log, entry models.QueryLogEntry, results []vector.SimilarityResult) {
+	if !queryLog.Enabled() {
+		return
+	}
+
+	entry.TenantID = tenant.FromContext(ctx)
+	entry.Chunks = make([]models.QueryLogChunk, 0, len(results))
+	for _, result := range results {
+		entry.Chunks = append(entry.Chunks, models.QueryLogChunk{
+			ID:    result.Chunk.ID,
+			DocID: result.Chunk.DocID,
+			Score: result.Similarity,
+		})
+	}
+
+	if err := queryLog.Record(entry); err != nil {
+		logger.Warn("failed to record query", zap.String("endpoint", entry.Endpoint), zap.Error(err))
+	}
+}
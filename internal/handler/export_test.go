@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+func TestExportChunks(t *testing.T) {
+	env := newTestEnv(t, testConfig(t))
+	env.addChunk(t, "b-0", "b", "second document")
+	env.addChunk(t, "a-0", "a", "first document")
+	if err := env.vectorStore.Add([]models.Chunk{{ID: "a-1", DocID: "a", Index: 1, Content: "first document, continued", Embedding: stubEmbedding("first document, continued")}}); err != nil {
+		t.Fatalf("failed to add chunk: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/admin/export-chunks", NewAdminHandler(env.logger, nil, nil, nil, env.vectorStore, nil).ExportChunks)
+
+	for _, withEmbeddings := range []bool{false, true} {
+		target := "/admin/export-chunks"
+		if withEmbeddings {
+			target += "?include_embeddings=true"
+		}
+
+		status, body := doRequest(t, app, http.MethodGet, target, "", nil)
+		if status != fiber.StatusOK {
+			t.Fatalf("GET %s = %d: %s", target, status, body)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+		if len(lines) != len(env.vectorStore.GetAll()) {
+			t.Fatalf("GET %s exported %d lines, want one per stored chunk (%d)", target, len(lines), len(env.vectorStore.GetAll()))
+		}
+
+		var ids []string
+		for i, line := range lines {
+			var chunk models.ExportedChunk
+			if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+				t.Fatalf("line %d does not parse: %v (%q)", i+1, err, line)
+			}
+			if chunk.Content == "" || chunk.DocID == "" {
+				t.Errorf("line %d = %+v, want the chunk's content and document", i+1, chunk)
+			}
+			if withEmbeddings != (len(chunk.Embedding) > 0) {
+				t.Errorf("line %d has %d embedding values with include_embeddings=%v", i+1, len(chunk.Embedding), withEmbeddings)
+			}
+			ids = append(ids, chunk.ID)
+		}
+
+		if got := strings.Join(ids, ","); got != "a-0,a-1,b-0" {
+			t.Errorf("exported chunks = %s, want them ordered by document and index", got)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/llm"
+	"go.uber.org/zap"
+)
+
+// groundingInstruction is appended to the system prompt under STRICT_GROUNDING
+const groundingInstruction = `Answer only from the KNOWLEDGE BASE above. Do not use outside knowledge or make assumptions beyond it. If the knowledge base does not contain the answer, say that you don't know.`
+
+// groundingNoContext replaces the knowledge base under STRICT_GROUNDING when nothing was retrieved
+const groundingNoContext = `No knowledge base passages were found for this question. Do not answer from outside knowledge; say that you don't know.`
+
+// minGroundingOverlap is the share of an answer's content words that should appear in the
+// context; answers below it are logged as possibly ungrounded
+const minGroundingOverlap = 0.5
+
+// minGroundingWords skips the grounding check for short answers such as "I don't know"
+const minGroundingWords = 5
+
+// checkGrounding logs a warning when an answer given under STRICT_GROUNDING shares few of its
+// content words with the context it was answered from. It is a lexical heuristic only.
+func (h *ChatHandler) checkGrounding(pc *preparedChat, answer string) {
+	if !h.cfg.RAG.StrictGrounding || len(pc.contextTexts) == 0 {
+		return
+	}
+
+	words := contentWords(answer)
+	if len(words) < minGroundingWords {
+		return
+	}
+
+	known := make(map[string]bool)
+	for _, text := range pc.contextTexts {
+		for _, word := range contentWords(text) {
+			known[word] = true
+		}
+	}
+
+	matched := 0
+	for _, word := range words {
+		if known[word] {
+			matched++
+		}
+	}
+
+	overlap := float64(matched) / float64(len(words))
+	if overlap < minGroundingOverlap {
+		h.logger.Warn("answer may not be grounded in the context",
+			zap.Float64("overlap", overlap),
+			zap.Int("answer_words", len(words)),
+		)
+	}
+}
+
+// contentWords lowercases text and returns its words of four or more characters, a cheap
+// stand-in for dropping stop words
+func contentWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	words := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) >= 4 {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+// groundingCheckPrompt instructs the LLM to verify an answer's claims against the passages
+const groundingCheckPrompt = `You verify answers against source passages. Check every factual claim in the answer against the passages.
+Reply with a JSON object only, for example {"grounded": false, "unsupported": ["claim not found in the passages"]}.
+Set "grounded" to true only if every claim is supported by the passages, and list each unsupported claim verbatim or closely paraphrased.`
+
+// verifyGrounding asks the LLM whether each claim in the answer is supported by the context
+// (GROUNDING_CHECK). Failures are logged and yield no verdict.
+func (h *ChatHandler) verifyGrounding(ctx context.Context, provider, apiKey, model string, contextTexts []string, answer string) *models.GroundingVerdict {
+	var builder strings.Builder
+	builder.WriteString("PASSAGES:\n")
+	for i, text := range contextTexts {
+		fmt.Fprintf(&builder, "[%d] %s\n\n", i+1, text)
+	}
+	builder.WriteString("ANSWER:\n")
+	builder.WriteString(answer)
+
+	reply, err := h.complete(ctx, provider, apiKey, model, groundingCheckPrompt, builder.String(), llm.Options{})
+	if err != nil {
+		h.logger.Warn("grounding check failed", zap.Error(err))
+		return nil
+	}
+
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		h.logger.Warn("unexpected grounding check reply", zap.String("reply", reply))
+		return nil
+	}
+
+	var verdict models.GroundingVerdict
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &verdict); err != nil {
+		h.logger.Warn("failed to parse grounding check reply", zap.Error(err), zap.String("reply", reply))
+		return nil
+	}
+
+	// A verdict listing unsupported claims is never grounded, whatever the flag says
+	if len(verdict.Unsupported) > 0 {
+		verdict.Grounded = false
+	}
+	if verdict.Unsupported == nil {
+		verdict.Unsupported = []string{}
+	}
+
+	if !verdict.Grounded {
+		h.logger.Info("answer failed grounding check", zap.Int("unsupported_claims", len(verdict.Unsupported)))
+	}
+
+	return &verdict
+}
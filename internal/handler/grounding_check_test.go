@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+const groundedAnswer = "RAG combines retrieval with generation."
+
+// groundingApp serves /chat and /chat/stream with GROUNDING_CHECK on. The stub answers chats
+// with groundedAnswer and grounding checks with verdict.
+func groundingApp(t *testing.T, verdict string) (*fiber.App, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	stub.reply = func(system, _ string) (int, string) {
+		if system == groundingCheckPrompt {
+			return http.StatusOK, verdict
+		}
+		return http.StatusOK, groundedAnswer
+	}
+
+	cfg := testConfig(t)
+	cfg.RAG.GroundingCheck = true
+	cfg.Bedrock.APIKey = "test-key"
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	handler := env.chatHandler(t, nil)
+	app := fiber.New()
+	app.Post("/chat", handler.Chat)
+	app.Post("/chat/stream", handler.ChatStream)
+
+	return app, stub
+}
+
+func TestGroundingCheckVerdict(t *testing.T) {
+	tests := []struct {
+		name    string
+		verdict string
+		want    *models.GroundingVerdict
+	}{
+		{
+			name:    "grounded",
+			verdict: `{"grounded": true, "unsupported": []}`,
+			want:    &models.GroundingVerdict{Grounded: true, Unsupported: []string{}},
+		},
+		{
+			name:    "unsupported claims",
+			verdict: `{"grounded": false, "unsupported": ["RAG was invented in 1950"]}`,
+			want:    &models.GroundingVerdict{Grounded: false, Unsupported: []string{"RAG was invented in 1950"}},
+		},
+		{
+			name:    "unsupported claims override the flag",
+			verdict: `{"grounded": true, "unsupported": ["RAG needs no index"]}`,
+			want:    &models.GroundingVerdict{Grounded: false, Unsupported: []string{"RAG needs no index"}},
+		},
+		{
+			name:    "wrapped in prose",
+			verdict: "Here is the verdict:\n```json\n{\"grounded\": true}\n```",
+			want:    &models.GroundingVerdict{Grounded: true, Unsupported: []string{}},
+		},
+		{
+			name:    "unparseable",
+			verdict: "I cannot verify this answer.",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, stub := groundingApp(t, tt.verdict)
+
+			resp := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+			if resp.Message != groundedAnswer {
+				t.Errorf("answer = %q, want it unchanged by the check", resp.Message)
+			}
+			if !reflect.DeepEqual(resp.Grounding, tt.want) {
+				t.Errorf("grounding = %+v, want %+v", resp.Grounding, tt.want)
+			}
+			if calls := stub.calls(); calls != 2 {
+				t.Errorf("LLM calls = %d, want the answer and the check", calls)
+			}
+
+			stub.mu.Lock()
+			checked := stub.lastUserMessage
+			stub.mu.Unlock()
+			if want := "PASSAGES:\n[1] RAG combines retrieval with generation.\n\nANSWER:\n" + groundedAnswer; checked != want {
+				t.Errorf("grounding check input = %q, want %q", checked, want)
+			}
+		})
+	}
+}
+
+func TestGroundingCheckStreamEvent(t *testing.T) {
+	app, _ := groundingApp(t, `{"grounded": false, "unsupported": ["RAG was invented in 1950"]}`)
+
+	status, body := doRequest(t, app, http.MethodPost, "/chat/stream", `{"message": "What is RAG?", "provider": "bedrock"}`, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("stream status = %d: %s", status, body)
+	}
+
+	events := sseEvents(t, body)
+	if len(events) < 2 || events[len(events)-1]["type"] != "done" {
+		t.Fatalf("events = %v, want them to end with done", events)
+	}
+	grounding := events[len(events)-2]
+	if grounding["type"] != "grounding" || grounding["grounded"] != false {
+		t.Fatalf("event before done = %v, want an ungrounded verdict", grounding)
+	}
+	if unsupported, _ := grounding["unsupported"].([]interface{}); len(unsupported) != 1 || unsupported[0] != "RAG was invented in 1950" {
+		t.Errorf("unsupported = %v, want the claim from the verdict", grounding["unsupported"])
+	}
+}
+
+func TestGroundingCheckOff(t *testing.T) {
+	stub := stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+	if resp := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`); resp.Grounding != nil {
+		t.Errorf("grounding = %+v without GROUNDING_CHECK", resp.Grounding)
+	}
+	if calls := stub.calls(); calls != 1 {
+		t.Errorf("LLM calls = %d, want no grounding check", calls)
+	}
+}
@@ -4,19 +4,25 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/mrkaynak/rag/internal/config"
 	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/vector"
 )
 
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	version string
-	cfg     *config.Config
+	version       string
+	cfg           *config.Config
+	vectorStore   *vector.Store
+	embeddingsSvc *embeddings.Service
 }
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(version string, cfg *config.Config) *HealthHandler {
+func NewHealthHandler(version string, cfg *config.Config, vectorStore *vector.Store, embeddingsSvc *embeddings.Service) *HealthHandler {
 	return &HealthHandler{
-		version: version,
-		cfg:     cfg,
+		version:       version,
+		cfg:           cfg,
+		vectorStore:   vectorStore,
+		embeddingsSvc: embeddingsSvc,
 	}
 }
 
@@ -28,6 +34,35 @@ func (h *HealthHandler) Health(c *fiber.Ctx) error {
 	})
 }
 
+// Ready reports whether the service can handle traffic, returning 503 with the failing
+// checks otherwise (GET /api/v1/ready). The embeddings provider is only checked with
+// EMBEDDING_HEALTH_PRECHECK, using the cached result within PROVIDER_HEALTH_TTL.
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	resp := models.ReadinessResponse{
+		Status: "ready",
+		Checks: map[string]string{"vector_store": "ok"},
+	}
+
+	if err := h.vectorStore.CheckWritable(); err != nil {
+		resp.Status = "not_ready"
+		resp.Checks["vector_store"] = err.Error()
+	}
+
+	if h.cfg.Embeddings.HealthPrecheck {
+		resp.Checks["embeddings"] = "ok"
+		if err := h.embeddingsSvc.CheckHealth(c.UserContext()); err != nil {
+			resp.Status = "not_ready"
+			resp.Checks["embeddings"] = err.Error()
+		}
+	}
+
+	if resp.Status != "ready" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(resp)
+	}
+
+	return c.JSON(resp)
+}
+
 // GetSystemPrompt returns the system prompt from config
 func (h *HealthHandler) GetSystemPrompt(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// readOnlyDir returns a directory without write permission, skipping the test where
+// permissions are not enforced (e.g. when running as root)
+func readOnlyDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatalf("failed to make %s read-only: %v", dir, err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+
+	if probe, err := os.CreateTemp(dir, "probe-*"); err == nil {
+		probe.Close()
+		os.Remove(probe.Name())
+		t.Skip("directory permissions are not enforced for this user")
+	}
+	return dir
+}
+
+func TestReadyProbesVectorStoreWrites(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		{"read-only directory", readOnlyDir},
+		{"missing directory", func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, testConfig(t))
+			app := fiber.New()
+			app.Get("/ready", NewHealthHandler("test", env.cfg, env.vectorStore, env.embeddingsSvc).Ready)
+
+			status, body := doRequest(t, app, http.MethodGet, "/ready", "", nil)
+			var resp models.ReadinessResponse
+			decodeJSON(t, body, &resp)
+			if status != fiber.StatusOK || resp.Checks["vector_store"] != "ok" {
+				t.Fatalf("ready = %d %+v, want ready with a writable store", status, resp)
+			}
+
+			// The store's directory becomes unwritable after startup
+			env.cfg.Storage.VectorStorePath = tt.path(t)
+
+			status, body = doRequest(t, app, http.MethodGet, "/ready", "", nil)
+			resp = models.ReadinessResponse{}
+			decodeJSON(t, body, &resp)
+			if status != fiber.StatusServiceUnavailable || resp.Status != "not_ready" {
+				t.Fatalf("ready = %d %+v, want 503 not_ready", status, resp)
+			}
+			if !strings.Contains(resp.Checks["vector_store"], "not writable") {
+				t.Errorf("vector_store check = %q, want a clear message", resp.Checks["vector_store"])
+			}
+		})
+	}
+}
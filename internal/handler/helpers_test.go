@@ -0,0 +1,399 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/answercache"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/llm"
+	"github.com/mrkaynak/rag/internal/service/maintenance"
+	"github.com/mrkaynak/rag/internal/service/reindex"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
+	"github.com/mrkaynak/rag/internal/service/settings"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"go.uber.org/zap"
+)
+
+// testConfig loads the default configuration with storage under a temporary directory
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	dir := t.TempDir()
+	cfg.Storage.UploadDir = filepath.Join(dir, "uploads")
+	cfg.Storage.VectorStorePath = filepath.Join(dir, "vectors")
+	cfg.Storage.BadgerDBPath = filepath.Join(dir, "badger")
+
+	// Pinned explicitly (the default is auto) so a stub returning another size is rejected; matches stubEmbedding
+	cfg.Embeddings.Dimensions = 3
+
+	return cfg
+}
+
+// openTestDB opens an in-memory BadgerDB closed at the end of the test
+func openTestDB(t *testing.T) *badger.DB {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// testEnv holds the services behind the document handlers
+type testEnv struct {
+	cfg           *config.Config
+	db            *badger.DB
+	logger        *zap.Logger
+	metadataStore *document.MetadataStore
+	docService    *document.Service
+	embeddingsSvc *embeddings.Service
+	vectorStore   *vector.Store
+	reindexSvc    *reindex.Service
+	purger        *maintenance.Purger
+}
+
+// newTestEnv creates the document services over a fresh database
+func newTestEnv(t *testing.T, cfg *config.Config) *testEnv {
+	t.Helper()
+
+	env := &testEnv{cfg: cfg, db: openTestDB(t), logger: zap.NewNop()}
+	env.metadataStore = document.NewMetadataStore(env.db)
+
+	var err error
+	env.docService, err = document.New(cfg, env.logger, env.metadataStore)
+	if err != nil {
+		t.Fatalf("failed to create document service: %v", err)
+	}
+
+	env.embeddingsSvc = embeddings.New(cfg, env.logger)
+
+	env.vectorStore, err = vector.New(cfg, env.db)
+	if err != nil {
+		t.Fatalf("failed to create vector store: %v", err)
+	}
+
+	env.reindexSvc = reindex.New(env.logger, env.docService, env.embeddingsSvc, env.vectorStore, env.metadataStore)
+	env.purger = maintenance.NewPurger(env.metadataStore, env.vectorStore, env.logger, 0)
+
+	return env
+}
+
+// uploadHandler creates an upload handler over the environment's services
+func (env *testEnv) uploadHandler() *UploadHandler {
+	return NewUploadHandler(env.cfg, env.logger, env.docService, env.embeddingsSvc, env.vectorStore, env.metadataStore, env.reindexSvc, env.purger)
+}
+
+// doRequest sends a request to app and returns the status and body
+func doRequest(t *testing.T, app *fiber.App, method, target, body string, headers map[string]string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	return resp.StatusCode, string(data)
+}
+
+// decodeJSON unmarshals a response body, failing the test on error
+func decodeJSON(t *testing.T, body string, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		t.Fatalf("failed to decode %q: %v", body, err)
+	}
+}
+
+// providerStub stands in for the embeddings and LLM providers. It replaces
+// http.DefaultTransport for the duration of the test, so tests using it must not run in
+// parallel.
+type providerStub struct {
+	mu sync.Mutex
+	// embedding returns the embedding of a text; it defaults to stubEmbedding
+	embedding func(text string) []float64
+	// reply answers a chat completion; it defaults to a fixed answer
+	reply func(systemPrompt, userMessage string) (int, string)
+	// usage, when set, is reported as the prompt and completion tokens of chat completions
+	usage *[2]int
+	// llmCalls counts chat completion requests
+	llmCalls int
+	// lastSystemPrompt and lastUserMessage hold the most recent chat completion's messages
+	lastSystemPrompt string
+	lastUserMessage  string
+	// lastModel and lastStop hold the model and stop sequences of the most recent chat
+	// completion
+	lastModel string
+	lastStop  []string
+}
+
+// stubProviders routes all outbound HTTP requests of the test to a providerStub
+func stubProviders(t *testing.T) *providerStub {
+	t.Helper()
+
+	stub := &providerStub{}
+	original := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		stub.ServeHTTP(rec, req)
+		return rec.Result(), nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = original })
+
+	return stub
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// stubEmbedding derives a deterministic, non-zero embedding from text
+func stubEmbedding(text string) []float64 {
+	return []float64{1 + float64(len(text)%7), 1, float64(strings.Count(text, " ")%3) + 0.5}
+}
+
+func (s *providerStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/api/embeddings"):
+		prompt, _ := body["prompt"].(string)
+		s.mu.Lock()
+		embed := s.embedding
+		s.mu.Unlock()
+		if embed == nil {
+			embed = stubEmbedding
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embedding": embed(prompt)})
+
+	case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		var system, user string
+		messages, _ := body["messages"].([]interface{})
+		for _, m := range messages {
+			msg, _ := m.(map[string]interface{})
+			content, _ := msg["content"].(string)
+			if msg["role"] == "system" {
+				system = content
+			} else {
+				user = content
+			}
+		}
+
+		var stop []string
+		sequences, _ := body["stop"].([]interface{})
+		for _, seq := range sequences {
+			text, _ := seq.(string)
+			stop = append(stop, text)
+		}
+		model, _ := body["model"].(string)
+		s.mu.Lock()
+		s.lastModel, s.lastStop = model, stop
+		s.mu.Unlock()
+
+		status, answer := s.answer(system, user)
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, answer)
+			return
+		}
+		resp := map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": answer}}},
+		}
+		s.mu.Lock()
+		if s.usage != nil {
+			resp["usage"] = map[string]int{"prompt_tokens": s.usage[0], "completion_tokens": s.usage[1]}
+		}
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(resp)
+
+	case strings.HasSuffix(r.URL.Path, "/converse"):
+		status, answer := s.answer(bedrockText(body["system"]), bedrockUserText(body["messages"]))
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, answer)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"output": map[string]interface{}{
+				"message": map[string]interface{}{"role": "assistant", "content": []map[string]string{{"text": answer}}},
+			},
+		})
+
+	case strings.HasSuffix(r.URL.Path, "/converse-stream"):
+		// Bedrock streams the answer in small deltas, so filters see it split mid-word
+		status, answer := s.answer(bedrockText(body["system"]), bedrockUserText(body["messages"]))
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, answer)
+			return
+		}
+		for len(answer) > 0 {
+			n := min(len(answer), 7)
+			delta, _ := json.Marshal(map[string]interface{}{"contentBlockDelta": map[string]interface{}{"delta": map[string]string{"text": answer[:n]}}})
+			fmt.Fprintf(w, "data: %s\n\n", delta)
+			answer = answer[n:]
+		}
+		io.WriteString(w, "data: {\"messageStop\":{}}\n\n")
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// answer records a chat completion's messages and returns the stubbed reply
+func (s *providerStub) answer(system, user string) (int, string) {
+	s.mu.Lock()
+	s.llmCalls++
+	s.lastSystemPrompt, s.lastUserMessage = system, user
+	reply := s.reply
+	s.mu.Unlock()
+
+	if reply != nil {
+		return reply(system, user)
+	}
+	return http.StatusOK, "stub answer"
+}
+
+// bedrockText joins the text of Bedrock content blocks
+func bedrockText(blocks interface{}) string {
+	var text strings.Builder
+	list, _ := blocks.([]interface{})
+	for _, block := range list {
+		content, _ := block.(map[string]interface{})
+		part, _ := content["text"].(string)
+		text.WriteString(part)
+	}
+	return text.String()
+}
+
+// bedrockUserText returns the text of the last Bedrock user message
+func bedrockUserText(messages interface{}) string {
+	var user string
+	list, _ := messages.([]interface{})
+	for _, m := range list {
+		msg, _ := m.(map[string]interface{})
+		if msg["role"] == "user" {
+			user = bedrockText(msg["content"])
+		}
+	}
+	return user
+}
+
+// calls returns how many chat completions were requested
+func (s *providerStub) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.llmCalls
+}
+
+// chatHandler creates a chat handler over the environment's services with the optional
+// answer cache
+func (env *testEnv) chatHandler(t *testing.T, answerCache *answercache.Cache) *ChatHandler {
+	t.Helper()
+
+	limiter := llm.NewLimiter(env.cfg.LLM.MaxConcurrency, env.cfg.LLM.QueueTimeout)
+	retrievalSvc := retrieval.New(env.cfg, env.logger, env.embeddingsSvc, env.vectorStore, env.metadataStore, nil, nil)
+
+	return NewChatHandler(
+		env.cfg,
+		env.logger,
+		env.vectorStore,
+		env.embeddingsSvc,
+		llm.NewOpenRouterClient(env.cfg, env.logger, limiter, nil),
+		llm.NewBedrockClient(env.cfg, env.logger, limiter, nil),
+		settings.NewWithDB(env.db, ""),
+		retrievalSvc,
+		nil,
+		nil,
+		nil,
+		nil,
+		answerCache,
+	)
+}
+
+// addChunk indexes a chunk of a document with the stub embedding of its content
+func (env *testEnv) addChunk(t *testing.T, id, docID, content string) {
+	t.Helper()
+
+	if err := env.metadataStore.Add(document.DocumentMetadata{ID: docID, FileName: docID + ".txt"}); err != nil {
+		t.Fatalf("failed to add metadata: %v", err)
+	}
+	if err := env.vectorStore.Add([]models.Chunk{{ID: id, DocID: docID, Content: content, Embedding: stubEmbedding(content)}}); err != nil {
+		t.Fatalf("failed to add chunk: %v", err)
+	}
+}
+
+// sseEvents decodes the "data: " frames of an SSE response body in order
+func sseEvents(t *testing.T, body string) []map[string]interface{} {
+	t.Helper()
+
+	var events []map[string]interface{}
+	for _, frame := range strings.Split(body, "\n\n") {
+		data, ok := strings.CutPrefix(strings.TrimSpace(frame), "data: ")
+		if !ok {
+			continue
+		}
+		var event map[string]interface{}
+		decodeJSON(t, data, &event)
+		events = append(events, event)
+	}
+	return events
+}
+
+// multipartFile returns a multipart form body holding one file and its content type
+func multipartFile(t *testing.T, filename, content string) (string, string) {
+	t.Helper()
+
+	var body strings.Builder
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	io.WriteString(part, content)
+	writer.Close()
+
+	return body.String(), writer.FormDataContentType()
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestChatKeywordFallbackIsDegraded(t *testing.T) {
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.EmbedFallback = "keyword"
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "refunds", "doc1", "Refunds are issued within five business days.")
+	env.addChunk(t, "security", "doc2", "All data is encrypted at rest.")
+
+	// The embeddings provider returns unusable vectors for queries
+	stub.embedding = func(string) []float64 { return []float64{0, 0, 0} }
+
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+	resp := chat(t, app, `{"message": "How long do refunds take?", "provider": "openrouter"}`)
+	if !resp.Degraded {
+		t.Error("chat answered from the keyword fallback is not flagged degraded")
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	prompt := stub.lastSystemPrompt + stub.lastUserMessage
+	if !strings.Contains(prompt, "Refunds are issued within five business days.") {
+		t.Errorf("prompt lacks the passage matching the query: %q", prompt)
+	}
+	if strings.Contains(prompt, "encrypted at rest") {
+		t.Errorf("prompt includes a passage sharing no query terms: %q", prompt)
+	}
+}
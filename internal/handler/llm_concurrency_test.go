@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestChatsBeyondLLMConcurrencyAreSerialized(t *testing.T) {
+	const limit, chats = 2, 6
+
+	stub := stubProviders(t)
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	stub.reply = func(_, _ string) (int, string) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return http.StatusOK, "stub answer"
+	}
+
+	cfg := testConfig(t)
+	cfg.LLM.MaxConcurrency = limit
+	cfg.LLM.QueueTimeout = 10 * time.Second
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+	var wg sync.WaitGroup
+	statuses := make([]int, chats)
+	for i := range statuses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i], _ = doRequest(t, app, http.MethodPost, "/chat", `{"message": "What is RAG?", "provider": "openrouter"}`, nil)
+		}()
+	}
+	wg.Wait()
+
+	for i, status := range statuses {
+		if status != fiber.StatusOK {
+			t.Errorf("chat %d = %d, want it queued and answered", i, status)
+		}
+	}
+	if peak > limit {
+		t.Errorf("%d LLM calls in flight at once, want at most %d", peak, limit)
+	}
+	if stub.calls() < chats {
+		t.Errorf("%d LLM calls, want every chat answered", stub.calls())
+	}
+}
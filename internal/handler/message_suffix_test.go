@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMessageSuffix(t *testing.T) {
+	tests := []struct {
+		name          string
+		configSuffix  string
+		requestSuffix string
+		want          string
+	}{
+		{"from the request", "", "Answer in one sentence.", "Answer in one sentence."},
+		{"configured default", "Answer concisely.", "", "Answer concisely."},
+		{"request overrides the default", "Answer concisely.", "Answer in French.", "Answer in French."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := stubProviders(t)
+			var embedded []string
+			stub.embedding = func(text string) []float64 {
+				stub.mu.Lock()
+				embedded = append(embedded, text)
+				stub.mu.Unlock()
+				return stubEmbedding(text)
+			}
+
+			cfg := testConfig(t)
+			cfg.RAG.MessageSuffix = tt.configSuffix
+			env := newTestEnv(t, cfg)
+			env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+			app := fiber.New()
+			app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+			body := `{"message": "What is RAG?", "provider": "openrouter"`
+			if tt.requestSuffix != "" {
+				body += `, "message_suffix": "` + tt.requestSuffix + `"`
+			}
+			chat(t, app, body+"}")
+
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+
+			if want := "What is RAG?\n\n" + tt.want; stub.lastUserMessage != want {
+				t.Errorf("user message = %q, want %q", stub.lastUserMessage, want)
+			}
+			if len(embedded) == 0 {
+				t.Fatal("the query was not embedded")
+			}
+			for _, text := range embedded {
+				if strings.Contains(text, tt.want) {
+					t.Errorf("embedded query %q includes the suffix", text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// minResultsApp serves /chat and /chat/stream with CONTEXT_MIN_SCORE and MIN_RESULTS over
+// two chunks
+func minResultsApp(t *testing.T, minScore float64, minResults int) (*fiber.App, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.ContextMinScore = minScore
+	cfg.RAG.MinResults = minResults
+	cfg.Bedrock.APIKey = "test-key"
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+	env.addChunk(t, "c2", "doc2", "Vector stores index embeddings")
+
+	handler := env.chatHandler(t, nil)
+	app := fiber.New()
+	app.Post("/chat", handler.Chat)
+	app.Post("/chat/stream", handler.ChatStream)
+	return app, stub
+}
+
+func TestChatMinResultsFallback(t *testing.T) {
+	const question = `{"message": "What is RAG?", "provider": "openrouter"}`
+
+	// The best chunk without any threshold
+	app, _ := minResultsApp(t, 0, 0)
+	unfiltered := chat(t, app, question)
+	if len(unfiltered.Sources) != 2 || unfiltered.LowConfidence {
+		t.Fatalf("sources without CONTEXT_MIN_SCORE = %+v, low confidence = %v", unfiltered.Sources, unfiltered.LowConfidence)
+	}
+	best := unfiltered.Sources[0]
+
+	// No chunk reaches a minimum score above the highest possible similarity
+	app, stub := minResultsApp(t, 1.01, 1)
+	resp := chat(t, app, question)
+	if len(resp.Sources) != 1 || resp.Sources[0].ID != best.ID {
+		t.Fatalf("sources = %+v, want only the best chunk %s", resp.Sources, best.ID)
+	}
+	if !resp.LowConfidence || !resp.Sources[0].LowConfidence || !resp.UsedContext {
+		t.Errorf("response low confidence = %v, source low confidence = %v, used context = %v; want the fallback flagged",
+			resp.LowConfidence, resp.Sources[0].LowConfidence, resp.UsedContext)
+	}
+
+	stub.mu.Lock()
+	prompt := stub.lastSystemPrompt
+	stub.mu.Unlock()
+	if !strings.Contains(prompt, best.Content) {
+		t.Errorf("system prompt = %q, want the fallback chunk as context", prompt)
+	}
+}
+
+func TestChatMinResultsOff(t *testing.T) {
+	app, _ := minResultsApp(t, 1.01, 0)
+
+	resp := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+	if len(resp.Sources) != 0 || resp.UsedContext || resp.LowConfidence {
+		t.Errorf("sources = %+v, used context = %v, low confidence = %v; want no context without MIN_RESULTS",
+			resp.Sources, resp.UsedContext, resp.LowConfidence)
+	}
+}
+
+func TestChatMinScoreKeepsConfidentContext(t *testing.T) {
+	app, _ := minResultsApp(t, 0.0001, 1)
+
+	resp := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+	if len(resp.Sources) == 0 || resp.LowConfidence {
+		t.Errorf("sources = %+v, low confidence = %v; want chunks above the threshold unflagged", resp.Sources, resp.LowConfidence)
+	}
+}
+
+func TestChatStreamMinResultsFallback(t *testing.T) {
+	app, _ := minResultsApp(t, 1.01, 1)
+
+	status, body := doRequest(t, app, http.MethodPost, "/chat/stream", `{"message": "What is RAG?", "provider": "bedrock"}`, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("stream status = %d: %s", status, body)
+	}
+
+	for _, event := range sseEvents(t, body) {
+		if event["type"] == "context" {
+			if event["low_confidence"] != true {
+				t.Errorf("context event = %v, want low_confidence", event)
+			}
+			return
+		}
+	}
+	t.Fatalf("no context event in %s", body)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// namespacePattern restricts namespace names to URL and key friendly characters
+var namespacePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// parseNamespace validates a namespace name, defaulting to models.DefaultNamespace when empty
+func parseNamespace(raw string) (string, error) {
+	ns := strings.TrimSpace(raw)
+	if ns == "" {
+		return models.DefaultNamespace, nil
+	}
+
+	if !namespacePattern.MatchString(ns) {
+		return "", errors.BadRequest("namespace must be 1-64 letters, digits, '-' or '_'")
+	}
+
+	return ns, nil
+}
+
+// parseNamespaces validates a list of namespaces to search, dropping duplicates.
+// An empty list means all namespaces.
+func parseNamespaces(raw []string) ([]string, error) {
+	var namespaces []string
+	seen := make(map[string]bool)
+
+	for _, r := range raw {
+		ns, err := parseNamespace(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	return namespaces, nil
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/service/settings"
+)
+
+func TestNamespaceSystemPrompt(t *testing.T) {
+	stub := stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	env.cfg.RAG.SystemPrompt = "You are the global assistant."
+
+	settingsHandler := NewSettingsHandler(env.logger, settings.NewWithDB(env.db, ""))
+	app := fiber.New()
+	app.Put("/namespaces/:namespace/system-prompt", settingsHandler.SaveNamespacePrompt)
+	app.Get("/namespaces/:namespace/system-prompt", settingsHandler.GetNamespacePrompt)
+	app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+	status, body := doRequest(t, app, http.MethodPut, "/namespaces/billing/system-prompt", `{"prompt": "You are the billing assistant."}`, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("save = %d: %s", status, body)
+	}
+
+	status, body = doRequest(t, app, http.MethodGet, "/namespaces/billing/system-prompt", "", nil)
+	var saved settings.NamespacePrompt
+	decodeJSON(t, body, &saved)
+	if status != fiber.StatusOK || saved.Namespace != "billing" || saved.Prompt != "You are the billing assistant." {
+		t.Fatalf("get = %d %+v, want the saved prompt", status, saved)
+	}
+	if status, _ := doRequest(t, app, http.MethodGet, "/namespaces/support/system-prompt", "", nil); status != fiber.StatusNotFound {
+		t.Errorf("get for a namespace without a prompt = %d, want 404", status)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"namespace default", `{"message": "How do refunds work?", "provider": "openrouter", "namespaces": ["billing"]}`, "You are the billing assistant."},
+		{"other namespace uses the global default", `{"message": "How do refunds work?", "provider": "openrouter", "namespaces": ["support"]}`, "You are the global assistant."},
+		{"request prompt overrides the namespace", `{"message": "How do refunds work?", "provider": "openrouter", "namespaces": ["billing"], "system_prompt": "You are terse."}`, "You are terse."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat(t, app, tt.body)
+
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			if !strings.HasPrefix(stub.lastSystemPrompt, tt.want) {
+				t.Errorf("system prompt = %q, want it to start with %q", stub.lastSystemPrompt, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/llm"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+	"go.uber.org/zap"
+)
+
+// overflowSummaryPrompt instructs the LLM to condense context chunks that did not fit the budget
+const overflowSummaryPrompt = `You condense reference passages. Summarize the passages below into a compact list of
+the facts most useful for answering the question. Use at most %d words. Reply with the summary only.`
+
+// fitContext keeps the highest-scoring results whose text fits MAX_CONTEXT_TOKENS. With
+// CONTEXT_OVERFLOW=summarize the results that did not fit are condensed by an LLM call into
+// a summary that fills the remaining budget; otherwise (or if summarizing fails) they are
+// dropped. results must be sorted by score. The summary is empty when nothing was summarized.
+func (h *ChatHandler) fitContext(ctx context.Context, req *models.ChatRequest, apiKey string, results []vector.SimilarityResult) ([]vector.SimilarityResult, string) {
+	budget := h.cfg.RAG.MaxContextTokens
+	if budget <= 0 {
+		return results, ""
+	}
+
+	// Measured on the context as joined into the prompt: estimates of the parts don't add up
+	// to the estimate of the whole, and numbering adds tokens of its own
+	var texts []string
+	used := 0
+	kept := len(results)
+
+	for i, result := range results {
+		tokens := tokenizer.EstimateTokens(h.joinContext(append(texts, result.Chunk.Content)))
+		if tokens > budget {
+			kept = i
+			break
+		}
+		texts = append(texts, result.Chunk.Content)
+		used = tokens
+	}
+
+	overflow := results[kept:]
+	results = results[:kept]
+	if len(overflow) == 0 {
+		return results, ""
+	}
+
+	// Retrieval-only requests never call the LLM, so they always drop
+	remaining := budget - used - tokenizer.EstimateTokens(contextSeparator)
+	if h.cfg.RAG.ContextOverflow != "summarize" || req.Mode == "retrieval" || remaining <= 0 {
+		h.logger.Info("dropped context chunks over the token budget",
+			zap.Int("dropped", len(overflow)),
+			zap.Int("kept", kept),
+			zap.Int("context_tokens", used),
+			zap.Int("budget", budget),
+		)
+		return results, ""
+	}
+
+	summary, err := h.summarizeOverflow(ctx, req, apiKey, overflow, remaining)
+	if err == nil {
+		summary = fitSummary(h.joinContext(texts), summary, budget, remaining)
+		if summary == "" {
+			err = fmt.Errorf("no room for a summary")
+		}
+	}
+	if err != nil {
+		h.logger.Warn("failed to summarize overflowing context, dropping it",
+			zap.Int("dropped", len(overflow)),
+			zap.Error(err),
+		)
+		return results, ""
+	}
+
+	h.logger.Info("summarized context chunks over the token budget",
+		zap.Int("summarized", len(overflow)),
+		zap.Int("summary_tokens", tokenizer.EstimateTokens(summary)),
+		zap.Int("budget", budget),
+	)
+
+	return results, summary
+}
+
+// fitSummary shortens summary until it fits the budget once appended to context, starting
+// from maxTokens, the room left estimated on its own
+func fitSummary(context, summary string, budget, maxTokens int) string {
+	for summary != "" && tokenizer.EstimateTokens(strings.Join([]string{context, summary}, contextSeparator)) > budget {
+		maxTokens--
+		summary = tokenizer.TruncateTokens(summary, maxTokens, false)
+	}
+	return summary
+}
+
+// summarizeOverflow condenses the overflowing results into at most maxTokens tokens
+func (h *ChatHandler) summarizeOverflow(ctx context.Context, req *models.ChatRequest, apiKey string, overflow []vector.SimilarityResult, maxTokens int) (string, error) {
+	model := h.cfg.RAG.ContextSummaryModel
+	if model == "" {
+		model = req.Model
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "QUESTION:\n%s\n\nPASSAGES:\n", req.Message)
+	for _, result := range overflow {
+		builder.WriteString(result.Chunk.Content)
+		builder.WriteString(contextSeparator)
+	}
+
+	// Words run slightly above one token each, so ask for fewer words than tokens
+	words := max(maxTokens*3/4, 1)
+
+	summary, err := h.complete(ctx, req.Provider, apiKey, model, fmt.Sprintf(overflowSummaryPrompt, words), builder.String(), llm.Options{})
+	if err != nil {
+		return "", err
+	}
+
+	// The model may ignore the word limit; the budget is enforced here
+	summary = tokenizer.TruncateTokens(strings.TrimSpace(summary), maxTokens, false)
+	if summary == "" {
+		return "", fmt.Errorf("summary is empty")
+	}
+
+	return summary, nil
+}
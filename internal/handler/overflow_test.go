@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+)
+
+// overflowResults returns results in score order, each chunk about 50 tokens long
+func overflowResults() []vector.SimilarityResult {
+	var results []vector.SimilarityResult
+	for i, topic := range []string{"retrieval", "chunking", "embeddings", "reranking", "caching"} {
+		results = append(results, vector.SimilarityResult{
+			Chunk:      models.Chunk{ID: topic, Content: strings.TrimSpace(strings.Repeat(topic+" matters here. ", 16))},
+			Similarity: 1 - float64(i)/10,
+		})
+	}
+	return results
+}
+
+// contextTokens counts the tokens of the kept results and summary as joined in the prompt
+func contextTokens(h *ChatHandler, results []vector.SimilarityResult, summary string) int {
+	var texts []string
+	for _, result := range results {
+		texts = append(texts, result.Chunk.Content)
+	}
+	context := h.joinContext(texts)
+	if summary != "" {
+		context += contextSeparator + summary
+	}
+	return tokenizer.EstimateTokens(context)
+}
+
+func TestFitContextDropsOverflow(t *testing.T) {
+	stub := stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	results := overflowResults()
+	budget := tokenizer.EstimateTokens(results[0].Chunk.Content)*2 + 10
+	env.cfg.RAG.MaxContextTokens = budget
+	env.cfg.RAG.ContextOverflow = "drop"
+
+	req := &models.ChatRequest{Message: "What matters?", Provider: "openrouter"}
+	handler := env.chatHandler(t, nil)
+	kept, summary := handler.fitContext(context.Background(), req, "key", results)
+
+	if len(kept) != 2 || kept[0].Chunk.ID != "retrieval" || kept[1].Chunk.ID != "chunking" {
+		t.Fatalf("kept %v, want the two best chunks", resultIDs(kept))
+	}
+	if summary != "" || stub.calls() != 0 {
+		t.Errorf("drop mode summarized %q with %d LLM calls, want neither", summary, stub.calls())
+	}
+	if tokens := contextTokens(handler, kept, ""); tokens > budget {
+		t.Errorf("context is %d tokens, over the %d token budget", tokens, budget)
+	}
+}
+
+func TestFitContextSummarizesOverflow(t *testing.T) {
+	for _, numbered := range []bool{false, true} {
+		stub := stubProviders(t)
+		var passages string
+		stub.reply = func(system, user string) (int, string) {
+			passages = user
+			// A model ignoring the word limit
+			return http.StatusOK, strings.Repeat("condensed facts about the remaining passages ", 40)
+		}
+
+		env := newTestEnv(t, testConfig(t))
+		results := overflowResults()
+		budget := tokenizer.EstimateTokens(results[0].Chunk.Content)*2 + 40
+		env.cfg.RAG.MaxContextTokens = budget
+		env.cfg.RAG.ContextOverflow = "summarize"
+		env.cfg.RAG.TrackUsedSources = numbered
+
+		req := &models.ChatRequest{Message: "What matters?", Provider: "openrouter"}
+		handler := env.chatHandler(t, nil)
+		kept, summary := handler.fitContext(context.Background(), req, "key", results)
+
+		if len(kept) != 2 {
+			t.Fatalf("numbered=%v: kept %v, want the two best chunks", numbered, resultIDs(kept))
+		}
+		if stub.calls() != 1 || summary == "" {
+			t.Fatalf("numbered=%v: %d LLM calls with summary %q, want one summary", numbered, stub.calls(), summary)
+		}
+		for _, dropped := range results[2:] {
+			if !strings.Contains(passages, dropped.Chunk.Content) {
+				t.Errorf("numbered=%v: overflowing chunk %s was not sent for summarizing", numbered, dropped.Chunk.ID)
+			}
+		}
+		if strings.Contains(passages, results[0].Chunk.Content) {
+			t.Errorf("numbered=%v: a kept chunk was sent for summarizing", numbered)
+		}
+		if tokens := contextTokens(handler, kept, summary); tokens > budget {
+			t.Errorf("numbered=%v: context with summary is %d tokens, over the %d token budget", numbered, tokens, budget)
+		}
+	}
+}
+
+func TestFitContextFallsBackToDrop(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		status int
+	}{
+		{"summary fails", "", http.StatusInternalServerError},
+		{"retrieval-only request", "retrieval", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := stubProviders(t)
+			stub.reply = func(_, _ string) (int, string) { return tt.status, "summary" }
+
+			env := newTestEnv(t, testConfig(t))
+			results := overflowResults()
+			env.cfg.RAG.MaxContextTokens = tokenizer.EstimateTokens(results[0].Chunk.Content) + 40
+			env.cfg.RAG.ContextOverflow = "summarize"
+
+			req := &models.ChatRequest{Message: "What matters?", Provider: "openrouter", Mode: tt.mode}
+			kept, summary := env.chatHandler(t, nil).fitContext(context.Background(), req, "key", results)
+			if len(kept) != 1 || summary != "" {
+				t.Errorf("kept %v with summary %q, want the best chunk only", resultIDs(kept), summary)
+			}
+		})
+	}
+}
+
+// resultIDs returns the chunk IDs of results in order
+func resultIDs(results []vector.SimilarityResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.Chunk.ID
+	}
+	return ids
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/querylog"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
+)
+
+// queriesApp serves search and the query embedding export as main does, recording query
+// embeddings when QUERY_LOGGING is on in cfg
+func queriesApp(t *testing.T, cfg *config.Config) (*fiber.App, *querylog.Log, *retrieval.Service) {
+	t.Helper()
+
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "chunk-1", "doc-1", "how to reset a password")
+
+	queryLog, err := querylog.New(cfg, env.db, env.logger)
+	if err != nil {
+		t.Fatalf("failed to create query log: %v", err)
+	}
+	t.Cleanup(queryLog.Close)
+
+	retrievalSvc := retrieval.New(cfg, env.logger, env.embeddingsSvc, env.vectorStore, env.metadataStore, nil, queryLog)
+	searchHandler := NewSearchHandler(cfg, env.logger, env.embeddingsSvc, retrievalSvc, queryLog)
+	adminHandler := NewAdminHandler(env.logger, env.reindexSvc, nil, nil, env.vectorStore, queryLog)
+
+	app := fiber.New()
+	app.Post("/search", searchHandler.Search)
+	app.Get("/admin/queries", adminHandler.ExportQueries)
+
+	return app, queryLog, retrievalSvc
+}
+
+// exportedQueries decodes the NDJSON query embedding export
+func exportedQueries(t *testing.T, body string) []models.QueryLogEmbedding {
+	t.Helper()
+
+	var records []models.QueryLogEmbedding
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if line == "" {
+			continue
+		}
+		var record models.QueryLogEmbedding
+		decodeJSON(t, line, &record)
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestQueryEmbeddingExport(t *testing.T) {
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.QueryLogEmbeddings = true
+	app, queryLog, _ := queriesApp(t, cfg)
+
+	query := "reset password for jane@example.com"
+	if status, body := doRequest(t, app, "POST", "/search", `{"query":"`+query+`"}`, nil); status != 200 {
+		t.Fatalf("search = %d %s", status, body)
+	}
+	queryLog.Close() // wait for the background write
+
+	status, body := doRequest(t, app, "GET", "/admin/queries", "", nil)
+	if status != 200 {
+		t.Fatalf("export = %d %s", status, body)
+	}
+
+	records := exportedQueries(t, body)
+	if len(records) != 1 {
+		t.Fatalf("exported %d queries, want 1: %s", len(records), body)
+	}
+	if got, want := records[0].Query, "reset password for [REDACTED:email]"; got != want {
+		t.Errorf("exported query = %q, want %q", got, want)
+	}
+	if strings.Contains(body, "jane@example.com") {
+		t.Errorf("export leaks the email address: %s", body)
+	}
+	if want := stubEmbedding(query); len(records[0].Embedding) != len(want) || records[0].Embedding[0] != want[0] {
+		t.Errorf("exported embedding = %v, want the query embedding %v", records[0].Embedding, want)
+	}
+}
+
+func TestQueryEmbeddingExportDisabled(t *testing.T) {
+	stubProviders(t)
+	app, _, _ := queriesApp(t, testConfig(t))
+
+	if status, body := doRequest(t, app, "POST", "/search", `{"query":"reset password"}`, nil); status != 200 {
+		t.Fatalf("search = %d %s", status, body)
+	}
+
+	status, body := doRequest(t, app, "GET", "/admin/queries", "", nil)
+	if status != 404 {
+		t.Fatalf("export with QUERY_LOGGING off = %d %s, want 404", status, body)
+	}
+}
+
+func TestQueryEmbeddingRecordsTopLevelQueryOnly(t *testing.T) {
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.QueryLogEmbeddings = true
+	app, queryLog, retrievalSvc := queriesApp(t, cfg)
+
+	queries := []string{"reset password", "change my password", "forgot password help"}
+	if _, err := retrievalSvc.RetrieveMulti(context.Background(), queries, "", 3, retrieval.Scope{}); err != nil {
+		t.Fatalf("RetrieveMulti failed: %v", err)
+	}
+	queryLog.Close()
+
+	_, body := doRequest(t, app, "GET", "/admin/queries", "", nil)
+	records := exportedQueries(t, body)
+	if len(records) != 1 || records[0].Query != "reset password" {
+		t.Errorf("recorded %+v, want only the top-level query", records)
+	}
+}
+
+func TestQueryEmbeddingExportBounds(t *testing.T) {
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.QueryLogEmbeddings = true
+	app, queryLog, _ := queriesApp(t, cfg)
+
+	queryLog.RecordEmbedding("first", []float64{1, 0, 0})
+	time.Sleep(2 * time.Millisecond)
+	middle := time.Now().UTC().Format(time.RFC3339Nano)
+	time.Sleep(2 * time.Millisecond)
+	queryLog.RecordEmbedding("second", []float64{0, 1, 0})
+	queryLog.Close()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"first", "second"}},
+		{"?since=" + middle, []string{"second"}},
+		{"?until=" + middle, []string{"first"}},
+		{"?since=" + middle + "&until=" + middle, nil},
+	}
+
+	for _, tt := range tests {
+		status, body := doRequest(t, app, "GET", "/admin/queries"+tt.query, "", nil)
+		if status != 200 {
+			t.Fatalf("export%s = %d %s", tt.query, status, body)
+		}
+
+		var got []string
+		for _, record := range exportedQueries(t, body) {
+			got = append(got, record.Query)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("export%s = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryEmbeddingExportFormat(t *testing.T) {
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.QueryLogEmbeddings = true
+	app, queryLog, _ := queriesApp(t, cfg)
+
+	queryLog.RecordEmbedding("token Bearer abcdefghijklmnopqrstuvwxyz", []float64{0.5, 0, 0})
+	queryLog.Close()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/queries", nil))
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", got)
+	}
+	if got := resp.Header.Get("Content-Disposition"); !strings.Contains(got, "query-embeddings.ndjson") {
+		t.Errorf("Content-Disposition = %q, want an NDJSON attachment", got)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+
+	// Only the anonymized text, embedding, ID and time are exported
+	var record map[string]interface{}
+	decodeJSON(t, strings.TrimSpace(string(data)), &record)
+	if record["query"] != "token [REDACTED:bearer_token]" {
+		t.Errorf("exported query = %v, want the token redacted", record["query"])
+	}
+	for key := range record {
+		switch key {
+		case "id", "timestamp", "query", "embedding":
+		default:
+			t.Errorf("export has field %q, want no caller or tenant identity", key)
+		}
+	}
+}
+
+func TestQueryEmbeddingExportInvalidBound(t *testing.T) {
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.QueryLogEmbeddings = true
+	app, _, _ := queriesApp(t, cfg)
+
+	status, body := doRequest(t, app, "GET", "/admin/queries?since=yesterday", "", nil)
+	if status != 400 || !strings.Contains(body, "since must be an RFC 3339 timestamp") {
+		t.Errorf("export with since=yesterday = %d %s, want 400", status, body)
+	}
+}
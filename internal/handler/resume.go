@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// resumePrompt asks the LLM to continue an answer that was cut off
+const resumePrompt = `%s
+
+Your previous answer to this question was interrupted. It ended with the text below. Continue it from exactly where it stops, without repeating any of it or adding an introduction.
+
+<partial_answer>
+%s
+</partial_answer>`
+
+// resumeToken identifies an interrupted streaming answer: the chat request and a hash of
+// the context it was answered from
+type resumeToken struct {
+	Request     models.ChatRequest `json:"request"`
+	ContextHash string             `json:"context_hash"`
+}
+
+// encodeResumeToken builds an opaque resume token for a chat request
+func encodeResumeToken(req models.ChatRequest, contextTexts []string) string {
+	data, _ := json.Marshal(resumeToken{Request: req, ContextHash: contextHash(contextTexts)})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeResumeToken parses and validates a resume token
+func decodeResumeToken(token string) (resumeToken, error) {
+	var decoded resumeToken
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return decoded, errors.BadRequest("invalid resume token").WithCode(errors.CodeInvalidResumeToken)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return decoded, errors.BadRequest("invalid resume token").WithCode(errors.CodeInvalidResumeToken)
+	}
+	if err := validateStruct(&decoded.Request); err != nil {
+		return decoded, errors.BadRequest("invalid resume token").WithCode(errors.CodeInvalidResumeToken)
+	}
+
+	return decoded, nil
+}
+
+// contextHash fingerprints the context passages an answer was generated from
+func contextHash(contextTexts []string) string {
+	h := sha256.New()
+	for _, text := range contextTexts {
+		h.Write([]byte(text))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChatResume continues a streaming answer that failed midway, using the resume token and
+// partial answer from the stream's error event (POST /api/v1/chat/resume). Only the
+// continuation is streamed; the context event reports context_changed when the retrieved
+// context no longer matches the interrupted answer's. Accepts ?format= like ChatStream.
+func (h *ChatHandler) ChatResume(c *fiber.Ctx) error {
+	format, err := parseStreamFormat(c.Query("format"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	var req models.ChatResumeRequest
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
+	}
+
+	token, err := decodeResumeToken(req.ResumeToken)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	chatReq := token.Request
+	pc, err := h.prepare(c.UserContext(), &chatReq, true)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	contextChanged := contextHash(pc.contextTexts) != token.ContextHash
+	if contextChanged {
+		h.logger.Warn("context changed since the interrupted answer", zap.String("provider", chatReq.Provider))
+	}
+
+	pc.userMessage = fmt.Sprintf(resumePrompt, pc.userMessage, req.Partial)
+
+	h.streamAnswer(c, format, token.Request, pc, req.Partial, map[string]interface{}{
+		"type":            "context",
+		"context":         pc.contextTexts,
+		"context_changed": contextChanged,
+		"degraded":        retrieval.Degraded(pc.results),
+		"low_confidence":  retrieval.LowConfidence(pc.results),
+	})
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// failStreamAfter makes the next Bedrock stream break after sending partial; other requests
+// still reach the provider stub
+func failStreamAfter(t *testing.T, partial string) {
+	t.Helper()
+
+	stubbed := http.DefaultTransport
+	failed := false
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if failed || !strings.HasSuffix(req.URL.Path, "/converse-stream") {
+			return stubbed.RoundTrip(req)
+		}
+		failed = true
+
+		delta, _ := json.Marshal(map[string]interface{}{"contentBlockDelta": map[string]interface{}{"delta": map[string]string{"text": partial}}})
+		body := io.MultiReader(strings.NewReader("data: "+string(delta)+"\n\n"), errorReader{})
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(body)}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = stubbed })
+}
+
+type errorReader struct{}
+
+func (errorReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+// resumeApp serves /chat/stream and /chat/resume over Bedrock with one indexed chunk
+func resumeApp(t *testing.T) (*fiber.App, *testEnv, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	cfg.Bedrock.APIKey = "test-key"
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	handler := env.chatHandler(t, nil)
+	app := fiber.New()
+	app.Post("/chat/stream", handler.ChatStream)
+	app.Post("/chat/resume", handler.ChatResume)
+
+	return app, env, stub
+}
+
+// interruptedStream streams a chat that breaks after partial and returns its error event
+func interruptedStream(t *testing.T, app *fiber.App, partial string) map[string]interface{} {
+	t.Helper()
+
+	failStreamAfter(t, partial)
+	status, body := doRequest(t, app, http.MethodPost, "/chat/stream", `{"message": "What is RAG?", "provider": "bedrock"}`, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("stream status = %d: %s", status, body)
+	}
+
+	events := sseEvents(t, body)
+	last := events[len(events)-1]
+	if last["type"] != "error" {
+		t.Fatalf("last event = %v, want an error event", last)
+	}
+	return last
+}
+
+// resume continues an interrupted answer and returns the stream's events
+func resume(t *testing.T, app *fiber.App, token, partial string) []map[string]interface{} {
+	t.Helper()
+
+	body, _ := json.Marshal(models.ChatResumeRequest{ResumeToken: token, Partial: partial})
+	status, resp := doRequest(t, app, http.MethodPost, "/chat/resume", string(body), nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("resume status = %d: %s", status, resp)
+	}
+	return sseEvents(t, resp)
+}
+
+// contextChanged returns the context_changed flag of a resumed stream's context event
+func contextChanged(t *testing.T, events []map[string]interface{}) bool {
+	t.Helper()
+
+	for _, event := range events {
+		if event["type"] == "context" {
+			changed, _ := event["context_changed"].(bool)
+			return changed
+		}
+	}
+	t.Fatalf("no context event in %v", events)
+	return false
+}
+
+func TestStreamErrorCarriesPartialAndResumeToken(t *testing.T) {
+	app, _, stub := resumeApp(t)
+
+	event := interruptedStream(t, app, "RAG retrieves relevant")
+	if event["partial"] != "RAG retrieves relevant" {
+		t.Errorf("partial = %v, want the text streamed before the error", event["partial"])
+	}
+	token, _ := event["resume_token"].(string)
+	if token == "" {
+		t.Fatalf("error event %v has no resume token", event)
+	}
+
+	stub.reply = func(_, _ string) (int, string) { return http.StatusOK, " passages first." }
+	events := resume(t, app, token, event["partial"].(string))
+
+	if contextChanged(t, events) {
+		t.Error("context_changed = true, want false for unchanged context")
+	}
+	var continued strings.Builder
+	for _, e := range events {
+		if e["type"] == "chunk" {
+			continued.WriteString(e["text"].(string))
+		}
+	}
+	if continued.String() != " passages first." {
+		t.Errorf("resumed stream = %q, want only the continuation", continued.String())
+	}
+
+	stub.mu.Lock()
+	user := stub.lastUserMessage
+	stub.mu.Unlock()
+	if !strings.Contains(user, "What is RAG?") || !strings.Contains(user, "<partial_answer>\nRAG retrieves relevant\n</partial_answer>") {
+		t.Errorf("resume prompt = %q, want the question and the partial answer", user)
+	}
+}
+
+func TestResumeDetectsStaleContext(t *testing.T) {
+	app, env, _ := resumeApp(t)
+
+	event := interruptedStream(t, app, "RAG retrieves relevant")
+	token := event["resume_token"].(string)
+
+	// A document added since the interruption changes the retrieved context
+	env.addChunk(t, "c2", "doc2", "RAG retrieves passages before generating.")
+
+	if !contextChanged(t, resume(t, app, token, "RAG retrieves relevant")) {
+		t.Error("context_changed = false, want true after the context changed")
+	}
+}
+
+func TestResumeDetectsTamperedContextHash(t *testing.T) {
+	app, _, _ := resumeApp(t)
+
+	event := interruptedStream(t, app, "RAG retrieves relevant")
+	data, err := base64.RawURLEncoding.DecodeString(event["resume_token"].(string))
+	if err != nil {
+		t.Fatalf("resume token is not base64: %v", err)
+	}
+	var token resumeToken
+	decodeJSON(t, string(data), &token)
+
+	token.ContextHash = contextHash([]string{"other context"})
+	data, _ = json.Marshal(token)
+
+	if !contextChanged(t, resume(t, app, base64.RawURLEncoding.EncodeToString(data), "RAG retrieves relevant")) {
+		t.Error("context_changed = false, want true for a token with another context hash")
+	}
+}
+
+func TestResumeRejectsInvalidToken(t *testing.T) {
+	app, _, stub := resumeApp(t)
+
+	noRequest, _ := json.Marshal(resumeToken{ContextHash: contextHash(nil)})
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "not a token!"},
+		{name: "not JSON", token: base64.RawURLEncoding.EncodeToString([]byte("garbage"))},
+		{name: "invalid request", token: base64.RawURLEncoding.EncodeToString(noRequest)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.ChatResumeRequest{ResumeToken: tt.token, Partial: "RAG retrieves"})
+			status, resp := doRequest(t, app, http.MethodPost, "/chat/resume", string(body), nil)
+			if status != fiber.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", status, resp)
+			}
+			var errResp models.ErrorResponse
+			decodeJSON(t, resp, &errResp)
+			if errResp.ErrorCode != "INVALID_RESUME_TOKEN" {
+				t.Errorf("error code = %q, want INVALID_RESUME_TOKEN", errResp.ErrorCode)
+			}
+		})
+	}
+
+	if stub.calls() != 0 {
+		t.Errorf("LLM calls = %d, want none for invalid tokens", stub.calls())
+	}
+}
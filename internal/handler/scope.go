@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/mrkaynak/rag/internal/service/retrieval"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// parseScope validates the namespaces and upload date range of a search or chat request
+func parseScope(namespaces []string, uploadedAfter, uploadedBefore *time.Time) (retrieval.Scope, error) {
+	parsed, err := parseNamespaces(namespaces)
+	if err != nil {
+		return retrieval.Scope{}, err
+	}
+
+	scope := retrieval.Scope{Namespaces: parsed}
+	if uploadedAfter != nil {
+		scope.UploadedAfter = *uploadedAfter
+	}
+	if uploadedBefore != nil {
+		scope.UploadedBefore = *uploadedBefore
+	}
+
+	if uploadedAfter != nil && uploadedBefore != nil && !scope.UploadedAfter.Before(scope.UploadedBefore) {
+		return retrieval.Scope{}, errors.BadRequest("uploaded_after must be before uploaded_before")
+	}
+
+	return scope, nil
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
+)
+
+func TestSearchUploadDateRange(t *testing.T) {
+	stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	env.addChunk(t, "old-0", "old", "Release notes from last year.")
+	env.addChunk(t, "new-0", "new", "Release notes from this week.")
+
+	for id, uploadedAt := range map[string]time.Time{"old": time.Now().AddDate(-1, 0, 0), "new": time.Now()} {
+		if _, err := env.metadataStore.Update(id, func(doc *document.DocumentMetadata) {
+			doc.UploadedAt = uploadedAt
+		}); err != nil {
+			t.Fatalf("failed to update document: %v", err)
+		}
+	}
+
+	retrievalSvc := retrieval.New(env.cfg, env.logger, env.embeddingsSvc, env.vectorStore, env.metadataStore, nil, nil)
+	app := fiber.New()
+	app.Post("/search", NewSearchHandler(env.cfg, env.logger, env.embeddingsSvc, retrievalSvc, nil).Search)
+
+	weekAgo := time.Now().AddDate(0, 0, -7).Format(time.RFC3339)
+	status, body := doRequest(t, app, http.MethodPost, "/search", `{"query": "release notes", "uploaded_after": "`+weekAgo+`"}`, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("search = %d: %s", status, body)
+	}
+
+	var resp models.SearchResponse
+	decodeJSON(t, body, &resp)
+	if len(resp.Results) != 1 || resp.Results[0].DocID != "new" {
+		t.Errorf("results = %+v, want only the document uploaded this week", resp.Results)
+	}
+
+	// An empty range is rejected
+	now := time.Now().Format(time.RFC3339)
+	status, body = doRequest(t, app, http.MethodPost, "/search",
+		`{"query": "release notes", "uploaded_after": "`+now+`", "uploaded_before": "`+weekAgo+`"}`, nil)
+	if status != fiber.StatusBadRequest {
+		t.Errorf("search with uploaded_after after uploaded_before = %d: %s, want 400", status, body)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/querylog"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// SearchHandler handles semantic search requests
+type SearchHandler struct {
+	cfg           *config.Config
+	logger        *zap.Logger
+	embeddingsSvc *embeddings.Service
+	retrievalSvc  *retrieval.Service
+	queryLog      *querylog.Log
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(
+	cfg *config.Config,
+	logger *zap.Logger,
+	embeddingsSvc *embeddings.Service,
+	retrievalSvc *retrieval.Service,
+	queryLog *querylog.Log,
+) *SearchHandler {
+	return &SearchHandler{
+		cfg:           cfg,
+		logger:        logger,
+		embeddingsSvc: embeddingsSvc,
+		retrievalSvc:  retrievalSvc,
+		queryLog:      queryLog,
+	}
+}
+
+// Search returns the chunks most similar to a query (POST /api/v1/search?include_embeddings=false&debug=false)
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	var req models.SearchRequest
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
+	}
+
+	topK := req.TopK
+	if topK <= 0 {
+		topK = h.cfg.RAG.MaxContextChunks
+	}
+	if limit := h.cfg.RAG.SearchMaxResults; limit > 0 && topK > limit {
+		topK = limit
+	}
+
+	scope, err := parseScope(req.Namespaces, req.UploadedAfter, req.UploadedBefore)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	results, err := h.retrievalSvc.Retrieve(c.UserContext(), req.Query, h.embeddingsSvc.APIKey(), topK, scope)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	results, confident := h.retrievalSvc.BoundResults(results)
+
+	chunks := h.retrievalSvc.ToRetrievedChunks(results, c.QueryBool("include_embeddings"), c.QueryBool("debug"))
+	for i := confident; i < len(chunks); i++ {
+		chunks[i].LowConfidence = true
+	}
+
+	recordQuery(c.UserContext(), h.logger, h.queryLog, models.QueryLogEntry{
+		Endpoint:   "search",
+		Query:      req.Query,
+		Namespaces: req.Namespaces,
+		Degraded:   retrieval.Degraded(results),
+	}, results)
+
+	return c.Status(fiber.StatusOK).JSON(models.SearchResponse{
+		Results:  chunks,
+		Degraded: retrieval.Degraded(results),
+	})
+}
+
+// sendError sends an error response
+func (h *SearchHandler) sendError(c *fiber.Ctx, err error) error {
+	if errors.IsDeadlineExceeded(err) {
+		err = errors.ErrDeadlineExceeded
+	}
+
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.Internal("internal server error")
+	}
+
+	return c.Status(appErr.Code).JSON(models.ErrorResponse{
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
+	})
+}
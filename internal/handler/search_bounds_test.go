@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
+)
+
+// searchBoundsApp serves /search with the given score and result bounds over three chunks
+func searchBoundsApp(t *testing.T, minScore float64, minResults, maxResults int) *fiber.App {
+	t.Helper()
+
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.SearchMinScore = minScore
+	cfg.RAG.SearchMinResults = minResults
+	cfg.RAG.SearchMaxResults = maxResults
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+	env.addChunk(t, "c2", "doc2", "Vector stores index embeddings")
+	env.addChunk(t, "c3", "doc3", "Chunking splits documents into passages for retrieval, one by one.")
+
+	retrievalSvc := retrieval.New(env.cfg, env.logger, env.embeddingsSvc, env.vectorStore, env.metadataStore, nil, nil)
+	app := fiber.New()
+	app.Post("/search", NewSearchHandler(env.cfg, env.logger, env.embeddingsSvc, retrievalSvc, nil).Search)
+	return app
+}
+
+// search runs a search and returns its results
+func search(t *testing.T, app *fiber.App, body string) []models.RetrievedChunk {
+	t.Helper()
+
+	status, resp := doRequest(t, app, http.MethodPost, "/search", body, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("search = %d: %s", status, resp)
+	}
+
+	var out models.SearchResponse
+	decodeJSON(t, resp, &out)
+	return out.Results
+}
+
+func TestSearchAllFilteredReturnsBestAsLowConfidence(t *testing.T) {
+	// No chunk reaches a minimum score above the highest possible similarity
+	const query = `{"query": "what is rag", "top_k": 3}`
+
+	if results := search(t, searchBoundsApp(t, 1.01, 0, 0), query); len(results) != 0 {
+		t.Errorf("results without SEARCH_MIN_RESULTS = %+v, want none", results)
+	}
+
+	unfiltered := search(t, searchBoundsApp(t, 0, 0, 0), query)
+	results := search(t, searchBoundsApp(t, 1.01, 1, 0), query)
+	if len(results) != 1 {
+		t.Fatalf("results = %+v, want only the best result", results)
+	}
+	if results[0].ID != unfiltered[0].ID {
+		t.Errorf("kept %s, want the best result %s", results[0].ID, unfiltered[0].ID)
+	}
+	if !results[0].LowConfidence {
+		t.Error("result below SEARCH_MIN_SCORE is not flagged low_confidence")
+	}
+	for _, result := range unfiltered {
+		if result.LowConfidence {
+			t.Errorf("result %s flagged low_confidence without SEARCH_MIN_SCORE", result.ID)
+		}
+	}
+}
+
+func TestSearchMaxResultsCapsTopK(t *testing.T) {
+	results := search(t, searchBoundsApp(t, 0, 0, 2), `{"query": "what is rag", "top_k": 3}`)
+	if len(results) != 2 {
+		t.Errorf("results = %d, want SEARCH_MAX_RESULTS", len(results))
+	}
+}
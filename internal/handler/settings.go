@@ -27,8 +27,8 @@ func NewSettingsHandler(logger *zap.Logger, settingsSvc *settings.Store) *Settin
 // SaveAPIKeys saves API keys (POST /api/v1/settings/api-keys)
 func (h *SettingsHandler) SaveAPIKeys(c *fiber.Ctx) error {
 	var keys settings.APIKeys
-	if err := c.BodyParser(&keys); err != nil {
-		return h.sendError(c, errors.BadRequest("invalid request body"))
+	if err := parseBody(c, &keys); err != nil {
+		return h.sendError(c, err)
 	}
 
 	if err := h.settingsSvc.SaveAPIKeys(keys); err != nil {
@@ -77,12 +77,8 @@ func (h *SettingsHandler) GetAPIKeys(c *fiber.Ctx) error {
 // SaveModel saves a model configuration (POST /api/v1/settings/models)
 func (h *SettingsHandler) SaveModel(c *fiber.Ctx) error {
 	var model settings.ModelConfig
-	if err := c.BodyParser(&model); err != nil {
-		return h.sendError(c, errors.BadRequest("invalid request body"))
-	}
-
-	if model.Provider == "" || model.ModelID == "" || model.DisplayName == "" {
-		return h.sendError(c, errors.BadRequest("provider, model_id, and display_name are required"))
+	if err := parseBody(c, &model); err != nil {
+		return h.sendError(c, err)
 	}
 
 	if err := h.settingsSvc.SaveModel(model); err != nil {
@@ -128,17 +124,98 @@ func (h *SettingsHandler) DeleteModel(c *fiber.Ctx) error {
 	})
 }
 
+// === Default Models ===
+
+// parseChatProvider validates an LLM provider path parameter
+func parseChatProvider(provider string) (string, error) {
+	switch provider {
+	case "openrouter", "bedrock":
+		return provider, nil
+	default:
+		return "", errors.BadRequest("provider must be 'openrouter' or 'bedrock'").WithCode(errors.CodeInvalidProvider)
+	}
+}
+
+// SetDefaultModel selects the saved model a provider's chats use when the request names
+// none (PUT /api/v1/settings/providers/:provider/default-model)
+func (h *SettingsHandler) SetDefaultModel(c *fiber.Ctx) error {
+	provider, err := parseChatProvider(c.Params("provider"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	var req settings.DefaultModel
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
+	}
+
+	model, err := h.settingsSvc.GetModel(req.ID)
+	if err != nil {
+		return h.sendError(c, errors.NotFound("model not found: "+req.ID).WithCode(errors.CodeModelNotFound))
+	}
+	if model.Provider != provider {
+		return h.sendError(c, errors.BadRequest("model "+req.ID+" belongs to provider "+model.Provider).WithCode(errors.CodeInvalidModel))
+	}
+
+	if err := h.settingsSvc.SetDefaultModel(provider, model.ID); err != nil {
+		h.logger.Error("failed to set default model", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to set default model"))
+	}
+
+	h.logger.Info("default model set", zap.String("provider", provider), zap.String("model_id", model.ModelID))
+
+	return c.Status(fiber.StatusOK).JSON(model)
+}
+
+// GetDefaultModel returns the saved model selected as a provider's default
+// (GET /api/v1/settings/providers/:provider/default-model)
+func (h *SettingsHandler) GetDefaultModel(c *fiber.Ctx) error {
+	provider, err := parseChatProvider(c.Params("provider"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	model, err := h.settingsSvc.GetDefaultModel(provider)
+	if err != nil {
+		h.logger.Error("failed to get default model", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to get default model"))
+	}
+
+	if model.ID == "" {
+		return h.sendError(c, errors.NotFound("no default model set for provider: "+provider).WithCode(errors.CodeModelNotFound))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model)
+}
+
+// ClearDefaultModel removes a provider's default model selection
+// (DELETE /api/v1/settings/providers/:provider/default-model)
+func (h *SettingsHandler) ClearDefaultModel(c *fiber.Ctx) error {
+	provider, err := parseChatProvider(c.Params("provider"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	if err := h.settingsSvc.ClearDefaultModel(provider); err != nil {
+		h.logger.Error("failed to clear default model", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to clear default model"))
+	}
+
+	h.logger.Info("default model cleared", zap.String("provider", provider))
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"message": "default model cleared successfully",
+	})
+}
+
 // === System Prompts ===
 
 // SaveSystemPrompt saves a system prompt (POST /api/v1/settings/system-prompts)
 func (h *SettingsHandler) SaveSystemPrompt(c *fiber.Ctx) error {
 	var prompt settings.SystemPrompt
-	if err := c.BodyParser(&prompt); err != nil {
-		return h.sendError(c, errors.BadRequest("invalid request body"))
-	}
-
-	if prompt.Name == "" || prompt.Prompt == "" {
-		return h.sendError(c, errors.BadRequest("name and prompt are required"))
+	if err := parseBody(c, &prompt); err != nil {
+		return h.sendError(c, err)
 	}
 
 	if err := h.settingsSvc.SaveSystemPrompt(prompt); err != nil {
@@ -193,6 +270,72 @@ func (h *SettingsHandler) DeleteSystemPrompt(c *fiber.Ctx) error {
 	})
 }
 
+// === Namespace Prompts ===
+
+// SaveNamespacePrompt sets a namespace's default system prompt (PUT /api/v1/settings/namespaces/:namespace/system-prompt)
+func (h *SettingsHandler) SaveNamespacePrompt(c *fiber.Ctx) error {
+	namespace, err := parseNamespace(c.Params("namespace"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	var prompt settings.NamespacePrompt
+	if err := parseBody(c, &prompt); err != nil {
+		return h.sendError(c, err)
+	}
+
+	prompt.Namespace = namespace
+
+	if err := h.settingsSvc.SaveNamespacePrompt(prompt); err != nil {
+		h.logger.Error("failed to save namespace prompt", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to save namespace prompt"))
+	}
+
+	h.logger.Info("namespace prompt saved", zap.String("namespace", namespace))
+
+	return c.Status(fiber.StatusOK).JSON(prompt)
+}
+
+// GetNamespacePrompt returns a namespace's default system prompt (GET /api/v1/settings/namespaces/:namespace/system-prompt)
+func (h *SettingsHandler) GetNamespacePrompt(c *fiber.Ctx) error {
+	namespace, err := parseNamespace(c.Params("namespace"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	prompt, err := h.settingsSvc.GetNamespacePrompt(namespace)
+	if err != nil {
+		h.logger.Error("failed to get namespace prompt", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to get namespace prompt"))
+	}
+
+	if prompt.Prompt == "" {
+		return h.sendError(c, errors.NotFound("no system prompt set for namespace: "+namespace).WithCode(errors.CodePromptNotFound))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(prompt)
+}
+
+// DeleteNamespacePrompt removes a namespace's default system prompt (DELETE /api/v1/settings/namespaces/:namespace/system-prompt)
+func (h *SettingsHandler) DeleteNamespacePrompt(c *fiber.Ctx) error {
+	namespace, err := parseNamespace(c.Params("namespace"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	if err := h.settingsSvc.DeleteNamespacePrompt(namespace); err != nil {
+		h.logger.Error("failed to delete namespace prompt", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to delete namespace prompt"))
+	}
+
+	h.logger.Info("namespace prompt deleted", zap.String("namespace", namespace))
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"message": "namespace prompt deleted successfully",
+	})
+}
+
 // sendError sends an error response
 func (h *SettingsHandler) sendError(c *fiber.Ctx, err error) error {
 	appErr, ok := err.(*errors.AppError)
@@ -201,7 +344,9 @@ func (h *SettingsHandler) sendError(c *fiber.Ctx, err error) error {
 	}
 
 	return c.Status(appErr.Code).JSON(models.ErrorResponse{
-		Error: appErr.Message,
-		Code:  appErr.Code,
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
 	})
 }
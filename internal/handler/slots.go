@@ -0,0 +1,48 @@
+package handler
+
+import "sync"
+
+// keyedSlots is a non-blocking counting semaphore per key, so one key exhausting its slots
+// never takes slots from another. A nil *keyedSlots admits everything.
+type keyedSlots struct {
+	mu     sync.Mutex
+	limit  int
+	counts map[string]int
+}
+
+// newKeyedSlots creates a semaphore allowing limit holders per key
+func newKeyedSlots(limit int) *keyedSlots {
+	return &keyedSlots{limit: limit, counts: make(map[string]int)}
+}
+
+// acquire takes a slot of key without waiting, returning false when all are held
+func (k *keyedSlots) acquire(key string) bool {
+	if k == nil {
+		return true
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.counts[key] >= k.limit {
+		return false
+	}
+	k.counts[key]++
+	return true
+}
+
+// release frees a slot taken by acquire, forgetting keys with no holders left
+func (k *keyedSlots) release(key string) {
+	if k == nil {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.counts[key] <= 1 {
+		delete(k.counts, key)
+		return
+	}
+	k.counts[key]--
+}
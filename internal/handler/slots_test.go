@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/middleware"
+)
+
+func TestKeyedSlotsPerKey(t *testing.T) {
+	slots := newKeyedSlots(2)
+
+	if !slots.acquire("a") || !slots.acquire("a") {
+		t.Fatal("acquire within the limit failed")
+	}
+	if slots.acquire("a") {
+		t.Error("acquire beyond the limit succeeded")
+	}
+	if !slots.acquire("b") {
+		t.Error("a full key took a slot from another key")
+	}
+
+	slots.release("a")
+	if !slots.acquire("a") {
+		t.Error("acquire after a release failed")
+	}
+
+	slots.release("a")
+	slots.release("a")
+	slots.release("b")
+	if len(slots.counts) != 0 {
+		t.Errorf("counts = %v after releasing every slot, want keys forgotten", slots.counts)
+	}
+
+	var unlimited *keyedSlots
+	for i := 0; i < 3; i++ {
+		if !unlimited.acquire("a") {
+			t.Fatal("nil keyedSlots rejected an acquire")
+		}
+	}
+	unlimited.release("a")
+}
+
+func TestKeyedSlotsConcurrentLimit(t *testing.T) {
+	const limit = 3
+	slots := newKeyedSlots(limit)
+
+	var mu sync.Mutex
+	held := map[string]int{}
+	peak := map[string]int{}
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b"} {
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if !slots.acquire(key) {
+						continue
+					}
+					mu.Lock()
+					held[key]++
+					peak[key] = max(peak[key], held[key])
+					mu.Unlock()
+
+					time.Sleep(10 * time.Microsecond)
+
+					mu.Lock()
+					held[key]--
+					mu.Unlock()
+					slots.release(key)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	for key, n := range peak {
+		if n > limit {
+			t.Errorf("key %s: %d slots held at once, want at most %d", key, n, limit)
+		}
+	}
+}
+
+// blockingUploads makes every upload's embedding wait until the returned release func is
+// called; started receives the content of each upload as it begins embedding
+func blockingUploads(t *testing.T, stub *providerStub) (started chan string, release func()) {
+	t.Helper()
+
+	started = make(chan string, 16)
+	gate := make(chan struct{})
+	stub.embedding = func(text string) []float64 {
+		started <- text
+		<-gate
+		return stubEmbedding(text)
+	}
+
+	var once sync.Once
+	release = func() { once.Do(func() { close(gate) }) }
+	t.Cleanup(release)
+	return started, release
+}
+
+// asyncUpload posts a text upload in the background and returns a channel with its status,
+// or 0 if the request failed
+func asyncUpload(app *fiber.App, body string, headers map[string]string) chan int {
+	req := httptest.NewRequest(http.MethodPost, "/documents/text", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	done := make(chan int, 1)
+	go func() {
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	return done
+}
+
+// waitStarted waits for an upload to begin embedding
+func waitStarted(t *testing.T, started chan string) {
+	t.Helper()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload did not start")
+	}
+}
+
+func TestUploadConcurrencyPerTenant(t *testing.T) {
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	cfg.Storage.MaxConcurrentUploads = 4
+	cfg.Storage.UploadsPerTenant = 1
+	env := newTestEnv(t, cfg)
+
+	app := fiber.New()
+	app.Post("/documents/text", middleware.Tenant("X-Tenant-ID"), env.uploadHandler().UploadText)
+
+	started, release := blockingUploads(t, stub)
+	tenantA := map[string]string{"X-Tenant-ID": "tenant-a"}
+	tenantB := map[string]string{"X-Tenant-ID": "tenant-b"}
+
+	firstA := asyncUpload(app, `{"filename": "a1.txt", "content": "Tenant A's first document."}`, tenantA)
+	waitStarted(t, started)
+
+	// Tenant A's slice is used up, in any namespace
+	if status, body := doRequest(t, app, http.MethodPost, "/documents/text", `{"filename": "a2.txt", "content": "Tenant A's second document.", "namespace": "other"}`, tenantA); status != fiber.StatusTooManyRequests {
+		t.Errorf("tenant A's second upload = %d, want 429: %s", status, body)
+	}
+
+	// Tenant B is not blocked by tenant A's running upload
+	firstB := asyncUpload(app, `{"filename": "b1.txt", "content": "Tenant B's first document."}`, tenantB)
+	waitStarted(t, started)
+
+	release()
+	for name, done := range map[string]chan int{"tenant A": firstA, "tenant B": firstB} {
+		if status := <-done; status != fiber.StatusCreated {
+			t.Errorf("%s's upload = %d, want 201", name, status)
+		}
+	}
+
+	// Finished uploads free their tenant's slot
+	if status, body := doRequest(t, app, http.MethodPost, "/documents/text", `{"filename": "a3.txt", "content": "Tenant A's third document."}`, tenantA); status != fiber.StatusCreated {
+		t.Errorf("tenant A's upload after the first finished = %d, want 201: %s", status, body)
+	}
+}
+
+func TestUploadConcurrencyPerNamespace(t *testing.T) {
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	cfg.Storage.MaxConcurrentUploads = 4
+	cfg.Storage.UploadsPerTenant = 1
+	env := newTestEnv(t, cfg)
+
+	app := fiber.New()
+	app.Post("/documents/text", env.uploadHandler().UploadText)
+
+	started, release := blockingUploads(t, stub)
+
+	// Without TENANT_HEADER each namespace gets its own slice; none means "default"
+	first := asyncUpload(app, `{"filename": "a.txt", "content": "A default namespace document."}`, nil)
+	waitStarted(t, started)
+
+	if status, body := doRequest(t, app, http.MethodPost, "/documents/text", `{"filename": "b.txt", "content": "Another default document.", "namespace": "default"}`, nil); status != fiber.StatusTooManyRequests {
+		t.Errorf("second upload to the default namespace = %d, want 429: %s", status, body)
+	}
+
+	other := asyncUpload(app, `{"filename": "c.txt", "content": "A team namespace document.", "namespace": "team-a"}`, nil)
+	waitStarted(t, started)
+
+	release()
+	for name, done := range map[string]chan int{"default": first, "team-a": other} {
+		if status := <-done; status != fiber.StatusCreated {
+			t.Errorf("upload to %s = %d, want 201", name, status)
+		}
+	}
+}
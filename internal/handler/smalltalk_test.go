@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/service/smalltalk"
+)
+
+func TestSmalltalkShortcut(t *testing.T) {
+	stub := stubProviders(t)
+	embeddings := 0
+	stub.embedding = func(text string) []float64 {
+		stub.mu.Lock()
+		embeddings++
+		stub.mu.Unlock()
+		return stubEmbedding(text)
+	}
+
+	env := newTestEnv(t, testConfig(t))
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	classifier, err := smalltalk.New("")
+	if err != nil {
+		t.Fatalf("failed to create classifier: %v", err)
+	}
+	handler := env.chatHandler(t, nil)
+	handler.smalltalkSvc = classifier
+
+	app := fiber.New()
+	app.Post("/chat", handler.Chat)
+
+	greeting := chat(t, app, `{"message": "Hello!", "provider": "openrouter"}`)
+	stub.mu.Lock()
+	embedded := embeddings
+	stub.mu.Unlock()
+	if embedded != 0 || stub.calls() != 0 {
+		t.Errorf("greeting made %d embedding and %d LLM calls, want none", embedded, stub.calls())
+	}
+	if want, _ := classifier.Match("Hello!"); greeting.Message != want {
+		t.Errorf("greeting reply = %q, want the canned %q", greeting.Message, want)
+	}
+
+	question := chat(t, app, `{"message": "Hello, what is RAG?", "provider": "openrouter"}`)
+	stub.mu.Lock()
+	embedded = embeddings
+	stub.mu.Unlock()
+	if embedded == 0 || stub.calls() == 0 {
+		t.Errorf("question made %d embedding and %d LLM calls, want retrieval and an answer", embedded, stub.calls())
+	}
+	if !question.UsedContext {
+		t.Error("question was answered without context")
+	}
+}
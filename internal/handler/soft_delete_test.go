@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/retrieval"
+)
+
+func TestSoftDeleteRestoreAndPurge(t *testing.T) {
+	stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	handler := env.uploadHandler()
+	retrievalSvc := retrieval.New(env.cfg, env.logger, env.embeddingsSvc, env.vectorStore, env.metadataStore, nil, nil)
+
+	app := fiber.New()
+	app.Post("/documents/text", handler.UploadText)
+	app.Get("/documents", handler.ListDocuments)
+	app.Delete("/documents/:id", handler.DeleteDocument)
+	app.Post("/documents/:id/restore", handler.RestoreDocument)
+	app.Post("/search", NewSearchHandler(env.cfg, env.logger, env.embeddingsSvc, retrievalSvc, nil).Search)
+
+	_, uploaded := uploadText(t, app, "notes.txt", "Soft-deleted documents can be restored.")
+	id := uploaded.DocumentID
+
+	search := func() int {
+		t.Helper()
+		status, body := doRequest(t, app, http.MethodPost, "/search", `{"query": "restore documents"}`, nil)
+		if status != fiber.StatusOK {
+			t.Fatalf("search = %d: %s", status, body)
+		}
+		var resp models.SearchResponse
+		decodeJSON(t, body, &resp)
+		return len(resp.Results)
+	}
+	list := func(target string) []document.DocumentMetadata {
+		t.Helper()
+		_, body := doRequest(t, app, http.MethodGet, target, "", nil)
+		var docs []document.DocumentMetadata
+		decodeJSON(t, body, &docs)
+		return docs
+	}
+
+	if search() == 0 {
+		t.Fatal("uploaded document is not searchable")
+	}
+
+	if status, body := doRequest(t, app, http.MethodDelete, "/documents/"+id, "", nil); status != fiber.StatusOK {
+		t.Fatalf("delete = %d: %s", status, body)
+	}
+	if n := search(); n != 0 {
+		t.Errorf("search after delete found %d chunks, want the document hidden", n)
+	}
+	if docs := list("/documents"); len(docs) != 0 {
+		t.Errorf("listing after delete = %+v, want the document hidden", docs)
+	}
+	if docs := list("/documents?include_deleted=true"); len(docs) != 1 || docs[0].DeletedAt == nil {
+		t.Errorf("listing with include_deleted = %+v, want the document marked deleted", docs)
+	}
+
+	if status, body := doRequest(t, app, http.MethodPost, "/documents/"+id+"/restore", "", nil); status != fiber.StatusOK {
+		t.Fatalf("restore = %d: %s", status, body)
+	}
+	if search() == 0 {
+		t.Error("restored document is not searchable")
+	}
+	if docs := list("/documents"); len(docs) != 1 || docs[0].DeletedAt != nil {
+		t.Errorf("listing after restore = %+v, want the document back", docs)
+	}
+
+	if status, body := doRequest(t, app, http.MethodDelete, "/documents/"+id+"?purge=true", "", nil); status != fiber.StatusOK {
+		t.Fatalf("purge = %d: %s", status, body)
+	}
+	if docs := list("/documents?include_deleted=true"); len(docs) != 0 {
+		t.Errorf("listing after purge = %+v, want the document gone", docs)
+	}
+	if chunks := documentChunks(env, id); len(chunks) != 0 {
+		t.Errorf("%d chunks left after purge, want none", len(chunks))
+	}
+	if status, _ := doRequest(t, app, http.MethodPost, "/documents/"+id+"/restore", "", nil); status != fiber.StatusNotFound {
+		t.Errorf("restore after purge = %d, want 404", status)
+	}
+}
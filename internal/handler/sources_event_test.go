@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+)
+
+// sourcesApp serves /chat/stream over a chunk of guide.md at index 2 and a chunk whose
+// document has no metadata
+func sourcesApp(t *testing.T, contextText bool) *fiber.App {
+	t.Helper()
+
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.Bedrock.APIKey = "test-key"
+	cfg.RAG.StreamContextText = contextText
+	env := newTestEnv(t, cfg)
+
+	if err := env.metadataStore.Add(document.DocumentMetadata{ID: "doc1", FileName: "guide.md"}); err != nil {
+		t.Fatalf("failed to add metadata: %v", err)
+	}
+	chunks := []models.Chunk{
+		{ID: "doc1-2", DocID: "doc1", Index: 2, Heading: "Overview", Content: "RAG combines retrieval with generation.", Embedding: stubEmbedding("RAG combines retrieval with generation.")},
+		{ID: "orphan-0", DocID: "orphan", Content: "Retrieval finds passages.", Embedding: stubEmbedding("Retrieval finds passages.")},
+	}
+	if err := env.vectorStore.Add(chunks); err != nil {
+		t.Fatalf("failed to add chunks: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/chat/stream", env.chatHandler(t, nil).ChatStream)
+	return app
+}
+
+// streamEvents posts a chat to /chat/stream and returns its events by type
+func streamEvents(t *testing.T, app *fiber.App) map[string]map[string]interface{} {
+	t.Helper()
+
+	status, body := doRequest(t, app, http.MethodPost, "/chat/stream", `{"message": "What is RAG?", "provider": "bedrock"}`, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("stream status = %d: %s", status, body)
+	}
+
+	byType := make(map[string]map[string]interface{})
+	for _, event := range sseEvents(t, body) {
+		if eventType, _ := event["type"].(string); byType[eventType] == nil {
+			byType[eventType] = event
+		}
+	}
+	return byType
+}
+
+func TestStreamSourcesEvent(t *testing.T) {
+	app := sourcesApp(t, false)
+
+	status, body := doRequest(t, app, http.MethodPost, "/chat/stream", `{"message": "What is RAG?", "provider": "bedrock"}`, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("stream status = %d: %s", status, body)
+	}
+	events := sseEvents(t, body)
+	if len(events) < 2 || events[0]["type"] != "sources" || events[1]["type"] != "context" {
+		t.Fatalf("events = %v, want sources then context first", events)
+	}
+
+	list, _ := events[0]["sources"].([]interface{})
+	if len(list) != 2 {
+		t.Fatalf("sources = %v, want one per retrieved chunk", events[0]["sources"])
+	}
+
+	byChunk := make(map[string]map[string]interface{})
+	for _, item := range list {
+		source, _ := item.(map[string]interface{})
+		chunkID, _ := source["chunk_id"].(string)
+		byChunk[chunkID] = source
+	}
+
+	guide := byChunk["doc1-2"]
+	if guide == nil {
+		t.Fatalf("sources = %v, want chunk doc1-2", list)
+	}
+	if guide["doc_id"] != "doc1" || guide["file_name"] != "guide.md" || guide["index"] != float64(2) || guide["heading"] != "Overview" {
+		t.Errorf("source = %v, want doc1, guide.md, index 2 and its heading", guide)
+	}
+	if score, _ := guide["score"].(float64); score <= 0 {
+		t.Errorf("score = %v, want the chunk's similarity", guide["score"])
+	}
+	if _, ok := guide["relevance"].(float64); !ok {
+		t.Errorf("relevance = %v, want a number", guide["relevance"])
+	}
+
+	orphan := byChunk["orphan-0"]
+	if orphan == nil {
+		t.Fatalf("sources = %v, want chunk orphan-0", list)
+	}
+	if _, ok := orphan["file_name"]; ok || orphan["doc_id"] != "orphan" {
+		t.Errorf("source = %v, want its doc ID without a file name", orphan)
+	}
+
+	// Without STREAM_CONTEXT_TEXT the context event carries no passage texts
+	if _, ok := events[1]["context"]; ok {
+		t.Errorf("context event = %v, want no context texts", events[1])
+	}
+}
+
+func TestStreamContextText(t *testing.T) {
+	events := streamEvents(t, sourcesApp(t, true))
+
+	texts, _ := events["context"]["context"].([]interface{})
+	if len(texts) != 2 {
+		t.Fatalf("context event = %v, want both passage texts", events["context"])
+	}
+	if sources, _ := events["sources"]["sources"].([]interface{}); len(sources) != 2 {
+		t.Errorf("sources event = %v, want it sent alongside the texts", events["sources"])
+	}
+}
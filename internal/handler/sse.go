@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// setSSEHeaders prepares the response for a server-sent event stream
+func setSSEHeaders(c *fiber.Ctx) {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("Transfer-Encoding", "chunked")
+}
+
+// writeEvent writes a single SSE data event and flushes it to the client
+func writeEvent(w *bufio.Writer, event map[string]interface{}) error {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	return w.Flush()
+}
+
+// streamFormat is the wire format of a chat stream: SSE frames or newline-delimited JSON.
+// Both carry the same events.
+type streamFormat string
+
+const (
+	formatSSE    streamFormat = "sse"
+	formatNDJSON streamFormat = "ndjson"
+)
+
+// parseStreamFormat validates a ?format= query value, defaulting to SSE when empty
+func parseStreamFormat(raw string) (streamFormat, error) {
+	switch streamFormat(raw) {
+	case "", formatSSE:
+		return formatSSE, nil
+	case formatNDJSON:
+		return formatNDJSON, nil
+	default:
+		return "", errors.BadRequest("format must be 'sse' or 'ndjson'")
+	}
+}
+
+// setHeaders prepares the response for a stream in this format
+func (f streamFormat) setHeaders(c *fiber.Ctx) {
+	setSSEHeaders(c)
+	if f == formatNDJSON {
+		c.Set("Content-Type", "application/x-ndjson")
+	}
+}
+
+// write writes a single event in this format and flushes it to the client
+func (f streamFormat) write(w *bufio.Writer, event map[string]interface{}) error {
+	if f != formatNDJSON {
+		return writeEvent(w, event)
+	}
+
+	data, _ := json.Marshal(event)
+	w.Write(data)
+	w.WriteByte('\n')
+	return w.Flush()
+}
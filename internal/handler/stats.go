@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// StatsHandler handles knowledge base statistics requests
+type StatsHandler struct {
+	logger        *zap.Logger
+	vectorStore   *vector.Store
+	metadataStore *document.MetadataStore
+	embeddingsSvc *embeddings.Service
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(logger *zap.Logger, vectorStore *vector.Store, metadataStore *document.MetadataStore, embeddingsSvc *embeddings.Service) *StatsHandler {
+	return &StatsHandler{
+		logger:        logger,
+		vectorStore:   vectorStore,
+		metadataStore: metadataStore,
+		embeddingsSvc: embeddingsSvc,
+	}
+}
+
+// Stats returns knowledge base statistics (GET /api/v1/stats)
+func (h *StatsHandler) Stats(c *fiber.Ctx) error {
+	docs, err := h.metadataStore.List()
+	if err != nil {
+		h.logger.Error("failed to list documents", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to get stats"))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.StatsResponse{
+		Documents:          len(docs),
+		Chunks:             h.vectorStore.Count(),
+		StoreVersion:       h.vectorStore.Version(),
+		EmbeddingDimension: h.embeddingsSvc.DetectedDimension(), // cached; never waits on the provider
+		EmbeddingNorms:     h.vectorStore.NormStats(),
+	})
+}
+
+// sendError sends an error response
+func (h *StatsHandler) sendError(c *fiber.Ctx, err error) error {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.Internal("internal server error")
+	}
+
+	return c.Status(appErr.Code).JSON(models.ErrorResponse{
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
+	})
+}
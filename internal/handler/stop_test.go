@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/service/settings"
+)
+
+func TestChatStopSequences(t *testing.T) {
+	stub := stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	saved := settings.NewWithDB(env.db, "")
+	if err := saved.SaveModel(settings.ModelConfig{
+		Provider:    "openrouter",
+		ModelID:     "test/listing",
+		DisplayName: "Listing",
+		Stop:        []string{"\n\n"},
+	}); err != nil {
+		t.Fatalf("failed to save model: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "requested",
+			body: `{"message": "List the steps", "provider": "openrouter", "model": "test/other", "stop": ["END", "###"]}`,
+			want: []string{"END", "###"},
+		},
+		{
+			name: "model default",
+			body: `{"message": "List the steps", "provider": "openrouter", "model": "test/listing"}`,
+			want: []string{"\n\n"},
+		},
+		{
+			name: "request overrides model default",
+			body: `{"message": "List the steps", "provider": "openrouter", "model": "test/listing", "stop": ["END"]}`,
+			want: []string{"END"},
+		},
+		{
+			name: "none",
+			body: `{"message": "List the steps", "provider": "openrouter", "model": "test/other"}`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat(t, app, tt.body)
+
+			stub.mu.Lock()
+			got := stub.lastStop
+			stub.mu.Unlock()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stop sent to the provider = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
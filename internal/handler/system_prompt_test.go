@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/settings"
+)
+
+// promptTestApp serves the system prompt test endpoint and /chat over one indexed chunk
+func promptTestApp(t *testing.T) (*fiber.App, *testEnv, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	env.cfg.RAG.SystemPrompt = "You are the default assistant."
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	handler := env.chatHandler(t, nil)
+	app := fiber.New()
+	app.Post("/settings/system-prompts/test", handler.TestSystemPrompt)
+	app.Post("/chat", handler.Chat)
+
+	return app, env, stub
+}
+
+func testPrompt(t *testing.T, app *fiber.App, body string) models.PromptTestResponse {
+	t.Helper()
+
+	status, resp := doRequest(t, app, http.MethodPost, "/settings/system-prompts/test", body, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("prompt test = %d: %s", status, resp)
+	}
+
+	var out models.PromptTestResponse
+	decodeJSON(t, resp, &out)
+	return out
+}
+
+func TestSystemPromptTestSendsSuppliedPrompt(t *testing.T) {
+	app, _, stub := promptTestApp(t)
+
+	resp := testPrompt(t, app, `{"prompt": "You are a pirate.", "message": "What is RAG?", "provider": "openrouter"}`)
+
+	stub.mu.Lock()
+	sent := stub.lastSystemPrompt
+	stub.mu.Unlock()
+	if !strings.HasPrefix(sent, "You are a pirate.") || resp.SystemPrompt != sent {
+		t.Errorf("sent system prompt = %q (reported %q), want the supplied prompt", sent, resp.SystemPrompt)
+	}
+	if !strings.Contains(sent, "RAG combines retrieval with generation.") || resp.ContextChunkCount != 1 {
+		t.Errorf("prompt test used %d chunks, want retrieval by default", resp.ContextChunkCount)
+	}
+	if resp.Message != "stub answer" {
+		t.Errorf("answer = %q, want the model's answer", resp.Message)
+	}
+
+	// The candidate prompt is not persisted: regular chats keep the default
+	chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if !strings.HasPrefix(stub.lastSystemPrompt, "You are the default assistant.") {
+		t.Errorf("chat after a prompt test used %q, want the default prompt", stub.lastSystemPrompt)
+	}
+}
+
+func TestSystemPromptTestSavedPrompt(t *testing.T) {
+	app, env, stub := promptTestApp(t)
+
+	store := settings.NewWithDB(env.db, "")
+	if err := store.SaveSystemPrompt(settings.SystemPrompt{ID: "concise", Name: "Concise", Prompt: "Answer in one sentence."}); err != nil {
+		t.Fatalf("failed to save prompt: %v", err)
+	}
+
+	testPrompt(t, app, `{"prompt_id": "concise", "message": "What is RAG?", "provider": "openrouter"}`)
+	stub.mu.Lock()
+	sent := stub.lastSystemPrompt
+	stub.mu.Unlock()
+	if !strings.HasPrefix(sent, "Answer in one sentence.") {
+		t.Errorf("sent system prompt = %q, want the saved prompt", sent)
+	}
+
+	if status, body := doRequest(t, app, http.MethodPost, "/settings/system-prompts/test",
+		`{"prompt_id": "missing", "message": "What is RAG?", "provider": "openrouter"}`, nil); status != fiber.StatusNotFound {
+		t.Errorf("unknown prompt_id = %d: %s, want 404", status, body)
+	}
+	if status, body := doRequest(t, app, http.MethodPost, "/settings/system-prompts/test",
+		`{"message": "What is RAG?", "provider": "openrouter"}`, nil); status != fiber.StatusBadRequest {
+		t.Errorf("no prompt = %d: %s, want 400", status, body)
+	}
+}
+
+func TestSystemPromptTestContext(t *testing.T) {
+	app, _, stub := promptTestApp(t)
+	embedded := 0
+	stub.embedding = func(text string) []float64 {
+		stub.mu.Lock()
+		embedded++
+		stub.mu.Unlock()
+		return stubEmbedding(text)
+	}
+
+	// use_rag=false skips retrieval
+	resp := testPrompt(t, app, `{"prompt": "You are a pirate.", "message": "What is RAG?", "provider": "openrouter", "use_rag": false}`)
+	if resp.ContextChunkCount != 0 || strings.Contains(resp.SystemPrompt, "RAG combines") {
+		t.Errorf("use_rag=false sent %d chunks, want none", resp.ContextChunkCount)
+	}
+
+	// Sample context replaces retrieval
+	resp = testPrompt(t, app, `{"prompt": "You are a pirate.", "message": "What is RAG?", "provider": "openrouter", "context": ["Sample passage about RAG."]}`)
+	stub.mu.Lock()
+	sent, embeddings := stub.lastSystemPrompt, embedded
+	stub.mu.Unlock()
+	if !strings.HasPrefix(sent, "You are a pirate.") || !strings.Contains(sent, "Sample passage about RAG.") {
+		t.Errorf("sent system prompt = %q, want the prompt with the sample context", sent)
+	}
+	if strings.Contains(sent, "RAG combines") || resp.ContextChunkCount != 1 {
+		t.Errorf("sample context test used %d chunks (%q), want only the sample", resp.ContextChunkCount, sent)
+	}
+	if embeddings != 0 {
+		t.Errorf("%d embedding calls without retrieval, want none", embeddings)
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/middleware"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+const tenantHeader = "X-Tenant-ID"
+
+// tenantApp serves the document, chunk and chunk export routes as main does, with two
+// documents owned by tenants "alice" and "bob"
+func tenantApp(t *testing.T) (*fiber.App, *testEnv) {
+	t.Helper()
+
+	cfg := testConfig(t)
+	cfg.Server.TenantHeader = tenantHeader
+	env := newTestEnv(t, cfg)
+
+	for i, owner := range []string{"alice", "bob"} {
+		docID := owner + "-doc"
+		if err := env.metadataStore.Add(document.DocumentMetadata{
+			ID:         docID,
+			FileName:   docID + ".txt",
+			ChunkCount: 1,
+			UploadedAt: time.Now(),
+			TenantID:   owner,
+		}); err != nil {
+			t.Fatalf("failed to add metadata: %v", err)
+		}
+
+		path := filepath.Join(cfg.Storage.UploadDir, docID+"_"+docID+".txt")
+		if err := os.WriteFile(path, []byte(owner+" secret"), 0644); err != nil {
+			t.Fatalf("failed to write original: %v", err)
+		}
+
+		embedding := []float64{0, 0, 0}
+		embedding[i] = 1
+		if err := env.vectorStore.Add([]models.Chunk{{
+			ID:        owner + "-chunk",
+			DocID:     docID,
+			Content:   owner + " secret",
+			Embedding: embedding,
+			TenantID:  owner,
+		}}); err != nil {
+			t.Fatalf("failed to add chunk: %v", err)
+		}
+	}
+
+	uploadHandler := env.uploadHandler()
+	chunksHandler := NewChunksHandler(env.logger, env.vectorStore)
+	adminHandler := NewAdminHandler(env.logger, env.reindexSvc, nil, nil, env.vectorStore, nil)
+
+	app := fiber.New()
+	tenantScope := middleware.Tenant(tenantHeader)
+	app.Get("/documents", tenantScope, uploadHandler.ListDocuments)
+	app.Get("/documents/:id", tenantScope, uploadHandler.GetDocument)
+	app.Delete("/documents/:id", tenantScope, uploadHandler.DeleteDocument)
+	app.Post("/documents/:id/restore", tenantScope, uploadHandler.RestoreDocument)
+	app.Get("/chunks", tenantScope, chunksHandler.ListChunks)
+	app.Get("/admin/export-chunks", tenantScope, adminHandler.ExportChunks)
+
+	return app, env
+}
+
+func as(tenantID string) map[string]string {
+	return map[string]string{tenantHeader: tenantID}
+}
+
+func TestTenantDocumentListing(t *testing.T) {
+	app, _ := tenantApp(t)
+
+	status, body := doRequest(t, app, http.MethodGet, "/documents?include_deleted=true", "", as("alice"))
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body %s", status, body)
+	}
+
+	var docs []document.DocumentMetadata
+	decodeJSON(t, body, &docs)
+	if len(docs) != 1 || docs[0].ID != "alice-doc" {
+		t.Fatalf("alice listed %+v, want only alice-doc", docs)
+	}
+}
+
+func TestTenantGetDocument(t *testing.T) {
+	app, _ := tenantApp(t)
+
+	status, body := doRequest(t, app, http.MethodGet, "/documents/bob-doc", "", as("alice"))
+	if status != fiber.StatusNotFound {
+		t.Fatalf("reading another tenant's document: status = %d, body %s", status, body)
+	}
+	if strings.Contains(body, "bob secret") {
+		t.Fatalf("another tenant's content leaked: %s", body)
+	}
+
+	var resp models.ErrorResponse
+	decodeJSON(t, body, &resp)
+	if resp.ErrorCode != errors.CodeDocumentNotFound {
+		t.Errorf("error_code = %q, want %q", resp.ErrorCode, errors.CodeDocumentNotFound)
+	}
+
+	status, body = doRequest(t, app, http.MethodGet, "/documents/bob-doc", "", as("bob"))
+	if status != fiber.StatusOK || !strings.Contains(body, "bob secret") {
+		t.Fatalf("owner read: status = %d, body %s", status, body)
+	}
+}
+
+func TestTenantDeleteDocument(t *testing.T) {
+	app, env := tenantApp(t)
+
+	status, body := doRequest(t, app, http.MethodDelete, "/documents/bob-doc", "", as("alice"))
+	if status != fiber.StatusNotFound {
+		t.Fatalf("deleting another tenant's document: status = %d, body %s", status, body)
+	}
+
+	doc, err := env.metadataStore.Get("bob-doc")
+	if err != nil || doc.DeletedAt != nil {
+		t.Fatalf("bob-doc was deleted by another tenant: %+v, %v", doc, err)
+	}
+	if env.vectorStore.Count() != 2 {
+		t.Fatalf("chunk count = %d, want 2", env.vectorStore.Count())
+	}
+}
+
+func TestTenantPurgeDocument(t *testing.T) {
+	app, env := tenantApp(t)
+
+	status, body := doRequest(t, app, http.MethodDelete, "/documents/bob-doc?purge=true", "", as("alice"))
+	if status != fiber.StatusNotFound {
+		t.Fatalf("purging another tenant's document: status = %d, body %s", status, body)
+	}
+
+	if _, err := env.metadataStore.Get("bob-doc"); err != nil {
+		t.Fatalf("bob-doc was purged by another tenant: %v", err)
+	}
+	if len(env.vectorStore.GetAllIncludingDeleted()) != 2 {
+		t.Fatal("bob-doc's chunks were purged by another tenant")
+	}
+
+	status, body = doRequest(t, app, http.MethodDelete, "/documents/bob-doc?purge=true", "", as("bob"))
+	if status != fiber.StatusOK {
+		t.Fatalf("owner purge: status = %d, body %s", status, body)
+	}
+}
+
+func TestTenantRestoreDocument(t *testing.T) {
+	app, env := tenantApp(t)
+
+	if status, body := doRequest(t, app, http.MethodDelete, "/documents/bob-doc", "", as("bob")); status != fiber.StatusOK {
+		t.Fatalf("owner delete: status = %d, body %s", status, body)
+	}
+
+	status, body := doRequest(t, app, http.MethodPost, "/documents/bob-doc/restore", "", as("alice"))
+	if status != fiber.StatusNotFound {
+		t.Fatalf("restoring another tenant's document: status = %d, body %s", status, body)
+	}
+
+	doc, err := env.metadataStore.Get("bob-doc")
+	if err != nil || doc.DeletedAt == nil {
+		t.Fatalf("bob-doc was restored by another tenant: %+v, %v", doc, err)
+	}
+}
+
+func TestTenantListChunks(t *testing.T) {
+	app, _ := tenantApp(t)
+
+	status, body := doRequest(t, app, http.MethodGet, "/chunks?doc_id=bob-doc", "", as("alice"))
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body %s", status, body)
+	}
+
+	var resp models.ChunkListResponse
+	decodeJSON(t, body, &resp)
+	if resp.Total != 0 {
+		t.Fatalf("alice listed bob's chunks: %+v", resp.Chunks)
+	}
+
+	_, body = doRequest(t, app, http.MethodGet, "/chunks", "", as("alice"))
+	decodeJSON(t, body, &resp)
+	if resp.Total != 1 || resp.Chunks[0].ID != "alice-chunk" {
+		t.Fatalf("alice listed %+v, want only alice-chunk", resp.Chunks)
+	}
+}
+
+func TestTenantExportChunks(t *testing.T) {
+	app, _ := tenantApp(t)
+
+	status, body := doRequest(t, app, http.MethodGet, "/admin/export-chunks", "", as("alice"))
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body %s", status, body)
+	}
+
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "alice-chunk") {
+		t.Fatalf("alice exported %q, want only alice-chunk", body)
+	}
+}
+
+func TestTenantHeaderErrors(t *testing.T) {
+	app, _ := tenantApp(t)
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		status   int
+		wantCode string
+	}{
+		{"missing", nil, fiber.StatusUnauthorized, errors.CodeTenantRequired},
+		{"invalid", as("no spaces allowed"), fiber.StatusBadRequest, errors.CodeInvalidTenant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doRequest(t, app, http.MethodGet, "/documents", "", tt.headers)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d (body %s)", status, tt.status, body)
+			}
+
+			var resp models.ErrorResponse
+			decodeJSON(t, body, &resp)
+			if resp.Code != tt.status || resp.ErrorCode != tt.wantCode || resp.Error == "" {
+				t.Errorf("response = %+v, want code %d and error_code %s", resp, tt.status, tt.wantCode)
+			}
+		})
+	}
+}
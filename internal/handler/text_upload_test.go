@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// textUploadApp serves JSON text uploads chunked into 100-character pieces without overlap
+func textUploadApp(t *testing.T) (*fiber.App, *testEnv) {
+	t.Helper()
+
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.ChunkUnit = "chars"
+	cfg.RAG.ChunkSize = 100
+	cfg.RAG.ChunkOverlap = 0
+	cfg.RAG.DedupeChunks = false
+	env := newTestEnv(t, cfg)
+
+	app := fiber.New()
+	app.Post("/documents/text", env.uploadHandler().UploadText)
+
+	return app, env
+}
+
+func TestUploadTextCreatesDocument(t *testing.T) {
+	app, env := textUploadApp(t)
+
+	content := strings.Repeat("0123456789", 25) // 250 characters: 3 chunks of up to 100
+	body := `{"filename": "notes/../guide.md", "content": "` + content + `", "tags": ["ops", "guide"]}`
+	status, resp := doRequest(t, app, http.MethodPost, "/documents/text", body, nil)
+	if status != fiber.StatusCreated {
+		t.Fatalf("status = %d, body %s", status, resp)
+	}
+
+	var out models.UploadResponse
+	decodeJSON(t, resp, &out)
+	if out.ChunkCount != 3 || out.FileName != "guide.md" || out.Action != models.UploadCreated {
+		t.Errorf("response = %+v, want 3 chunks of guide.md created", out)
+	}
+
+	doc, err := env.metadataStore.Get(out.DocumentID)
+	if err != nil {
+		t.Fatalf("document metadata missing: %v", err)
+	}
+	if doc.ChunkCount != 3 || doc.FileType != "text/plain" || strings.Join(doc.Tags, ",") != "ops,guide" {
+		t.Errorf("metadata = %+v, want 3 text/plain chunks tagged ops,guide", doc)
+	}
+	if chunks := documentChunks(env, out.DocumentID); len(chunks) != 3 {
+		t.Errorf("indexed chunks = %d, want 3", len(chunks))
+	}
+}
+
+func TestUploadTextRejectsInvalidRequests(t *testing.T) {
+	app, env := textUploadApp(t)
+
+	for _, body := range []string{
+		`{"filename": "a.txt"}`,
+		`{"filename": "a.txt", "content": "   "}`,
+		`{"content": "text"}`,
+		`{"filename": "/", "content": "text"}`,
+	} {
+		if status, resp := doRequest(t, app, http.MethodPost, "/documents/text", body, nil); status != fiber.StatusBadRequest {
+			t.Errorf("%s: status = %d (%s), want 400", body, status, resp)
+		}
+	}
+
+	if docs, _ := env.metadataStore.List(); len(docs) != 0 {
+		t.Errorf("%d documents stored from invalid requests", len(docs))
+	}
+}
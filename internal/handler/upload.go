@@ -1,19 +1,32 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	badger "github.com/dgraph-io/badger/v4"
 	"github.com/gofiber/fiber/v2"
 	"github.com/mrkaynak/rag/internal/config"
 	"github.com/mrkaynak/rag/internal/models"
 	"github.com/mrkaynak/rag/internal/service/document"
 	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/maintenance"
+	"github.com/mrkaynak/rag/internal/service/reindex"
 	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/internal/tenant"
 	"github.com/mrkaynak/rag/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -46,6 +59,13 @@ type UploadHandler struct {
 	embeddingsSvc *embeddings.Service
 	vectorStore   *vector.Store
 	metadataStore *document.MetadataStore
+	reindexSvc    *reindex.Service
+	purger        *maintenance.Purger
+
+	// uploadSlots bounds concurrent uploads (nil when unlimited)
+	uploadSlots chan struct{}
+	// tenantSlots bounds each tenant's concurrent uploads (nil when unlimited)
+	tenantSlots *keyedSlots
 }
 
 // NewUploadHandler creates a new upload handler
@@ -56,23 +76,86 @@ func NewUploadHandler(
 	embeddingsSvc *embeddings.Service,
 	vectorStore *vector.Store,
 	metadataStore *document.MetadataStore,
+	reindexSvc *reindex.Service,
+	purger *maintenance.Purger,
 ) *UploadHandler {
-	return &UploadHandler{
+	h := &UploadHandler{
 		cfg:           cfg,
 		logger:        logger,
 		docService:    docService,
 		embeddingsSvc: embeddingsSvc,
 		vectorStore:   vectorStore,
 		metadataStore: metadataStore,
+		reindexSvc:    reindexSvc,
+		purger:        purger,
+	}
+	if cfg.Storage.MaxConcurrentUploads > 0 {
+		h.uploadSlots = make(chan struct{}, cfg.Storage.MaxConcurrentUploads)
+	}
+	if cfg.Storage.UploadsPerTenant > 0 {
+		h.tenantSlots = newKeyedSlots(cfg.Storage.UploadsPerTenant)
+	}
+
+	return h
+}
+
+// acquireUploadSlot reserves one of the MAX_CONCURRENT_UPLOADS slots and one of the
+// UPLOAD_CONCURRENCY_PER_TENANT slots of key without waiting. It returns false when either
+// is exhausted, holding neither.
+func (h *UploadHandler) acquireUploadSlot(key string) bool {
+	if !h.tenantSlots.acquire(key) {
+		return false
+	}
+	if h.uploadSlots == nil {
+		return true
+	}
+
+	select {
+	case h.uploadSlots <- struct{}{}:
+		return true
+	default:
+		h.tenantSlots.release(key)
+		return false
+	}
+}
+
+// releaseUploadSlot frees the slots taken by acquireUploadSlot
+func (h *UploadHandler) releaseUploadSlot(key string) {
+	if h.uploadSlots != nil {
+		<-h.uploadSlots
+	}
+	h.tenantSlots.release(key)
+}
+
+// uploadKey identifies whose share of the upload slots a request uses: its tenant when
+// TENANT_HEADER is set, otherwise the namespace it uploads into
+func uploadKey(c *fiber.Ctx, namespace string) string {
+	if tenantID := tenant.FromContext(c.UserContext()); tenantID != "" {
+		return "tenant:" + tenantID
+	}
+	if namespace == "" {
+		namespace = models.DefaultNamespace
 	}
+	return "namespace:" + namespace
 }
 
-// detectAndValidateFileType detects the file type and validates it against allowed types
-func detectAndValidateFileType(file *multipart.FileHeader) (string, error) {
+// errTooManyUploads is returned when MAX_CONCURRENT_UPLOADS uploads are already running, or
+// UPLOAD_CONCURRENCY_PER_TENANT of the caller's
+var errTooManyUploads = errors.TooManyRequests("too many uploads in progress, please retry shortly").WithCode(errors.CodeTooManyUploads)
+
+// errReindexInProgress rejects writes while a reindex is about to swap the store
+var errReindexInProgress = errors.ServiceUnavailable("a reindex is in progress, please retry shortly").WithCode(errors.CodeReindexInProgress)
+
+// detectAndValidateFileType detects the file type and validates it against allowed types.
+// Source code extensions are accepted as plain text when allowCode is set, and JSON/YAML
+// extensions when allowStructured is.
+func detectAndValidateFileType(file *multipart.FileHeader, allowCode, allowStructured bool) (string, error) {
 	// First check file extension
 	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if !AllowedExtensions[ext] {
-		return "", fmt.Errorf("file extension '%s' is not allowed. Supported formats: .txt, .md", ext)
+	isCode := allowCode && document.CodeExtensions[ext]
+	isStructured := allowStructured && document.StructuredExtensions[ext]
+	if !AllowedExtensions[ext] && !isCode && !isStructured {
+		return "", fmt.Errorf("file extension '%s' is not allowed. Supported formats: %s", ext, supportedFormats(allowCode, allowStructured))
 	}
 
 	// Open file to detect content type
@@ -89,8 +172,7 @@ func detectAndValidateFileType(file *multipart.FileHeader) (string, error) {
 		return "", fmt.Errorf("failed to read file for type detection: %w", err)
 	}
 
-	// Detect content type
-	contentType := http.DetectContentType(buffer[:n])
+	contentType := detectMediaType(buffer[:n])
 
 	// Validate content type
 	if !AllowedMimeTypes[contentType] {
@@ -100,29 +182,93 @@ func detectAndValidateFileType(file *multipart.FileHeader) (string, error) {
 	return contentType, nil
 }
 
+// detectMediaType sniffs the media type of data without parameters, since
+// http.DetectContentType reports text as "text/plain; charset=utf-8"
+func detectMediaType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
+
+// supportedFormats describes the accepted upload extensions for error messages
+func supportedFormats(allowCode, allowStructured bool) string {
+	formats := ".txt, .md"
+	if allowStructured {
+		formats += ", .json, .yaml, .yml"
+	}
+	if allowCode {
+		formats += " and common source code files"
+	}
+	return formats
+}
+
 // Upload handles document upload and processing
 func (h *UploadHandler) Upload(c *fiber.Ctx) error {
-	// Get API key from config based on provider (not needed for Ollama)
-	var apiKey string
-	switch h.cfg.Embeddings.Provider {
-	case "ollama":
-		// No API key needed for Ollama
-		apiKey = ""
-	case "openrouter":
-		apiKey = h.cfg.OpenRouter.APIKey
-	case "bedrock":
-		apiKey = h.cfg.Bedrock.APIKey
+	// Reject writes while a reindex is about to swap the store
+	if h.reindexSvc.Running() {
+		return h.sendError(c, errReindexInProgress)
+	}
+
+	slotKey := uploadKey(c, c.FormValue("namespace"))
+	if !h.acquireUploadSlot(slotKey) {
+		return h.sendError(c, errTooManyUploads)
+	}
+	defer h.releaseUploadSlot(slotKey)
+
+	// Embeddings provider (EMBEDDING_PROVIDER unless overridden); Ollama needs no API key
+	embeddingProvider, err := h.embeddingsSvc.ResolveProvider(c.FormValue("embedding_provider"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	file, fileType, err := h.parseUploadFile(c)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	namespace, err := parseNamespace(c.FormValue("namespace"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	h.logger.Info("processing file upload",
+		zap.String("filename", file.Filename),
+		zap.Int64("size", file.Size),
+		zap.String("type", fileType),
+	)
+
+	// Open uploaded file
+	fileContent, err := file.Open()
+	if err != nil {
+		h.logger.Error("failed to open uploaded file", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to open file"))
 	}
+	defer fileContent.Close()
 
-	if h.cfg.Embeddings.Provider != "ollama" && apiKey == "" {
-		return h.sendError(c, errors.Unauthorized("API key is not configured"))
+	resp, err := h.indexDocument(c.UserContext(), indexRequest{
+		fileName:          file.Filename,
+		fileType:          fileType,
+		size:              file.Size,
+		reader:            fileContent,
+		namespace:         namespace,
+		embeddingProvider: embeddingProvider,
+	}, nil)
+	if err != nil {
+		return h.sendError(c, err)
 	}
 
+	return c.Status(uploadStatus(resp)).JSON(resp)
+}
+
+// parseUploadFile reads the multipart file and validates its size and type
+func (h *UploadHandler) parseUploadFile(c *fiber.Ctx) (*multipart.FileHeader, string, error) {
 	// Parse multipart form
 	file, err := c.FormFile("file")
 	if err != nil {
 		h.logger.Warn("failed to parse file", zap.Error(err))
-		return h.sendError(c, errors.BadRequest("file is required. Please select a file to upload."))
+		return nil, "", errors.BadRequest("file is required. Please select a file to upload.").WithCode(errors.CodeInvalidFile)
 	}
 
 	// Validate file size
@@ -132,58 +278,298 @@ func (h *UploadHandler) Upload(c *fiber.Ctx) error {
 			zap.Int64("size", file.Size),
 			zap.Int64("max_size", MaxFileSize),
 		)
-		return h.sendError(c, errors.BadRequest(
+		return nil, "", errors.BadRequest(
 			fmt.Sprintf("file too large. Maximum file size is %d MB", MaxFileSize/(1024*1024)),
-		))
+		).WithCode(errors.CodeInvalidFile)
 	}
 
 	// Validate file is not empty
 	if file.Size == 0 {
 		h.logger.Warn("empty file uploaded", zap.String("filename", file.Filename))
-		return h.sendError(c, errors.BadRequest("uploaded file is empty. Please select a valid file."))
+		return nil, "", errors.BadRequest("uploaded file is empty. Please select a valid file.").WithCode(errors.CodeInvalidFile)
 	}
 
 	// Detect and validate file type
-	fileType, err := detectAndValidateFileType(file)
+	fileType, err := detectAndValidateFileType(file, h.cfg.RAG.CodeIndexing, h.cfg.RAG.StructuredChunking)
 	if err != nil {
 		h.logger.Warn("invalid file type",
 			zap.String("filename", file.Filename),
 			zap.Error(err),
 		)
-		return h.sendError(c, errors.BadRequest(err.Error()))
+		return nil, "", errors.BadRequest(err.Error()).WithCode(errors.CodeInvalidFile)
 	}
 
-	h.logger.Info("processing file upload",
+	return file, fileType, nil
+}
+
+// UploadStream handles document upload and streams embedding progress as SSE (POST /api/v1/upload/stream)
+func (h *UploadHandler) UploadStream(c *fiber.Ctx) error {
+	if h.reindexSvc.Running() {
+		return h.sendError(c, errReindexInProgress)
+	}
+
+	slotKey := uploadKey(c, c.FormValue("namespace"))
+	if !h.acquireUploadSlot(slotKey) {
+		return h.sendError(c, errTooManyUploads)
+	}
+	// The slot is handed to the stream writer once streaming starts
+	streaming := false
+	defer func() {
+		if !streaming {
+			h.releaseUploadSlot(slotKey)
+		}
+	}()
+
+	embeddingProvider, err := h.embeddingsSvc.ResolveProvider(c.FormValue("embedding_provider"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	file, fileType, err := h.parseUploadFile(c)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	namespace, err := parseNamespace(c.FormValue("namespace"))
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	// Read the file up front, the multipart form is released before the stream writer runs
+	fileContent, err := file.Open()
+	if err != nil {
+		h.logger.Error("failed to open uploaded file", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to open file"))
+	}
+	data, err := io.ReadAll(fileContent)
+	fileContent.Close()
+	if err != nil {
+		h.logger.Error("failed to read uploaded file", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to read file"))
+	}
+
+	h.logger.Info("processing streaming file upload",
 		zap.String("filename", file.Filename),
 		zap.Int64("size", file.Size),
 		zap.String("type", fileType),
 	)
 
-	// Open uploaded file
-	fileContent, err := file.Open()
+	req := indexRequest{
+		fileName:          file.Filename,
+		fileType:          fileType,
+		size:              file.Size,
+		reader:            bytes.NewReader(data),
+		namespace:         namespace,
+		embeddingProvider: embeddingProvider,
+	}
+
+	setSSEHeaders(c)
+
+	// The fiber context is recycled once the handler returns, so capture the trace context
+	ctx := c.UserContext()
+
+	streaming = true
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.releaseUploadSlot(slotKey)
+
+		resp, err := h.indexDocument(ctx, req, func(embedded, total int) {
+			writeEvent(w, map[string]interface{}{
+				"type":     "progress",
+				"embedded": embedded,
+				"total":    total,
+			})
+		})
+		if err != nil {
+			message := "internal server error"
+			errorCode := errors.CodeInternal
+			if appErr, ok := err.(*errors.AppError); ok {
+				message = appErr.Message
+				errorCode = appErr.ErrorCode
+			}
+			writeEvent(w, map[string]interface{}{
+				"type":       "error",
+				"error":      message,
+				"error_code": errorCode,
+			})
+			return
+		}
+
+		writeEvent(w, map[string]interface{}{
+			"type":        "done",
+			"document_id": resp.DocumentID,
+			"filename":    resp.FileName,
+			"chunk_count": resp.ChunkCount,
+			"action":      resp.Action,
+		})
+	})
+
+	return nil
+}
+
+// UploadText indexes a document supplied as JSON text (POST /api/v1/documents/text)
+func (h *UploadHandler) UploadText(c *fiber.Ctx) error {
+	if h.reindexSvc.Running() {
+		return h.sendError(c, errReindexInProgress)
+	}
+
+	var req models.TextUploadRequest
+	if err := parseBody(c, &req); err != nil {
+		return h.sendError(c, err)
+	}
+
+	slotKey := uploadKey(c, req.Namespace)
+	if !h.acquireUploadSlot(slotKey) {
+		return h.sendError(c, errTooManyUploads)
+	}
+	defer h.releaseUploadSlot(slotKey)
+
+	embeddingProvider, err := h.embeddingsSvc.ResolveProvider(req.EmbeddingProvider)
 	if err != nil {
-		h.logger.Error("failed to open uploaded file", zap.Error(err))
-		return h.sendError(c, errors.InternalWrap(err, "failed to open file"))
+		return h.sendError(c, err)
 	}
-	defer fileContent.Close()
 
-	// Process document
-	doc, err := h.docService.ProcessUpload(file.Filename, fileContent)
+	fileName := filepath.Base(strings.TrimSpace(req.FileName))
+	if fileName == "" || fileName == "." || fileName == string(filepath.Separator) {
+		return h.sendError(c, errors.BadRequest("filename is required"))
+	}
+
+	size := int64(len(req.Content))
+	if size > MaxFileSize {
+		return h.sendError(c, errors.BadRequest(
+			fmt.Sprintf("content too large. Maximum size is %d MB", MaxFileSize/(1024*1024)),
+		))
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		return h.sendError(c, errors.BadRequest("content is required"))
+	}
+
+	namespace, err := parseNamespace(req.Namespace)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	h.logger.Info("processing text upload",
+		zap.String("filename", fileName),
+		zap.Int64("size", size),
+	)
+
+	resp, err := h.indexDocument(c.UserContext(), indexRequest{
+		fileName:          fileName,
+		fileType:          "text/plain",
+		size:              size,
+		reader:            strings.NewReader(req.Content),
+		tags:              req.Tags,
+		namespace:         namespace,
+		embeddingProvider: embeddingProvider,
+	}, nil)
 	if err != nil {
-		h.logger.Error("failed to process document", zap.Error(err))
 		return h.sendError(c, err)
 	}
 
+	return c.Status(uploadStatus(resp)).JSON(resp)
+}
+
+// uploadStatus is 201 for a newly indexed document and 200 when an existing one was reused
+// or replaced
+func uploadStatus(resp *models.UploadResponse) int {
+	if resp.Action == models.UploadCreated {
+		return fiber.StatusCreated
+	}
+	return fiber.StatusOK
+}
+
+// indexRequest describes a document to be processed and indexed
+type indexRequest struct {
+	fileName  string
+	fileType  string
+	size      int64
+	reader    io.Reader
+	tags      []string
+	namespace string
+	// embeddingProvider is the resolved embeddings provider for this document
+	embeddingProvider string
+}
+
+// indexDocument runs the chunk, embed and index pipeline for a document.
+// progress, if set, is called as chunks are embedded. Uploads whose content matches an
+// existing document are skipped or replace it in place according to ON_DUPLICATE.
+func (h *UploadHandler) indexDocument(ctx context.Context, req indexRequest, progress embeddings.ProgressFunc) (*models.UploadResponse, error) {
+	stamp := vector.ModelStamp{Provider: req.embeddingProvider, Model: h.embeddingsSvc.ModelFor(req.embeddingProvider)}
+	if err := h.checkModelStamp(stamp); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(req.reader)
+	if err != nil {
+		h.logger.Error("failed to read uploaded content", zap.Error(err))
+		return nil, errors.InternalWrap(err, "failed to read file")
+	}
+
+	sum := sha256.Sum256(data)
+	contentHash := hex.EncodeToString(sum[:])
+
+	// The tenant comes from the request context (TENANT_HEADER) and is "" when isolation is off
+	tenantID := tenant.FromContext(ctx)
+
+	var existing *document.DocumentMetadata
+	if h.cfg.Storage.OnDuplicate != "allow" {
+		existing, err = h.metadataStore.FindByHash(contentHash, tenantID, req.namespace)
+		if err != nil {
+			h.logger.Error("failed to look up duplicate documents", zap.Error(err))
+			return nil, errors.InternalWrap(err, "failed to check for duplicate documents")
+		}
+	}
+
+	if existing != nil && h.cfg.Storage.OnDuplicate == "error" {
+		return nil, errors.Conflict(fmt.Sprintf("document %s (%s) has the same content", existing.ID, existing.FileName)).WithCode(errors.CodeDuplicateDocument)
+	}
+
+	if existing != nil && h.cfg.Storage.OnDuplicate == "skip" {
+		h.logger.Info("skipping duplicate upload",
+			zap.String("doc_id", existing.ID),
+			zap.String("filename", req.fileName),
+		)
+		return &models.UploadResponse{
+			DocumentID: existing.ID,
+			FileName:   existing.FileName,
+			ChunkCount: existing.ChunkCount,
+			Action:     models.UploadSkipped,
+		}, nil
+	}
+
+	// Process document, reusing the duplicate's ID when replacing it
+	var doc *models.Document
+	if existing != nil {
+		doc, err = h.docService.ProcessUploadAs(existing.ID, req.fileName, bytes.NewReader(data))
+	} else {
+		doc, err = h.docService.ProcessUpload(req.fileName, bytes.NewReader(data))
+	}
+	if err != nil {
+		h.logger.Error("failed to process document", zap.Error(err))
+		return nil, err
+	}
+
 	h.logger.Info("document processed",
 		zap.String("doc_id", doc.ID),
 		zap.Int("chunks", len(doc.Chunks)),
+		zap.Int("duplicate_chunks", doc.DuplicateChunks),
 	)
 
+	for i := range doc.Chunks {
+		doc.Chunks[i].Namespace = req.namespace
+		doc.Chunks[i].TenantID = tenantID
+	}
+
 	// Generate embeddings
-	chunks, err := h.embeddingsSvc.GenerateEmbeddings(doc.Chunks, apiKey)
+	chunks, err := h.embeddingsSvc.GenerateEmbeddingsWithProvider(ctx, req.embeddingProvider, doc.Chunks, progress)
 	if err != nil {
 		h.logger.Error("failed to generate embeddings", zap.Error(err))
-		return h.sendError(c, err)
+		return nil, err
+	}
+
+	if len(chunks) == 0 {
+		return nil, errors.BadRequest("document produced no chunks with usable embeddings").WithCode(errors.CodeEmbeddingFailed)
 	}
 
 	h.logger.Info("embeddings generated",
@@ -191,20 +577,41 @@ func (h *UploadHandler) Upload(c *fiber.Ctx) error {
 		zap.Int("chunks", len(chunks)),
 	)
 
-	// Store in vector store
-	if err := h.vectorStore.Add(chunks); err != nil {
+	// Store in vector store. A replaced duplicate's chunks are swapped in one step once the
+	// new ones are embedded, and its original is only overwritten after that.
+	if existing != nil {
+		if err := h.vectorStore.ReplaceDocument(existing.ID, chunks); err != nil {
+			h.logger.Error("failed to replace document chunks", zap.Error(err))
+			return nil, err
+		}
+		if err := h.docService.ReplaceOriginal(existing.ID, existing.FileName, req.fileName, data); err != nil {
+			h.logger.Error("failed to replace original file", zap.Error(err))
+			// Non-fatal, the stored chunks already reflect the new upload
+		}
+	} else if err := h.vectorStore.Add(chunks); err != nil {
 		h.logger.Error("failed to add to vector store", zap.Error(err))
-		return h.sendError(c, err)
+		return nil, err
+	}
+
+	// The first indexed vectors decide the model queries are embedded with
+	if err := h.vectorStore.StampModel(stamp, false); err != nil {
+		h.logger.Warn("failed to stamp embedding model", zap.Error(err))
 	}
 
 	// Save metadata
 	metadata := document.DocumentMetadata{
-		ID:         doc.ID,
-		FileName:   doc.FileName,
-		FileSize:   file.Size,
-		FileType:   fileType,
-		ChunkCount: len(chunks),
-		UploadedAt: doc.CreatedAt,
+		ID:                doc.ID,
+		FileName:          doc.FileName,
+		FileSize:          req.size,
+		FileType:          req.fileType,
+		ChunkCount:        len(chunks),
+		ContentHash:       contentHash,
+		DuplicateChunks:   doc.DuplicateChunks,
+		UploadedAt:        doc.CreatedAt,
+		Tags:              req.tags,
+		Namespace:         req.namespace,
+		TenantID:          tenantID,
+		EmbeddingProvider: req.embeddingProvider,
 	}
 
 	if err := h.metadataStore.Add(metadata); err != nil {
@@ -212,43 +619,176 @@ func (h *UploadHandler) Upload(c *fiber.Ctx) error {
 		// Non-fatal, continue
 	}
 
+	if h.cfg.Storage.StoreDocumentContent {
+		if err := h.metadataStore.SaveContent(doc.ID, doc.Content); err != nil {
+			h.logger.Error("failed to save document content", zap.Error(err))
+			// Non-fatal, content can still be read from the original file
+		}
+	}
+
+	action := models.UploadCreated
+	if existing != nil {
+		action = models.UploadReplaced
+	}
+
 	h.logger.Info("document indexed successfully",
 		zap.String("doc_id", doc.ID),
-		zap.String("filename", file.Filename),
+		zap.String("filename", req.fileName),
+		zap.String("action", action),
 	)
 
-	return c.Status(fiber.StatusCreated).JSON(models.UploadResponse{
+	return &models.UploadResponse{
 		DocumentID: doc.ID,
 		FileName:   doc.FileName,
 		ChunkCount: len(chunks),
-	})
+		Action:     action,
+	}, nil
+}
+
+// checkModelStamp rejects uploads embedded with an embedding_provider override whose model
+// differs from the one the index was built with: their vectors would share the index but
+// not its embedding space
+func (h *UploadHandler) checkModelStamp(stamp vector.ModelStamp) error {
+	if stamp.Provider == h.embeddingsSvc.Provider() {
+		return nil
+	}
+
+	indexed := h.vectorStore.Model()
+	if indexed.IsZero() || indexed == stamp {
+		return nil
+	}
+
+	return errors.BadRequest(fmt.Sprintf(
+		"the index was built with %s model %s, so documents cannot be embedded with %s model %s (reindex to switch models)",
+		indexed.Provider, indexed.Model, stamp.Provider, stamp.Model)).WithCode(errors.CodeModelMismatch)
 }
 
-// ListDocuments returns all uploaded documents (GET /api/v1/documents)
+// ListDocuments returns all uploaded documents, including soft-deleted ones with
+// ?include_deleted=true (GET /api/v1/documents)
 func (h *UploadHandler) ListDocuments(c *fiber.Ctx) error {
-	docs, err := h.metadataStore.List()
+	list := h.metadataStore.List
+	if c.QueryBool("include_deleted") {
+		list = h.metadataStore.ListAll
+	}
+
+	all, err := list()
 	if err != nil {
 		h.logger.Error("failed to list documents", zap.Error(err))
 		return h.sendError(c, errors.InternalWrap(err, "failed to list documents"))
 	}
 
+	docs := make([]document.DocumentMetadata, 0, len(all))
+	for _, doc := range all {
+		if tenant.Allows(c.UserContext(), doc.TenantID) {
+			docs = append(docs, doc)
+		}
+	}
+
+	etag, err := documentsETag(docs)
+	if err != nil {
+		h.logger.Error("failed to compute documents etag", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to list documents"))
+	}
+
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(docs)
 }
 
-// DeleteDocument deletes a document and its chunks (DELETE /api/v1/documents/:id)
+// documentsETag returns a strong ETag derived from the sorted document metadata, so any
+// added, deleted or updated document changes it
+func documentsETag(docs []document.DocumentMetadata) (string, error) {
+	sorted := append([]document.DocumentMetadata(nil), docs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	data, err := json.Marshal(sorted)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches the given ETag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetDocument returns a document with its full text (GET /api/v1/documents/:id)
+func (h *UploadHandler) GetDocument(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return h.sendError(c, errors.BadRequest("document id is required"))
+	}
+
+	if err := h.checkOwner(c.UserContext(), id); err != nil {
+		return h.sendError(c, err)
+	}
+
+	doc, err := h.docService.GetDocument(id)
+	if err != nil {
+		h.logger.Warn("failed to get document", zap.String("doc_id", id), zap.Error(err))
+		return h.sendError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(doc)
+}
+
+// DeleteDocument soft-deletes a document, hiding it and its chunks until it is restored or
+// purged. With ?purge=true it is removed permanently. (DELETE /api/v1/documents/:id)
 func (h *UploadHandler) DeleteDocument(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
 		return h.sendError(c, errors.BadRequest("document id is required"))
 	}
 
-	// Delete from metadata
-	if err := h.metadataStore.Delete(id); err != nil {
+	if err := h.checkOwner(c.UserContext(), id); err != nil {
+		return h.sendError(c, err)
+	}
+
+	if h.reindexSvc.Running() {
+		return h.sendError(c, errReindexInProgress)
+	}
+
+	if c.QueryBool("purge") {
+		if err := h.purger.Purge(id); err != nil {
+			h.logger.Error("failed to purge document", zap.Error(err))
+			return h.sendError(c, errors.InternalWrap(err, "failed to purge document"))
+		}
+
+		h.logger.Info("document purged", zap.String("doc_id", id))
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"success": true,
+			"message": "document purged successfully",
+		})
+	}
+
+	now := time.Now()
+	if _, err := h.metadataStore.SetDeleted(id, &now); err != nil {
+		if err == badger.ErrKeyNotFound {
+			return h.sendError(c, errors.NotFound("document not found").WithCode(errors.CodeDocumentNotFound))
+		}
 		h.logger.Error("failed to delete document metadata", zap.Error(err))
 		return h.sendError(c, errors.InternalWrap(err, "failed to delete document"))
 	}
 
-	// Delete chunks from vector store
+	// Hide chunks from search
 	if err := h.vectorStore.DeleteByDocID(id); err != nil {
 		h.logger.Error("failed to delete document chunks", zap.Error(err))
 		return h.sendError(c, errors.InternalWrap(err, "failed to delete document chunks"))
@@ -262,15 +802,69 @@ func (h *UploadHandler) DeleteDocument(c *fiber.Ctx) error {
 	})
 }
 
+// RestoreDocument restores a soft-deleted document (POST /api/v1/documents/:id/restore)
+func (h *UploadHandler) RestoreDocument(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return h.sendError(c, errors.BadRequest("document id is required"))
+	}
+
+	if err := h.checkOwner(c.UserContext(), id); err != nil {
+		return h.sendError(c, err)
+	}
+
+	if h.reindexSvc.Running() {
+		return h.sendError(c, errReindexInProgress)
+	}
+
+	doc, err := h.metadataStore.SetDeleted(id, nil)
+	if err != nil {
+		if err == badger.ErrKeyNotFound {
+			return h.sendError(c, errors.NotFound("document not found").WithCode(errors.CodeDocumentNotFound))
+		}
+		h.logger.Error("failed to restore document metadata", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to restore document"))
+	}
+
+	if err := h.vectorStore.RestoreByDocID(id); err != nil {
+		h.logger.Error("failed to restore document chunks", zap.Error(err))
+		return h.sendError(c, errors.InternalWrap(err, "failed to restore document chunks"))
+	}
+
+	h.logger.Info("document restored", zap.String("doc_id", id))
+
+	return c.Status(fiber.StatusOK).JSON(doc)
+}
+
+// checkOwner returns a not found error unless the document exists and belongs to the
+// caller's tenant. Other tenants' documents are reported as missing, so their IDs can't be probed.
+func (h *UploadHandler) checkOwner(ctx context.Context, id string) error {
+	doc, err := h.metadataStore.Get(id)
+	if err == badger.ErrKeyNotFound || err == nil && !tenant.Allows(ctx, doc.TenantID) {
+		return errors.NotFound("document not found").WithCode(errors.CodeDocumentNotFound)
+	}
+	if err != nil {
+		h.logger.Error("failed to get document metadata", zap.String("doc_id", id), zap.Error(err))
+		return errors.InternalWrap(err, "failed to get document metadata")
+	}
+	return nil
+}
+
 // sendError sends an error response
 func (h *UploadHandler) sendError(c *fiber.Ctx, err error) error {
+	if errors.IsDeadlineExceeded(err) {
+		err = errors.ErrDeadlineExceeded
+	}
+
 	appErr, ok := err.(*errors.AppError)
 	if !ok {
 		appErr = errors.Internal("internal server error")
 	}
 
 	return c.Status(appErr.Code).JSON(models.ErrorResponse{
-		Error: appErr.Message,
-		Code:  appErr.Code,
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+		Fields:    appErr.Fields,
 	})
 }
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUploadStreamReportsProgress(t *testing.T) {
+	stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.ChunkUnit = "chars"
+	cfg.RAG.ChunkSize = 100
+	cfg.RAG.ChunkOverlap = 0
+	cfg.RAG.DedupeChunks = false
+	env := newTestEnv(t, cfg)
+
+	app := fiber.New()
+	app.Post("/upload/stream", env.uploadHandler().UploadStream)
+
+	body, contentType := multipartFile(t, "guide.txt", strings.Repeat("0123456789", 95))
+	status, resp := doRequest(t, app, http.MethodPost, "/upload/stream", body, map[string]string{"Content-Type": contentType})
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body %s", status, resp)
+	}
+
+	events := sseEvents(t, resp)
+	if len(events) < 2 {
+		t.Fatalf("events = %v, want progress then done", events)
+	}
+
+	last := 0.0
+	for _, event := range events[:len(events)-1] {
+		if event["type"] != "progress" {
+			t.Fatalf("event %v before done, want only progress", event)
+		}
+		embedded, total := event["embedded"].(float64), event["total"].(float64)
+		if embedded < last || embedded > total || total != 10 {
+			t.Errorf("progress %v of 10 after %v, want monotonic progress", embedded, last)
+		}
+		last = embedded
+	}
+	if last != 10 {
+		t.Errorf("last progress = %v, want all 10 chunks embedded", last)
+	}
+
+	done := events[len(events)-1]
+	if done["type"] != "done" || done["chunk_count"] != 10.0 || done["document_id"] == "" {
+		t.Fatalf("final event = %v, want done with the document", done)
+	}
+	if _, err := env.metadataStore.Get(done["document_id"].(string)); err != nil {
+		t.Errorf("streamed document is not stored: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"testing"
+)
+
+// fileHeader returns the multipart header of an uploaded file with the given content
+func fileHeader(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("failed to read form: %v", err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+
+	return form.File["file"][0]
+}
+
+func TestDetectMediaTypeDropsParameters(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{[]byte("plain text notes"), "text/plain"},
+		{[]byte("\xef\xbb\xbfnotes with a byte order mark"), "text/plain"},
+		{[]byte("%PDF-1.4 binary"), "application/pdf"},
+	}
+
+	for _, tt := range tests {
+		if got := detectMediaType(tt.data); got != tt.want {
+			t.Errorf("detectMediaType(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDetectAndValidateFileType(t *testing.T) {
+	contentType, err := detectAndValidateFileType(fileHeader(t, "notes.txt", []byte("plain text notes")), false, false)
+	if err != nil {
+		t.Fatalf("text upload rejected: %v", err)
+	}
+	if contentType != "text/plain" {
+		t.Errorf("content type = %q, want text/plain", contentType)
+	}
+
+	if _, err := detectAndValidateFileType(fileHeader(t, "notes.txt", []byte("%PDF-1.4 binary")), false, false); err == nil {
+		t.Error("PDF content with a .txt extension was accepted")
+	}
+	if _, err := detectAndValidateFileType(fileHeader(t, "main.go", []byte("package main")), false, false); err == nil {
+		t.Error("source file accepted with CODE_INDEXING off")
+	}
+	if _, err := detectAndValidateFileType(fileHeader(t, "main.go", []byte("package main")), true, false); err != nil {
+		t.Errorf("source file rejected with CODE_INDEXING on: %v", err)
+	}
+}
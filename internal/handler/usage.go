@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/service/usage"
+)
+
+// UsageHandler handles LLM usage and cost requests
+type UsageHandler struct {
+	tracker *usage.Tracker
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(tracker *usage.Tracker) *UsageHandler {
+	return &UsageHandler{
+		tracker: tracker,
+	}
+}
+
+// Usage returns the token usage and estimated cost per provider and model (GET /api/v1/usage)
+func (h *UsageHandler) Usage(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(h.tracker.Snapshot())
+}
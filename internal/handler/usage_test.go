@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/llm"
+	"github.com/mrkaynak/rag/internal/service/usage"
+)
+
+func TestChatsAccumulateUsage(t *testing.T) {
+	stub := stubProviders(t)
+	stub.usage = &[2]int{1000, 200}
+
+	env := newTestEnv(t, testConfig(t))
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+
+	tracker, err := usage.New(`{"test/model": {"input": 3, "output": 15}}`, nil)
+	if err != nil {
+		t.Fatalf("failed to create usage tracker: %v", err)
+	}
+	handler := env.chatHandler(t, nil)
+	handler.openRouterClient = llm.NewOpenRouterClient(env.cfg, env.logger, nil, tracker)
+
+	app := fiber.New()
+	app.Post("/chat", handler.Chat)
+	app.Get("/usage", NewUsageHandler(tracker).Usage)
+
+	chat(t, app, `{"message": "What is RAG?", "provider": "openrouter", "model": "test/model"}`)
+	chat(t, app, `{"message": "How does retrieval work?", "provider": "openrouter", "model": "test/model"}`)
+
+	status, body := doRequest(t, app, http.MethodGet, "/usage", "", nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("usage = %d: %s", status, body)
+	}
+	var resp models.UsageResponse
+	decodeJSON(t, body, &resp)
+
+	if len(resp.Models) != 1 {
+		t.Fatalf("usage models = %+v, want the one model used", resp.Models)
+	}
+	m := resp.Models[0]
+	if m.Provider != "openrouter" || m.Requests != 2 || m.InputTokens != 2000 || m.OutputTokens != 400 {
+		t.Errorf("usage = %+v, want the provider's reported tokens of both chats", m)
+	}
+	// 2000 input tokens at $3/M plus 400 output tokens at $15/M
+	if want := 0.012; !m.Priced || math.Abs(m.Cost-want) > 1e-9 || math.Abs(resp.Cost-want) > 1e-9 {
+		t.Errorf("cost = %g (total %g), want %g", m.Cost, resp.Cost, want)
+	}
+}
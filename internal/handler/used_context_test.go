@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestChatReportsUsedContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   int
+	}{
+		{"empty knowledge base", nil, 0},
+		{"populated knowledge base", []string{"RAG combines retrieval with generation.", "RAG grounds answers in documents."}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubProviders(t)
+			env := newTestEnv(t, testConfig(t))
+			for i, content := range tt.chunks {
+				env.addChunk(t, "c"+string(rune('1'+i)), "doc1", content)
+			}
+
+			app := fiber.New()
+			app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+			resp := chat(t, app, `{"message": "What is RAG?", "provider": "openrouter"}`)
+			if resp.UsedContext != (tt.want > 0) || resp.ContextChunkCount != tt.want {
+				t.Errorf("used_context = %v, context_chunk_count = %d; want %v, %d",
+					resp.UsedContext, resp.ContextChunkCount, tt.want > 0, tt.want)
+			}
+		})
+	}
+}
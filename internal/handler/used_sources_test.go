@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// usedSourcesApp serves /chat with TRACK_USED_SOURCES on over two indexed chunks
+func usedSourcesApp(t *testing.T, track bool) (*fiber.App, *providerStub) {
+	t.Helper()
+
+	stub := stubProviders(t)
+	cfg := testConfig(t)
+	cfg.RAG.TrackUsedSources = track
+	env := newTestEnv(t, cfg)
+	env.addChunk(t, "c1", "doc1", "RAG combines retrieval with generation.")
+	env.addChunk(t, "c2", "doc2", "Vector stores index embeddings.")
+
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+	return app, stub
+}
+
+// promptCitation finds the number a context block carries in the system prompt
+func promptCitation(t *testing.T, systemPrompt, content string) string {
+	t.Helper()
+
+	m := regexp.MustCompile(`\[(\d+)\] ` + regexp.QuoteMeta(content)).FindStringSubmatch(systemPrompt)
+	if m == nil {
+		t.Fatalf("context block %q is not numbered in the prompt:\n%s", content, systemPrompt)
+	}
+	return m[1]
+}
+
+func TestChatNumbersContextForCitations(t *testing.T) {
+	app, stub := usedSourcesApp(t, true)
+
+	var cite string
+	stub.reply = func(systemPrompt, _ string) (int, string) {
+		cite = promptCitation(t, systemPrompt, "Vector stores index embeddings.")
+		return http.StatusOK, "Embeddings live in vector stores [" + cite + "]."
+	}
+
+	resp := chat(t, app, `{"message": "Where are embeddings kept?", "provider": "openrouter"}`)
+
+	if len(resp.UsedSources) != 1 {
+		t.Fatalf("used_sources = %v, want the cited block only", resp.UsedSources)
+	}
+	if got := resp.Context[resp.UsedSources[0]]; got != "Vector stores index embeddings." {
+		t.Errorf("used source = %q, want the block cited as [%s]", got, cite)
+	}
+	if stub.calls() != 1 {
+		t.Errorf("LLM calls = %d, want no follow-up call when the answer cites its sources", stub.calls())
+	}
+}
+
+func TestChatContextUnnumberedWithoutTracking(t *testing.T) {
+	app, stub := usedSourcesApp(t, false)
+
+	resp := chat(t, app, `{"message": "Where are embeddings kept?", "provider": "openrouter"}`)
+	if len(resp.UsedSources) != 0 {
+		t.Errorf("used_sources = %v, want none with TRACK_USED_SOURCES off", resp.UsedSources)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if strings.Contains(stub.lastSystemPrompt, "[1] ") {
+		t.Errorf("context is numbered with TRACK_USED_SOURCES off:\n%s", stub.lastSystemPrompt)
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+func TestValidateStructTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   interface{}
+		rules map[string]string
+	}{
+		{"valid chat", &models.ChatRequest{Message: "hi", Provider: "openrouter"}, nil},
+		{"required fields", &models.ChatRequest{}, map[string]string{"message": "required", "provider": "required"}},
+		{"oneof provider", &models.ChatRequest{Message: "hi", Provider: "nope"}, map[string]string{"provider": "oneof"}},
+		{"optional oneof set", &models.ChatRequest{Message: "hi", Provider: "bedrock", Mode: "draft"}, map[string]string{"mode": "oneof"}},
+		{"empty element", &models.ChatRequest{Message: "hi", Provider: "bedrock", Stop: []string{"END", ""}}, map[string]string{"stop[1]": "required"}},
+		{"negative top_k", &models.SearchRequest{Query: "q", TopK: -1}, map[string]string{"top_k": "min"}},
+		{"too many texts", &models.EmbedRequest{Texts: make([]string, 101)}, map[string]string{"texts": "max"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStruct(tt.req)
+			if tt.rules == nil {
+				if err != nil {
+					t.Fatalf("validateStruct = %v, want valid", err)
+				}
+				return
+			}
+
+			appErr, ok := err.(*errors.AppError)
+			if !ok || appErr.Code != http.StatusBadRequest || appErr.ErrorCode != errors.CodeValidationFailed {
+				t.Fatalf("validateStruct = %v, want a 400 %s", err, errors.CodeValidationFailed)
+			}
+
+			rules := make(map[string]string)
+			for _, field := range appErr.Fields {
+				rules[field.Field] = field.Rule
+				if field.Message == "" {
+					t.Errorf("field %s has no message", field.Field)
+				}
+			}
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("field errors = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestParseBodyReturnsFieldErrors(t *testing.T) {
+	stubProviders(t)
+	env := newTestEnv(t, testConfig(t))
+	app := fiber.New()
+	app.Post("/chat", env.chatHandler(t, nil).Chat)
+
+	status, body := doRequest(t, app, http.MethodPost, "/chat", `{"provider": "azure"}`, nil)
+	var resp models.ErrorResponse
+	decodeJSON(t, body, &resp)
+
+	want := []errors.FieldError{
+		{Field: "message", Rule: "required", Message: "message is required"},
+		{Field: "provider", Rule: "oneof", Message: "provider must be one of: openrouter, bedrock"},
+	}
+	if status != fiber.StatusBadRequest || !reflect.DeepEqual(resp.Fields, want) {
+		t.Errorf("response = %d %+v, want 400 with %+v", status, resp.Fields, want)
+	}
+
+	if status, _ := doRequest(t, app, http.MethodPost, "/chat", `{"message": "hi", "provider": "openrouter"}`, nil); status != fiber.StatusOK {
+		t.Errorf("valid request = %d, want 200", status)
+	}
+}
@@ -0,0 +1,30 @@
+package handler
+
+// verbosityLevel is the reply token bound and system prompt instruction of a verbosity
+type verbosityLevel struct {
+	maxTokens   int
+	instruction string
+}
+
+// verbosityLevels maps a chat request's verbosity to its level. "normal", the default,
+// leaves the prompt and the model's token limit unchanged.
+var verbosityLevels = map[string]verbosityLevel{
+	"concise": {
+		maxTokens:   300,
+		instruction: "Keep your answer concise: a few sentences at most, with no preamble or repetition.",
+	},
+	"detailed": {
+		maxTokens:   2048,
+		instruction: "Give a detailed, thorough answer, covering every relevant point in the context and explaining your reasoning.",
+	},
+}
+
+// applyVerbosity appends the verbosity instruction to the system prompt and returns the
+// prompt with the matching reply token bound (0 = unbounded)
+func applyVerbosity(systemPrompt, verbosity string) (string, int) {
+	level, ok := verbosityLevels[verbosity]
+	if !ok {
+		return systemPrompt, 0
+	}
+	return systemPrompt + "\n\n" + level.instruction, level.maxTokens
+}
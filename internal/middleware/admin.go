@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// AdminAuth creates a middleware guarding admin endpoints with a static API key.
+// The key is expected as "Authorization: Bearer <key>". When no key is configured,
+// admin endpoints are disabled entirely.
+func AdminAuth(apiKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if apiKey == "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":      "admin API is disabled (ADMIN_API_KEY is not set)",
+				"code":       fiber.StatusForbidden,
+				"error_code": errors.CodeAdminDisabled,
+			})
+		}
+
+		token := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":      "invalid admin API key",
+				"code":       fiber.StatusUnauthorized,
+				"error_code": errors.CodeInvalidAPIKey,
+			})
+		}
+
+		return c.Next()
+	}
+}
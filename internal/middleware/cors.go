@@ -10,7 +10,7 @@ func CORS() fiber.Handler {
 	return cors.New(cors.Config{
 		AllowOrigins:     "*",
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization," + RequestTimeoutHeader,
 		AllowCredentials: false,
 		MaxAge:           3600,
 	})
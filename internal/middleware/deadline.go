@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// RequestTimeoutHeader lets callers set their own deadline for a request
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// Deadline creates a middleware that bounds the total time spent on a request by attaching
+// a deadline to the request's user context, which retrieval, embedding and LLM calls honor.
+// The X-Request-Timeout header ("30s" or a number of seconds) overrides defaultTimeout and
+// is capped at maxTimeout; the cap only applies to client-supplied timeouts. With neither
+// set, requests have no deadline.
+func Deadline(defaultTimeout, maxTimeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timeout := defaultTimeout
+
+		if raw := strings.TrimSpace(c.Get(RequestTimeoutHeader)); raw != "" {
+			parsed, err := parseTimeout(raw)
+			if err != nil || parsed <= 0 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":      RequestTimeoutHeader + " must be a positive duration such as 30s or a number of seconds",
+					"code":       fiber.StatusBadRequest,
+					"error_code": errors.CodeInvalidRequestTimeout,
+				})
+			}
+			timeout = parsed
+
+			if maxTimeout > 0 && timeout > maxTimeout {
+				timeout = maxTimeout
+			}
+		}
+
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// Streamed bodies are written after the handler returns, so their context must stay
+		// alive until the deadline; release it then rather than now
+		if c.Response().IsBodyStream() {
+			time.AfterFunc(timeout, cancel)
+		} else {
+			cancel()
+		}
+
+		return err
+	}
+}
+
+// parseTimeout parses a Go duration ("30s", "2m") or a plain number of seconds
+func parseTimeout(raw string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(raw)
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// deadlineApp returns the remaining time until the request deadline, or "none"
+func deadlineApp(defaultTimeout, maxTimeout time.Duration) *fiber.App {
+	app := fiber.New()
+	app.Use(Deadline(defaultTimeout, maxTimeout))
+	app.Get("/", func(c *fiber.Ctx) error {
+		deadline, ok := c.UserContext().Deadline()
+		if !ok {
+			return c.SendString("none")
+		}
+		return c.SendString(time.Until(deadline).Round(time.Second).String())
+	})
+	return app
+}
+
+func getDeadline(t *testing.T, app *fiber.App, header string) (int, string) {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(RequestTimeoutHeader, header)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	buf := make([]byte, 256)
+	n, _ := resp.Body.Read(buf)
+	return resp.StatusCode, string(buf[:n])
+}
+
+func TestDeadline(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultTimeout time.Duration
+		maxTimeout     time.Duration
+		header         string
+		want           string
+	}{
+		{"no default, no header", 0, 10 * time.Minute, "", "none"},
+		{"default applies", 30 * time.Second, 10 * time.Minute, "", "30s"},
+		{"default is not capped", 20 * time.Minute, 10 * time.Minute, "", "20m0s"},
+		{"header overrides default", 30 * time.Second, 10 * time.Minute, "5s", "5s"},
+		{"header in seconds", 0, 10 * time.Minute, "45", "45s"},
+		{"header capped at max", 0, time.Minute, "5m", "1m0s"},
+		{"header uncapped without max", 0, 0, "5m", "5m0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, got := getDeadline(t, deadlineApp(tt.defaultTimeout, tt.maxTimeout), tt.header)
+			if status != fiber.StatusOK {
+				t.Fatalf("status = %d (%s)", status, got)
+			}
+			if got != tt.want {
+				t.Errorf("deadline in %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeadlineRejectsInvalidHeader(t *testing.T) {
+	for _, header := range []string{"soon", "-5s", "0"} {
+		status, _ := getDeadline(t, deadlineApp(0, time.Minute), header)
+		if status != fiber.StatusBadRequest {
+			t.Errorf("%s: %q: status = %d, want 400", RequestTimeoutHeader, header, status)
+		}
+	}
+}
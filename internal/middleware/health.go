@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// HealthChecker reports whether a dependency is usable
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// RequireHealthy creates a middleware that rejects requests with 503 while the checker
+// reports a failure, instead of letting them fail midway. It is a no-op when disabled.
+func RequireHealthy(enabled bool, checker HealthChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !enabled {
+			return c.Next()
+		}
+
+		if err := checker.CheckHealth(c.UserContext()); err != nil {
+			errorCode := errors.CodeServiceUnavailable
+			if appErr, ok := err.(*errors.AppError); ok {
+				errorCode = appErr.ErrorCode
+			}
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error":      err.Error(),
+				"code":       fiber.StatusServiceUnavailable,
+				"error_code": errorCode,
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -1,13 +1,19 @@
 package middleware
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/pkg/httpdebug"
 	"go.uber.org/zap"
 )
 
-// Logger creates a logging middleware
+// maxSampledBody caps how much of each body a sampled request logs
+const maxSampledBody = 64 << 10
+
+// Logger creates a logging middleware. Requests marked by Sample additionally log their
+// headers and bodies, with credentials redacted.
 func Logger(logger *zap.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
@@ -25,6 +31,23 @@ func Logger(logger *zap.Logger) fiber.Handler {
 			zap.String("user_agent", c.Get("User-Agent")),
 		)
 
+		if Sampled(c) {
+			// Streamed bodies are written after the handler returns and are not available here
+			var responseBody []byte
+			if !c.Response().IsBodyStream() {
+				responseBody = c.Response().Body()
+			}
+
+			logger.Info("sampled request details",
+				zap.String("method", c.Method()),
+				zap.String("path", c.Path()),
+				zap.Any("request_headers", httpdebug.RedactHeaders(http.Header(c.GetReqHeaders()))),
+				zap.String("request_body", httpdebug.RedactBody(c.Body(), maxSampledBody)),
+				zap.Any("response_headers", httpdebug.RedactHeaders(http.Header(c.GetRespHeaders()))),
+				zap.String("response_body", httpdebug.RedactBody(responseBody, maxSampledBody)),
+			)
+		}
+
 		return err
 	}
 }
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"math/rand/v2"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sampledKey marks a request in fiber locals as sampled for verbose logging
+const sampledKey = "log_sampled"
+
+// Sample creates a middleware that marks a random fraction of requests (rate, 0-1) for
+// verbose logging. Logger and handlers check Sampled before emitting request details, so
+// full payloads can be inspected in production without logging every request.
+func Sample(rate float64) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if rate > 0 && rand.Float64() < rate {
+			c.Locals(sampledKey, true)
+		}
+		return c.Next()
+	}
+}
+
+// Sampled reports whether the request was marked for verbose logging
+func Sampled(c *fiber.Ctx) bool {
+	sampled, _ := c.Locals(sampledKey).(bool)
+	return sampled
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampleMarksConfiguredFraction(t *testing.T) {
+	const requests = 10000
+
+	for _, rate := range []float64{0, 0.01, 0.1, 0.5, 1} {
+		sampled := 0
+		app := fiber.New()
+		app.Use(Sample(rate))
+		app.Get("/", func(c *fiber.Ctx) error {
+			if Sampled(c) {
+				sampled++
+			}
+			return nil
+		})
+
+		for i := 0; i < requests; i++ {
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+		}
+
+		// Five standard deviations of the binomial count; exact for rates 0 and 1
+		want := rate * requests
+		tolerance := 5 * math.Sqrt(requests*rate*(1-rate))
+		if math.Abs(float64(sampled)-want) > tolerance {
+			t.Errorf("rate %g: %d of %d requests sampled, want %.0f ± %.0f", rate, sampled, requests, want, tolerance)
+		}
+	}
+}
+
+func TestLoggerDetailsOnlySampledRequests(t *testing.T) {
+	for _, rate := range []float64{0, 1} {
+		core, logs := observer.New(zap.InfoLevel)
+		app := fiber.New()
+		app.Use(Sample(rate))
+		app.Use(Logger(zap.New(core)))
+		app.Post("/", func(c *fiber.Ctx) error {
+			return c.SendString(`{"answer": "ok"}`)
+		})
+
+		req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"message": "hello", "api_key": "secret-key"}`))
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		details := logs.FilterMessage("sampled request details").All()
+		if rate == 0 {
+			if len(details) != 0 {
+				t.Errorf("unsampled request logged details: %v", details[0].ContextMap())
+			}
+			continue
+		}
+
+		if len(details) != 1 {
+			t.Fatalf("sampled request logged %d detail entries, want 1", len(details))
+		}
+		fields := details[0].ContextMap()
+		if fields["request_body"] != `{"api_key":"[REDACTED]","message":"hello"}` || fields["response_body"] != `{"answer":"ok"}` {
+			t.Errorf("logged bodies = %v / %v, want the redacted request and response bodies", fields["request_body"], fields["response_body"])
+		}
+		for key, value := range fields {
+			if logged := fmt.Sprint(value); strings.Contains(logged, "secret-token") || strings.Contains(logged, "secret-key") {
+				t.Errorf("%s logs a credential: %s", key, logged)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/tenant"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// tenantPattern restricts tenant IDs to URL and key friendly characters
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+
+// Tenant creates a middleware that reads the caller's tenant ID from the given header and
+// attaches it to the request's user context, where uploads and retrieval pick it up. The
+// header must be set by a trusted gateway in front of the service. Requests without a valid
+// tenant ID are rejected. When header is empty, tenant isolation is off and requests pass through.
+func Tenant(header string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if header == "" {
+			return c.Next()
+		}
+
+		id := strings.TrimSpace(c.Get(header))
+		if id == "" {
+			return sendTenantError(c, errors.Unauthorized(header+" header is required").WithCode(errors.CodeTenantRequired))
+		}
+		if !tenantPattern.MatchString(id) {
+			return sendTenantError(c, errors.BadRequest(header+" must be 1-128 letters, digits, '-', '_', '.' or ':'").WithCode(errors.CodeInvalidTenant))
+		}
+
+		c.SetUserContext(tenant.WithID(c.UserContext(), id))
+
+		return c.Next()
+	}
+}
+
+// sendTenantError sends a tenant error in the same shape as handler errors
+func sendTenantError(c *fiber.Ctx, appErr *errors.AppError) error {
+	return c.Status(appErr.Code).JSON(models.ErrorResponse{
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		ErrorCode: appErr.ErrorCode,
+	})
+}
@@ -1,6 +1,13 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// DefaultNamespace is the namespace of documents uploaded without one
+const DefaultNamespace = "default"
 
 // Document represents an uploaded document
 type Document struct {
@@ -9,6 +16,8 @@ type Document struct {
 	Content   string    `json:"content"`
 	Chunks    []Chunk   `json:"chunks,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
+	// DuplicateChunks counts chunks dropped as repeats of earlier ones (CHUNK_DEDUPE)
+	DuplicateChunks int `json:"duplicate_chunks,omitempty"`
 }
 
 // Chunk represents a text chunk with embeddings
@@ -18,21 +27,217 @@ type Chunk struct {
 	Content   string    `json:"content"`
 	Embedding []float64 `json:"embedding,omitempty"`
 	Index     int       `json:"index"`
+	Heading   string    `json:"heading,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	// TenantID is the tenant that uploaded the chunk's document (with TENANT_HEADER set)
+	TenantID string `json:"tenant_id,omitempty"`
+	// Quantized holds the int8 form of Embedding when VECTOR_QUANTIZATION=int8
+	Quantized *QuantizedEmbedding `json:"quantized,omitempty"`
+	// Embedding32 holds the float32 form of Embedding when VECTOR_QUANTIZATION=float32
+	Embedding32 []float32 `json:"embedding32,omitempty"`
+	// CompressedContent holds the deflated Content when COMPRESS_CHUNK_TEXT is enabled
+	CompressedContent []byte `json:"compressed_content,omitempty"`
+	// EmbeddingText, when set, is embedded instead of Content (e.g. with a title prefix)
+	EmbeddingText string `json:"-"`
+	// Deleted marks a chunk of a soft-deleted document; it is kept but never searched or listed
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// QuantizedEmbedding is an embedding scaled into int8 values (stored as bytes). Each
+// dimension is scaled with that dimension's min/max range in the store's quantization scale
+// ScaleID. Embeddings quantized before per-dimension scales existed have no ScaleID and use
+// the vector's own Min/Max for every dimension.
+type QuantizedEmbedding struct {
+	Min     float64 `json:"min,omitempty"`
+	Max     float64 `json:"max,omitempty"`
+	ScaleID uint64  `json:"scale_id,omitempty"`
+	Values  []byte  `json:"values"`
+
+	// Scale is the scale ScaleID refers to, attached by the vector store
+	Scale *QuantizationScale `json:"-"`
+}
+
+// QuantizationScale holds the per-dimension value ranges int8 embeddings are scaled with. A
+// scale is never changed once embeddings use it; widening it creates a new one.
+type QuantizationScale struct {
+	ID  uint64    `json:"id"`
+	Min []float64 `json:"min"`
+	Max []float64 `json:"max"`
 }
 
 // ChatRequest represents a chat request
 type ChatRequest struct {
-	Message      string `json:"message" validate:"required"`
-	Provider     string `json:"provider" validate:"required,oneof=openrouter bedrock"`
-	Model        string `json:"model,omitempty"`
-	SystemPrompt string `json:"system_prompt,omitempty"`
+	Message      string   `json:"message" validate:"required"`
+	Provider     string   `json:"provider" validate:"required,oneof=openrouter bedrock"`
+	Model        string   `json:"model,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+	Namespaces   []string `json:"namespaces,omitempty"`
+	// UploadedAfter/UploadedBefore restrict retrieval to documents uploaded in that range
+	UploadedAfter  *time.Time `json:"uploaded_after,omitempty"`
+	UploadedBefore *time.Time `json:"uploaded_before,omitempty"`
+	// MessageSuffix is appended to the message sent to the LLM but not used for retrieval
+	MessageSuffix string `json:"message_suffix,omitempty"`
+	// Mode is "generate" (default) or "retrieval" to skip the LLM and return the prompt
+	Mode string `json:"mode,omitempty" validate:"omitempty,oneof=generate retrieval"`
+	// Stop sequences end generation; defaults to the stop sequences saved for the model
+	Stop []string `json:"stop,omitempty" validate:"omitempty,dive,required"`
+	// Verbosity is "concise", "normal" (default) or "detailed"; it bounds the reply length
+	// and adds a matching instruction to the system prompt
+	Verbosity string `json:"verbosity,omitempty" validate:"omitempty,oneof=concise normal detailed"`
+}
+
+// ChatResumeRequest continues a streaming answer that failed midway
+type ChatResumeRequest struct {
+	ResumeToken string `json:"resume_token" validate:"required"`
+	Partial     string `json:"partial" validate:"required"`
+}
+
+// PromptTestRequest represents a chat run with a candidate system prompt
+type PromptTestRequest struct {
+	// Prompt is the candidate prompt; PromptID selects a saved prompt instead
+	Prompt     string   `json:"prompt,omitempty"`
+	PromptID   string   `json:"prompt_id,omitempty"`
+	Message    string   `json:"message" validate:"required"`
+	Provider   string   `json:"provider" validate:"required,oneof=openrouter bedrock"`
+	Model      string   `json:"model,omitempty"`
+	Namespaces []string `json:"namespaces,omitempty"`
+	// UseRAG retrieves context for the message (default true, ignored when Context is set)
+	UseRAG *bool `json:"use_rag,omitempty"`
+	// Context supplies sample context passages instead of retrieving them
+	Context []string `json:"context,omitempty"`
+}
+
+// PromptTestResponse represents the answer produced by a candidate system prompt
+type PromptTestResponse struct {
+	Message           string       `json:"message"`
+	SystemPrompt      string       `json:"system_prompt"`
+	Context           []string     `json:"context,omitempty"`
+	ContextChunkCount int          `json:"context_chunk_count"`
+	TokenMetrics      TokenMetrics `json:"token_metrics"`
 }
 
 // ChatResponse represents a chat response
 type ChatResponse struct {
-	Message      string       `json:"message"`
-	Context      []string     `json:"context,omitempty"`
-	TokenMetrics TokenMetrics `json:"token_metrics,omitempty"`
+	Message     string           `json:"message"`
+	Context     []string         `json:"context,omitempty"`
+	Sources     []RetrievedChunk `json:"sources,omitempty"`
+	UsedSources []int            `json:"used_sources,omitempty"`
+	// Grounding is the verification verdict, set with GROUNDING_CHECK when context was used
+	Grounding *GroundingVerdict `json:"grounding,omitempty"`
+	// UsedContext is true when at least one retrieved chunk was included in the prompt
+	UsedContext       bool         `json:"used_context"`
+	ContextChunkCount int          `json:"context_chunk_count"`
+	TokenMetrics      TokenMetrics `json:"token_metrics,omitempty"`
+	// Degraded is true when the context came from the keyword fallback (EMBED_FALLBACK)
+	Degraded bool `json:"degraded,omitempty"`
+	// LowConfidence is true when no chunk cleared CONTEXT_MIN_SCORE and the context is the
+	// MIN_RESULTS fallback
+	LowConfidence bool `json:"low_confidence,omitempty"`
+	// Cached is true when the answer was served from the answer cache (ANSWER_CACHE)
+	Cached bool `json:"cached,omitempty"`
+}
+
+// GroundingVerdict reports whether every claim of an answer is supported by its context
+type GroundingVerdict struct {
+	Grounded bool `json:"grounded"`
+	// Unsupported lists the claims the context does not support
+	Unsupported []string `json:"unsupported"`
+}
+
+// RetrievedChunk represents a retrieved chunk with its raw score and normalized relevance
+type RetrievedChunk struct {
+	ID        string  `json:"id"`
+	DocID     string  `json:"doc_id"`
+	Index     int     `json:"index"`
+	Heading   string  `json:"heading,omitempty"`
+	Namespace string  `json:"namespace,omitempty"`
+	Content   string  `json:"content"`
+	Score     float64 `json:"score"`
+	Relevance float64 `json:"relevance"`
+	// LowConfidence marks a result below SEARCH_MIN_SCORE kept to reach SEARCH_MIN_RESULTS,
+	// or below CONTEXT_MIN_SCORE kept as chat context by the MIN_RESULTS fallback
+	LowConfidence bool `json:"low_confidence,omitempty"`
+	// Embedding is only populated when explicitly requested (?include_embeddings=true)
+	Embedding []float64 `json:"embedding,omitempty"`
+	// Debug explains the chunk's score and rank (?debug=true)
+	Debug *ChunkDebug `json:"debug,omitempty"`
+}
+
+// Source describes a retrieved chunk without its text, for the stream's sources event
+type Source struct {
+	DocID     string  `json:"doc_id"`
+	FileName  string  `json:"file_name,omitempty"`
+	ChunkID   string  `json:"chunk_id"`
+	Index     int     `json:"index"`
+	Heading   string  `json:"heading,omitempty"`
+	Namespace string  `json:"namespace,omitempty"`
+	Score     float64 `json:"score"`
+	Relevance float64 `json:"relevance"`
+}
+
+// ChunkDebug explains why a chunk was retrieved. Method is "vector" or "keyword" (the BM25
+// fallback); VectorScore or KeywordScore is that search's raw score, RecencyFactor the
+// RECENCY_BOOST multiplier, and FinalScore the score the chunk was ranked by.
+type ChunkDebug struct {
+	Rank          int      `json:"rank"`
+	Method        string   `json:"method"`
+	VectorScore   *float64 `json:"vector_score,omitempty"`
+	KeywordScore  *float64 `json:"keyword_score,omitempty"`
+	RecencyFactor *float64 `json:"recency_factor,omitempty"`
+	RerankScore   *float64 `json:"rerank_score,omitempty"`
+	FinalScore    float64  `json:"final_score"`
+	Tokens        int      `json:"tokens"`
+}
+
+// SearchRequest represents a semantic search request
+type SearchRequest struct {
+	Query          string     `json:"query" validate:"required"`
+	TopK           int        `json:"top_k,omitempty" validate:"min=0"`
+	Namespaces     []string   `json:"namespaces,omitempty"`
+	UploadedAfter  *time.Time `json:"uploaded_after,omitempty"`
+	UploadedBefore *time.Time `json:"uploaded_before,omitempty"`
+}
+
+// SearchResponse represents a semantic search response
+type SearchResponse struct {
+	Results []RetrievedChunk `json:"results"`
+	// Degraded is true when the results came from the keyword fallback (EMBED_FALLBACK)
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// EmbedRequest represents a request to embed arbitrary texts
+type EmbedRequest struct {
+	Texts []string `json:"texts" validate:"required,min=1,max=100,dive,required"`
+}
+
+// EmbedResponse holds one embedding per input text, in input order
+type EmbedResponse struct {
+	Provider   string      `json:"provider"`
+	Model      string      `json:"model"`
+	Dimensions int         `json:"dimensions"`
+	Embeddings [][]float64 `json:"embeddings"`
+	// Truncated lists the indices of texts cut to EMBEDDING_MAX_INPUT_TOKENS before embedding
+	Truncated []int `json:"truncated,omitempty"`
+}
+
+// ChatDebugResponse represents the fully assembled prompt for a chat request
+type ChatDebugResponse struct {
+	SystemPrompt    string           `json:"system_prompt"`
+	UserMessage     string           `json:"user_message"`
+	Chunks          []RetrievedChunk `json:"chunks"`
+	EstimatedTokens int              `json:"estimated_tokens"`
+}
+
+// ChatRetrievalResponse represents the prompt-ready context returned by retrieval mode
+type ChatRetrievalResponse struct {
+	SystemPrompt      string           `json:"system_prompt"`
+	UserMessage       string           `json:"user_message"`
+	Context           []string         `json:"context"`
+	Sources           []RetrievedChunk `json:"sources"`
+	UsedContext       bool             `json:"used_context"`
+	ContextChunkCount int              `json:"context_chunk_count"`
+	Degraded          bool             `json:"degraded,omitempty"`
+	LowConfidence     bool             `json:"low_confidence,omitempty"`
 }
 
 // TokenMetrics represents token usage information
@@ -47,12 +252,36 @@ type UploadResponse struct {
 	DocumentID string `json:"document_id"`
 	FileName   string `json:"file_name"`
 	ChunkCount int    `json:"chunk_count"`
+	// Action is what was done with the upload: created, or for a duplicate under
+	// ON_DUPLICATE, skipped or replaced
+	Action string `json:"action"`
+}
+
+// Upload actions reported in UploadResponse.Action
+const (
+	UploadCreated  = "created"
+	UploadSkipped  = "skipped"
+	UploadReplaced = "replaced"
+)
+
+// TextUploadRequest represents a document upload supplied as JSON text
+type TextUploadRequest struct {
+	FileName  string   `json:"filename" validate:"required"`
+	Content   string   `json:"content" validate:"required"`
+	Tags      []string `json:"tags,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	// EmbeddingProvider overrides EMBEDDING_PROVIDER for this document
+	EmbeddingProvider string `json:"embedding_provider,omitempty"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error string `json:"error"`
 	Code  int    `json:"code"`
+	// ErrorCode is a machine-readable code such as INVALID_PROVIDER (see pkg/errors)
+	ErrorCode string `json:"error_code"`
+	// Fields lists the individual validation failures of a rejected request body
+	Fields []errors.FieldError `json:"fields,omitempty"`
 }
 
 // HealthResponse represents a health check response
@@ -60,3 +289,120 @@ type HealthResponse struct {
 	Status  string `json:"status"`
 	Version string `json:"version"`
 }
+
+// ReadinessResponse represents a readiness check response. Checks maps each dependency to
+// "ok" or a description of its failure.
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// ChunkInfo represents chunk metadata without the embedding
+type ChunkInfo struct {
+	ID            string `json:"id"`
+	DocID         string `json:"doc_id"`
+	Index         int    `json:"index"`
+	Heading       string `json:"heading,omitempty"`
+	Snippet       string `json:"snippet"`
+	ContentLength int    `json:"content_length"`
+}
+
+// ChunkListResponse represents a page of chunk metadata
+type ChunkListResponse struct {
+	Chunks []ChunkInfo `json:"chunks"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// ExportedChunk represents one line of the NDJSON chunk export
+type ExportedChunk struct {
+	ID        string    `json:"id"`
+	DocID     string    `json:"doc_id"`
+	Index     int       `json:"index"`
+	Heading   string    `json:"heading,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Content   string    `json:"content"`
+	Embedding []float64 `json:"embedding,omitempty"`
+}
+
+// QueryLogEntry is one recorded query (QUERY_LOG) and one line of the evaluation export
+type QueryLogEntry struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// Endpoint is "chat" or "search"
+	Endpoint   string          `json:"endpoint"`
+	TenantID   string          `json:"tenant_id,omitempty"`
+	Query      string          `json:"query"`
+	Namespaces []string        `json:"namespaces,omitempty"`
+	Provider   string          `json:"provider,omitempty"`
+	Model      string          `json:"model,omitempty"`
+	Chunks     []QueryLogChunk `json:"chunks"`
+	// Answer is the final answer, empty for searches and retrieval-only chats
+	Answer   string `json:"answer,omitempty"`
+	Degraded bool   `json:"degraded,omitempty"`
+}
+
+// QueryLogEmbedding is one query recorded with its embedding (QUERY_LOGGING) and one
+// line of GET /api/v1/admin/queries. Query is anonymized before it is stored; Embedding is
+// computed from the original query and is not.
+type QueryLogEmbedding struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Query     string    `json:"query"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// QueryLogChunk is a chunk retrieved for a logged query, in rank order
+type QueryLogChunk struct {
+	ID    string  `json:"id"`
+	DocID string  `json:"doc_id"`
+	Score float64 `json:"score"`
+}
+
+// StatsResponse represents knowledge base statistics
+type StatsResponse struct {
+	Documents    int    `json:"documents"`
+	Chunks       int    `json:"chunks"`
+	StoreVersion uint64 `json:"store_version"`
+	// EmbeddingDimension is the native dimension of EMBEDDING_MODEL, omitted if it could not
+	// be detected
+	EmbeddingDimension int `json:"embedding_dimension,omitempty"`
+	// EmbeddingNorms is omitted while the store is empty
+	EmbeddingNorms *EmbeddingNormStats `json:"embedding_norms,omitempty"`
+}
+
+// EmbeddingNormStats describes the lengths of a sample of stored embeddings
+type EmbeddingNormStats struct {
+	Sampled       int     `json:"sampled"`
+	Normalized    int     `json:"normalized"`
+	AllNormalized bool    `json:"all_normalized"`
+	MinNorm       float64 `json:"min_norm"`
+	MaxNorm       float64 `json:"max_norm"`
+	MeanNorm      float64 `json:"mean_norm"`
+}
+
+// UsageResponse represents the LLM token usage and estimated cost accumulated since Since
+type UsageResponse struct {
+	Since        time.Time    `json:"since"`
+	Persisted    bool         `json:"persisted"`
+	Requests     int64        `json:"requests"`
+	InputTokens  int64        `json:"input_tokens"`
+	OutputTokens int64        `json:"output_tokens"`
+	TotalTokens  int64        `json:"total_tokens"`
+	Cost         float64      `json:"cost"`
+	Models       []ModelUsage `json:"models"`
+}
+
+// ModelUsage represents the usage of a single provider/model. Cost is in USD and only
+// computed when the model has a configured price (Priced).
+type ModelUsage struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	Cost         float64 `json:"cost"`
+	Priced       bool    `json:"priced"`
+}
@@ -0,0 +1,116 @@
+package answercache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// prefixAnswer prefixes cached answer keys
+const prefixAnswer = "answercache:"
+
+// Cache stores LLM answers keyed by everything that determines them, so a repeated question
+// over an unchanged corpus is answered without another LLM call (ANSWER_CACHE). Entries
+// expire after ANSWER_CACHE_TTL. A nil Cache stores nothing and never hits.
+type Cache struct {
+	db  *badger.DB
+	ttl time.Duration
+}
+
+// New creates an answer cache stored in db. Entries from a previous run are dropped: they
+// are keyed on the vector store version, which restarts at zero with the process.
+func New(db *badger.DB, ttl time.Duration) (*Cache, error) {
+	if err := db.DropPrefix([]byte(prefixAnswer)); err != nil {
+		return nil, fmt.Errorf("failed to clear answer cache: %w", err)
+	}
+	return &Cache{db: db, ttl: ttl}, nil
+}
+
+// Enabled reports whether answers are cached
+func (c *Cache) Enabled() bool {
+	return c != nil
+}
+
+// KeyParts are the inputs an answer depends on
+type KeyParts struct {
+	// Query is the user message sent to the LLM, including any message suffix
+	Query        string
+	SystemPrompt string
+	ChunkIDs     []string
+	Provider     string
+	Model        string
+	MaxTokens    int
+	Stop         []string
+	// StoreVersion is the vector store version read before retrieval, so an answer is never
+	// served after the corpus changed
+	StoreVersion uint64
+}
+
+// Key hashes the parts into a cache key. The query is compared case-insensitively and with
+// whitespace collapsed; everything else must match exactly.
+func Key(parts KeyParts) string {
+	h := sha256.New()
+	fields := []string{
+		normalizeQuery(parts.Query),
+		parts.SystemPrompt,
+		strings.Join(parts.ChunkIDs, ","),
+		parts.Provider,
+		parts.Model,
+		fmt.Sprint(parts.MaxTokens),
+		strings.Join(parts.Stop, "\x00"),
+		fmt.Sprint(parts.StoreVersion),
+	}
+	for _, field := range fields {
+		// Length-prefix each field so different splits never hash alike
+		fmt.Fprintf(h, "%d:%s", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeQuery lowercases the query and collapses its whitespace
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// Get returns the cached answer for key, if any
+func (c *Cache) Get(key string) (string, bool, error) {
+	if c == nil {
+		return "", false, nil
+	}
+
+	var answer string
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(prefixAnswer + key))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			answer = string(val)
+			return nil
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read cached answer: %w", err)
+	}
+
+	return answer, true, nil
+}
+
+// Set caches answer under key for ANSWER_CACHE_TTL
+func (c *Cache) Set(key, answer string) error {
+	if c == nil {
+		return nil
+	}
+
+	return c.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(prefixAnswer+key), []byte(answer)).WithTTL(c.ttl))
+	})
+}
@@ -0,0 +1,159 @@
+package answercache
+
+import (
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+func openTestDB(t *testing.T) *badger.DB {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func newTestCache(t *testing.T, db *badger.DB, ttl time.Duration) *Cache {
+	t.Helper()
+
+	cache, err := New(db, ttl)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	return cache
+}
+
+var baseParts = KeyParts{
+	Query:        "What is RAG?",
+	SystemPrompt: "Answer from the context.",
+	ChunkIDs:     []string{"c1", "c2"},
+	Provider:     "openrouter",
+	Model:        "model-a",
+	MaxTokens:    512,
+	StoreVersion: 7,
+}
+
+func TestKeyNormalizesQuery(t *testing.T) {
+	parts := baseParts
+	parts.Query = "  what   is\trag? "
+
+	if Key(parts) != Key(baseParts) {
+		t.Error("queries differing only in case and whitespace should share a key")
+	}
+}
+
+func TestKeyChangesWithEveryPart(t *testing.T) {
+	tests := map[string]func(p *KeyParts){
+		"query":         func(p *KeyParts) { p.Query = "What is RAG? Answer briefly." },
+		"system prompt": func(p *KeyParts) { p.SystemPrompt = "Answer in French." },
+		"chunk ids":     func(p *KeyParts) { p.ChunkIDs = []string{"c1", "c3"} },
+		"chunk order":   func(p *KeyParts) { p.ChunkIDs = []string{"c2", "c1"} },
+		"provider":      func(p *KeyParts) { p.Provider = "bedrock" },
+		"model":         func(p *KeyParts) { p.Model = "model-b" },
+		"max tokens":    func(p *KeyParts) { p.MaxTokens = 128 },
+		"stop":          func(p *KeyParts) { p.Stop = []string{"END"} },
+		"store version": func(p *KeyParts) { p.StoreVersion = 8 },
+	}
+
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			parts := baseParts
+			change(&parts)
+			if Key(parts) == Key(baseParts) {
+				t.Errorf("changing the %s should change the key", name)
+			}
+		})
+	}
+}
+
+func TestKeyFieldBoundaries(t *testing.T) {
+	a := KeyParts{Query: "ab", SystemPrompt: "c"}
+	b := KeyParts{Query: "a", SystemPrompt: "bc"}
+
+	if Key(a) == Key(b) {
+		t.Error("fields split differently should not share a key")
+	}
+}
+
+func TestHitAndMiss(t *testing.T) {
+	cache := newTestCache(t, openTestDB(t), time.Hour)
+	key := Key(baseParts)
+
+	if _, ok, err := cache.Get(key); err != nil || ok {
+		t.Fatalf("Get before Set = %v, %v; want a miss", ok, err)
+	}
+
+	if err := cache.Set(key, "RAG is retrieval-augmented generation."); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	answer, ok, err := cache.Get(key)
+	if err != nil || !ok || answer != "RAG is retrieval-augmented generation." {
+		t.Fatalf("Get after Set = %q, %v, %v; want a hit", answer, ok, err)
+	}
+}
+
+func TestStoreVersionBumpInvalidates(t *testing.T) {
+	cache := newTestCache(t, openTestDB(t), time.Hour)
+
+	if err := cache.Set(Key(baseParts), "old answer"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	bumped := baseParts
+	bumped.StoreVersion++
+
+	if _, ok, _ := cache.Get(Key(bumped)); ok {
+		t.Error("an answer cached before a store mutation was served after it")
+	}
+}
+
+func TestEntriesExpire(t *testing.T) {
+	cache := newTestCache(t, openTestDB(t), time.Second)
+	key := Key(baseParts)
+
+	if err := cache.Set(key, "answer"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Badger TTLs have one-second resolution
+	time.Sleep(2100 * time.Millisecond)
+
+	if _, ok, _ := cache.Get(key); ok {
+		t.Error("an expired answer was served")
+	}
+}
+
+func TestNewDropsPreviousEntries(t *testing.T) {
+	db := openTestDB(t)
+	key := Key(baseParts)
+
+	if err := newTestCache(t, db, time.Hour).Set(key, "answer"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// The store version restarts at zero with the process, so old keys could collide
+	if _, ok, _ := newTestCache(t, db, time.Hour).Get(key); ok {
+		t.Error("an answer from a previous run was served")
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var cache *Cache
+
+	if cache.Enabled() {
+		t.Error("nil cache reports enabled")
+	}
+	if err := cache.Set("k", "v"); err != nil {
+		t.Errorf("Set on nil cache: %v", err)
+	}
+	if _, ok, err := cache.Get("k"); ok || err != nil {
+		t.Errorf("Get on nil cache = %v, %v; want a miss", ok, err)
+	}
+}
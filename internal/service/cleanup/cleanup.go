@@ -0,0 +1,101 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultPatterns match citation artifacts models leave despite being told not to mention
+// their context: "(Context-1)", "[Source 2]" style markers and sentence openers such as
+// "Based on the provided context,". They only match these specific phrasings, so ordinary
+// uses of words like "context" or bare [1] citation markers are left alone.
+var DefaultPatterns = []string{
+	`[ \t]*\((?i:context|source|reference|document)[- ]?\d+(?:\s*,\s*(?i:context|source|reference|document)?[- ]?\d+)*\)`,
+	`[ \t]*\[(?i:context|source|reference|document)[- ]?\d+\]`,
+	`(?im)^[ \t]*(?:based on|according to) (?:the )?(?:provided|given|available) (?:context|information|text|documents?|knowledge base)\s*[,:]\s*`,
+}
+
+// Cleaner strips citation artifacts from generated answers
+type Cleaner struct {
+	patterns []*regexp.Regexp
+}
+
+// New creates a cleaner from a JSON array of regular expressions. An empty patternsJSON uses
+// DefaultPatterns.
+func New(patternsJSON string) (*Cleaner, error) {
+	patterns := DefaultPatterns
+	if strings.TrimSpace(patternsJSON) != "" {
+		// Decoded into a fresh slice: decoding into DefaultPatterns would overwrite it
+		patterns = nil
+		if err := json.Unmarshal([]byte(patternsJSON), &patterns); err != nil {
+			return nil, fmt.Errorf("invalid RESPONSE_CLEANUP_PATTERNS: %w", err)
+		}
+	}
+
+	c := &Cleaner{}
+	for _, p := range patterns {
+		pattern, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESPONSE_CLEANUP_PATTERNS pattern %q: %w", p, err)
+		}
+		c.patterns = append(c.patterns, pattern)
+	}
+
+	return c, nil
+}
+
+// Clean returns text with every pattern match removed and the number of removals. Text that
+// followed a match at the start of a line is capitalized, so "Based on the context, the
+// answer" becomes "The answer". A nil cleaner returns text unchanged.
+func (c *Cleaner) Clean(text string) (string, int) {
+	if c == nil {
+		return text, 0
+	}
+
+	count := 0
+	for _, pattern := range c.patterns {
+		matches := pattern.FindAllStringIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		var builder strings.Builder
+		last := 0
+		for _, m := range matches {
+			if m[0] == m[1] {
+				continue
+			}
+			builder.WriteString(text[last:m[0]])
+			last = m[1]
+			count++
+
+			if m[0] == 0 || text[m[0]-1] == '\n' {
+				last += capitalizeNext(&builder, text[last:])
+			}
+		}
+		builder.WriteString(text[last:])
+		text = builder.String()
+	}
+
+	return text, count
+}
+
+// capitalizeNext writes the first rune of rest in upper case and returns its byte length
+func capitalizeNext(builder *strings.Builder, rest string) int {
+	r, size := utf8.DecodeRuneInString(rest)
+	if size == 0 || !unicode.IsLower(r) {
+		return 0
+	}
+
+	builder.WriteRune(unicode.ToUpper(r))
+	return size
+}
+
+// Enabled reports whether answers are cleaned
+func (c *Cleaner) Enabled() bool {
+	return c != nil
+}
@@ -0,0 +1,73 @@
+package cleanup
+
+import "testing"
+
+func TestCleanRemovesArtifacts(t *testing.T) {
+	c, err := New("")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		count int
+	}{
+		{"context marker", "RAG retrieves documents (Context-1).", "RAG retrieves documents.", 1},
+		{"several sources", "It is fast (Source 1, 2) and cheap [Document 3].", "It is fast and cheap.", 2},
+		{"opener", "Based on the provided context, the answer is 42.", "The answer is 42.", 1},
+		{"opener on a later line", "Summary:\nAccording to the given information: it works.", "Summary:\nIt works.", 1},
+		{"bare citation kept", "RAG grounds answers [1].", "RAG grounds answers [1].", 0},
+		{"word context kept", "The context window limits how much text fits.", "The context window limits how much text fits.", 0},
+		{"mid-sentence phrase kept", "This is based on the provided context of the meeting.", "This is based on the provided context of the meeting.", 0},
+		{"parenthesised numbers kept", "Use a chunk size (1000) with overlap (200).", "Use a chunk size (1000) with overlap (200).", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count := c.Clean(tt.input)
+			if got != tt.want || count != tt.count {
+				t.Errorf("Clean(%q) = %q, %d; want %q, %d", tt.input, got, count, tt.want, tt.count)
+			}
+		})
+	}
+}
+
+func TestCustomPatterns(t *testing.T) {
+	c, err := New(`["\\s*\\(see above\\)"]`)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if got, _ := c.Clean("It is configurable (see above)."); got != "It is configurable." {
+		t.Errorf("Clean = %q, want the custom pattern removed", got)
+	}
+	if got, _ := c.Clean("It is fast (Context-1)."); got != "It is fast (Context-1)." {
+		t.Errorf("Clean = %q, want only the custom patterns applied", got)
+	}
+
+	// Custom patterns leave the defaults intact
+	defaults, err := New("")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got, _ := defaults.Clean("It is fast (Context-1)."); got != "It is fast." {
+		t.Errorf("default Clean after custom patterns = %q, want the defaults unchanged", got)
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	for _, patterns := range []string{`not json`, `["("]`} {
+		if _, err := New(patterns); err == nil {
+			t.Errorf("New(%s) succeeded, want an error", patterns)
+		}
+	}
+}
+
+func TestNilCleanerKeepsText(t *testing.T) {
+	var c *Cleaner
+	if got, count := c.Clean("Text (Context-1)."); got != "Text (Context-1)." || count != 0 || c.Enabled() {
+		t.Errorf("nil cleaner changed the text to %q", got)
+	}
+}
@@ -0,0 +1,205 @@
+package document
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// CodeExtensions lists the source file extensions indexed with code-aware chunking
+var CodeExtensions = map[string]bool{
+	".go":    true,
+	".py":    true,
+	".js":    true,
+	".jsx":   true,
+	".ts":    true,
+	".tsx":   true,
+	".java":  true,
+	".kt":    true,
+	".rs":    true,
+	".c":     true,
+	".h":     true,
+	".cpp":   true,
+	".hpp":   true,
+	".cs":    true,
+	".rb":    true,
+	".php":   true,
+	".swift": true,
+	".scala": true,
+	".sh":    true,
+	".sql":   true,
+}
+
+// charQuoteExtensions lists the languages where single quotes only delimit character
+// literals, so a quote that does not close one (e.g. a Rust lifetime such as 'a) is ignored
+// rather than read as the start of a string
+var charQuoteExtensions = map[string]bool{
+	".go":    true,
+	".rs":    true,
+	".java":  true,
+	".kt":    true,
+	".c":     true,
+	".h":     true,
+	".cpp":   true,
+	".hpp":   true,
+	".cs":    true,
+	".scala": true,
+}
+
+// IsCodeFile reports whether the filename has a known source code extension
+func IsCodeFile(filename string) bool {
+	return CodeExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// chunkCode splits source code into chunks on top-level block boundaries. A boundary is
+// a non-indented line at brace depth zero that follows a blank line, which keeps doc
+// comments with their function and never splits inside a brace block or an indented
+// (e.g. Python) body. Lines are grouped until the chunk size is exceeded, then the chunk
+// is cut at the last boundary. Blocks larger than twice the chunk size are split on line
+// boundaries so a single huge function cannot produce an unbounded chunk.
+func (s *Service) chunkCode(docID, filename, text string) []models.Chunk {
+	chunkSize := s.cfg.RAG.ChunkSize
+	charQuotes := charQuoteExtensions[strings.ToLower(filepath.Ext(filename))]
+
+	var chunks []models.Chunk
+	emit := func(lines []string) {
+		content := strings.TrimSpace(strings.Join(lines, "\n"))
+		if content == "" {
+			return
+		}
+
+		chunks = append(chunks, models.Chunk{
+			ID:      uuid.New().String(),
+			DocID:   docID,
+			Content: content,
+			Index:   len(chunks),
+		})
+	}
+
+	var current []string
+	size := 0
+	depth := 0
+	split := 0 // number of lines in current that can be emitted without splitting a block
+	prevBlank := true
+
+	for _, line := range strings.Split(text, "\n") {
+		blank := strings.TrimSpace(line) == ""
+		if depth == 0 && !blank && prevBlank && !isIndented(line) {
+			split = len(current)
+		}
+
+		current = append(current, line)
+		size += utf8.RuneCountInString(line) + 1
+		prevBlank = blank
+
+		depth += braceDelta(line, charQuotes)
+		if depth < 0 {
+			depth = 0
+		}
+
+		if size > chunkSize && split > 0 {
+			emit(current[:split])
+			current = append([]string(nil), current[split:]...)
+			size = linesSize(current)
+			split = 0
+		}
+
+		// Hard limit for oversized blocks
+		if size >= chunkSize*2 {
+			emit(current)
+			current = nil
+			size = 0
+			split = 0
+		}
+	}
+
+	emit(current)
+
+	return chunks
+}
+
+// linesSize returns the rune length of lines joined by newlines
+func linesSize(lines []string) int {
+	size := 0
+	for _, line := range lines {
+		size += utf8.RuneCountInString(line) + 1
+	}
+	return size
+}
+
+// braceDelta returns the change in brace depth for a line, ignoring braces in strings
+// and line comments. With charQuotes, single quotes only open character literals.
+func braceDelta(line string, charQuotes bool) int {
+	delta := 0
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if quote != 0 {
+			if r == '\\' {
+				i++
+			} else if r == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch r {
+		case '\'':
+			if !charQuotes {
+				quote = r
+			} else if end := charLiteralEnd(runes, i); end > 0 {
+				i = end
+			}
+		case '"', '`':
+			quote = r
+		case '/':
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				return delta
+			}
+		case '#':
+			// Comment in shell/Python/Ruby style languages
+			return delta
+		case '{':
+			delta++
+		case '}':
+			delta--
+		}
+	}
+
+	return delta
+}
+
+// charLiteralEnd returns the index of the quote closing the character literal opened at
+// runes[start], or -1 when the quote does not open one, as with a lifetime or label
+func charLiteralEnd(runes []rune, start int) int {
+	if start+2 < len(runes) && runes[start+1] != '\\' && runes[start+2] == '\'' {
+		return start + 2
+	}
+
+	// Escapes such as '\n', '\'' or '\u{1F600}'
+	if start+1 < len(runes) && runes[start+1] == '\\' {
+		for i := start + 3; i < len(runes) && i <= start+12; i++ {
+			if runes[i] == '\'' {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// isIndented reports whether a non-blank line starts with whitespace, i.e. it continues
+// the previous block in indentation-based languages
+func isIndented(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+
+	return line[0] == ' ' || line[0] == '\t'
+}
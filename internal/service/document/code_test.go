@@ -0,0 +1,158 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+const goFixture = `package shapes
+
+import "math"
+
+// Circle is a circle with a radius
+type Circle struct {
+	Radius float64
+}
+
+// Area returns the area of the circle
+func (c Circle) Area() float64 {
+	if c.Radius <= 0 {
+		return 0
+	}
+	return math.Pi * c.Radius * c.Radius
+}
+
+// Describe returns a description with braces in a string
+func (c Circle) Describe() string {
+	label := "circle {"
+	return label + "}" + string('{')
+}
+
+// Scale returns the circle scaled by factor
+func (c Circle) Scale(factor float64) Circle {
+	return Circle{Radius: c.Radius * factor}
+}
+`
+
+const rustFixture = `pub struct Parser<'a> {
+    input: &'a str,
+    pos: usize,
+}
+
+impl<'a> Parser<'a> {
+    pub fn new(input: &'a str) -> Self {
+        Parser { input, pos: 0 }
+    }
+
+    pub fn peek(&self) -> Option<char> {
+        self.input[self.pos..].chars().next().filter(|c| *c != '{' && *c != '\'')
+    }
+}
+
+fn longest<'a>(x: &'a str, y: &'a str) -> &'a str {
+    if x.len() > y.len() { x } else { y }
+}
+
+fn shortest<'b>(x: &'b str, y: &'b str) -> &'b str {
+    if x.len() < y.len() { x } else { y }
+}
+`
+
+// codeChunks chunks a source file with the given chunk size
+func codeChunks(t *testing.T, filename, source string, chunkSize int) []string {
+	t.Helper()
+
+	cfg := testConfig(t)
+	cfg.RAG.CodeIndexing = true
+	cfg.RAG.ChunkSize = chunkSize
+
+	chunks, _ := newTestService(t, cfg).ChunkDocument("doc", filename, source)
+	contents := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		contents[i] = chunk.Content
+	}
+	return contents
+}
+
+func TestCodeChunkingSplitsOnFunctionBoundaries(t *testing.T) {
+	chunks := codeChunks(t, "shapes.go", goFixture, 120)
+	if len(chunks) < 3 {
+		t.Fatalf("chunks = %d, want the fixture split into several blocks", len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		// Every chunk holds whole blocks: balanced braces, doc comments with their code
+		depth := 0
+		for _, line := range strings.Split(chunk, "\n") {
+			depth += braceDelta(line, true)
+		}
+		if depth != 0 {
+			t.Errorf("chunk %d splits a brace block (depth %d):\n%s", i, depth, chunk)
+		}
+		if i > 0 && !strings.HasPrefix(chunk, "//") && !strings.HasPrefix(chunk, "func") && !strings.HasPrefix(chunk, "type") {
+			t.Errorf("chunk %d does not start at a declaration or its doc comment:\n%s", i, chunk)
+		}
+	}
+
+	for _, name := range []string{"func (c Circle) Area", "func (c Circle) Describe", "func (c Circle) Scale"} {
+		found := false
+		for _, chunk := range chunks {
+			if strings.Contains(chunk, name) {
+				found = true
+				if !strings.Contains(chunk, "// "+strings.TrimPrefix(name, "func (c Circle) ")) {
+					t.Errorf("%s is chunked without its doc comment", name)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s is missing from the chunks", name)
+		}
+	}
+}
+
+func TestCodeChunkingHandlesRustLifetimes(t *testing.T) {
+	chunks := codeChunks(t, "parser.rs", rustFixture, 150)
+
+	// With lifetimes read as string quotes, the brace depth never returns to zero after
+	// the struct and everything lands in one chunk
+	if len(chunks) < 3 {
+		t.Fatalf("chunks = %d, want the struct, impl and functions split apart", len(chunks))
+	}
+	for i, chunk := range chunks {
+		depth := 0
+		for _, line := range strings.Split(chunk, "\n") {
+			depth += braceDelta(line, true)
+		}
+		if depth != 0 {
+			t.Errorf("chunk %d splits a brace block (depth %d):\n%s", i, depth, chunk)
+		}
+	}
+}
+
+func TestBraceDelta(t *testing.T) {
+	tests := []struct {
+		line       string
+		charQuotes bool
+		want       int
+	}{
+		{"func main() {", true, 1},
+		{`s := "{{" + "}"`, true, 0},
+		{"r := '{'", true, 0},
+		{`r := '\''; if x {`, true, 1},
+		{`r := '\u{7b}' {`, true, 1},
+		{"struct Parser<'a> {", true, 1},
+		{"fn f<'a>(x: &'a str) -> &'a str {", true, 1},
+		{"'outer: loop {", true, 1},
+		{"x := 1 // {", true, 0},
+		{"# {", false, 0},
+		{"s = 'a {'", false, 0},
+		{"d = {'key': 'value'", false, 1},
+		{"}", true, -1},
+	}
+
+	for _, tt := range tests {
+		if got := braceDelta(tt.line, tt.charQuotes); got != tt.want {
+			t.Errorf("braceDelta(%q, %v) = %d, want %d", tt.line, tt.charQuotes, got, tt.want)
+		}
+	}
+}
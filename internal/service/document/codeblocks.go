@@ -0,0 +1,64 @@
+package document
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// codeBlockMarker replaces fenced code blocks in embedded text when EMBED_CODE_BLOCKS=false
+const codeBlockMarker = "[code block]"
+
+// codeBlockRanges returns the [start, end) rune ranges of fenced (``` or ~~~) code blocks,
+// including the fence lines. An unterminated fence runs to the end of the text.
+func codeBlockRanges(text string) [][2]int {
+	var ranges [][2]int
+	start := -1
+	offset := 0
+
+	for _, line := range strings.Split(text, "\n") {
+		lineEnd := offset + utf8.RuneCountInString(line) + 1
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if start < 0 {
+				start = offset
+			} else {
+				ranges = append(ranges, [2]int{start, lineEnd})
+				start = -1
+			}
+		}
+
+		offset = lineEnd
+	}
+
+	if start >= 0 {
+		ranges = append(ranges, [2]int{start, offset})
+	}
+
+	return ranges
+}
+
+// stripCodeBlocks returns the text of runes[from:to] with every part covered by a code block
+// range replaced by codeBlockMarker
+func stripCodeBlocks(runes []rune, from, to int, blocks [][2]int) string {
+	var b strings.Builder
+	pos := from
+
+	for _, block := range blocks {
+		if block[1] <= pos || block[0] >= to {
+			continue
+		}
+
+		if block[0] > pos {
+			b.WriteString(string(runes[pos:block[0]]))
+		}
+		b.WriteString("\n" + codeBlockMarker + "\n")
+		pos = min(block[1], to)
+	}
+
+	if pos < to {
+		b.WriteString(string(runes[pos:to]))
+	}
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,27 @@
+package document
+
+import (
+	"crypto/sha256"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// dedupeChunks drops chunks whose content repeats an earlier chunk of the same document, as
+// templated reports often do, and renumbers the rest. It returns how many were dropped.
+func dedupeChunks(chunks []models.Chunk) ([]models.Chunk, int) {
+	seen := make(map[[sha256.Size]byte]bool, len(chunks))
+	kept := chunks[:0]
+
+	for _, chunk := range chunks {
+		hash := sha256.Sum256([]byte(chunk.Content))
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		chunk.Index = len(kept)
+		kept = append(kept, chunk)
+	}
+
+	return kept, len(chunks) - len(kept)
+}
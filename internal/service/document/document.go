@@ -6,29 +6,37 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	badger "github.com/dgraph-io/badger/v4"
 	"github.com/google/uuid"
 	"github.com/mrkaynak/rag/internal/config"
 	"github.com/mrkaynak/rag/internal/models"
 	"github.com/mrkaynak/rag/pkg/errors"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+	"go.uber.org/zap"
 )
 
 // Service handles document operations
 type Service struct {
-	cfg *config.Config
+	cfg           *config.Config
+	logger        *zap.Logger
+	metadataStore *MetadataStore
 }
 
 // New creates a new document service
-func New(cfg *config.Config) (*Service, error) {
+func New(cfg *config.Config, logger *zap.Logger, metadataStore *MetadataStore) (*Service, error) {
 	// Ensure upload directory exists
 	if err := os.MkdirAll(cfg.Storage.UploadDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create upload directory: %w", err)
 	}
 
 	return &Service{
-		cfg: cfg,
+		cfg:           cfg,
+		logger:        logger,
+		metadataStore: metadataStore,
 	}, nil
 }
 
@@ -36,15 +44,34 @@ func New(cfg *config.Config) (*Service, error) {
 func (s *Service) ProcessUpload(filename string, reader io.Reader) (*models.Document, error) {
 	docID := uuid.New().String()
 
-	// Read file content
-	content, err := s.readContent(reader)
+	// Save the original file byte-for-byte
+	if err := s.saveFile(docID, filename, reader); err != nil {
+		return nil, errors.InternalWrap(err, "failed to save file")
+	}
+
+	// Extract text content from the saved original for chunking
+	file, err := os.Open(s.filePath(docID, filename))
 	if err != nil {
 		return nil, errors.InternalWrap(err, "failed to read file content")
 	}
+	defer file.Close()
 
-	// Save original file
-	if err := s.saveFile(docID, filename, content); err != nil {
-		return nil, errors.InternalWrap(err, "failed to save file")
+	doc, err := s.ProcessUploadAs(docID, filename, file)
+	if err != nil {
+		_ = os.Remove(s.filePath(docID, filename))
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// ProcessUploadAs processes an upload like ProcessUpload under an existing document ID. The
+// document's saved original is left untouched; call ReplaceOriginal once the new chunks are
+// indexed, so a failed replacement keeps the document as it was.
+func (s *Service) ProcessUploadAs(docID, filename string, reader io.Reader) (*models.Document, error) {
+	content, err := s.readContent(reader)
+	if err != nil {
+		return nil, errors.InternalWrap(err, "failed to read file content")
 	}
 
 	// Create document
@@ -56,12 +83,100 @@ func (s *Service) ProcessUpload(filename string, reader io.Reader) (*models.Docu
 	}
 
 	// Split into chunks
-	chunks := s.chunkText(doc.ID, content)
-	doc.Chunks = chunks
+	doc.Chunks, doc.DuplicateChunks = s.ChunkDocument(doc.ID, filename, content)
 
 	return doc, nil
 }
 
+// ReplaceOriginal overwrites a document's saved original with data, removing the previous
+// file if it was stored under another name. The new file is written next to the old one and
+// renamed over it, so a failed write keeps the previous original.
+func (s *Service) ReplaceOriginal(docID, oldFilename, filename string, data []byte) error {
+	tmp, err := os.CreateTemp(s.cfg.Storage.UploadDir, ".replace-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.filePath(docID, filename)); err != nil {
+		return err
+	}
+
+	if oldFilename != filename {
+		if err := os.Remove(s.filePath(docID, oldFilename)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ChunkDocument splits document content into chunks using the current chunking settings.
+// With CHUNK_DEDUPE on, repeated chunks are dropped and their number returned.
+func (s *Service) ChunkDocument(docID, filename, content string) ([]models.Chunk, int) {
+	chunks := s.splitDocument(docID, filename, content)
+	if !s.cfg.RAG.DedupeChunks {
+		return chunks, 0
+	}
+	return dedupeChunks(chunks)
+}
+
+// splitDocument splits document content with the code, structured or text chunker
+func (s *Service) splitDocument(docID, filename, content string) []models.Chunk {
+	if s.cfg.RAG.StructuredChunking && IsStructuredFile(filename) {
+		header := "File: " + filename
+		chunks := s.fitInputLimit(docID, s.chunkStructured(docID, filename, content), header, false)
+		setEmbeddingText(chunks, header, false)
+		return chunks
+	}
+
+	if s.cfg.RAG.CodeIndexing && IsCodeFile(filename) {
+		var header string
+		if s.cfg.RAG.CodeEmbedPath {
+			header = "File: " + filename
+		}
+
+		chunks := s.fitInputLimit(docID, s.chunkCode(docID, filename, content), header, false)
+		setEmbeddingText(chunks, header, false)
+
+		return chunks
+	}
+
+	// Code files are never normalized since their whitespace can be significant
+	if s.cfg.RAG.NormalizeWhitespace {
+		content = normalizeWhitespace(content)
+	}
+
+	var header string
+	if s.cfg.RAG.ChunkContextualize {
+		header = "Document: " + strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+
+	withHeading := s.cfg.RAG.ChunkHeadings == "embed"
+	chunks := s.chunkText(docID, content, header, withHeading)
+	setEmbeddingText(chunks, header, withHeading)
+
+	return chunks
+}
+
+// ReadOriginal reads the saved original file of a document
+func (s *Service) ReadOriginal(docID, filename string) (string, error) {
+	file, err := os.Open(s.filePath(docID, filename))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return s.readContent(file)
+}
+
 // readContent reads content from reader based on file type
 func (s *Service) readContent(reader io.Reader) (string, error) {
 	var builder strings.Builder
@@ -89,61 +204,218 @@ func (s *Service) readContent(reader io.Reader) (string, error) {
 	return content, nil
 }
 
-// saveFile saves file to disk
-func (s *Service) saveFile(docID, filename, content string) error {
-	filePath := filepath.Join(s.cfg.Storage.UploadDir, fmt.Sprintf("%s_%s", docID, filename))
-
-	file, err := os.Create(filePath)
+// saveFile copies the raw uploaded bytes to disk
+func (s *Service) saveFile(docID, filename string, reader io.Reader) error {
+	file, err := os.Create(s.filePath(docID, filename))
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	_, err = file.WriteString(content)
+	_, err = io.Copy(file, reader)
 	return err
 }
 
-// chunkText splits text into overlapping chunks
-func (s *Service) chunkText(docID, text string) []models.Chunk {
-	chunkSize := s.cfg.RAG.ChunkSize
-	overlap := s.cfg.RAG.ChunkOverlap
+// filePath returns the on-disk path of a document's original file
+func (s *Service) filePath(docID, filename string) string {
+	return filepath.Join(s.cfg.Storage.UploadDir, fmt.Sprintf("%s_%s", docID, filename))
+}
 
+// chunkText splits text into overlapping chunks. CHUNK_UNIT selects whether CHUNK_SIZE
+// and CHUNK_OVERLAP are measured in characters or estimated tokens. With
+// CHUNK_SPLIT_OVERSIZED, a chunk whose embedded text would exceed EMBEDDING_MAX_INPUT_TOKENS
+// next to header (and its section line, with withHeading) is cut into pieces that fit.
+func (s *Service) chunkText(docID, text, header string, withHeading bool) []models.Chunk {
 	var chunks []models.Chunk
 	runes := []rune(text)
-	index := 0
 
-	for i := 0; i < len(runes); i += chunkSize - overlap {
-		end := i + chunkSize
-		if end > len(runes) {
-			end = len(runes)
-		}
+	var headings []headingMark
+	if s.cfg.RAG.ChunkHeadings != "off" {
+		headings = headingMarks(text)
+	}
 
-		chunkContent := strings.TrimSpace(string(runes[i:end]))
+	var codeBlocks [][2]int
+	if !s.cfg.RAG.EmbedCodeBlocks {
+		codeBlocks = codeBlockRanges(text)
+	}
+
+	var spans [][2]int
+	if s.cfg.RAG.ChunkUnit == "tokens" {
+		spans = tokenSpans(runes, s.cfg.RAG.ChunkSize, s.cfg.RAG.ChunkOverlap)
+	} else {
+		spans = charSpans(len(runes), s.cfg.RAG.ChunkSize, s.cfg.RAG.ChunkOverlap)
+	}
+
+	build := func(from, to int, heading string) (models.Chunk, bool) {
+		chunkContent := strings.TrimSpace(string(runes[from:to]))
 		if chunkContent == "" {
-			continue
+			return models.Chunk{}, false
 		}
 
 		chunk := models.Chunk{
 			ID:      uuid.New().String(),
 			DocID:   docID,
 			Content: chunkContent,
-			Index:   index,
+			Index:   len(chunks),
+			Heading: heading,
 		}
 
-		chunks = append(chunks, chunk)
-		index++
+		// Keep code blocks in the stored content but leave them out of the embedding.
+		// Chunks that are entirely code are embedded as-is rather than as a bare marker.
+		if len(codeBlocks) > 0 {
+			stripped := stripCodeBlocks(runes, from, to, codeBlocks)
+			prose := strings.TrimSpace(strings.ReplaceAll(stripped, codeBlockMarker, ""))
+			if stripped != chunkContent && prose != "" {
+				chunk.EmbeddingText = stripped
+			}
+		}
 
-		if end >= len(runes) {
-			break
+		return chunk, true
+	}
+
+	for _, span := range spans {
+		heading := headingAt(headings, span[0])
+		chunk, ok := build(span[0], span[1], heading)
+		if !ok {
+			continue
+		}
+
+		budget := s.oversizedBudget(chunk, header, withHeading)
+		if budget == 0 {
+			chunks = append(chunks, chunk)
+			continue
+		}
+
+		// Pieces are cut from the same runes, so they are stripped like the chunk would be
+		pieces := 0
+		for _, sub := range tokenSpans(runes[span[0]:span[1]], budget, 0) {
+			if piece, ok := build(span[0]+sub[0], span[0]+sub[1], heading); ok {
+				chunks = append(chunks, piece)
+				pieces++
+			}
 		}
+		s.logSplit(docID, chunk, budget, pieces)
 	}
 
 	return chunks
 }
 
-// GetDocument retrieves a document by ID
+// charSpans returns [start, end) rune windows of chunkSize characters overlapping by overlap
+func charSpans(length, chunkSize, overlap int) [][2]int {
+	var spans [][2]int
+
+	for i := 0; i < length; i += chunkSize - overlap {
+		end := i + chunkSize
+		if end > length {
+			end = length
+		}
+
+		spans = append(spans, [2]int{i, end})
+
+		if end >= length {
+			break
+		}
+	}
+
+	return spans
+}
+
+// tokenSpans returns [start, end) rune windows cut at word boundaries so each window holds
+// at most chunkSize estimated tokens and consecutive windows share about overlap tokens.
+// A single word larger than chunkSize still forms its own window.
+func tokenSpans(runes []rune, chunkSize, overlap int) [][2]int {
+	offsets := tokenizer.WordOffsets(string(runes))
+
+	// wordEnd returns the rune offset where word i ends (including trailing whitespace)
+	wordEnd := func(i int) int {
+		if i < len(offsets) {
+			return offsets[i]
+		}
+		return len(runes)
+	}
+	tokens := func(from, to int) int {
+		return tokenizer.EstimateTokens(string(runes[offsets[from]:wordEnd(to)]))
+	}
+
+	var spans [][2]int
+	for start := 0; start < len(offsets); {
+		// Every word is at least one token, so no more than chunkSize words can fit
+		limit := min(len(offsets)-start, chunkSize)
+		n := sort.Search(limit+1, func(n int) bool {
+			return tokens(start, start+n) > chunkSize
+		}) - 1
+		end := start + max(n, 1)
+
+		spans = append(spans, [2]int{offsets[start], wordEnd(end)})
+
+		if end >= len(offsets) {
+			break
+		}
+
+		// Largest tail of the window within the overlap budget, always moving forward
+		k := sort.Search(end-start, func(k int) bool {
+			return k > 0 && tokens(end-k, end) > overlap
+		}) - 1
+		start = end - max(k, 0)
+	}
+
+	return spans
+}
+
+// setEmbeddingText prefixes each chunk's embedded text with a document header line and/or
+// its section heading. The chunk content itself is left untouched so displayed/returned
+// text stays clean.
+func setEmbeddingText(chunks []models.Chunk, header string, withHeading bool) {
+	for i := range chunks {
+		var prefix []string
+		if header != "" {
+			prefix = append(prefix, header)
+		}
+		if withHeading && chunks[i].Heading != "" {
+			prefix = append(prefix, "Section: "+chunks[i].Heading)
+		}
+
+		if len(prefix) > 0 {
+			body := chunks[i].EmbeddingText
+			if body == "" {
+				body = chunks[i].Content
+			}
+			chunks[i].EmbeddingText = strings.Join(prefix, "\n") + "\n\n" + body
+		}
+	}
+}
+
+// GetDocument retrieves a document and its full text by ID. The stored content is used
+// when STORE_DOCUMENT_CONTENT is enabled, otherwise the original file is read from disk.
 func (s *Service) GetDocument(docID string) (*models.Document, error) {
-	// In a production system, this would query a database
-	// For now, this is a placeholder
-	return nil, errors.NotFound("document not found")
+	meta, err := s.metadataStore.Get(docID)
+	if err == badger.ErrKeyNotFound {
+		return nil, errors.NotFound("document not found").WithCode(errors.CodeDocumentNotFound)
+	}
+	if err != nil {
+		return nil, errors.InternalWrap(err, "failed to get document metadata")
+	}
+
+	var content string
+	if s.cfg.Storage.StoreDocumentContent {
+		content, err = s.metadataStore.GetContent(docID)
+		if err != nil && err != badger.ErrKeyNotFound {
+			return nil, errors.InternalWrap(err, "failed to get document content")
+		}
+	}
+
+	// Documents uploaded before content storage was enabled fall back to disk
+	if content == "" {
+		content, err = s.ReadOriginal(docID, meta.FileName)
+		if err != nil {
+			return nil, errors.InternalWrap(err, "failed to read document content")
+		}
+	}
+
+	return &models.Document{
+		ID:        meta.ID,
+		FileName:  meta.FileName,
+		Content:   content,
+		CreatedAt: meta.UploadedAt,
+	}, nil
 }
@@ -0,0 +1,78 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+)
+
+// numberedWords returns n distinct words separated by spaces
+func numberedWords(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+	return strings.Join(words, " ")
+}
+
+func TestChunkTextByTokens(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RAG.ChunkUnit = "tokens"
+	cfg.RAG.ChunkSize = 50
+	cfg.RAG.ChunkOverlap = 10
+
+	chunks, _ := newTestService(t, cfg).ChunkDocument("doc", "notes.txt", numberedWords(500))
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %d, want several", len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if tokens := tokenizer.EstimateTokens(chunk.Content); tokens > cfg.RAG.ChunkSize {
+			t.Errorf("chunk %d has %d tokens, want at most %d", i, tokens, cfg.RAG.ChunkSize)
+		}
+		if i == 0 {
+			continue
+		}
+
+		// Consecutive chunks share whole words worth at most CHUNK_OVERLAP tokens
+		prev := strings.Fields(chunks[i-1].Content)
+		shared := 0
+		for n := 1; n <= len(prev); n++ {
+			if strings.HasPrefix(chunk.Content, strings.Join(prev[len(prev)-n:], " ")+" ") {
+				shared = n
+			}
+		}
+		if shared == 0 {
+			t.Errorf("chunk %d does not overlap the previous chunk", i)
+		}
+		if tokens := tokenizer.EstimateTokens(strings.Join(prev[len(prev)-shared:], " ")); tokens > cfg.RAG.ChunkOverlap {
+			t.Errorf("chunk %d overlaps by %d tokens, want at most %d", i, tokens, cfg.RAG.ChunkOverlap)
+		}
+	}
+
+	if last := strings.Fields(chunks[len(chunks)-1].Content); last[len(last)-1] != "word499" {
+		t.Errorf("last chunk ends with %q, want the last word", last[len(last)-1])
+	}
+}
+
+func TestChunkTextByChars(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RAG.ChunkSize = 100
+	cfg.RAG.ChunkOverlap = 20
+
+	text := numberedWords(200)
+	chunks, _ := newTestService(t, cfg).ChunkDocument("doc", "notes.txt", text)
+
+	want := len(charSpans(utf8.RuneCountInString(text), 100, 20))
+	if len(chunks) != want {
+		t.Fatalf("chunks = %d, want %d", len(chunks), want)
+	}
+	for i, chunk := range chunks {
+		if n := utf8.RuneCountInString(chunk.Content); n > 100 {
+			t.Errorf("chunk %d has %d characters, want at most 100", i, n)
+		}
+	}
+}
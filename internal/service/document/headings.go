@@ -0,0 +1,79 @@
+package document
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// markdownHeadingPattern matches ATX headings such as "## Installation"
+	markdownHeadingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	// htmlHeadingPattern matches single-line HTML headings such as "<h2>Installation</h2>"
+	htmlHeadingPattern = regexp.MustCompile(`(?i)^\s*<h([1-6])[^>]*>(.*?)</h[1-6]>`)
+)
+
+// headingMark records the heading path in effect from a rune offset onwards
+type headingMark struct {
+	offset int
+	path   string
+}
+
+// headingMarks scans text line by line and returns the heading path (e.g. "Guide > Setup")
+// that starts at each heading line, in order of appearance.
+func headingMarks(text string) []headingMark {
+	var marks []headingMark
+	var stack [6]string
+	offset := 0
+
+	for _, line := range strings.Split(text, "\n") {
+		level, title := parseHeading(line)
+		if level > 0 {
+			stack[level-1] = title
+			for i := level; i < len(stack); i++ {
+				stack[i] = ""
+			}
+
+			var parts []string
+			for _, part := range stack[:level] {
+				if part != "" {
+					parts = append(parts, part)
+				}
+			}
+
+			marks = append(marks, headingMark{offset: offset, path: strings.Join(parts, " > ")})
+		}
+
+		offset += utf8.RuneCountInString(line) + 1
+	}
+
+	return marks
+}
+
+// parseHeading returns the level and title of a heading line, or 0 if the line is not a heading
+func parseHeading(line string) (int, string) {
+	if m := markdownHeadingPattern.FindStringSubmatch(line); m != nil {
+		return len(m[1]), m[2]
+	}
+
+	if m := htmlHeadingPattern.FindStringSubmatch(line); m != nil {
+		if title := strings.TrimSpace(m[2]); title != "" {
+			return int(m[1][0] - '0'), title
+		}
+	}
+
+	return 0, ""
+}
+
+// headingAt returns the heading path in effect at the given rune offset
+func headingAt(marks []headingMark, offset int) string {
+	i := sort.Search(len(marks), func(i int) bool {
+		return marks[i].offset > offset
+	})
+	if i == 0 {
+		return ""
+	}
+
+	return marks[i-1].path
+}
@@ -0,0 +1,34 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"go.uber.org/zap"
+)
+
+// testConfig returns a configuration chunking by characters with uploads under a
+// temporary directory
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Storage.UploadDir = t.TempDir()
+	cfg.RAG.ChunkSize = 1000
+	cfg.RAG.ChunkOverlap = 100
+	cfg.RAG.ChunkUnit = "chars"
+	cfg.RAG.EmbedCodeBlocks = true
+	cfg.Embeddings.MaxInputTokens = 8192
+	return cfg
+}
+
+// newTestService creates a document service without a metadata store
+func newTestService(t *testing.T, cfg *config.Config) *Service {
+	t.Helper()
+
+	svc, err := New(cfg, zap.NewNop(), nil)
+	if err != nil {
+		t.Fatalf("failed to create document service: %v", err)
+	}
+	return svc
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/models"
 )
 
 // MetadataStore handles document metadata storage with BadgerDB
@@ -21,9 +22,27 @@ type DocumentMetadata struct {
 	FileType   string    `json:"file_type"`
 	ChunkCount int       `json:"chunk_count"`
 	UploadedAt time.Time `json:"uploaded_at"`
+	Tags       []string  `json:"tags,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	// ContentHash is the SHA-256 of the uploaded file, used to detect duplicates (ON_DUPLICATE)
+	ContentHash string `json:"content_hash,omitempty"`
+	// DuplicateChunks counts repeated chunks dropped before embedding (CHUNK_DEDUPE)
+	DuplicateChunks int `json:"duplicate_chunks,omitempty"`
+	// EmbeddingProvider is the provider the document was uploaded with (empty =
+	// EMBEDDING_PROVIDER); a reindex re-embeds it with EMBEDDING_PROVIDER and clears it
+	EmbeddingProvider string `json:"embedding_provider,omitempty"`
+	// DeletedAt is set while the document is soft-deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// ReindexError is set when the last reindex could not re-embed the document and its
+	// chunks had to be dropped; the document is not searchable until it is uploaded again
+	ReindexError string `json:"reindex_error,omitempty"`
 }
 
-const prefixDocument = "doc:"
+const (
+	prefixDocument = "doc:"
+	prefixContent  = "content:"
+)
 
 // NewMetadataStore creates a new metadata store
 func NewMetadataStore(db *badger.DB) *MetadataStore {
@@ -63,8 +82,52 @@ func (m *MetadataStore) Get(id string) (DocumentMetadata, error) {
 	return doc, err
 }
 
-// List returns all document metadata
+// List returns the metadata of all documents that are not soft-deleted
 func (m *MetadataStore) List() ([]DocumentMetadata, error) {
+	all, err := m.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]DocumentMetadata, 0, len(all))
+	for _, doc := range all {
+		if doc.DeletedAt == nil {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+// FindByHash returns the live document with the given content hash in a tenant and
+// namespace, or nil if there is none. Documents stored before namespaces existed are in the
+// default namespace.
+func (m *MetadataStore) FindByHash(hash, tenantID, namespace string) (*DocumentMetadata, error) {
+	docs, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace = normalizeNamespace(namespace)
+	for _, doc := range docs {
+		if doc.ContentHash == hash && doc.TenantID == tenantID && normalizeNamespace(doc.Namespace) == namespace {
+			return &doc, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// normalizeNamespace maps the empty namespace of legacy documents to the default one
+func normalizeNamespace(namespace string) string {
+	if namespace == "" {
+		return models.DefaultNamespace
+	}
+	return namespace
+}
+
+// ListAll returns all document metadata, including soft-deleted documents
+func (m *MetadataStore) ListAll() ([]DocumentMetadata, error) {
 	docs := []DocumentMetadata{} // Initialize as empty array, not nil
 
 	err := m.db.View(func(txn *badger.Txn) error {
@@ -95,9 +158,79 @@ func (m *MetadataStore) List() ([]DocumentMetadata, error) {
 	return docs, err
 }
 
-// Delete deletes a document metadata
+// SetDeleted soft-deletes a document as of at, or restores it when at is nil, and returns
+// the updated metadata
+func (m *MetadataStore) SetDeleted(id string, at *time.Time) (DocumentMetadata, error) {
+	return m.Update(id, func(doc *DocumentMetadata) {
+		doc.DeletedAt = at
+	})
+}
+
+// Update applies fn to a document's stored metadata in one transaction and returns the
+// result, so fields changed concurrently by others are kept. It returns
+// badger.ErrKeyNotFound for unknown documents.
+func (m *MetadataStore) Update(id string, fn func(doc *DocumentMetadata)) (DocumentMetadata, error) {
+	var doc DocumentMetadata
+
+	err := m.db.Update(func(txn *badger.Txn) error {
+		key := []byte(prefixDocument + id)
+
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &doc)
+		}); err != nil {
+			return err
+		}
+
+		fn(&doc)
+
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("failed to marshal document: %w", err)
+		}
+
+		return txn.Set(key, data)
+	})
+
+	return doc, err
+}
+
+// SaveContent stores the full extracted text of a document
+func (m *MetadataStore) SaveContent(id, content string) error {
+	return m.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(prefixContent+id), []byte(content))
+	})
+}
+
+// GetContent retrieves the stored full text of a document
+func (m *MetadataStore) GetContent(id string) (string, error) {
+	var content string
+
+	err := m.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(prefixContent + id))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			content = string(val)
+			return nil
+		})
+	})
+
+	return content, err
+}
+
+// Delete deletes a document metadata and its stored content
 func (m *MetadataStore) Delete(id string) error {
 	return m.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete([]byte(prefixDocument + id))
+		if err := txn.Delete([]byte(prefixDocument + id)); err != nil {
+			return err
+		}
+		return txn.Delete([]byte(prefixContent + id))
 	})
 }
@@ -0,0 +1,97 @@
+package document
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+	"go.uber.org/zap"
+)
+
+// oversizedBudget returns the estimated tokens of its own text a chunk may have to fit
+// EMBEDDING_MAX_INPUT_TOKENS, leaving room for the header and, with withHeading, the
+// section line that setEmbeddingText adds. It returns 0 when the chunk already fits or
+// CHUNK_SPLIT_OVERSIZED is off.
+func (s *Service) oversizedBudget(chunk models.Chunk, header string, withHeading bool) int {
+	if !s.cfg.RAG.SplitOversized {
+		return 0
+	}
+
+	budget := s.cfg.Embeddings.MaxInputTokens - tokenizer.EstimateTokens(header)
+	if withHeading && chunk.Heading != "" {
+		budget -= tokenizer.EstimateTokens("Section: " + chunk.Heading)
+	}
+	budget = max(budget, 1)
+
+	if tokenizer.EstimateTokens(embeddedText(chunk)) <= budget {
+		return 0
+	}
+	return budget
+}
+
+// embeddedText returns the text a chunk is embedded with, before any header
+func embeddedText(chunk models.Chunk) string {
+	if chunk.EmbeddingText != "" {
+		return chunk.EmbeddingText
+	}
+	return chunk.Content
+}
+
+// logSplit logs that an oversized chunk was split into pieces
+func (s *Service) logSplit(docID string, chunk models.Chunk, budget, pieces int) {
+	s.logger.Info("split oversized chunk to fit the embedding input limit",
+		zap.String("document_id", docID),
+		zap.Int("chunk_index", chunk.Index),
+		zap.Int("tokens", tokenizer.EstimateTokens(embeddedText(chunk))),
+		zap.Int("limit", budget),
+		zap.Int("pieces", pieces),
+	)
+}
+
+// fitInputLimit hard-splits chunks whose embedded text would exceed EMBEDDING_MAX_INPUT_TOKENS
+// into consecutive pieces that fit, so an oversized block from a boundary-aware chunker (a
+// huge function or JSON value) is neither rejected by the embedding model nor silently
+// truncated by it. Pieces keep the chunk's heading. The text chunker splits its own chunks
+// so that code-block stripping carries over to the pieces.
+func (s *Service) fitInputLimit(docID string, chunks []models.Chunk, header string, withHeading bool) []models.Chunk {
+	fitted := make([]models.Chunk, 0, len(chunks))
+	split := 0
+
+	for _, chunk := range chunks {
+		budget := s.oversizedBudget(chunk, header, withHeading)
+		if budget == 0 {
+			fitted = append(fitted, chunk)
+			continue
+		}
+
+		runes := []rune(chunk.Content)
+		pieces := 0
+		for _, span := range tokenSpans(runes, budget, 0) {
+			content := strings.TrimSpace(string(runes[span[0]:span[1]]))
+			if content == "" {
+				continue
+			}
+			fitted = append(fitted, models.Chunk{
+				ID:      uuid.New().String(),
+				DocID:   docID,
+				Content: content,
+				Heading: chunk.Heading,
+			})
+			pieces++
+		}
+
+		split++
+		s.logSplit(docID, chunk, budget, pieces)
+	}
+
+	if split == 0 {
+		return chunks
+	}
+
+	for i := range fitted {
+		fitted[i].Index = i
+	}
+
+	return fitted
+}
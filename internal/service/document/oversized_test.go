@@ -0,0 +1,78 @@
+package document
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+)
+
+// oversizedDocument returns a single paragraph far over 50 tokens with a fenced code block
+// in the middle
+func oversizedDocument() string {
+	prose := strings.Repeat("plain words about the topic ", 40)
+	code := "```go\n" + strings.Repeat("codeline := compute(value)\n", 30) + "```\n"
+	return prose + "\n" + code + prose
+}
+
+func TestSplitOversizedOffKeepsChunksWhole(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RAG.ChunkSize = 100000
+	cfg.Embeddings.MaxInputTokens = 50
+
+	chunks, _ := newTestService(t, cfg).ChunkDocument("doc", "notes.md", oversizedDocument())
+	if len(chunks) != 1 {
+		t.Errorf("chunks = %d, want the document whole with CHUNK_SPLIT_OVERSIZED off", len(chunks))
+	}
+}
+
+func TestSplitOversizedFitsInputLimit(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RAG.ChunkSize = 100000
+	cfg.RAG.SplitOversized = true
+	cfg.RAG.ChunkContextualize = true
+	cfg.Embeddings.MaxInputTokens = 50
+
+	chunks, _ := newTestService(t, cfg).ChunkDocument("doc", "notes.md", oversizedDocument())
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %d, want the oversized chunk split", len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("chunk %d has index %d", i, chunk.Index)
+		}
+		if tokens := tokenizer.EstimateTokens(chunk.EmbeddingText); tokens > 50 {
+			t.Errorf("chunk %d embeds %d tokens, want at most 50", i, tokens)
+		}
+	}
+}
+
+func TestSplitOversizedKeepsCodeBlockStripping(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RAG.ChunkSize = 100000
+	cfg.RAG.SplitOversized = true
+	cfg.RAG.EmbedCodeBlocks = false
+	cfg.Embeddings.MaxInputTokens = 50
+
+	chunks, _ := newTestService(t, cfg).ChunkDocument("doc", "notes.md", oversizedDocument())
+
+	stripped := 0
+	for i, chunk := range chunks {
+		if !strings.Contains(chunk.EmbeddingText, codeBlockMarker) {
+			continue
+		}
+		stripped++
+		if strings.Contains(chunk.EmbeddingText, "codeline") {
+			t.Errorf("chunk %d embeds code next to the marker: %q", i, chunk.EmbeddingText)
+		}
+		if !strings.Contains(chunk.Content, "codeline") {
+			t.Errorf("chunk %d lost its code from the stored content", i)
+		}
+	}
+
+	// The pieces on either side of the code block mix prose with code
+	if stripped == 0 {
+		t.Error("no split piece had its code block stripped from the embedded text")
+	}
+}
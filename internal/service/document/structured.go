@@ -0,0 +1,371 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// StructuredExtensions lists the data file extensions indexed with structure-aware chunking
+var StructuredExtensions = map[string]bool{
+	".json": true,
+	".yaml": true,
+	".yml":  true,
+}
+
+// IsStructuredFile reports whether the filename has a JSON or YAML extension
+func IsStructuredFile(filename string) bool {
+	return StructuredExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// structUnit is a logical unit of a structured document: a key with its value or an
+// array element, with its path from the document root (e.g. "servers[0].host")
+type structUnit struct {
+	path string
+	text string
+}
+
+// chunkStructured splits a JSON or YAML document into chunks of whole top-level keys and
+// array elements, packing consecutive small units together up to the chunk size. Units
+// larger than the chunk size are split into their own keys and elements, recursively, so
+// a chunk never ends mid-object. Each chunk's content starts with the paths of its units,
+// which are also kept as its heading. Documents that fail to parse are chunked as text.
+func (s *Service) chunkStructured(docID, filename, text string) []models.Chunk {
+	chunkSize := s.cfg.RAG.ChunkSize
+
+	var units []structUnit
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		var err error
+		units, err = jsonUnits([]byte(text), chunkSize)
+		if err != nil {
+			return s.chunkText(docID, text, "File: "+filename, false)
+		}
+	default:
+		units = yamlDocumentUnits(text, chunkSize)
+	}
+
+	var chunks []models.Chunk
+	var paths, texts []string
+	size := 0
+
+	emit := func() {
+		if len(texts) == 0 {
+			return
+		}
+		heading := strings.Join(paths, ", ")
+		chunks = append(chunks, models.Chunk{
+			ID:      uuid.New().String(),
+			DocID:   docID,
+			Content: "Path: " + heading + "\n" + strings.Join(texts, "\n"),
+			Index:   len(chunks),
+			Heading: heading,
+		})
+		paths, texts, size = nil, nil, 0
+	}
+
+	for _, unit := range units {
+		unitSize := utf8.RuneCountInString(unit.text) + 1
+		if size > 0 && size+unitSize > chunkSize {
+			emit()
+		}
+		paths = append(paths, unit.path)
+		texts = append(texts, unit.text)
+		size += unitSize
+	}
+	emit()
+
+	if len(chunks) == 0 {
+		return s.chunkText(docID, text, "File: "+filename, false)
+	}
+
+	return chunks
+}
+
+// jsonUnits splits a JSON document into units. A document that fits the chunk size is a
+// single unit at path "$".
+func jsonUnits(data []byte, chunkSize int) ([]structUnit, error) {
+	var root json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	if utf8.RuneCount(data) <= chunkSize {
+		return []structUnit{{path: "$", text: indentJSON(root)}}, nil
+	}
+
+	return splitJSON(root, "", chunkSize)
+}
+
+// splitJSON splits an object into its keys or an array into its elements, in document
+// order, recursing into those still larger than the chunk size. Scalars are one unit.
+func splitJSON(value json.RawMessage, path string, chunkSize int) ([]structUnit, error) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+
+	open, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := open.(json.Delim)
+	if !ok {
+		return []structUnit{{path: rootPath(path), text: indentJSON(value)}}, nil
+	}
+
+	var units []structUnit
+	for i := 0; dec.More(); i++ {
+		childPath := fmt.Sprintf("%s[%d]", path, i)
+		label := ""
+
+		if delim == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			name, _ := key.(string)
+			childPath = joinPath(path, name)
+			quoted, _ := json.Marshal(name)
+			label = string(quoted) + ": "
+		}
+
+		var child json.RawMessage
+		if err := dec.Decode(&child); err != nil {
+			return nil, err
+		}
+
+		text := label + indentJSON(child)
+		if utf8.RuneCountInString(text) <= chunkSize || !isContainer(child) {
+			units = append(units, structUnit{path: childPath, text: text})
+			continue
+		}
+
+		nested, err := splitJSON(child, childPath, chunkSize)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, nested...)
+	}
+
+	if _, err := dec.Token(); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// Empty objects and arrays are still a unit of their own
+	if len(units) == 0 {
+		units = append(units, structUnit{path: rootPath(path), text: indentJSON(value)})
+	}
+
+	return units, nil
+}
+
+// isContainer reports whether a JSON value is an object or array
+func isContainer(value json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(value)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// indentJSON re-indents a JSON value so nested values don't keep their original depth
+func indentJSON(value json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, value, "", "  "); err != nil {
+		return strings.TrimSpace(string(value))
+	}
+	return buf.String()
+}
+
+// yamlDocumentUnits splits a YAML file into units. In a file of several "---" documents the
+// paths of each start with its position, e.g. "$[1].servers", so keys from different
+// documents don't share a path.
+func yamlDocumentUnits(text string, chunkSize int) []structUnit {
+	docs := yamlDocuments(strings.Split(text, "\n"))
+	if len(docs) == 1 {
+		return yamlUnits(docs[0], 0, "", chunkSize)
+	}
+
+	var units []structUnit
+	for i, doc := range docs {
+		units = append(units, yamlUnits(doc, 0, fmt.Sprintf("$[%d]", i), chunkSize)...)
+	}
+	return units
+}
+
+// yamlDocuments splits YAML lines at "---" markers in the first column. Comments and blank
+// lines outside any document go with the next one, or the last one at the end of the file.
+func yamlDocuments(lines []string) [][]string {
+	var docs [][]string
+	var current, pending []string
+	hasContent := false
+
+	for _, line := range lines {
+		if line == "---" || strings.HasPrefix(line, "--- ") {
+			if hasContent {
+				docs = append(docs, current)
+				current, hasContent = nil, false
+			}
+			pending = append(pending, current...)
+			current = nil
+			continue
+		}
+
+		current = append(current, line)
+		if !isYAMLFiller(strings.TrimSpace(line)) && !hasContent {
+			current = append(pending, current...)
+			pending, hasContent = nil, true
+		}
+	}
+
+	if hasContent {
+		docs = append(docs, current)
+	} else if len(docs) > 0 {
+		last := len(docs) - 1
+		docs[last] = append(docs[last], append(pending, current...)...)
+	} else {
+		docs = append(docs, append(pending, current...))
+	}
+
+	return docs
+}
+
+// yamlUnits splits YAML lines at indentation indent into keys and sequence items, recursing
+// into keys whose block value is larger than the chunk size. Comments are kept with the unit
+// that follows them, unless indented into the one before. It works on the text rather than a parsed document, so units keep
+// their original formatting; flow values and block scalars stay whole.
+func yamlUnits(lines []string, indent int, path string, chunkSize int) []structUnit {
+	type block struct {
+		name   string
+		lines  []string
+		header int // index of the unit's first line in lines, after leading comments
+		item   bool
+	}
+
+	var blocks []block
+	var pending []string // comments and blank lines before the next unit
+	items := 0
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		lineIndent := len(line) - len(strings.TrimLeft(line, " "))
+
+		// Comments indented into the current unit belong to it (in a block scalar they are
+		// its text); other blank and comment lines wait for the next unit
+		if isYAMLFiller(trimmed) {
+			if trimmed != "" && lineIndent > indent && len(blocks) > 0 {
+				last := &blocks[len(blocks)-1]
+				last.lines = append(last.lines, pending...)
+				last.lines = append(last.lines, line)
+				pending = nil
+				continue
+			}
+			pending = append(pending, line)
+			continue
+		}
+
+		// Nested lines, and "- item" lines at this indentation directly under a "key:",
+		// continue the current unit
+		isItem := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+		if len(blocks) > 0 && (lineIndent > indent || isItem && !blocks[len(blocks)-1].item) {
+			last := &blocks[len(blocks)-1]
+			last.lines = append(last.lines, pending...)
+			last.lines = append(last.lines, line)
+			pending = nil
+			continue
+		}
+
+		name := fmt.Sprintf("%s[%d]", path, items)
+		if isItem {
+			items++
+		} else {
+			key, _, _ := strings.Cut(trimmed, ":")
+			name = joinPath(path, strings.Trim(strings.TrimSpace(key), `"'`))
+		}
+
+		blocks = append(blocks, block{name: name, lines: append(pending, line), header: len(pending), item: isItem})
+		pending = nil
+	}
+
+	if len(blocks) > 0 {
+		blocks[len(blocks)-1].lines = append(blocks[len(blocks)-1].lines, pending...)
+	}
+
+	var units []structUnit
+	for _, b := range blocks {
+		text := strings.TrimSpace(strings.Join(dedent(b.lines, indent), "\n"))
+		if utf8.RuneCountInString(text) > chunkSize && !b.item {
+			if nested := yamlChildren(b.lines[b.header:], indent, b.name, chunkSize); len(nested) > 0 {
+				units = append(units, nested...)
+				continue
+			}
+		}
+		units = append(units, structUnit{path: rootPath(b.name), text: text})
+	}
+
+	return units
+}
+
+// yamlChildren splits a "key:" unit into the units of its block value, or returns nil when
+// the key has an inline value or block scalar
+func yamlChildren(lines []string, indent int, path string, chunkSize int) []structUnit {
+	header := strings.TrimSpace(lines[0])
+	if i := strings.Index(header, " #"); i >= 0 {
+		header = strings.TrimSpace(header[:i])
+	}
+	if !strings.HasSuffix(header, ":") {
+		return nil
+	}
+
+	children := lines[1:]
+	for _, line := range children {
+		if trimmed := strings.TrimSpace(line); !isYAMLFiller(trimmed) {
+			childIndent := len(line) - len(strings.TrimLeft(line, " "))
+			if childIndent < indent {
+				return nil
+			}
+			return yamlUnits(children, childIndent, path, chunkSize)
+		}
+	}
+
+	return nil
+}
+
+// dedent removes up to n leading spaces from each line, so nested units read as if they
+// were at the top level
+func dedent(lines []string, n int) []string {
+	if n == 0 {
+		return lines
+	}
+
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		spaces := len(line) - len(strings.TrimLeft(line, " "))
+		out[i] = line[min(spaces, n):]
+	}
+	return out
+}
+
+// isYAMLFiller reports whether a trimmed YAML line holds no content: blank, a comment or a
+// document marker
+func isYAMLFiller(trimmed string) bool {
+	return trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" || trimmed == "..."
+}
+
+// joinPath appends a key to a dotted path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// rootPath names the document root "$"
+func rootPath(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return path
+}
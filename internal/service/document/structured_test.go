@@ -0,0 +1,162 @@
+package document
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// unitPaths returns the paths of units in order
+func unitPaths(units []structUnit) []string {
+	paths := make([]string, len(units))
+	for i, unit := range units {
+		paths[i] = unit.path
+	}
+	return paths
+}
+
+func TestYAMLUnits(t *testing.T) {
+	tests := []struct {
+		name      string
+		yaml      string
+		chunkSize int
+		paths     []string
+		texts     []string
+	}{
+		{
+			name:      "top-level keys",
+			yaml:      "name: api\nversion: 2\n",
+			chunkSize: 1000,
+			paths:     []string{"name", "version"},
+			texts:     []string{"name: api", "version: 2"},
+		},
+		{
+			name:      "nested map that fits stays whole",
+			yaml:      "server:\n  host: localhost\n  port: 8080\n",
+			chunkSize: 1000,
+			paths:     []string{"server"},
+			texts:     []string{"server:\n  host: localhost\n  port: 8080"},
+		},
+		{
+			name:      "nested map too large is split into its keys",
+			yaml:      "server:\n  host: localhost\n  tls:\n    cert: a.pem\n    key: a.key\n",
+			chunkSize: 40,
+			paths:     []string{"server.host", "server.tls"},
+			texts:     []string{"host: localhost", "tls:\n  cert: a.pem\n  key: a.key"},
+		},
+		{
+			name:      "sequence items",
+			yaml:      "servers:\n- host: a.example.com\n  port: 1\n- host: b.example.com\n  port: 2\n",
+			chunkSize: 40,
+			paths:     []string{"servers[0]", "servers[1]"},
+			texts:     []string{"- host: a.example.com\n  port: 1", "- host: b.example.com\n  port: 2"},
+		},
+		{
+			name:      "indented sequence items",
+			yaml:      "servers:\n  - host: a.example.com\n  - host: b.example.com\n",
+			chunkSize: 30,
+			paths:     []string{"servers[0]", "servers[1]"},
+			texts:     []string{"- host: a.example.com", "- host: b.example.com"},
+		},
+		{
+			name:      "top-level sequence",
+			yaml:      "- one\n- two\n",
+			chunkSize: 1000,
+			paths:     []string{"[0]", "[1]"},
+			texts:     []string{"- one", "- two"},
+		},
+		{
+			name:      "block scalar stays whole",
+			yaml:      "script: |\n  steps:\n  - build\n  - test\n  # not a comment\nafter: 1\n",
+			chunkSize: 10,
+			paths:     []string{"script", "after"},
+			texts:     []string{"script: |\n  steps:\n  - build\n  - test\n  # not a comment", "after: 1"},
+		},
+		{
+			name:      "folded block scalar stays whole",
+			yaml:      "description: >-\n  a long\n  folded text\n",
+			chunkSize: 10,
+			paths:     []string{"description"},
+			texts:     []string{"description: >-\n  a long\n  folded text"},
+		},
+		{
+			name:      "comments go with the following unit",
+			yaml:      "# the service name\nname: api\n\n# listen port\nport: 80 # inline\n",
+			chunkSize: 1000,
+			paths:     []string{"name", "port"},
+			texts:     []string{"# the service name\nname: api", "# listen port\nport: 80 # inline"},
+		},
+		{
+			name:      "quoted keys",
+			yaml:      "\"api.host\": a\n'port': 1\n",
+			chunkSize: 1000,
+			paths:     []string{"api.host", "port"},
+			texts:     []string{"\"api.host\": a", "'port': 1"},
+		},
+		{
+			name:      "single document with a marker",
+			yaml:      "# header\n---\nname: api\n",
+			chunkSize: 1000,
+			paths:     []string{"name"},
+			texts:     []string{"# header\nname: api"},
+		},
+		{
+			name:      "documents keep separate paths",
+			yaml:      "name: api\nport: 80\n---\nname: worker\n--- # third\nname: cron\n...\n",
+			chunkSize: 1000,
+			paths:     []string{"$[0].name", "$[0].port", "$[1].name", "$[2].name"},
+			texts:     []string{"name: api", "port: 80", "name: worker", "name: cron\n..."},
+		},
+		{
+			name:      "documents are split independently",
+			yaml:      "server:\n  host: a\n  port: 1\n---\nserver:\n  host: b\n  port: 2\n",
+			chunkSize: 20,
+			paths:     []string{"$[0].server.host", "$[0].server.port", "$[1].server.host", "$[1].server.port"},
+			texts:     []string{"host: a", "port: 1", "host: b", "port: 2"},
+		},
+		{
+			name:      "empty documents are skipped",
+			yaml:      "---\n---\nname: api\n---\n# trailing comment\n",
+			chunkSize: 1000,
+			paths:     []string{"name"},
+			texts:     []string{"name: api\n# trailing comment"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units := yamlDocumentUnits(tt.yaml, tt.chunkSize)
+
+			if paths := unitPaths(units); !reflect.DeepEqual(paths, tt.paths) {
+				t.Fatalf("paths = %q, want %q", paths, tt.paths)
+			}
+			for i, unit := range units {
+				if unit.text != tt.texts[i] {
+					t.Errorf("unit %s = %q, want %q", unit.path, unit.text, tt.texts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChunkStructuredYAMLDocuments(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RAG.StructuredChunking = true
+	cfg.RAG.ChunkSize = 30
+
+	yaml := "kind: Service\nname: api\n---\nkind: Deployment\nname: api\n"
+	chunks, _ := newTestService(t, cfg).ChunkDocument("doc", "deploy.yaml", yaml)
+	if len(chunks) != 2 {
+		t.Fatalf("chunks = %d, want one per document", len(chunks))
+	}
+
+	wantHeadings := []string{"$[0].kind, $[0].name", "$[1].kind, $[1].name"}
+	for i, chunk := range chunks {
+		if chunk.Heading != wantHeadings[i] {
+			t.Errorf("chunk %d heading = %q, want %q", i, chunk.Heading, wantHeadings[i])
+		}
+		if !strings.HasPrefix(chunk.Content, "Path: "+wantHeadings[i]+"\n") {
+			t.Errorf("chunk %d content = %q, want it to start with its paths", i, chunk.Content)
+		}
+	}
+}
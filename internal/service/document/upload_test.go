@@ -0,0 +1,33 @@
+package document
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestProcessUploadSavesOriginalBytes(t *testing.T) {
+	cfg := testConfig(t)
+	svc := newTestService(t, cfg)
+
+	// Line endings, trailing whitespace, a byte order mark and a missing final newline are
+	// all lost if the original is rebuilt from extracted text
+	original := []byte("\xef\xbb\xbfTitle\r\n\r\nFirst line   \r\nSecond\tline\r\n\r\n\r\nLast line")
+
+	doc, err := svc.ProcessUpload("notes.txt", bytes.NewReader(original))
+	if err != nil {
+		t.Fatalf("ProcessUpload failed: %v", err)
+	}
+
+	saved, err := os.ReadFile(svc.filePath(doc.ID, "notes.txt"))
+	if err != nil {
+		t.Fatalf("failed to read saved original: %v", err)
+	}
+	if !bytes.Equal(saved, original) {
+		t.Errorf("saved original = %q, want the uploaded bytes %q", saved, original)
+	}
+
+	if len(doc.Chunks) == 0 {
+		t.Error("no chunks extracted from the upload")
+	}
+}
@@ -0,0 +1,90 @@
+package document
+
+import (
+	"strings"
+	"unicode"
+)
+
+// normalizeWhitespace cleans up prose before chunking: unicode spaces become plain spaces,
+// zero-width characters are dropped, runs of spaces inside a line collapse to one, trailing
+// whitespace is trimmed and consecutive blank lines collapse to a single blank line. Leading
+// indentation and fenced (``` or ~~~) code blocks are left untouched.
+func normalizeWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	blank := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		isFence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+
+		if inFence {
+			out = append(out, line)
+			if isFence {
+				inFence = false
+			}
+			continue
+		}
+		if isFence {
+			inFence = true
+			blank = false
+			out = append(out, strings.TrimRightFunc(line, unicode.IsSpace))
+			continue
+		}
+
+		line = normalizeLine(line)
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// normalizeLine normalizes the whitespace of a single line of prose, keeping its indentation
+func normalizeLine(line string) string {
+	var b strings.Builder
+	b.Grow(len(line))
+
+	indent := true
+	space := false
+
+	for _, r := range line {
+		switch {
+		case r == '\u200b' || r == '\u200c' || r == '\u200d' || r == '\ufeff':
+			continue
+		case unicode.IsSpace(r):
+			if indent {
+				// Keep tabs in indentation, which can be significant (e.g. nested lists)
+				if r != '\t' {
+					r = ' '
+				}
+				b.WriteRune(r)
+				continue
+			}
+			space = true
+		default:
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			indent = false
+			b.WriteRune(r)
+		}
+	}
+
+	if indent {
+		// Whitespace-only line
+		return ""
+	}
+
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+	"go.uber.org/zap"
+)
+
+// batchLimits bounds a single batched embedding request
+type batchLimits struct {
+	// maxInputs is the most texts per request; 1 means the provider is never batched
+	maxInputs int
+	// maxTokens caps the estimated tokens of all texts in a request (0 = unbounded)
+	maxTokens int
+}
+
+// batchLimits returns the request limits of a provider with EMBEDDING_BATCHING on. Only
+// OpenRouter's (OpenAI-compatible) endpoint accepts several inputs; Ollama's and Bedrock's
+// embedding endpoints take one text per request.
+func (s *Service) batchLimits(provider string) batchLimits {
+	if !s.cfg.Embeddings.Batching {
+		return batchLimits{maxInputs: 1}
+	}
+
+	switch provider {
+	case "openrouter":
+		return batchLimits{
+			maxInputs: s.cfg.Embeddings.OpenRouterBatchInputs,
+			maxTokens: s.cfg.Embeddings.OpenRouterBatchTokens,
+		}
+	default:
+		return batchLimits{maxInputs: 1}
+	}
+}
+
+// splitBatches groups text indices into consecutive batches within the limits. A text that
+// alone exceeds maxTokens gets a batch of its own.
+func splitBatches(texts []string, limits batchLimits) [][]int {
+	var batches [][]int
+	var current []int
+	tokens := 0
+
+	for i, text := range texts {
+		n := tokenizer.EstimateTokens(text)
+		full := len(current) >= limits.maxInputs ||
+			(limits.maxTokens > 0 && len(current) > 0 && tokens+n > limits.maxTokens)
+		if full {
+			batches = append(batches, current)
+			current, tokens = nil, 0
+		}
+		current = append(current, i)
+		tokens += n
+	}
+
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// prefetchEmbeddings embeds texts in batches when the provider supports it and returns the
+// embeddings by text index. Texts of failed batches are left nil, so the caller embeds them
+// one by one with its usual retries.
+func (s *Service) prefetchEmbeddings(ctx context.Context, provider, model string, texts []string, apiKey string) [][]float64 {
+	limits := s.batchLimits(provider)
+	if limits.maxInputs <= 1 || len(texts) < 2 {
+		return nil
+	}
+
+	embeddings := make([][]float64, len(texts))
+
+	for _, batch := range splitBatches(texts, limits) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		inputs := make([]string, len(batch))
+		for i, idx := range batch {
+			inputs[i] = texts[idx]
+		}
+
+		results, err := s.generateOpenRouterEmbeddings(ctx, model, inputs, apiKey)
+		if err != nil {
+			s.logger.Warn("batched embedding request failed, embedding its texts one by one",
+				zap.Int("inputs", len(inputs)),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		for i, idx := range batch {
+			embeddings[idx] = results[i]
+		}
+	}
+
+	return embeddings
+}
+
+// openRouterBatchRequest represents an OpenRouter embeddings request with several inputs
+type openRouterBatchRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// generateOpenRouterEmbeddings embeds several texts in one OpenRouter request, returning
+// the embeddings in input order
+func (s *Service) generateOpenRouterEmbeddings(ctx context.Context, model string, texts []string, apiKey string) ([][]float64, error) {
+	start := time.Now()
+
+	callCtx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	jsonData, err := json.Marshal(openRouterBatchRequest{Model: model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(callCtx, "POST", s.cfg.Embeddings.OpenRouterURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	for name, value := range s.cfg.OpenRouter.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, s.callError(ctx, "failed to execute request", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, s.callError(ctx, "failed to read response", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response openRouterResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("embeddings API error: %s", response.Error.Message)
+	}
+
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings API returned %d embeddings for %d inputs", len(response.Data), len(texts))
+	}
+
+	embeddings := make([][]float64, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || data.Index >= len(texts) || embeddings[data.Index] != nil {
+			return nil, fmt.Errorf("embeddings API returned an invalid index %d", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+
+	if s.cfg.Embeddings.LogRequests {
+		s.logger.Debug("batched embedding request",
+			zap.String("provider", "openrouter"),
+			zap.String("model", model),
+			zap.Int("inputs", len(texts)),
+			zap.Duration("latency", time.Since(start)),
+		)
+	}
+
+	return embeddings, nil
+}
@@ -0,0 +1,44 @@
+package embeddings
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// maxLoggedPreview caps the input preview logged with EMBEDDING_LOG_CONTENT
+const maxLoggedPreview = 200
+
+// logRequest records a single embedding call at debug level when EMBEDDING_LOG_REQUESTS is
+// set. The input itself is only logged, truncated, when EMBEDDING_LOG_CONTENT is also set.
+func (s *Service) logRequest(provider, model, text string, start time.Time, embedding []float64, err error) {
+	if !s.cfg.Embeddings.LogRequests {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("provider", provider),
+		zap.String("model", model),
+		zap.Int("input_chars", utf8.RuneCountInString(text)),
+		zap.Int("dimensions", len(embedding)),
+		zap.Duration("latency", time.Since(start)),
+	}
+	if s.cfg.Embeddings.LogContent {
+		fields = append(fields, zap.String("input_preview", preview(text)))
+	}
+
+	if err != nil {
+		s.logger.Debug("embedding request failed", append(fields, zap.Error(err))...)
+		return
+	}
+	s.logger.Debug("embedding request", fields...)
+}
+
+// preview returns at most maxLoggedPreview runes of text
+func preview(text string) string {
+	if utf8.RuneCountInString(text) <= maxLoggedPreview {
+		return text
+	}
+	return string([]rune(text)[:maxLoggedPreview]) + "...[truncated]"
+}
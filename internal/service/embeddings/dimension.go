@@ -0,0 +1,140 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// dimensionProbe is the text embedded to discover the model's native dimension
+const dimensionProbe = "dimension probe"
+
+const (
+	// dimensionProbeTimeout bounds a single dimension probe
+	dimensionProbeTimeout = 30 * time.Second
+
+	// dimensionRetryMin and dimensionRetryMax bound the backoff after a failed probe
+	dimensionRetryMin = 5 * time.Second
+	dimensionRetryMax = 5 * time.Minute
+)
+
+// DetectDimension returns the native dimension of EMBEDDING_MODEL by embedding a probe string.
+// A success is cached for good; a failure is cached and returned without calling the provider
+// again until a backoff (doubling from 5s to 5m) has passed. It also sets the expected dimension
+// when EMBEDDING_DIMENSIONS is unset (auto), as the first embedding would.
+func (s *Service) DetectDimension(ctx context.Context) (int, error) {
+	// probeMu serializes probes; detectMu only guards the cached result, so readers such as
+	// DetectedDimension never wait on the provider
+	s.probeMu.Lock()
+	defer s.probeMu.Unlock()
+
+	s.detectMu.Lock()
+	detected, cachedErr, retryAt := s.detected, s.detectErr, s.detectRetryAt
+	s.detectMu.Unlock()
+
+	if detected > 0 {
+		return detected, nil
+	}
+	if cachedErr != nil && time.Now().Before(retryAt) {
+		return 0, cachedErr
+	}
+
+	dimension, err := s.probeDimension(ctx)
+
+	s.detectMu.Lock()
+	defer s.detectMu.Unlock()
+
+	if err != nil {
+		s.detectFailures++
+		s.detectErr = err
+		s.detectRetryAt = time.Now().Add(dimensionBackoff(s.detectFailures))
+		return 0, err
+	}
+
+	s.detected = dimension
+	s.detectErr = nil
+	s.detectFailures = 0
+	s.SetDimensions(dimension)
+
+	return dimension, nil
+}
+
+// DetectedDimension returns the cached native dimension of EMBEDDING_MODEL without calling the
+// provider (0 until a probe succeeded). When nothing is cached and no failure is being backed
+// off, it starts a probe in the background so a later call can report it.
+func (s *Service) DetectedDimension() int {
+	s.detectMu.Lock()
+	defer s.detectMu.Unlock()
+
+	if s.detected > 0 {
+		return s.detected
+	}
+	if !s.detecting && (s.detectErr == nil || !time.Now().Before(s.detectRetryAt)) {
+		s.detecting = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), dimensionProbeTimeout)
+			defer cancel()
+
+			if _, err := s.DetectDimension(ctx); err != nil {
+				s.logger.Warn("failed to detect embedding dimension", zap.Error(err))
+			}
+
+			s.detectMu.Lock()
+			s.detecting = false
+			s.detectMu.Unlock()
+		}()
+	}
+
+	return 0
+}
+
+// probeDimension embeds the dimension probe and checks its length against MAX_EMBEDDING_DIM
+func (s *Service) probeDimension(ctx context.Context) (int, error) {
+	provider := s.cfg.Embeddings.Provider
+	if provider != "ollama" && s.APIKey() == "" {
+		return 0, errors.Unauthorized("API key is not configured for embedding provider: " + provider).WithCode(errors.CodeProviderNotConfigured)
+	}
+
+	embedding, err := s.embed(ctx, provider, s.cfg.Embeddings.Model, dimensionProbe, s.APIKey())
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed dimension probe: %w", err)
+	}
+	if len(embedding) == 0 {
+		return 0, fmt.Errorf("provider returned an empty embedding for the dimension probe")
+	}
+	if err := s.checkMaxDimensions(len(embedding)); err != nil {
+		return 0, err
+	}
+
+	return len(embedding), nil
+}
+
+// dimensionBackoff returns how long to wait before probing again after the given number of
+// consecutive failures
+func dimensionBackoff(failures int) time.Duration {
+	backoff := dimensionRetryMin
+	for i := 1; i < failures && backoff < dimensionRetryMax; i++ {
+		backoff *= 2
+	}
+	if backoff > dimensionRetryMax {
+		backoff = dimensionRetryMax
+	}
+	return backoff
+}
+
+// embed embeds a single text with the given provider and model, without retries or checks
+func (s *Service) embed(ctx context.Context, provider, model, text, apiKey string) ([]float64, error) {
+	switch provider {
+	case "ollama":
+		return s.generateOllamaEmbedding(ctx, model, text)
+	case "openrouter":
+		return s.generateOpenRouterEmbedding(ctx, model, text, apiKey)
+	case "bedrock":
+		return s.generateBedrockEmbedding(ctx, model, text, apiKey)
+	default:
+		return nil, errors.BadRequest("unsupported embedding provider").WithCode(errors.CodeInvalidProvider)
+	}
+}
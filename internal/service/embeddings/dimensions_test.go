@@ -0,0 +1,139 @@
+package embeddings
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func TestDimensionsDefaultToAuto(t *testing.T) {
+	server := newOllamaServer(t, 5)
+	t.Setenv("EMBEDDING_DIMENSIONS", "")
+	cfg := testConfig(t, server.URL)
+	if cfg.Embeddings.Dimensions != 0 {
+		t.Fatalf("default EMBEDDING_DIMENSIONS = %d, want 0 (auto)", cfg.Embeddings.Dimensions)
+	}
+	svc := New(cfg, zap.NewNop())
+
+	chunks := []models.Chunk{{ID: "c1", DocID: "d1", Content: "hello"}}
+	result, err := svc.GenerateEmbeddings(context.Background(), chunks, "")
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings in auto mode failed: %v", err)
+	}
+	if len(result[0].Embedding) != 5 {
+		t.Fatalf("embedding has %d dimensions, want 5", len(result[0].Embedding))
+	}
+	if got := svc.Dimensions(); got != 5 {
+		t.Errorf("Dimensions() after the first embedding = %d, want 5", got)
+	}
+}
+
+func TestExplicitDimensionsRejectMismatch(t *testing.T) {
+	server := newOllamaServer(t, 5)
+	t.Setenv("EMBEDDING_DIMENSIONS", "4")
+	cfg := testConfig(t, server.URL)
+	svc := New(cfg, zap.NewNop())
+
+	chunks := []models.Chunk{{ID: "c1", DocID: "d1", Content: "hello"}}
+	_, err := svc.GenerateEmbeddings(context.Background(), chunks, "")
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.ErrorCode != errors.CodeDimensionMismatch {
+		t.Fatalf("GenerateEmbeddings with EMBEDDING_DIMENSIONS=4 = %v, want %s", err, errors.CodeDimensionMismatch)
+	}
+}
+
+func TestDetectDimensionCachesSuccess(t *testing.T) {
+	server := newOllamaServer(t, 6)
+	t.Setenv("EMBEDDING_DIMENSIONS", "")
+	svc := New(testConfig(t, server.URL), zap.NewNop())
+
+	for i := 0; i < 3; i++ {
+		if dimension, err := svc.DetectDimension(context.Background()); err != nil || dimension != 6 {
+			t.Fatalf("DetectDimension = %d, %v; want 6", dimension, err)
+		}
+	}
+	if got := server.calls(); got != 1 {
+		t.Errorf("provider called %d times, want 1", got)
+	}
+	if got := svc.Dimensions(); got != 6 {
+		t.Errorf("Dimensions() after detection = %d, want 6", got)
+	}
+}
+
+func TestDetectDimensionBacksOffAfterFailure(t *testing.T) {
+	server := newOllamaServer(t, 6)
+	server.fail(500)
+	svc := New(testConfig(t, server.URL), zap.NewNop())
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.DetectDimension(context.Background()); err == nil {
+			t.Fatal("DetectDimension succeeded against a failing provider")
+		}
+	}
+	if got := server.calls(); got != 1 {
+		t.Errorf("provider called %d times during the backoff, want 1", got)
+	}
+
+	// Once the backoff has passed the provider is asked again
+	server.fail(0)
+	svc.detectMu.Lock()
+	svc.detectRetryAt = time.Now()
+	svc.detectMu.Unlock()
+	if dimension, err := svc.DetectDimension(context.Background()); err != nil || dimension != 6 {
+		t.Fatalf("DetectDimension after the backoff = %d, %v; want 6", dimension, err)
+	}
+}
+
+func TestDetectDimensionRejectsOverMax(t *testing.T) {
+	server := newOllamaServer(t, 6)
+	cfg := testConfig(t, server.URL)
+	cfg.Embeddings.MaxDimensions = 4
+	svc := New(cfg, zap.NewNop())
+
+	_, err := svc.DetectDimension(context.Background())
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.ErrorCode != errors.CodeDimensionMismatch {
+		t.Fatalf("DetectDimension above MAX_EMBEDDING_DIM = %v, want %s", err, errors.CodeDimensionMismatch)
+	}
+	if got := svc.Dimensions(); got != 0 {
+		t.Errorf("Dimensions() after a rejected probe = %d, want 0", got)
+	}
+}
+
+func TestDetectedDimensionProbesInBackground(t *testing.T) {
+	server := newOllamaServer(t, 6)
+	svc := New(testConfig(t, server.URL), zap.NewNop())
+
+	if got := svc.DetectedDimension(); got != 0 {
+		t.Fatalf("DetectedDimension before any probe = %d, want 0", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.DetectedDimension() != 6 {
+		if time.Now().After(deadline) {
+			t.Fatal("background probe never reported the dimension")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := server.calls(); got != 1 {
+		t.Errorf("provider called %d times, want 1", got)
+	}
+}
+
+func TestDimensionBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  5 * time.Second,
+		2:  10 * time.Second,
+		3:  20 * time.Second,
+		20: 5 * time.Minute,
+	}
+	for failures, want := range tests {
+		if got := dimensionBackoff(failures); got != want {
+			t.Errorf("dimensionBackoff(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
@@ -2,15 +2,24 @@ package embeddings
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mrkaynak/rag/internal/config"
 	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/tracing"
+	"github.com/mrkaynak/rag/internal/service/vector"
 	"github.com/mrkaynak/rag/pkg/errors"
+	"github.com/mrkaynak/rag/pkg/httpdebug"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 const (
@@ -23,14 +32,103 @@ const (
 // Service handles embedding generation
 type Service struct {
 	cfg        *config.Config
+	logger     *zap.Logger
 	httpClient *http.Client
+	dimensions atomic.Int64 // expected embedding length, 0 until auto-detected
+
+	// detected caches the model's native dimension found by DetectDimension; a failed probe
+	// is cached in detectErr until detectRetryAt
+	probeMu        sync.Mutex
+	detectMu       sync.Mutex
+	detected       int
+	detectErr      error
+	detectFailures int
+	detectRetryAt  time.Time
+	detecting      bool // a background probe started by DetectedDimension is running
+
+	// healthyAt is when CheckHealth last succeeded (zero when unknown or failed)
+	healthMu  sync.Mutex
+	healthyAt time.Time
 }
 
 // New creates a new embeddings service
-func New(cfg *config.Config) *Service {
-	return &Service{
+func New(cfg *config.Config, logger *zap.Logger) *Service {
+	s := &Service{
 		cfg:        cfg,
-		httpClient: &http.Client{},
+		logger:     logger,
+		httpClient: httpdebug.NewClient(cfg.LLM.DebugRaw, logger, "embeddings"),
+	}
+	s.dimensions.Store(int64(cfg.Embeddings.Dimensions))
+
+	return s
+}
+
+// Dimensions returns the expected embedding dimension (0 if not yet auto-detected)
+func (s *Service) Dimensions() int {
+	return int(s.dimensions.Load())
+}
+
+// SetDimensions sets the expected embedding dimension when it has not been configured or
+// detected yet, e.g. from the vector store's dimension stamp
+func (s *Service) SetDimensions(n int) {
+	s.dimensions.CompareAndSwap(0, int64(n))
+}
+
+// checkDimensions asserts that an embedding has the expected length and is within
+// MAX_EMBEDDING_DIM. In auto mode (EMBEDDING_DIMENSIONS=auto) the first successful
+// embedding sets the expectation.
+func (s *Service) checkDimensions(n int) error {
+	if err := s.checkMaxDimensions(n); err != nil {
+		return err
+	}
+
+	if s.dimensions.CompareAndSwap(0, int64(n)) {
+		return nil
+	}
+
+	if expected := s.dimensions.Load(); int64(n) != expected {
+		return errors.Internal(fmt.Sprintf(
+			"embedding dimension mismatch: provider returned %d dimensions but %d are expected (check EMBEDDING_DIMENSIONS and EMBEDDING_MODEL)",
+			n, expected)).WithCode(errors.CodeDimensionMismatch)
+	}
+
+	return nil
+}
+
+// checkMaxDimensions rejects an embedding longer than MAX_EMBEDDING_DIM
+func (s *Service) checkMaxDimensions(n int) error {
+	if n > s.cfg.Embeddings.MaxDimensions {
+		return errors.Internal(fmt.Sprintf(
+			"provider returned a %d-dimensional embedding, more than MAX_EMBEDDING_DIM=%d (check EMBEDDING_MODEL)",
+			n, s.cfg.Embeddings.MaxDimensions)).WithCode(errors.CodeDimensionMismatch)
+	}
+	return nil
+}
+
+// Provider returns the configured embeddings provider (EMBEDDING_PROVIDER)
+func (s *Service) Provider() string {
+	return s.cfg.Embeddings.Provider
+}
+
+// Model returns the configured embedding model (EMBEDDING_MODEL)
+func (s *Service) Model() string {
+	return s.cfg.Embeddings.Model
+}
+
+// APIKey returns the API key for the configured embeddings provider (empty for Ollama)
+func (s *Service) APIKey() string {
+	return s.APIKeyFor(s.cfg.Embeddings.Provider)
+}
+
+// APIKeyFor returns the API key for an embeddings provider (empty for Ollama)
+func (s *Service) APIKeyFor(provider string) string {
+	switch provider {
+	case "openrouter":
+		return s.cfg.OpenRouter.APIKey
+	case "bedrock":
+		return s.cfg.Bedrock.APIKey
+	default:
+		return ""
 	}
 }
 
@@ -44,50 +142,178 @@ type openRouterRequest struct {
 type openRouterResponse struct {
 	Data []struct {
 		Embedding []float64 `json:"embedding"`
+		Index     int       `json:"index"`
 	} `json:"data"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
 }
 
+// ProgressFunc is called after each chunk is processed with the number of chunks done so far
+type ProgressFunc func(embedded, total int)
+
 // GenerateEmbeddings generates embeddings for chunks with retry logic
-func (s *Service) GenerateEmbeddings(chunks []models.Chunk, apiKey string) ([]models.Chunk, error) {
+func (s *Service) GenerateEmbeddings(ctx context.Context, chunks []models.Chunk, apiKey string) ([]models.Chunk, error) {
+	return s.GenerateEmbeddingsWithProgress(ctx, chunks, apiKey, nil)
+}
+
+// GenerateEmbeddingsWithProgress generates embeddings like GenerateEmbeddings and reports
+// progress after each chunk. progress may be nil.
+func (s *Service) GenerateEmbeddingsWithProgress(ctx context.Context, chunks []models.Chunk, apiKey string, progress ProgressFunc) ([]models.Chunk, error) {
+	return s.generate(ctx, s.cfg.Embeddings.Provider, s.cfg.Embeddings.Model, chunks, apiKey, progress)
+}
+
+// GenerateEmbeddingsWithProvider generates embeddings like GenerateEmbeddingsWithProgress
+// using the given provider (see ResolveProvider) instead of EMBEDDING_PROVIDER
+func (s *Service) GenerateEmbeddingsWithProvider(ctx context.Context, provider string, chunks []models.Chunk, progress ProgressFunc) ([]models.Chunk, error) {
+	return s.generate(ctx, provider, s.ModelFor(provider), chunks, s.APIKeyFor(provider), progress)
+}
+
+// GenerateEmbeddingsWithModel generates embeddings like GenerateEmbeddings using an explicit
+// provider and model, e.g. the ones the vector store was built with, instead of
+// EMBEDDING_PROVIDER and EMBEDDING_MODEL
+func (s *Service) GenerateEmbeddingsWithModel(ctx context.Context, provider, model string, chunks []models.Chunk, apiKey string) ([]models.Chunk, error) {
+	return s.generate(ctx, provider, model, chunks, apiKey, nil)
+}
+
+// ResolveProvider validates a per-request embeddings provider, defaulting to
+// EMBEDDING_PROVIDER when empty. Providers other than Ollama need a configured API key.
+func (s *Service) ResolveProvider(provider string) (string, error) {
+	if provider == "" {
+		provider = s.cfg.Embeddings.Provider
+	}
+
+	switch provider {
+	case "ollama", "openrouter", "bedrock":
+	default:
+		return "", errors.BadRequest("embedding_provider must be 'ollama', 'openrouter', or 'bedrock'").WithCode(errors.CodeInvalidProvider)
+	}
+
+	if provider != "ollama" && s.APIKeyFor(provider) == "" {
+		return "", errors.Unauthorized("API key is not configured for embedding provider: " + provider).WithCode(errors.CodeProviderNotConfigured)
+	}
+
+	if s.ModelFor(provider) == "" {
+		return "", errors.BadRequest(fmt.Sprintf(
+			"no embedding model is configured for provider %s (set EMBEDDING_MODEL_%s)", provider, strings.ToUpper(provider))).WithCode(errors.CodeInvalidModel)
+	}
+
+	return provider, nil
+}
+
+// ModelFor returns the embedding model used with a provider: EMBEDDING_MODEL for
+// EMBEDDING_PROVIDER and EMBEDDING_MODEL_<PROVIDER> for the others, "" when unset. Model
+// names are provider-specific, so EMBEDDING_MODEL is never sent to another provider.
+func (s *Service) ModelFor(provider string) string {
+	if provider == s.cfg.Embeddings.Provider {
+		return s.cfg.Embeddings.Model
+	}
+
+	switch provider {
+	case "ollama":
+		return s.cfg.Embeddings.OllamaModel
+	case "openrouter":
+		return s.cfg.Embeddings.OpenRouterModel
+	case "bedrock":
+		return s.cfg.Embeddings.BedrockModel
+	default:
+		return ""
+	}
+}
+
+// generate embeds chunks with the given provider, retrying failed chunks
+func (s *Service) generate(ctx context.Context, provider, model string, chunks []models.Chunk, apiKey string, progress ProgressFunc) (result []models.Chunk, err error) {
+	_, span := tracing.Start(ctx, "embeddings.generate",
+		attribute.String("embedding.provider", provider),
+		attribute.String("embedding.model", model),
+		attribute.Int("embedding.chunks", len(chunks)),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	// API key not required for Ollama
-	if s.cfg.Embeddings.Provider != "ollama" && apiKey == "" {
-		return nil, errors.BadRequest("API key is required for embeddings")
+	if provider != "ollama" && apiKey == "" {
+		return nil, errors.BadRequest("API key is required for embeddings").WithCode(errors.CodeProviderNotConfigured)
 	}
 
+	texts := make([]string, len(chunks))
+	for i := range chunks {
+		texts[i] = chunks[i].Content
+		if chunks[i].EmbeddingText != "" {
+			texts[i] = chunks[i].EmbeddingText
+		}
+	}
+
+	// With EMBEDDING_BATCHING, embed what the provider allows in batches first
+	prefetched := s.prefetchEmbeddings(ctx, provider, model, texts, apiKey)
+
 	var failedChunks []int
+	skipped := make(map[int]bool)
 	successCount := 0
 
 	for i := range chunks {
+		// Stop once the request deadline has passed instead of failing every remaining chunk
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		var embedding []float64
 		var lastErr error
 
+		text := texts[i]
+
 		// Retry logic with exponential backoff
 		for attempt := 0; attempt < MaxRetries; attempt++ {
-			switch s.cfg.Embeddings.Provider {
-			case "ollama":
-				embedding, lastErr = s.generateOllamaEmbedding(chunks[i].Content)
-			case "openrouter":
-				embedding, lastErr = s.generateOpenRouterEmbedding(chunks[i].Content, apiKey)
-			case "bedrock":
-				embedding, lastErr = s.generateBedrockEmbedding(chunks[i].Content, apiKey)
+			switch {
+			case attempt == 0 && prefetched != nil && prefetched[i] != nil:
+				embedding, lastErr = prefetched[i], nil
+			case provider == "ollama":
+				embedding, lastErr = s.generateOllamaEmbedding(ctx, model, text)
+			case provider == "openrouter":
+				embedding, lastErr = s.generateOpenRouterEmbedding(ctx, model, text, apiKey)
+			case provider == "bedrock":
+				embedding, lastErr = s.generateBedrockEmbedding(ctx, model, text, apiKey)
 			default:
-				return nil, errors.BadRequest("unsupported embedding provider")
+				return nil, errors.BadRequest("unsupported embedding provider").WithCode(errors.CodeInvalidProvider)
 			}
 
 			// Success - break retry loop
 			if lastErr == nil {
+				// A dimension mismatch is a configuration problem, retrying won't help
+				if err := s.checkDimensions(len(embedding)); err != nil {
+					return nil, err
+				}
+
+				// Zero/NaN vectors would be stored but never retrieved
+				if err := vector.CheckEmbedding(embedding); err != nil {
+					if s.cfg.Embeddings.InvalidVectors != "skip" {
+						return nil, errors.Internal(fmt.Sprintf(
+							"provider returned an unusable embedding for chunk %s (index %d): %v", chunks[i].ID, chunks[i].Index, err))
+					}
+
+					s.logger.Warn("skipping chunk with unusable embedding",
+						zap.String("chunk_id", chunks[i].ID),
+						zap.String("doc_id", chunks[i].DocID),
+						zap.Int("index", chunks[i].Index),
+						zap.Error(err),
+					)
+					skipped[i] = true
+					break
+				}
+
 				chunks[i].Embedding = embedding
 				successCount++
 				break
 			}
 
-			// Failed - wait before retry (except on last attempt)
+			// Failed - wait before retry (except on last attempt), unless the request
+			// deadline would pass before the retry could start
 			if attempt < MaxRetries-1 {
 				backoff := InitialBackoff * time.Duration(1<<uint(attempt)) // Exponential: 1s, 2s, 4s
-				time.Sleep(backoff)
+				if err := sleepUnlessDeadline(ctx, backoff); err != nil {
+					// The provider has already failed this chunk
+					s.invalidateHealth()
+					return nil, err
+				}
 			}
 		}
 
@@ -95,23 +321,44 @@ func (s *Service) GenerateEmbeddings(chunks []models.Chunk, apiKey string) ([]mo
 		if lastErr != nil {
 			failedChunks = append(failedChunks, i)
 		}
+
+		if progress != nil {
+			progress(i+1, len(chunks))
+		}
 	}
 
 	// If any chunks failed after all retries, return error with details
 	if len(failedChunks) > 0 {
+		s.invalidateHealth()
 		return nil, errors.Internal(
 			fmt.Sprintf("failed to generate embeddings for %d/%d chunks (indices: %v) after %d retries",
 				len(failedChunks), len(chunks), failedChunks, MaxRetries),
-		)
+		).WithCode(errors.CodeEmbeddingFailed)
+	}
+
+	if len(skipped) > 0 {
+		kept := make([]models.Chunk, 0, len(chunks)-len(skipped))
+		for i, chunk := range chunks {
+			if !skipped[i] {
+				kept = append(kept, chunk)
+			}
+		}
+		return kept, nil
 	}
 
 	return chunks, nil
 }
 
 // generateOpenRouterEmbedding generates embedding for a single text using OpenRouter
-func (s *Service) generateOpenRouterEmbedding(text, apiKey string) ([]float64, error) {
+func (s *Service) generateOpenRouterEmbedding(ctx context.Context, model, text, apiKey string) (embedding []float64, err error) {
+	start := time.Now()
+	defer func() { s.logRequest("openrouter", model, text, start, embedding, err) }()
+
+	callCtx, cancel := s.callContext(ctx)
+	defer cancel()
+
 	reqBody := openRouterRequest{
-		Model: s.cfg.Embeddings.Model,
+		Model: model,
 		Input: text,
 	}
 
@@ -120,23 +367,26 @@ func (s *Service) generateOpenRouterEmbedding(text, apiKey string) ([]float64, e
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://openrouter.ai/api/v1/embeddings", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(callCtx, "POST", s.cfg.Embeddings.OpenRouterURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	for name, value := range s.cfg.OpenRouter.Headers {
+		req.Header.Set(name, value)
+	}
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, s.callError(ctx, "failed to execute request", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, s.callError(ctx, "failed to read response", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -173,7 +423,13 @@ type bedrockEmbeddingResponse struct {
 }
 
 // generateBedrockEmbedding generates embedding using AWS Bedrock
-func (s *Service) generateBedrockEmbedding(text, apiKey string) ([]float64, error) {
+func (s *Service) generateBedrockEmbedding(ctx context.Context, model, text, apiKey string) (embedding []float64, err error) {
+	start := time.Now()
+	defer func() { s.logRequest("bedrock", model, text, start, embedding, err) }()
+
+	callCtx, cancel := s.callContext(ctx)
+	defer cancel()
+
 	reqBody := bedrockEmbeddingRequest{
 		InputText: text,
 	}
@@ -183,28 +439,33 @@ func (s *Service) generateBedrockEmbedding(text, apiKey string) ([]float64, erro
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Build Bedrock embedding endpoint URL
-	url := fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com/model/%s/invoke",
-		s.cfg.Bedrock.Region,
-		s.cfg.Embeddings.Model)
+	// Build Bedrock embedding endpoint URL (regional endpoint unless a gateway is configured)
+	baseURL := s.cfg.Embeddings.BedrockBaseURL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", s.cfg.Bedrock.Region)
+	}
+	url := fmt.Sprintf("%s/model/%s/invoke", baseURL, model)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(callCtx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	for name, value := range s.cfg.Bedrock.Headers {
+		req.Header.Set(name, value)
+	}
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, s.callError(ctx, "failed to execute request", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, s.callError(ctx, "failed to read response", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -239,9 +500,15 @@ type ollamaResponse struct {
 }
 
 // generateOllamaEmbedding generates embedding using Ollama
-func (s *Service) generateOllamaEmbedding(text string) ([]float64, error) {
+func (s *Service) generateOllamaEmbedding(ctx context.Context, model, text string) (embedding []float64, err error) {
+	start := time.Now()
+	defer func() { s.logRequest("ollama", model, text, start, embedding, err) }()
+
+	callCtx, cancel := s.callContext(ctx)
+	defer cancel()
+
 	reqBody := ollamaRequest{
-		Model:  s.cfg.Embeddings.Model,
+		Model:  model,
 		Prompt: text,
 	}
 
@@ -250,9 +517,9 @@ func (s *Service) generateOllamaEmbedding(text string) ([]float64, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/embeddings", s.cfg.Ollama.BaseURL)
+	url := s.cfg.Ollama.BaseURL + s.cfg.Embeddings.OllamaPath
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(callCtx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -261,13 +528,13 @@ func (s *Service) generateOllamaEmbedding(text string) ([]float64, error) {
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, s.callError(ctx, "failed to execute request", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, s.callError(ctx, "failed to read response", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -285,3 +552,21 @@ func (s *Service) generateOllamaEmbedding(text string) ([]float64, error) {
 
 	return response.Embedding, nil
 }
+
+// sleepUnlessDeadline waits for d before a retry. It returns the context error immediately
+// if ctx is done or its deadline falls within d, since the retry could not finish in time.
+func sleepUnlessDeadline(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return errors.Wrap(context.DeadlineExceeded, http.StatusGatewayTimeout, "request deadline reached while retrying embeddings")
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,68 @@
+package embeddings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func TestModelFor(t *testing.T) {
+	cfg := testConfig(t, "")
+	cfg.Embeddings.Provider = "openrouter"
+	cfg.Embeddings.Model = "openai/text-embedding-3-small"
+	cfg.Embeddings.OllamaModel = "nomic-embed-text"
+	cfg.Embeddings.OpenRouterModel = "ignored-for-the-configured-provider"
+	svc := New(cfg, zap.NewNop())
+
+	tests := map[string]string{
+		"openrouter": "openai/text-embedding-3-small",
+		"ollama":     "nomic-embed-text",
+		"bedrock":    "",
+	}
+	for provider, want := range tests {
+		if got := svc.ModelFor(provider); got != want {
+			t.Errorf("ModelFor(%q) = %q, want %q", provider, got, want)
+		}
+	}
+}
+
+func TestResolveProviderRequiresModel(t *testing.T) {
+	cfg := testConfig(t, "")
+	cfg.Embeddings.Provider = "openrouter"
+	svc := New(cfg, zap.NewNop())
+
+	_, err := svc.ResolveProvider("ollama")
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != 400 || appErr.ErrorCode != errors.CodeInvalidModel {
+		t.Fatalf("ResolveProvider without EMBEDDING_MODEL_OLLAMA = %v, want a 400 %s", err, errors.CodeInvalidModel)
+	}
+
+	cfg.Embeddings.OllamaModel = "nomic-embed-text"
+	if provider, err := svc.ResolveProvider("ollama"); err != nil || provider != "ollama" {
+		t.Errorf("ResolveProvider with a model = %q, %v", provider, err)
+	}
+	if provider, err := svc.ResolveProvider(""); err != nil || provider != "openrouter" {
+		t.Errorf("ResolveProvider default = %q, %v; want EMBEDDING_PROVIDER", provider, err)
+	}
+}
+
+func TestGenerateEmbeddingsWithProviderSendsProviderModel(t *testing.T) {
+	server := newOllamaServer(t, 3)
+	cfg := testConfig(t, server.URL)
+	cfg.Embeddings.Provider = "openrouter"
+	cfg.Embeddings.Model = "openai/text-embedding-3-small"
+	cfg.Embeddings.OllamaModel = "nomic-embed-text"
+	svc := New(cfg, zap.NewNop())
+
+	chunks := []models.Chunk{{ID: "c1", DocID: "d1", Content: "hello"}}
+	if _, err := svc.GenerateEmbeddingsWithProvider(context.Background(), "ollama", chunks, nil); err != nil {
+		t.Fatalf("GenerateEmbeddingsWithProvider failed: %v", err)
+	}
+
+	if got := server.lastModel(); got != "nomic-embed-text" {
+		t.Errorf("Ollama was sent model %q, want EMBEDDING_MODEL_OLLAMA", got)
+	}
+}
@@ -0,0 +1,93 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestEmbeddingEndpointsConfigurable(t *testing.T) {
+	tests := []struct {
+		provider string
+		model    string
+		point    func(cfg *config.Config, url string)
+		wantPath string
+	}{
+		{
+			provider: "openrouter",
+			model:    "openai/text-embedding-3-small",
+			point:    func(cfg *config.Config, url string) { cfg.Embeddings.OpenRouterURL = url + "/gateway/embeddings" },
+			wantPath: "/gateway/embeddings",
+		},
+		{
+			provider: "bedrock",
+			model:    "amazon.titan-embed-text-v2:0",
+			point:    func(cfg *config.Config, url string) { cfg.Embeddings.BedrockBaseURL = url + "/bedrock" },
+			wantPath: "/bedrock/model/amazon.titan-embed-text-v2:0/invoke",
+		},
+		{
+			provider: "ollama",
+			model:    "nomic-embed-text",
+			point: func(cfg *config.Config, url string) {
+				cfg.Ollama.BaseURL = url
+				cfg.Embeddings.OllamaPath = "/proxy/api/embeddings"
+			},
+			wantPath: "/proxy/api/embeddings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+
+				embedding := []float64{0.1, 0.2, 0.3}
+				var body interface{}
+				switch tt.provider {
+				case "openrouter":
+					body = map[string]interface{}{"data": []map[string]interface{}{{"embedding": embedding}}}
+				default:
+					body = map[string]interface{}{"embedding": embedding}
+				}
+				json.NewEncoder(w).Encode(body)
+			}))
+			t.Cleanup(server.Close)
+
+			cfg := testConfig(t, "http://127.0.0.1:1")
+			tt.point(cfg, server.URL)
+			svc := New(cfg, zap.NewNop())
+
+			chunks := []models.Chunk{{ID: "c1", DocID: "d1", Content: "hello"}}
+			result, err := svc.GenerateEmbeddingsWithModel(context.Background(), tt.provider, tt.model, chunks, "test-key")
+			if err != nil {
+				t.Fatalf("GenerateEmbeddingsWithModel failed: %v", err)
+			}
+			if len(result) != 1 || len(result[0].Embedding) != 3 {
+				t.Fatalf("result = %+v, want the mock server's embedding", result)
+			}
+			if len(paths) != 1 || paths[0] != tt.wantPath {
+				t.Errorf("requested paths = %v, want %s", paths, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestEmbeddingEndpointDefaults(t *testing.T) {
+	cfg := testConfig(t, "http://localhost:11434")
+	if cfg.Embeddings.OpenRouterURL != "https://openrouter.ai/api/v1/embeddings" {
+		t.Errorf("OPENROUTER_EMBEDDINGS_URL default = %q", cfg.Embeddings.OpenRouterURL)
+	}
+	if cfg.Embeddings.BedrockBaseURL != "" {
+		t.Errorf("BEDROCK_EMBEDDINGS_BASE_URL default = %q, want the regional endpoint", cfg.Embeddings.BedrockBaseURL)
+	}
+	if cfg.Embeddings.OllamaPath != "/api/embeddings" {
+		t.Errorf("OLLAMA_EMBEDDINGS_PATH default = %q", cfg.Embeddings.OllamaPath)
+	}
+}
@@ -0,0 +1,65 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// healthProbe is the text embedded to check that the embeddings provider is reachable
+const healthProbe = "health check"
+
+// healthCheckTimeout bounds a single provider health check
+const healthCheckTimeout = 10 * time.Second
+
+// CheckHealth reports whether the embeddings provider can embed text. A successful check is
+// reused for PROVIDER_HEALTH_TTL so readiness probes and request prechecks don't call the
+// provider every time; failures are never cached, so recovery is noticed on the next call.
+func (s *Service) CheckHealth(ctx context.Context) error {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if ttl := s.cfg.Embeddings.HealthTTL; ttl > 0 && !s.healthyAt.IsZero() && time.Since(s.healthyAt) < ttl {
+		return nil
+	}
+	s.healthyAt = time.Time{}
+
+	if err := s.probe(ctx); err != nil {
+		return errors.ServiceUnavailable(fmt.Sprintf("embedding provider %s is unavailable: %v", s.cfg.Embeddings.Provider, err)).
+			WithCode(errors.CodeEmbeddingProviderUnavailable)
+	}
+
+	s.healthyAt = time.Now()
+	return nil
+}
+
+// probe embeds a short text with the configured provider
+func (s *Service) probe(ctx context.Context) error {
+	provider := s.cfg.Embeddings.Provider
+	if provider != "ollama" && s.APIKey() == "" {
+		return fmt.Errorf("API key is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	embedding, err := s.embed(ctx, provider, s.cfg.Embeddings.Model, healthProbe, s.APIKey())
+	if err != nil {
+		return err
+	}
+	if len(embedding) == 0 {
+		return fmt.Errorf("provider returned an empty embedding")
+	}
+
+	return nil
+}
+
+// invalidateHealth drops a cached healthy result after an embedding request failed, so the
+// next check asks the provider again
+func (s *Service) invalidateHealth() {
+	s.healthMu.Lock()
+	s.healthyAt = time.Time{}
+	s.healthMu.Unlock()
+}
@@ -0,0 +1,112 @@
+package embeddings
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"go.uber.org/zap"
+)
+
+// healthService returns an Ollama embeddings service with the given PROVIDER_HEALTH_TTL
+func healthService(t *testing.T, ttl time.Duration) (*Service, *ollamaServer) {
+	t.Helper()
+
+	server := newOllamaServer(t, 4)
+	t.Setenv("EMBEDDING_PROVIDER", "ollama")
+	cfg := testConfig(t, server.URL)
+	cfg.Embeddings.HealthTTL = ttl
+
+	return New(cfg, zap.NewNop()), server
+}
+
+func TestCheckHealthCachedWithinTTL(t *testing.T) {
+	svc, server := healthService(t, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := svc.CheckHealth(context.Background()); err != nil {
+				t.Errorf("CheckHealth failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := server.calls(); calls != 1 {
+		t.Errorf("provider called %d times within the TTL, want 1", calls)
+	}
+}
+
+func TestCheckHealthExpires(t *testing.T) {
+	svc, server := healthService(t, 50*time.Millisecond)
+
+	svc.CheckHealth(context.Background())
+	svc.CheckHealth(context.Background())
+	time.Sleep(60 * time.Millisecond)
+	svc.CheckHealth(context.Background())
+
+	if calls := server.calls(); calls != 2 {
+		t.Errorf("provider called %d times, want once per TTL window (2)", calls)
+	}
+}
+
+func TestCheckHealthWithoutTTL(t *testing.T) {
+	svc, server := healthService(t, 0)
+
+	for i := 0; i < 3; i++ {
+		svc.CheckHealth(context.Background())
+	}
+	if calls := server.calls(); calls != 3 {
+		t.Errorf("provider called %d times with PROVIDER_HEALTH_TTL=0, want every check (3)", calls)
+	}
+}
+
+func TestCheckHealthDoesNotCacheFailures(t *testing.T) {
+	svc, server := healthService(t, time.Minute)
+
+	server.fail(http.StatusServiceUnavailable)
+	for i := 0; i < 2; i++ {
+		if err := svc.CheckHealth(context.Background()); err == nil {
+			t.Fatal("CheckHealth succeeded with the provider down")
+		}
+	}
+	if calls := server.calls(); calls != 2 {
+		t.Errorf("provider called %d times while down, want every check (2)", calls)
+	}
+
+	// Recovery is noticed on the next check
+	server.fail(0)
+	if err := svc.CheckHealth(context.Background()); err != nil {
+		t.Errorf("CheckHealth after recovery failed: %v", err)
+	}
+}
+
+func TestFailedEmbeddingInvalidatesHealth(t *testing.T) {
+	svc, server := healthService(t, time.Minute)
+
+	if err := svc.CheckHealth(context.Background()); err != nil {
+		t.Fatalf("CheckHealth failed: %v", err)
+	}
+
+	// The deadline stops the retries after the first failed attempt
+	server.fail(http.StatusServiceUnavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := svc.GenerateEmbeddings(ctx, []models.Chunk{{ID: "c1", Content: "hello"}}, ""); err == nil {
+		t.Fatal("GenerateEmbeddings succeeded with the provider down")
+	}
+
+	before := server.calls()
+	if err := svc.CheckHealth(context.Background()); err == nil {
+		t.Error("CheckHealth reused the healthy result after an embedding failure")
+	}
+	if server.calls() != before+1 {
+		t.Errorf("CheckHealth did not ask the provider after an embedding failure")
+	}
+}
@@ -0,0 +1,90 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/config"
+)
+
+// testConfig loads the default configuration with an OpenRouter key and the given Ollama URL
+func testConfig(t *testing.T, ollamaURL string) *config.Config {
+	t.Helper()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Ollama.BaseURL = ollamaURL
+
+	return cfg
+}
+
+// ollamaServer serves Ollama embeddings of the given dimension and records the requests
+type ollamaServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	dims     int
+	status   int // non-zero fails every request with this status
+	models   []string
+	requests int
+}
+
+func newOllamaServer(t *testing.T, dims int) *ollamaServer {
+	t.Helper()
+
+	s := &ollamaServer{dims: dims}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		s.mu.Lock()
+		s.requests++
+		s.models = append(s.models, req.Model)
+		dims, status := s.dims, s.status
+		s.mu.Unlock()
+
+		if status != 0 {
+			http.Error(w, "unavailable", status)
+			return
+		}
+
+		embedding := make([]float64, dims)
+		for i := range embedding {
+			embedding[i] = float64(i + 1)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embedding": embedding})
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// calls returns how many embeddings were requested
+func (s *ollamaServer) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// lastModel returns the model of the most recent request
+func (s *ollamaServer) lastModel() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.models) == 0 {
+		return ""
+	}
+	return s.models[len(s.models)-1]
+}
+
+// fail makes every following request fail with the given status (0 to succeed again)
+func (s *ollamaServer) fail(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
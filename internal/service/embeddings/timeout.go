@@ -0,0 +1,28 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// callContext bounds a single embeddings HTTP call by EMBEDDING_TIMEOUT. Each call gets its
+// own deadline rather than sharing a client-level timeout, so a slow call (e.g. a large
+// Ollama batch) never cuts short the calls running beside it.
+func (s *Service) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.Embeddings.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.cfg.Embeddings.Timeout)
+}
+
+// callError describes a failed embeddings call. A call that ran out of EMBEDDING_TIMEOUT
+// while the request itself still has time is reported as a provider timeout, not as an
+// expired request deadline, so it is retried like any other provider failure.
+func (s *Service) callError(ctx context.Context, message string, err error) error {
+	if ctx.Err() == nil && errors.IsDeadlineExceeded(err) {
+		return fmt.Errorf("%s: embeddings call timed out after %s (EMBEDDING_TIMEOUT)", message, s.cfg.Embeddings.Timeout)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
@@ -0,0 +1,35 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// warmupTimeout bounds the startup warmup; loading a large Ollama model can take a while
+const warmupTimeout = 2 * time.Minute
+
+// warmupText is the dummy input embedded by Warmup
+const warmupText = "warmup"
+
+// Warmup embeds a tiny dummy string with EMBEDDING_PROVIDER and EMBEDDING_MODEL so the model
+// is loaded before the first request needs it (Ollama loads models lazily). It returns how
+// long the call took.
+func (s *Service) Warmup(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	chunks, err := s.GenerateEmbeddings(ctx, []models.Chunk{{Content: warmupText}}, s.APIKey())
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+	if len(chunks) == 0 {
+		return elapsed, fmt.Errorf("provider returned no usable embedding")
+	}
+
+	return elapsed, nil
+}
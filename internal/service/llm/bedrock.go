@@ -3,39 +3,64 @@ package llm
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/service/tracing"
+	"github.com/mrkaynak/rag/internal/service/usage"
 	"github.com/mrkaynak/rag/pkg/errors"
+	"github.com/mrkaynak/rag/pkg/httpdebug"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 // BedrockClient handles AWS Bedrock API interactions
 type BedrockClient struct {
 	cfg        *config.Config
 	httpClient *http.Client
+	limiter    *Limiter
+	usage      *usage.Tracker
+
+	// noSystemModels remembers models that rejected the native system field so later
+	// requests go straight to the concatenated fallback
+	noSystemModels sync.Map
 }
 
-// NewBedrockClient creates a new Bedrock client
-func NewBedrockClient(cfg *config.Config) *BedrockClient {
+// NewBedrockClient creates a new Bedrock client. limiter is shared with the other
+// providers to bound concurrent LLM calls; completed calls are recorded in tracker.
+func NewBedrockClient(cfg *config.Config, logger *zap.Logger, limiter *Limiter, tracker *usage.Tracker) *BedrockClient {
 	return &BedrockClient{
 		cfg:        cfg,
-		httpClient: &http.Client{},
+		httpClient: httpdebug.NewClient(cfg.LLM.DebugRaw, logger, "bedrock"),
+		limiter:    limiter,
+		usage:      tracker,
 	}
 }
 
 // bedrockRequest represents Bedrock converse API request
 type bedrockRequest struct {
-	Messages []bedrockMessage `json:"messages"`
+	Messages        []bedrockMessage        `json:"messages"`
+	System          []bedrockContent        `json:"system,omitempty"`
+	InferenceConfig *bedrockInferenceConfig `json:"inferenceConfig,omitempty"`
+}
+
+// bedrockInferenceConfig represents the generation parameters of a converse request
+type bedrockInferenceConfig struct {
+	StopSequences []string `json:"stopSequences,omitempty"`
+	MaxTokens     int      `json:"maxTokens,omitempty"`
 }
 
 // bedrockMessage represents a chat message
 type bedrockMessage struct {
-	Role    string              `json:"role"`
-	Content []bedrockContent    `json:"content"`
+	Role    string           `json:"role"`
+	Content []bedrockContent `json:"content"`
 }
 
 // bedrockContent represents message content
@@ -48,16 +73,17 @@ type bedrockResponse struct {
 	Output struct {
 		Message bedrockMessage `json:"message"`
 	} `json:"output"`
+	Usage *bedrockUsage `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 		Code    string `json:"code"`
 	} `json:"error,omitempty"`
 }
 
-// Chat sends a chat request to AWS Bedrock
-func (c *BedrockClient) Chat(apiKey, model, systemPrompt, userMessage string) (string, error) {
+// Chat sends a chat request to AWS Bedrock with the optional generation parameters in opts
+func (c *BedrockClient) Chat(ctx context.Context, apiKey, model, systemPrompt, userMessage string, opts Options) (reply string, err error) {
 	if apiKey == "" {
-		return "", errors.Unauthorized("Bedrock API key is required")
+		return "", errors.Unauthorized("Bedrock API key is required").WithCode(errors.CodeProviderNotConfigured)
 	}
 
 	// Use default modelId if not specified
@@ -65,46 +91,26 @@ func (c *BedrockClient) Chat(apiKey, model, systemPrompt, userMessage string) (s
 		model = c.cfg.Bedrock.ModelID
 	}
 
-	// Combine system prompt with user message (Bedrock converse format)
-	fullMessage := userMessage
-	if systemPrompt != "" {
-		fullMessage = fmt.Sprintf("System: %s\n\nUser: %s", systemPrompt, userMessage)
-	}
-
-	messages := []bedrockMessage{
-		{
-			Role: "user",
-			Content: []bedrockContent{
-				{Text: fullMessage},
-			},
-		},
-	}
-
-	reqBody := bedrockRequest{
-		Messages: messages,
-	}
+	ctx, span := tracing.Start(ctx, "llm.chat",
+		attribute.String("llm.provider", "bedrock"),
+		attribute.String("llm.model", model),
+	)
+	defer func() { tracing.End(span, err) }()
 
-	jsonData, err := json.Marshal(reqBody)
+	release, err := c.limiter.Acquire(ctx)
 	if err != nil {
-		return "", errors.InternalWrap(err, "failed to marshal request")
+		return "", err
 	}
+	defer release()
 
 	// Build Bedrock endpoint URL
 	url := fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com/model/%s/converse",
 		c.cfg.Bedrock.Region,
 		model)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", errors.InternalWrap(err, "failed to create request")
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.converse(ctx, url, apiKey, model, systemPrompt, userMessage, opts)
 	if err != nil {
-		return "", errors.InternalWrap(err, "failed to execute request")
+		return "", err
 	}
 	defer resp.Body.Close()
 
@@ -114,7 +120,7 @@ func (c *BedrockClient) Chat(apiKey, model, systemPrompt, userMessage string) (s
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(resp.StatusCode, fmt.Sprintf("Bedrock API error: %s", string(body)))
+		return "", errors.Provider(resp.StatusCode, fmt.Sprintf("Bedrock API error: %s", string(body)))
 	}
 
 	var response bedrockResponse
@@ -123,21 +129,122 @@ func (c *BedrockClient) Chat(apiKey, model, systemPrompt, userMessage string) (s
 	}
 
 	if response.Error != nil {
-		return "", errors.Internal(fmt.Sprintf("Bedrock API error: %s (code: %s)", response.Error.Message, response.Error.Code))
+		return "", errors.Internal(fmt.Sprintf("Bedrock API error: %s (code: %s)", response.Error.Message, response.Error.Code)).WithCode(errors.CodeProviderError)
 	}
 
 	if len(response.Output.Message.Content) == 0 {
-		return "", errors.Internal("no response from Bedrock")
+		return "", errors.Internal("no response from Bedrock").WithCode(errors.CodeProviderError)
 	}
 
 	// Find the first content item with actual text (skip reasoning content)
 	for _, content := range response.Output.Message.Content {
 		if content.Text != "" {
+			inputTokens, outputTokens := response.Usage.tokens()
+			recordUsage(c.usage, "bedrock", model, inputTokens, outputTokens, systemPrompt, userMessage, content.Text)
 			return content.Text, nil
 		}
 	}
 
-	return "", errors.Internal("no text content found in Bedrock response")
+	return "", errors.Internal("no text content found in Bedrock response").WithCode(errors.CodeProviderError)
+}
+
+// newBedrockRequest builds a converse request. With nativeSystem the system prompt is sent
+// in the top-level system field; otherwise it is prefixed to the user message, which is
+// the only option for models that reject the system field.
+func newBedrockRequest(systemPrompt, userMessage string, opts Options, nativeSystem bool) bedrockRequest {
+	var req bedrockRequest
+
+	if len(opts.Stop) > 0 || opts.MaxTokens > 0 {
+		req.InferenceConfig = &bedrockInferenceConfig{StopSequences: opts.Stop, MaxTokens: opts.MaxTokens}
+	}
+
+	if systemPrompt != "" && nativeSystem {
+		req.System = []bedrockContent{{Text: systemPrompt}}
+	} else if systemPrompt != "" {
+		userMessage = fmt.Sprintf("System: %s\n\nUser: %s", systemPrompt, userMessage)
+	}
+
+	req.Messages = []bedrockMessage{
+		{
+			Role: "user",
+			Content: []bedrockContent{
+				{Text: userMessage},
+			},
+		},
+	}
+
+	return req
+}
+
+// converse posts a converse request using the native system field, retrying once with the
+// system prompt folded into the user message if the model rejects the system field.
+// Models that rejected it are remembered and use the fallback directly afterwards.
+// The caller owns the returned response body.
+func (c *BedrockClient) converse(ctx context.Context, url, apiKey, model, systemPrompt, userMessage string, opts Options) (*http.Response, error) {
+	if _, ok := c.noSystemModels.Load(model); ok {
+		return c.post(ctx, url, apiKey, newBedrockRequest(systemPrompt, userMessage, opts, false))
+	}
+
+	resp, err := c.post(ctx, url, apiKey, newBedrockRequest(systemPrompt, userMessage, opts, true))
+	if err != nil || systemPrompt == "" || resp.StatusCode != http.StatusBadRequest {
+		return resp, err
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !rejectsSystemField(body) {
+		// Unrelated validation error; hand the already-read body back to the caller
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+
+	c.noSystemModels.Store(model, struct{}{})
+
+	return c.post(ctx, url, apiKey, newBedrockRequest(systemPrompt, userMessage, opts, false))
+}
+
+// systemUnsupportedPattern matches Bedrock's validation message for models without system
+// prompt support, e.g. "This model doesn't support system messages."
+var systemUnsupportedPattern = regexp.MustCompile(`(?i)(doesn't|does not|don't|do not) support system (messages?|prompts?)`)
+
+// rejectsSystemField reports whether a 400 response body is Bedrock's error for a model that
+// does not accept the converse system field, as opposed to any other validation error
+func rejectsSystemField(body []byte) bool {
+	var errBody struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &errBody); err != nil || errBody.Message == "" {
+		return false
+	}
+
+	return systemUnsupportedPattern.MatchString(errBody.Message)
+}
+
+// post sends a JSON request to a Bedrock endpoint
+func (c *BedrockClient) post(ctx context.Context, url, apiKey string, reqBody bedrockRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, errors.InternalWrap(err, "failed to marshal request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, errors.InternalWrap(err, "failed to create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	for name, value := range c.cfg.Bedrock.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.InternalWrap(err, "failed to execute request")
+	}
+
+	return resp, nil
 }
 
 // bedrockStreamEvent represents a streaming event from Bedrock
@@ -148,12 +255,30 @@ type bedrockStreamEvent struct {
 		} `json:"delta"`
 	} `json:"contentBlockDelta,omitempty"`
 	MessageStop *struct{} `json:"messageStop,omitempty"`
+	Metadata    *struct {
+		Usage *bedrockUsage `json:"usage,omitempty"`
+	} `json:"metadata,omitempty"`
+}
+
+// bedrockUsage represents the token usage reported by Bedrock
+type bedrockUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
 }
 
-// ChatStream sends a streaming chat request to AWS Bedrock
-func (c *BedrockClient) ChatStream(apiKey, model, systemPrompt, userMessage string, callback func(string) error) error {
+// tokens returns the reported input and output tokens, zero when usage is missing
+func (u *bedrockUsage) tokens() (int, int) {
+	if u == nil {
+		return 0, 0
+	}
+	return u.InputTokens, u.OutputTokens
+}
+
+// ChatStream sends a streaming chat request to AWS Bedrock with the optional generation
+// parameters in opts
+func (c *BedrockClient) ChatStream(ctx context.Context, apiKey, model, systemPrompt, userMessage string, opts Options, callback func(string) error) (err error) {
 	if apiKey == "" {
-		return errors.Unauthorized("Bedrock API key is required")
+		return errors.Unauthorized("Bedrock API key is required").WithCode(errors.CodeProviderNotConfigured)
 	}
 
 	// Use default modelId if not specified
@@ -161,55 +286,38 @@ func (c *BedrockClient) ChatStream(apiKey, model, systemPrompt, userMessage stri
 		model = c.cfg.Bedrock.ModelID
 	}
 
-	// Combine system prompt with user message
-	fullMessage := userMessage
-	if systemPrompt != "" {
-		fullMessage = fmt.Sprintf("System: %s\n\nUser: %s", systemPrompt, userMessage)
-	}
-
-	messages := []bedrockMessage{
-		{
-			Role: "user",
-			Content: []bedrockContent{
-				{Text: fullMessage},
-			},
-		},
-	}
+	ctx, span := tracing.Start(ctx, "llm.chat_stream",
+		attribute.String("llm.provider", "bedrock"),
+		attribute.String("llm.model", model),
+	)
+	defer func() { tracing.End(span, err) }()
 
-	reqBody := bedrockRequest{
-		Messages: messages,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
+	release, err := c.limiter.Acquire(ctx)
 	if err != nil {
-		return errors.InternalWrap(err, "failed to marshal request")
+		return err
 	}
+	defer release()
 
 	// Build Bedrock streaming endpoint URL
 	url := fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com/model/%s/converse-stream",
 		c.cfg.Bedrock.Region,
 		model)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	resp, err := c.converse(ctx, url, apiKey, model, systemPrompt, userMessage, opts)
 	if err != nil {
-		return errors.InternalWrap(err, "failed to create request")
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return errors.InternalWrap(err, "failed to execute request")
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return errors.New(resp.StatusCode, fmt.Sprintf("Bedrock API error: %s", string(body)))
+		return errors.Provider(resp.StatusCode, fmt.Sprintf("Bedrock API error: %s", string(body)))
 	}
 
 	// Read SSE stream
+	var reply strings.Builder
+	var streamUsage *bedrockUsage
+
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -233,13 +341,15 @@ func (c *BedrockClient) ChatStream(apiKey, model, systemPrompt, userMessage stri
 
 		// Handle content delta
 		if event.ContentBlockDelta != nil && event.ContentBlockDelta.Delta.Text != "" {
+			reply.WriteString(event.ContentBlockDelta.Delta.Text)
 			if err := callback(event.ContentBlockDelta.Delta.Text); err != nil {
 				return err
 			}
 		}
 
-		// Handle stream end
-		if event.MessageStop != nil {
+		// Handle stream end; the metadata event with token usage follows messageStop
+		if event.Metadata != nil {
+			streamUsage = event.Metadata.Usage
 			break
 		}
 	}
@@ -248,5 +358,8 @@ func (c *BedrockClient) ChatStream(apiKey, model, systemPrompt, userMessage stri
 		return errors.InternalWrap(err, "failed to read stream")
 	}
 
+	inputTokens, outputTokens := streamUsage.tokens()
+	recordUsage(c.usage, "bedrock", model, inputTokens, outputTokens, systemPrompt, userMessage, reply.String())
+
 	return nil
 }
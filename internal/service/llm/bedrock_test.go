@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"go.uber.org/zap"
+)
+
+// bedrockStub records converse requests and answers them, rejecting the system field with
+// rejectSystem (a 400 body) when set
+type bedrockStub struct {
+	mu           sync.Mutex
+	requests     []bedrockRequest
+	paths        []string
+	rejectSystem string
+}
+
+func (s *bedrockStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body bedrockRequest
+	json.NewDecoder(req.Body).Decode(&body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, body)
+	s.paths = append(s.paths, req.URL.Path)
+	s.mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	switch {
+	case len(body.System) > 0 && s.rejectSystem != "":
+		rec.WriteHeader(http.StatusBadRequest)
+		rec.WriteString(s.rejectSystem)
+	case strings.HasSuffix(req.URL.Path, "/converse-stream"):
+		rec.WriteString(`data: {"contentBlockDelta":{"delta":{"text":"streamed"}}}` + "\n\n")
+		rec.WriteString(`data: {"messageStop":{}}` + "\n\n")
+	default:
+		json.NewEncoder(rec).Encode(map[string]interface{}{
+			"output": map[string]interface{}{
+				"message": map[string]interface{}{"role": "assistant", "content": []map[string]string{{"text": "answer"}}},
+			},
+		})
+	}
+	return rec.Result(), nil
+}
+
+// sent returns the requests received so far
+func (s *bedrockStub) sent() []bedrockRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]bedrockRequest(nil), s.requests...)
+}
+
+// newStubbedBedrock returns a Bedrock client whose requests are served by stub
+func newStubbedBedrock(t *testing.T, stub *bedrockStub) *BedrockClient {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Bedrock.Region = "us-east-1"
+	cfg.Bedrock.ModelID = "test-model"
+
+	client := NewBedrockClient(cfg, zap.NewNop(), nil, nil)
+	client.httpClient = &http.Client{Transport: stub}
+	return client
+}
+
+func TestBedrockSendsNativeSystemField(t *testing.T) {
+	stub := &bedrockStub{}
+	client := newStubbedBedrock(t, stub)
+
+	reply, err := client.Chat(context.Background(), "key", "", "be brief", "hello", Options{})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if reply != "answer" {
+		t.Errorf("reply = %q, want answer", reply)
+	}
+
+	var streamed strings.Builder
+	if err := client.ChatStream(context.Background(), "key", "", "be brief", "hello", Options{}, func(text string) error {
+		streamed.WriteString(text)
+		return nil
+	}); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if streamed.String() != "streamed" {
+		t.Errorf("streamed = %q, want streamed", streamed.String())
+	}
+
+	requests := stub.sent()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	for i, req := range requests {
+		if len(req.System) != 1 || req.System[0].Text != "be brief" {
+			t.Errorf("request %d system = %+v, want the system prompt", i, req.System)
+		}
+		if got := req.Messages[0].Content[0].Text; got != "hello" {
+			t.Errorf("request %d user message = %q, want it without the system prompt", i, got)
+		}
+	}
+}
+
+func TestBedrockFallsBackWhenSystemFieldRejected(t *testing.T) {
+	stub := &bedrockStub{rejectSystem: `{"message":"This model doesn't support system messages."}`}
+	client := newStubbedBedrock(t, stub)
+
+	if _, err := client.Chat(context.Background(), "key", "", "be brief", "hello", Options{}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	requests := stub.sent()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want the native attempt and one retry", len(requests))
+	}
+	retry := requests[1]
+	if len(retry.System) != 0 {
+		t.Errorf("retry still sends the system field: %+v", retry.System)
+	}
+	if got := retry.Messages[0].Content[0].Text; got != "System: be brief\n\nUser: hello" {
+		t.Errorf("retry user message = %q, want the system prompt folded in", got)
+	}
+
+	// The model is remembered and goes straight to the fallback
+	if _, err := client.Chat(context.Background(), "key", "", "be brief", "hello", Options{}); err != nil {
+		t.Fatalf("second Chat failed: %v", err)
+	}
+	if requests := stub.sent(); len(requests) != 3 || len(requests[2].System) != 0 {
+		t.Errorf("second call sent %d requests in total, want one more without the system field", len(requests))
+	}
+}
+
+func TestBedrockDoesNotRetryUnrelatedValidationErrors(t *testing.T) {
+	stub := &bedrockStub{rejectSystem: `{"message":"Malformed input request: system prompt exceeds the maximum length, please reformat your input"}`}
+	client := newStubbedBedrock(t, stub)
+
+	_, err := client.Chat(context.Background(), "key", "", "be brief", "hello", Options{})
+	if err == nil {
+		t.Fatal("Chat succeeded, want the validation error")
+	}
+	if !strings.Contains(err.Error(), "maximum length") {
+		t.Errorf("error = %v, want the provider's message", err)
+	}
+	if requests := stub.sent(); len(requests) != 1 {
+		t.Errorf("requests = %d, want no retry", len(requests))
+	}
+}
+
+func TestRejectsSystemField(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`{"message":"This model doesn't support system messages."}`, true},
+		{`{"message":"The model does not support system prompts"}`, true},
+		{`{"message":"Invalid system configuration"}`, false},
+		{`{"message":"messages: field required"}`, false},
+		{`not json mentioning system messages`, false},
+	}
+
+	for _, tt := range tests {
+		if got := rejectsSystemField([]byte(tt.body)); got != tt.want {
+			t.Errorf("rejectsSystemField(%s) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// Limiter bounds the number of concurrent outbound LLM calls across all providers.
+// Calls beyond the limit queue for up to the configured wait before being rejected.
+type Limiter struct {
+	slots chan struct{} // nil when unlimited
+	wait  time.Duration
+}
+
+// NewLimiter creates a limiter allowing maxConcurrency calls at once (0 = unlimited)
+func NewLimiter(maxConcurrency int, wait time.Duration) *Limiter {
+	l := &Limiter{wait: wait}
+	if maxConcurrency > 0 {
+		l.slots = make(chan struct{}, maxConcurrency)
+	}
+	return l
+}
+
+// Acquire waits for a free slot and returns a function releasing it. It returns a
+// service unavailable error if no slot frees up within the wait or ctx is done first.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil || l.slots == nil {
+		return func() {}, nil
+	}
+
+	release := func() { <-l.slots }
+
+	// Fast path without allocating a timer
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, errors.ServiceUnavailable("LLM provider is busy, please retry shortly").WithCode(errors.CodeProviderBusy)
+	case <-ctx.Done():
+		if errors.IsDeadlineExceeded(ctx.Err()) {
+			return nil, errors.ErrDeadlineExceeded
+		}
+		return nil, errors.ServiceUnavailable("LLM request cancelled while waiting for a free slot")
+	}
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+func TestLimiterRejectsAfterWait(t *testing.T) {
+	l := NewLimiter(1, 20*time.Millisecond)
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	start := time.Now()
+	_, err = l.Acquire(context.Background())
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != 503 || appErr.ErrorCode != errors.CodeProviderBusy {
+		t.Fatalf("Acquire on a full limiter = %v, want a 503 %s", err, errors.CodeProviderBusy)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("rejected after %v, want the queue wait first", waited)
+	}
+
+	// A released slot is free again
+	release()
+	release, err = l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	}
+	release()
+}
+
+func TestLimiterQueuesUntilSlotFrees(t *testing.T) {
+	l := NewLimiter(1, time.Second)
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	time.AfterFunc(20*time.Millisecond, release)
+
+	second, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("queued Acquire failed: %v", err)
+	}
+	second()
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	for _, l := range []*Limiter{nil, NewLimiter(0, 0)} {
+		for i := 0; i < 100; i++ {
+			if _, err := l.Acquire(context.Background()); err != nil {
+				t.Fatalf("unlimited Acquire failed: %v", err)
+			}
+		}
+	}
+}
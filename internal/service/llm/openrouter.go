@@ -2,34 +2,47 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/service/tracing"
+	"github.com/mrkaynak/rag/internal/service/usage"
 	"github.com/mrkaynak/rag/pkg/errors"
+	"github.com/mrkaynak/rag/pkg/httpdebug"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 // OpenRouterClient handles OpenRouter API interactions
 type OpenRouterClient struct {
 	cfg        *config.Config
 	httpClient *http.Client
+	limiter    *Limiter
+	usage      *usage.Tracker
 }
 
-// NewOpenRouterClient creates a new OpenRouter client
-func NewOpenRouterClient(cfg *config.Config) *OpenRouterClient {
+// NewOpenRouterClient creates a new OpenRouter client. limiter is shared with the other
+// providers to bound concurrent LLM calls; completed calls are recorded in tracker.
+func NewOpenRouterClient(cfg *config.Config, logger *zap.Logger, limiter *Limiter, tracker *usage.Tracker) *OpenRouterClient {
 	return &OpenRouterClient{
 		cfg:        cfg,
-		httpClient: &http.Client{},
+		httpClient: httpdebug.NewClient(cfg.LLM.DebugRaw, logger, "openrouter"),
+		limiter:    limiter,
+		usage:      tracker,
 	}
 }
 
 // openRouterRequest represents OpenRouter chat API request
 type openRouterRequest struct {
-	Model    string                   `json:"model"`
-	Messages []openRouterMessage      `json:"messages"`
-	Stream   bool                     `json:"stream"`
+	Model     string              `json:"model"`
+	Messages  []openRouterMessage `json:"messages"`
+	Stream    bool                `json:"stream"`
+	Stop      []string            `json:"stop,omitempty"`
+	MaxTokens int                 `json:"max_tokens,omitempty"`
 }
 
 // openRouterMessage represents a chat message
@@ -43,16 +56,20 @@ type openRouterResponse struct {
 	Choices []struct {
 		Message openRouterMessage `json:"message"`
 	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 		Code    string `json:"code"`
 	} `json:"error,omitempty"`
 }
 
-// Chat sends a chat request to OpenRouter
-func (c *OpenRouterClient) Chat(apiKey, model, systemPrompt, userMessage string) (string, error) {
+// Chat sends a chat request to OpenRouter with the optional generation parameters in opts
+func (c *OpenRouterClient) Chat(ctx context.Context, apiKey, model, systemPrompt, userMessage string, opts Options) (reply string, err error) {
 	if apiKey == "" {
-		return "", errors.Unauthorized("OpenRouter API key is required")
+		return "", errors.Unauthorized("OpenRouter API key is required").WithCode(errors.CodeProviderNotConfigured)
 	}
 
 	// Use default model if not specified
@@ -60,6 +77,18 @@ func (c *OpenRouterClient) Chat(apiKey, model, systemPrompt, userMessage string)
 		model = c.cfg.OpenRouter.Model
 	}
 
+	ctx, span := tracing.Start(ctx, "llm.chat",
+		attribute.String("llm.provider", "openrouter"),
+		attribute.String("llm.model", model),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	messages := []openRouterMessage{
 		{
 			Role:    "system",
@@ -72,9 +101,11 @@ func (c *OpenRouterClient) Chat(apiKey, model, systemPrompt, userMessage string)
 	}
 
 	reqBody := openRouterRequest{
-		Model:    model,
-		Messages: messages,
-		Stream:   false,
+		Model:     model,
+		Messages:  messages,
+		Stream:    false,
+		Stop:      opts.Stop,
+		MaxTokens: opts.MaxTokens,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -82,15 +113,16 @@ func (c *OpenRouterClient) Chat(apiKey, model, systemPrompt, userMessage string)
 		return "", errors.InternalWrap(err, "failed to marshal request")
 	}
 
-	req, err := http.NewRequest("POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", errors.InternalWrap(err, "failed to create request")
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-	req.Header.Set("HTTP-Referer", "https://github.com/mrkaynak/rag")
-	req.Header.Set("X-Title", "Enterprise RAG System")
+	for name, value := range c.cfg.OpenRouter.Headers {
+		req.Header.Set(name, value)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -104,7 +136,7 @@ func (c *OpenRouterClient) Chat(apiKey, model, systemPrompt, userMessage string)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(resp.StatusCode, fmt.Sprintf("OpenRouter API error: %s", string(body)))
+		return "", errors.Provider(resp.StatusCode, fmt.Sprintf("OpenRouter API error: %s", string(body)))
 	}
 
 	var response openRouterResponse
@@ -113,12 +145,20 @@ func (c *OpenRouterClient) Chat(apiKey, model, systemPrompt, userMessage string)
 	}
 
 	if response.Error != nil {
-		return "", errors.Internal(fmt.Sprintf("OpenRouter API error: %s (code: %s)", response.Error.Message, response.Error.Code))
+		return "", errors.Internal(fmt.Sprintf("OpenRouter API error: %s (code: %s)", response.Error.Message, response.Error.Code)).WithCode(errors.CodeProviderError)
 	}
 
 	if len(response.Choices) == 0 {
-		return "", errors.Internal("no response from OpenRouter")
+		return "", errors.Internal("no response from OpenRouter").WithCode(errors.CodeProviderError)
+	}
+
+	reply = response.Choices[0].Message.Content
+
+	var inputTokens, outputTokens int
+	if response.Usage != nil {
+		inputTokens, outputTokens = response.Usage.PromptTokens, response.Usage.CompletionTokens
 	}
+	recordUsage(c.usage, "openrouter", model, inputTokens, outputTokens, systemPrompt, userMessage, reply)
 
-	return response.Choices[0].Message.Content, nil
+	return reply, nil
 }
@@ -0,0 +1,9 @@
+package llm
+
+// Options holds the optional generation parameters of a chat request
+type Options struct {
+	// Stop sequences end generation
+	Stop []string
+	// MaxTokens bounds the length of the reply (0 = provider default)
+	MaxTokens int
+}
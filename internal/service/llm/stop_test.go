@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"go.uber.org/zap"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestOpenRouterSendsStopSequences(t *testing.T) {
+	tests := []struct {
+		name string
+		stop []string
+		want interface{}
+	}{
+		{name: "stop sequences", stop: []string{"\n\n", "END"}, want: []interface{}{"\n\n", "END"}},
+		{name: "none", stop: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent map[string]interface{}
+			client := NewOpenRouterClient(&config.Config{}, zap.NewNop(), nil, nil)
+			client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				json.NewDecoder(req.Body).Decode(&sent)
+				rec := httptest.NewRecorder()
+				rec.WriteString(`{"choices": [{"message": {"role": "assistant", "content": "answer"}}]}`)
+				return rec.Result(), nil
+			})}
+
+			if _, err := client.Chat(context.Background(), "key", "test/model", "be brief", "hello", Options{Stop: tt.stop}); err != nil {
+				t.Fatalf("Chat failed: %v", err)
+			}
+
+			got, present := sent["stop"]
+			if tt.want == nil {
+				if present {
+					t.Errorf("request stop = %v, want the field omitted", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("request stop = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBedrockSendsStopSequences(t *testing.T) {
+	stub := &bedrockStub{}
+	client := newStubbedBedrock(t, stub)
+	stop := []string{"\n\n", "END"}
+
+	if _, err := client.Chat(context.Background(), "key", "", "be brief", "hello", Options{Stop: stop}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if err := client.ChatStream(context.Background(), "key", "", "be brief", "hello", Options{Stop: stop}, func(string) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if _, err := client.Chat(context.Background(), "key", "", "be brief", "hello", Options{}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	requests := stub.sent()
+	if len(requests) != 3 {
+		t.Fatalf("requests = %d, want 3", len(requests))
+	}
+	for i, req := range requests[:2] {
+		if req.InferenceConfig == nil || !reflect.DeepEqual(req.InferenceConfig.StopSequences, stop) {
+			t.Errorf("request %d inference config = %+v, want stop sequences %q", i, req.InferenceConfig, stop)
+		}
+	}
+	if cfg := requests[2].InferenceConfig; cfg != nil && len(cfg.StopSequences) > 0 {
+		t.Errorf("request without stop sequences sent %q", cfg.StopSequences)
+	}
+}
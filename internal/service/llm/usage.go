@@ -0,0 +1,19 @@
+package llm
+
+import (
+	"github.com/mrkaynak/rag/internal/service/usage"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+)
+
+// recordUsage reports a completed call to the usage tracker. Token counts the provider did
+// not report (zero) are estimated from the prompt and reply.
+func recordUsage(tracker *usage.Tracker, provider, model string, inputTokens, outputTokens int, systemPrompt, userMessage, reply string) {
+	if inputTokens == 0 {
+		inputTokens = tokenizer.EstimateTokens(systemPrompt) + tokenizer.EstimateTokens(userMessage)
+	}
+	if outputTokens == 0 {
+		outputTokens = tokenizer.EstimateTokens(reply)
+	}
+
+	tracker.Record(provider, model, inputTokens, outputTokens)
+}
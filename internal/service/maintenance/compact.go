@@ -0,0 +1,124 @@
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"go.uber.org/zap"
+)
+
+// compactCheckInterval is how often the vector store is checked for reclaimable space
+const compactCheckInterval = time.Hour
+
+// Compactor compacts the vector store, on request or automatically once the share of
+// soft-deleted and stale entries in its file reaches VECTOR_COMPACT_RATIO. Compaction
+// purges soft-deleted documents, so it removes their metadata along with their chunks.
+type Compactor struct {
+	metadataStore *document.MetadataStore
+	vectorStore   *vector.Store
+	logger        *zap.Logger
+	ratio         float64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewCompactor creates a new vector store auto-compactor
+func NewCompactor(metadataStore *document.MetadataStore, vectorStore *vector.Store, logger *zap.Logger, ratio float64) *Compactor {
+	return &Compactor{
+		metadataStore: metadataStore,
+		vectorStore:   vectorStore,
+		logger:        logger,
+		ratio:         ratio,
+	}
+}
+
+// Start checks the vector store right away, since stale space mostly appears after a
+// restart with new storage settings, and then periodically. A non-positive ratio disables
+// auto-compaction.
+func (c *Compactor) Start() {
+	if c.ratio <= 0 || c.stop != nil {
+		return
+	}
+
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+
+		c.check()
+
+		ticker := time.NewTicker(compactCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.check()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+
+	c.logger.Info("vector store auto-compaction enabled", zap.Float64("ratio", c.ratio))
+}
+
+// Stop stops the periodic check and waits for it to exit
+func (c *Compactor) Stop() {
+	if c.stop == nil {
+		return
+	}
+
+	close(c.stop)
+	<-c.done
+	c.stop = nil
+}
+
+// check compacts the vector store if enough of its entries are deleted or stale
+func (c *Compactor) check() {
+	stats, err := c.vectorStore.CompactionStats()
+	if err != nil {
+		c.logger.Warn("failed to measure vector store for compaction", zap.Error(err))
+		return
+	}
+
+	if stats.FileBytes == 0 || stats.StaleRatio < c.ratio {
+		return
+	}
+
+	result, err := c.Compact()
+	if err != nil {
+		c.logger.Warn("scheduled vector store compaction failed", zap.Error(err))
+		return
+	}
+
+	c.logger.Info("vector store compacted automatically",
+		zap.Float64("stale_ratio", stats.StaleRatio),
+		zap.Int64("reclaimed_bytes", result.ReclaimedBytes),
+	)
+}
+
+// Compact compacts the vector store and removes the metadata of the soft-deleted documents
+// whose chunks it dropped. A document restored meanwhile keeps its metadata.
+func (c *Compactor) Compact() (vector.CompactResult, error) {
+	result, err := c.vectorStore.Compact()
+	if err != nil {
+		return result, err
+	}
+
+	for _, docID := range result.PurgedDocIDs {
+		doc, err := c.metadataStore.Get(docID)
+		if err != nil || doc.DeletedAt == nil {
+			continue
+		}
+		if err := c.metadataStore.Delete(docID); err != nil {
+			return result, fmt.Errorf("failed to delete document metadata: %w", err)
+		}
+	}
+
+	return result, nil
+}
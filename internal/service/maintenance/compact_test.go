@@ -0,0 +1,63 @@
+package maintenance
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCompactPurgesDeletedDocuments(t *testing.T) {
+	metadataStore, vectorStore := testStores(t)
+	addDocument(t, metadataStore, vectorStore, "kept", false, "first", "second")
+	addDocument(t, metadataStore, vectorStore, "deleted", true, "third", "fourth", "fifth")
+
+	result, err := NewCompactor(metadataStore, vectorStore, zap.NewNop(), 0).Compact()
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	if result.PurgedChunks != 3 || len(result.PurgedDocIDs) != 1 || result.PurgedDocIDs[0] != "deleted" {
+		t.Errorf("purged %d chunks of %v, want 3 of [deleted]", result.PurgedChunks, result.PurgedDocIDs)
+	}
+	if _, err := metadataStore.Get("deleted"); err == nil {
+		t.Error("metadata of the purged document is still stored")
+	}
+	if _, err := metadataStore.Get("kept"); err != nil {
+		t.Errorf("metadata of a live document was removed: %v", err)
+	}
+	if n := len(vectorStore.GetAllIncludingDeleted()); n != 2 {
+		t.Errorf("%d chunks left, want 2", n)
+	}
+}
+
+func TestCompactorTriggersAtRatio(t *testing.T) {
+	metadataStore, vectorStore := testStores(t)
+	addDocument(t, metadataStore, vectorStore, "a", false, "one", "two", "three")
+	addDocument(t, metadataStore, vectorStore, "b", false, "four", "five", "six")
+	addDocument(t, metadataStore, vectorStore, "c", true, "seven", "eight")
+
+	compactor := NewCompactor(metadataStore, vectorStore, zap.NewNop(), 0.3)
+
+	// 2 of 8 chunks are deleted, below the ratio
+	compactor.check()
+	if n := len(vectorStore.GetAllIncludingDeleted()); n != 8 {
+		t.Fatalf("compacted below the ratio: %d chunks left, want 8", n)
+	}
+
+	// 5 of 8 chunks are deleted, above the ratio
+	if err := vectorStore.DeleteByDocID("b"); err != nil {
+		t.Fatalf("DeleteByDocID failed: %v", err)
+	}
+	compactor.check()
+	if n := len(vectorStore.GetAllIncludingDeleted()); n != 3 {
+		t.Fatalf("did not compact above the ratio: %d chunks left, want 3", n)
+	}
+
+	stats, err := vectorStore.CompactionStats()
+	if err != nil {
+		t.Fatalf("CompactionStats failed: %v", err)
+	}
+	if stats.StaleRatio != 0 || stats.DeletedChunks != 0 || stats.LiveChunks != 3 {
+		t.Errorf("stats after compaction = %+v", stats)
+	}
+}
@@ -0,0 +1,66 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/vector"
+)
+
+// testStores opens a metadata store and a vector store over an in-memory BadgerDB
+func testStores(t *testing.T) (*document.MetadataStore, *vector.Store) {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &config.Config{}
+	cfg.Storage.VectorStorePath = t.TempDir()
+
+	vectorStore, err := vector.New(cfg, db)
+	if err != nil {
+		t.Fatalf("failed to create vector store: %v", err)
+	}
+
+	return document.NewMetadataStore(db), vectorStore
+}
+
+// addDocument indexes a document with one chunk per content, soft-deleting it if deleted
+func addDocument(t *testing.T, metadataStore *document.MetadataStore, vectorStore *vector.Store, docID string, deleted bool, contents ...string) {
+	t.Helper()
+
+	if err := metadataStore.Add(document.DocumentMetadata{ID: docID, FileName: docID + ".txt", ChunkCount: len(contents)}); err != nil {
+		t.Fatalf("failed to add metadata: %v", err)
+	}
+
+	chunks := make([]models.Chunk, len(contents))
+	for i, content := range contents {
+		chunks[i] = models.Chunk{
+			ID:        docID + "-" + string(rune('a'+i)),
+			DocID:     docID,
+			Index:     i,
+			Content:   content,
+			Embedding: []float64{float64(i + 1), float64(len(content)), 1},
+		}
+	}
+	if err := vectorStore.Add(chunks); err != nil {
+		t.Fatalf("failed to add chunks: %v", err)
+	}
+
+	if deleted {
+		now := time.Now()
+		if _, err := metadataStore.SetDeleted(docID, &now); err != nil {
+			t.Fatalf("failed to soft-delete metadata: %v", err)
+		}
+		if err := vectorStore.DeleteByDocID(docID); err != nil {
+			t.Fatalf("failed to soft-delete chunks: %v", err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"go.uber.org/zap"
+)
+
+// maxPurgeInterval caps how long an expired soft-deleted document can outlive its retention
+const maxPurgeInterval = time.Hour
+
+// Purger permanently removes soft-deleted documents, either on request or periodically once
+// they have been deleted for longer than the retention period
+type Purger struct {
+	metadataStore *document.MetadataStore
+	vectorStore   *vector.Store
+	logger        *zap.Logger
+	retention     time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPurger creates a new soft-delete purger
+func NewPurger(metadataStore *document.MetadataStore, vectorStore *vector.Store, logger *zap.Logger, retention time.Duration) *Purger {
+	return &Purger{
+		metadataStore: metadataStore,
+		vectorStore:   vectorStore,
+		logger:        logger,
+		retention:     retention,
+	}
+}
+
+// Start launches the periodic purge loop. A non-positive retention keeps soft-deleted
+// documents until they are purged explicitly.
+func (p *Purger) Start() {
+	if p.retention <= 0 || p.stop != nil {
+		return
+	}
+
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(min(p.retention, maxPurgeInterval))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := p.PurgeExpired(); err != nil {
+					p.logger.Warn("scheduled purge of deleted documents failed", zap.Error(err))
+				}
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	p.logger.Info("deleted document purge scheduled", zap.Duration("retention", p.retention))
+}
+
+// Stop stops the periodic purge loop and waits for it to exit
+func (p *Purger) Stop() {
+	if p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	<-p.done
+	p.stop = nil
+}
+
+// Purge permanently removes a document's chunks, metadata and stored content. The chunks go
+// first: their removal waits for a running reindex, which rewrites the metadata of the
+// documents it indexed, so the metadata is only deleted once that is done.
+func (p *Purger) Purge(docID string) error {
+	if err := p.vectorStore.PurgeByDocID(docID); err != nil {
+		return fmt.Errorf("failed to delete document chunks: %w", err)
+	}
+
+	if err := p.metadataStore.Delete(docID); err != nil {
+		return fmt.Errorf("failed to delete document metadata: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeExpired purges every document soft-deleted longer than the retention period ago and
+// returns how many were removed
+func (p *Purger) PurgeExpired() (int, error) {
+	docs, err := p.metadataStore.ListAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	cutoff := time.Now().Add(-p.retention)
+	purged := 0
+
+	for _, doc := range docs {
+		if doc.DeletedAt == nil || doc.DeletedAt.After(cutoff) {
+			continue
+		}
+
+		if err := p.Purge(doc.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		p.logger.Info("purged deleted documents", zap.Int("documents", purged))
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,48 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"go.uber.org/zap"
+)
+
+func TestPurgeExpiredKeepsRecentDeletions(t *testing.T) {
+	metadataStore, vectorStore := testStores(t)
+	addDocument(t, metadataStore, vectorStore, "expired", true, "old chunk")
+	addDocument(t, metadataStore, vectorStore, "recent", true, "new chunk")
+	addDocument(t, metadataStore, vectorStore, "live", false, "live chunk")
+
+	longAgo := time.Now().Add(-2 * time.Hour)
+	if _, err := metadataStore.SetDeleted("expired", &longAgo); err != nil {
+		t.Fatalf("failed to backdate deletion: %v", err)
+	}
+
+	purger := NewPurger(metadataStore, vectorStore, zap.NewNop(), time.Hour)
+	purged, err := purger.PurgeExpired()
+	if err != nil {
+		t.Fatalf("PurgeExpired failed: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("purged %d documents, want only the expired one", purged)
+	}
+
+	if _, err := metadataStore.Get("expired"); err != badger.ErrKeyNotFound {
+		t.Errorf("expired document metadata: err = %v, want it removed", err)
+	}
+	for _, id := range []string{"recent", "live"} {
+		if _, err := metadataStore.Get(id); err != nil {
+			t.Errorf("document %s: %v, want it kept", id, err)
+		}
+	}
+
+	for _, chunk := range vectorStore.GetAllIncludingDeleted() {
+		if chunk.DocID == "expired" {
+			t.Errorf("chunk %s of the expired document survived the purge", chunk.ID)
+		}
+	}
+	if n := len(vectorStore.GetAllIncludingDeleted()); n != 2 {
+		t.Errorf("%d chunks left, want those of the recent and live documents", n)
+	}
+}
@@ -0,0 +1,133 @@
+package querylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/google/uuid"
+	"github.com/mrkaynak/rag/internal/models"
+	"go.uber.org/zap"
+)
+
+// prefixEmbedding prefixes query embedding keys, which are ordered by time
+const prefixEmbedding = "queryembedding:"
+
+// pendingEmbeddings bounds the query embeddings waiting to be written; more are dropped
+const pendingEmbeddings = 256
+
+// EmbeddingsEnabled reports whether query embeddings are being recorded (QUERY_LOGGING)
+func (l *Log) EmbeddingsEnabled() bool {
+	return l != nil && l.pending != nil
+}
+
+// embeddingKey orders query embeddings by time; the ID keeps records of the same instant apart
+func embeddingKey(at time.Time, id string) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%s", prefixEmbedding, at.UnixNano(), id))
+}
+
+// RecordEmbedding queues a query and its embedding to be stored in the background without
+// tenant or caller identity. The query text is anonymized with the built-in redaction rules
+// before it is written; the embedding is kept as is. When the queue is full the record is
+// dropped rather than slowing down retrieval.
+func (l *Log) RecordEmbedding(query string, embedding []float64) {
+	if !l.EmbeddingsEnabled() {
+		return
+	}
+
+	record := models.QueryLogEmbedding{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now().UTC(),
+		Query:     query,
+		Embedding: embedding,
+	}
+
+	// closeMu keeps Close from closing pending during the send
+	l.closeMu.RLock()
+	defer l.closeMu.RUnlock()
+	if l.closed {
+		return
+	}
+
+	select {
+	case l.pending <- record:
+	default:
+		l.logger.Warn("query embedding log is backed up, dropping a query")
+	}
+}
+
+// writeEmbeddings stores queued query embeddings until Close
+func (l *Log) writeEmbeddings() {
+	defer close(l.done)
+
+	for record := range l.pending {
+		record.Query, _ = l.anonymize.Redact(record.Query)
+
+		data, err := json.Marshal(record)
+		if err == nil {
+			err = l.db.Update(func(txn *badger.Txn) error {
+				return txn.SetEntry(badger.NewEntry(embeddingKey(record.Timestamp, record.ID), data).WithTTL(l.embeddingTTL))
+			})
+		}
+		if err != nil {
+			l.logger.Warn("failed to record query embedding", zap.Error(err))
+		}
+	}
+}
+
+// Close stops accepting query embeddings and waits until the queued ones are written
+func (l *Log) Close() {
+	if !l.EmbeddingsEnabled() {
+		return
+	}
+
+	l.closeMu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.pending)
+	}
+	l.closeMu.Unlock()
+
+	<-l.done
+}
+
+// EachEmbedding calls fn for every unexpired query embedding recorded in [since, until),
+// oldest first, reading one record at a time. Zero bounds leave that side open. Iteration
+// stops at the first error from fn.
+func (l *Log) EachEmbedding(since, until time.Time, fn func(record models.QueryLogEmbedding) error) error {
+	if !l.EmbeddingsEnabled() {
+		return nil
+	}
+
+	return l.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(prefixEmbedding)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		start := []byte(prefixEmbedding)
+		if !since.IsZero() {
+			start = embeddingKey(since, "")
+		}
+
+		for it.Seek(start); it.Valid(); it.Next() {
+			var record models.QueryLogEmbedding
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &record)
+			}); err != nil {
+				return fmt.Errorf("failed to decode query embedding: %w", err)
+			}
+
+			if !until.IsZero() && !record.Timestamp.Before(until) {
+				return nil
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,133 @@
+package querylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/google/uuid"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/redact"
+	"go.uber.org/zap"
+)
+
+// prefixEntry prefixes query log keys, which are ordered by time
+const prefixEntry = "querylog:"
+
+// Log persists chat and search queries with the chunks they retrieved and the answers they
+// got (QUERY_LOG), so production traffic can be exported as an evaluation dataset, and
+// optionally anonymized queries with their embeddings (QUERY_LOGGING). A nil Log
+// records nothing.
+type Log struct {
+	db      *badger.DB
+	logger  *zap.Logger
+	entries bool
+
+	// Query embeddings are written by a background worker so retrieval never waits on
+	// BadgerDB; pending is nil unless QUERY_LOGGING is on
+	embeddingTTL time.Duration
+	anonymize    *redact.Redactor
+	pending      chan models.QueryLogEmbedding
+	done         chan struct{}
+	closeMu      sync.RWMutex
+	closed       bool
+}
+
+// New creates a query log stored in db, or returns nil when neither QUERY_LOG nor
+// QUERY_LOGGING is on. Call Close before closing db.
+func New(cfg *config.Config, db *badger.DB, logger *zap.Logger) (*Log, error) {
+	if !cfg.RAG.QueryLog && !cfg.RAG.QueryLogEmbeddings {
+		return nil, nil
+	}
+
+	l := &Log{db: db, logger: logger, entries: cfg.RAG.QueryLog}
+	if cfg.RAG.QueryLogEmbeddings {
+		anonymize, err := redact.New("")
+		if err != nil {
+			return nil, err
+		}
+
+		l.embeddingTTL = cfg.RAG.QueryLogEmbeddingsTTL
+		l.anonymize = anonymize
+		l.pending = make(chan models.QueryLogEmbedding, pendingEmbeddings)
+		l.done = make(chan struct{})
+		go l.writeEmbeddings()
+	}
+
+	return l, nil
+}
+
+// Enabled reports whether queries are being recorded (QUERY_LOG)
+func (l *Log) Enabled() bool {
+	return l != nil && l.entries
+}
+
+// entryKey orders entries by time; the ID keeps entries of the same instant apart
+func entryKey(at time.Time, id string) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%s", prefixEntry, at.UnixNano(), id))
+}
+
+// Record stores an entry, filling in its ID and timestamp when unset
+func (l *Log) Record(entry models.QueryLogEntry) error {
+	if !l.Enabled() {
+		return nil
+	}
+
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode query log entry: %w", err)
+	}
+
+	return l.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(entryKey(entry.Timestamp, entry.ID), data)
+	})
+}
+
+// Each calls fn for every entry recorded in [since, until), oldest first, reading one entry
+// at a time. Zero bounds leave that side open. Iteration stops at the first error from fn.
+func (l *Log) Each(since, until time.Time, fn func(entry models.QueryLogEntry) error) error {
+	if !l.Enabled() {
+		return nil
+	}
+
+	return l.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(prefixEntry)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		start := []byte(prefixEntry)
+		if !since.IsZero() {
+			start = entryKey(since, "")
+		}
+
+		for it.Seek(start); it.Valid(); it.Next() {
+			var entry models.QueryLogEntry
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &entry)
+			}); err != nil {
+				return fmt.Errorf("failed to decode query log entry: %w", err)
+			}
+
+			if !until.IsZero() && !entry.Timestamp.Before(until) {
+				return nil
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,86 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule names a pattern whose matches are redacted
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// DefaultRules cover common PII and secret formats
+var DefaultRules = []Rule{
+	{Name: "email", Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	{Name: "aws_access_key", Pattern: `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "api_key", Pattern: `\b(sk|pk|rk)-[A-Za-z0-9_-]{20,}\b`},
+	{Name: "bearer_token", Pattern: `(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}=*`},
+	{Name: "private_key", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	{Name: "credit_card", Pattern: `\b\d(?:[ -]?\d){12,18}\b`},
+}
+
+// compiledRule is a Rule with its pattern compiled
+type compiledRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Redactor replaces PII and secrets in generated answers with [REDACTED:<name>] markers
+type Redactor struct {
+	rules []compiledRule
+}
+
+// New creates a redactor from a JSON array of rules ([{"name": "...", "pattern": "..."}]).
+// An empty rulesJSON uses DefaultRules.
+func New(rulesJSON string) (*Redactor, error) {
+	rules := DefaultRules
+	if strings.TrimSpace(rulesJSON) != "" {
+		if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+			return nil, fmt.Errorf("invalid ANSWER_REDACTION_RULES: %w", err)
+		}
+	}
+
+	r := &Redactor{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("invalid ANSWER_REDACTION_RULES: pattern %q has no name", rule.Pattern)
+		}
+
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ANSWER_REDACTION_RULES pattern %q: %w", rule.Name, err)
+		}
+
+		r.rules = append(r.rules, compiledRule{name: rule.Name, pattern: pattern})
+	}
+
+	return r, nil
+}
+
+// Redact returns text with every rule match replaced and the number of replacements.
+// A nil redactor returns text unchanged.
+func (r *Redactor) Redact(text string) (string, int) {
+	if r == nil {
+		return text, 0
+	}
+
+	count := 0
+	for _, rule := range r.rules {
+		marker := "[REDACTED:" + rule.name + "]"
+		text = rule.pattern.ReplaceAllStringFunc(text, func(string) string {
+			count++
+			return marker
+		})
+	}
+
+	return text, count
+}
+
+// Enabled reports whether answers are filtered
+func (r *Redactor) Enabled() bool {
+	return r != nil
+}
@@ -0,0 +1,312 @@
+package reindex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Job states
+const (
+	StateIdle      = "idle"
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+)
+
+// Status represents the progress of a reindex job
+type Status struct {
+	State              string     `json:"state"`
+	TotalDocuments     int        `json:"total_documents"`
+	ProcessedDocuments int        `json:"processed_documents"`
+	FailedDocuments    int        `json:"failed_documents"`
+	Chunks             int        `json:"chunks"`
+	Errors             []string   `json:"errors,omitempty"`
+	StartedAt          *time.Time `json:"started_at,omitempty"`
+	FinishedAt         *time.Time `json:"finished_at,omitempty"`
+}
+
+// Service re-chunks and re-embeds every stored document in the background
+type Service struct {
+	logger        *zap.Logger
+	docService    *document.Service
+	embeddingsSvc *embeddings.Service
+	vectorStore   *vector.Store
+	metadataStore *document.MetadataStore
+
+	running atomic.Bool
+	mu      sync.RWMutex
+	status  Status
+	changed chan struct{} // closed and replaced on every status update
+}
+
+// New creates a new reindex service
+func New(
+	logger *zap.Logger,
+	docService *document.Service,
+	embeddingsSvc *embeddings.Service,
+	vectorStore *vector.Store,
+	metadataStore *document.MetadataStore,
+) *Service {
+	return &Service{
+		logger:        logger,
+		docService:    docService,
+		embeddingsSvc: embeddingsSvc,
+		vectorStore:   vectorStore,
+		metadataStore: metadataStore,
+		status:        Status{State: StateIdle},
+		changed:       make(chan struct{}),
+	}
+}
+
+// Running reports whether a reindex job is in progress
+func (s *Service) Running() bool {
+	return s.running.Load()
+}
+
+// Status returns a snapshot of the current (or last) job status
+func (s *Service) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := s.status
+	status.Errors = append([]string(nil), s.status.Errors...)
+	return status
+}
+
+// Changes returns a channel that is closed on the next status update
+func (s *Service) Changes() <-chan struct{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.changed
+}
+
+// Start launches a reindex job in the background
+func (s *Service) Start() (Status, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return s.Status(), errors.Conflict("a reindex is already running").WithCode(errors.CodeReindexInProgress)
+	}
+
+	now := time.Now()
+	s.update(func(status *Status) {
+		*status = Status{State: StateRunning, StartedAt: &now}
+	})
+
+	go s.run()
+
+	return s.Status(), nil
+}
+
+// failure is a document the reindex could not re-embed
+type failure struct {
+	doc document.DocumentMetadata
+	err error
+}
+
+// run performs the reindex and atomically swaps the vector store contents
+func (s *Service) run() {
+	defer s.running.Store(false)
+
+	// Store writes wait from here until the swap: writes in flight when the job started are
+	// part of what it reads, later ones are applied on top of the result
+	release := s.vectorStore.HoldWrites()
+	defer release()
+
+	// Soft-deleted documents are reindexed too so they stay restorable
+	docs, err := s.metadataStore.ListAll()
+	if err != nil {
+		s.logger.Error("reindex failed to list documents", zap.Error(err))
+		s.finish(StateFailed, fmt.Sprintf("failed to list documents: %v", err))
+		return
+	}
+
+	s.update(func(status *Status) {
+		status.TotalDocuments = len(docs)
+	})
+
+	s.logger.Info("reindex started", zap.Int("documents", len(docs)))
+
+	// Group existing chunks so failed documents keep their current index
+	existing := make(map[string][]models.Chunk)
+	for _, chunk := range s.vectorStore.GetAllIncludingDeleted() {
+		// Drop dead chunks stored before embeddings were validated
+		if err := vector.CheckEmbedding(chunk.Embedding); err != nil {
+			s.logger.Warn("dropping chunk with unusable embedding", zap.String("chunk_id", chunk.ID), zap.Error(err))
+			continue
+		}
+		existing[chunk.DocID] = append(existing[chunk.DocID], chunk)
+	}
+
+	var allChunks []models.Chunk
+	var reindexed []document.DocumentMetadata
+	var failed []failure
+
+	for _, doc := range docs {
+		chunks, duplicates, err := s.reindexDocument(doc)
+		if err != nil {
+			s.logger.Warn("failed to reindex document", zap.String("doc_id", doc.ID), zap.Error(err))
+			failed = append(failed, failure{doc: doc, err: err})
+			s.update(func(status *Status) {
+				status.ProcessedDocuments++
+				status.FailedDocuments++
+				status.Errors = append(status.Errors, fmt.Sprintf("%s (%s): %v", doc.FileName, doc.ID, err))
+			})
+			continue
+		}
+
+		for i := range chunks {
+			chunks[i].Deleted = doc.DeletedAt != nil
+		}
+
+		allChunks = append(allChunks, chunks...)
+		doc.ChunkCount = len(chunks)
+		doc.DuplicateChunks = duplicates
+		reindexed = append(reindexed, doc)
+		delete(existing, doc.ID)
+
+		s.update(func(status *Status) {
+			status.ProcessedDocuments++
+			status.Chunks += len(chunks)
+		})
+	}
+
+	// Failed documents keep their current chunks unless those have another dimension than
+	// the new vectors, in which case they cannot share the store and are dropped
+	dims := 0
+	if len(allChunks) > 0 {
+		dims = len(allChunks[0].Embedding)
+	}
+
+	var dropped []failure
+	for _, f := range failed {
+		chunks := existing[f.doc.ID]
+		delete(existing, f.doc.ID)
+
+		if dims > 0 && len(chunks) > 0 && len(chunks[0].Embedding) != dims {
+			dropped = append(dropped, f)
+			continue
+		}
+		allChunks = append(allChunks, chunks...)
+	}
+
+	// Keep chunks whose document metadata is missing rather than silently dropping them
+	for docID, chunks := range existing {
+		if dims > 0 && len(chunks[0].Embedding) != dims {
+			s.logger.Warn("dropping chunks of unknown document with stale dimension",
+				zap.String("doc_id", docID), zap.Int("chunks", len(chunks)))
+			continue
+		}
+		allChunks = append(allChunks, chunks...)
+	}
+
+	if err := s.vectorStore.Replace(allChunks); err != nil {
+		s.logger.Error("reindex failed to swap vector store", zap.Error(err))
+		s.finish(StateFailed, fmt.Sprintf("failed to swap vector store: %v", err))
+		return
+	}
+
+	// The store now holds vectors of the configured model
+	stamp := vector.ModelStamp{Provider: s.embeddingsSvc.Provider(), Model: s.embeddingsSvc.Model()}
+	if err := s.vectorStore.StampModel(stamp, true); err != nil {
+		s.logger.Warn("failed to stamp embedding model", zap.Error(err))
+	}
+
+	// Only the indexing fields are updated, so deletes and restores waiting on the store
+	// are not reverted
+	for _, doc := range reindexed {
+		s.updateMetadata(doc.ID, func(meta *document.DocumentMetadata) {
+			meta.ChunkCount = doc.ChunkCount
+			meta.DuplicateChunks = doc.DuplicateChunks
+			meta.EmbeddingProvider = ""
+			meta.ReindexError = ""
+		})
+	}
+
+	for _, f := range dropped {
+		s.logger.Warn("dropped chunks of document that failed to reindex", zap.String("doc_id", f.doc.ID))
+		s.updateMetadata(f.doc.ID, func(meta *document.DocumentMetadata) {
+			meta.ChunkCount = 0
+			meta.ReindexError = f.err.Error()
+		})
+		s.update(func(status *Status) {
+			status.Errors = append(status.Errors, fmt.Sprintf("%s (%s): chunks dropped, upload it again to index it", f.doc.FileName, f.doc.ID))
+		})
+	}
+
+	status := s.finish(StateCompleted, "")
+	s.logger.Info("reindex completed",
+		zap.Int("documents", status.ProcessedDocuments),
+		zap.Int("failed", status.FailedDocuments),
+		zap.Int("dropped", len(dropped)),
+		zap.Int("chunks", status.Chunks),
+	)
+}
+
+// updateMetadata updates a document's metadata after the swap; documents purged meanwhile
+// are skipped
+func (s *Service) updateMetadata(docID string, fn func(meta *document.DocumentMetadata)) {
+	if _, err := s.metadataStore.Update(docID, fn); err != nil && err != badger.ErrKeyNotFound {
+		s.logger.Warn("failed to update document metadata", zap.String("doc_id", docID), zap.Error(err))
+	}
+}
+
+// reindexDocument re-reads, re-chunks and re-embeds a single document, returning its chunks
+// and how many duplicate chunks were dropped
+func (s *Service) reindexDocument(doc document.DocumentMetadata) ([]models.Chunk, int, error) {
+	// Uses the stored content when available, otherwise the original file
+	full, err := s.docService.GetDocument(doc.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read document content: %w", err)
+	}
+
+	chunks, duplicates := s.docService.ChunkDocument(doc.ID, doc.FileName, full.Content)
+	if len(chunks) == 0 {
+		return nil, 0, fmt.Errorf("document produced no chunks")
+	}
+
+	for i := range chunks {
+		chunks[i].Namespace = doc.Namespace
+		chunks[i].TenantID = doc.TenantID
+	}
+
+	// Every document is re-embedded with EMBEDDING_PROVIDER, including those uploaded with
+	// another provider, so the rebuilt index holds a single embedding space
+	chunks, err = s.embeddingsSvc.GenerateEmbeddingsWithProvider(context.Background(), s.embeddingsSvc.Provider(), chunks, nil)
+	return chunks, duplicates, err
+}
+
+// update applies a mutation to the job status under lock
+func (s *Service) update(fn func(status *Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(&s.status)
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// finish marks the job as finished and returns the final status
+func (s *Service) finish(state, errMsg string) Status {
+	now := time.Now()
+	s.update(func(status *Status) {
+		status.State = state
+		status.FinishedAt = &now
+		if errMsg != "" {
+			status.Errors = append(status.Errors, errMsg)
+		}
+	})
+
+	return s.Status()
+}
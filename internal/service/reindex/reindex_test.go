@@ -0,0 +1,292 @@
+package reindex
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"go.uber.org/zap"
+)
+
+// ollamaStub serves Ollama embeddings of a configurable dimension. While held, requests
+// signal entered and wait for release.
+type ollamaStub struct {
+	dims     atomic.Int64
+	gate     chan struct{}
+	entered  chan struct{}
+	openGate sync.Once
+}
+
+// hold makes requests wait until release is called or the test ends
+func (s *ollamaStub) hold(t *testing.T) {
+	s.gate = make(chan struct{})
+	t.Cleanup(s.release)
+}
+
+// release lets held requests through
+func (s *ollamaStub) release() {
+	s.openGate.Do(func() { close(s.gate) })
+}
+
+func newOllamaStub(t *testing.T, dims int) (*ollamaStub, *httptest.Server) {
+	t.Helper()
+
+	stub := &ollamaStub{entered: make(chan struct{}, 100)}
+	stub.dims.Store(int64(dims))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stub.gate != nil {
+			stub.entered <- struct{}{}
+			<-stub.gate
+		}
+
+		embedding := make([]float64, stub.dims.Load())
+		for i := range embedding {
+			embedding[i] = float64(i + 1)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embedding": embedding})
+	}))
+	t.Cleanup(server.Close)
+
+	return stub, server
+}
+
+type testEnv struct {
+	metadataStore *document.MetadataStore
+	vectorStore   *vector.Store
+	svc           *Service
+}
+
+func newTestEnv(t *testing.T, ollamaURL string) *testEnv {
+	t.Helper()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	dir := t.TempDir()
+	cfg.Storage.UploadDir = filepath.Join(dir, "uploads")
+	cfg.Storage.VectorStorePath = filepath.Join(dir, "vectors")
+	cfg.Storage.StoreDocumentContent = true
+	cfg.Embeddings.Provider = "ollama"
+	cfg.Embeddings.Dimensions = 0
+	cfg.Ollama.BaseURL = ollamaURL
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	logger := zap.NewNop()
+	env := &testEnv{metadataStore: document.NewMetadataStore(db)}
+
+	docService, err := document.New(cfg, logger, env.metadataStore)
+	if err != nil {
+		t.Fatalf("failed to create document service: %v", err)
+	}
+
+	env.vectorStore, err = vector.New(cfg, db)
+	if err != nil {
+		t.Fatalf("failed to create vector store: %v", err)
+	}
+
+	env.svc = New(logger, docService, embeddings.New(cfg, logger), env.vectorStore, env.metadataStore)
+	return env
+}
+
+// addDocument indexes a document with 3-dimensional vectors. Documents without content
+// cannot be re-read and fail to reindex.
+func (env *testEnv) addDocument(t *testing.T, docID, content string) {
+	t.Helper()
+
+	if err := env.metadataStore.Add(document.DocumentMetadata{ID: docID, FileName: docID + ".txt", ChunkCount: 1}); err != nil {
+		t.Fatalf("failed to add metadata: %v", err)
+	}
+	if content != "" {
+		if err := env.metadataStore.SaveContent(docID, content); err != nil {
+			t.Fatalf("failed to save content: %v", err)
+		}
+	}
+
+	chunk := models.Chunk{ID: docID + "-old", DocID: docID, Content: "old " + docID, Embedding: []float64{1, 2, 3}}
+	if err := env.vectorStore.Add([]models.Chunk{chunk}); err != nil {
+		t.Fatalf("failed to add chunk: %v", err)
+	}
+}
+
+// wait blocks until the job finishes and returns its status
+func (env *testEnv) wait(t *testing.T) Status {
+	t.Helper()
+
+	deadline := time.After(10 * time.Second)
+	for {
+		changes := env.svc.Changes()
+		if status := env.svc.Status(); status.State != StateRunning {
+			return status
+		}
+		select {
+		case <-changes:
+		case <-deadline:
+			t.Fatal("reindex did not finish")
+		}
+	}
+}
+
+func chunksOf(store *vector.Store, docID string) []models.Chunk {
+	var chunks []models.Chunk
+	for _, chunk := range store.GetAllIncludingDeleted() {
+		if chunk.DocID == docID {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+func TestReindexReplacesChunks(t *testing.T) {
+	_, server := newOllamaStub(t, 3)
+	env := newTestEnv(t, server.URL)
+	env.addDocument(t, "a", "Reindexed content of document a.")
+
+	if _, err := env.svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	status := env.wait(t)
+
+	if status.State != StateCompleted || status.ProcessedDocuments != 1 || status.FailedDocuments != 0 {
+		t.Fatalf("status = %+v, want one document reindexed", status)
+	}
+	chunks := chunksOf(env.vectorStore, "a")
+	if len(chunks) == 0 || chunks[0].ID == "a-old" {
+		t.Errorf("chunks after reindex = %+v, want freshly chunked ones", chunks)
+	}
+}
+
+func TestReindexHoldsWritesUntilSwap(t *testing.T) {
+	stub, server := newOllamaStub(t, 3)
+	env := newTestEnv(t, server.URL)
+	env.addDocument(t, "a", "Reindexed content of document a.")
+
+	stub.hold(t)
+	if _, err := env.svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	<-stub.entered
+
+	// A write that reaches the store while the job is embedding must not be swapped away
+	added := make(chan error, 1)
+	go func() {
+		added <- env.vectorStore.Add([]models.Chunk{{ID: "late", DocID: "late-doc", Content: "late", Embedding: []float64{3, 2, 1}}})
+	}()
+
+	select {
+	case err := <-added:
+		t.Fatalf("write went through during the reindex (err %v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	stub.release()
+	if err := <-added; err != nil {
+		t.Fatalf("held write failed: %v", err)
+	}
+	if status := env.wait(t); status.State != StateCompleted {
+		t.Fatalf("status = %+v, want completed", status)
+	}
+
+	if len(chunksOf(env.vectorStore, "late-doc")) != 1 {
+		t.Error("a write held during the reindex was lost")
+	}
+}
+
+func TestReindexKeepsFailedDocumentsOfSameDimension(t *testing.T) {
+	_, server := newOllamaStub(t, 3)
+	env := newTestEnv(t, server.URL)
+	env.addDocument(t, "a", "Reindexed content of document a.")
+	env.addDocument(t, "b", "")
+
+	env.svc.Start()
+	status := env.wait(t)
+
+	if status.State != StateCompleted || status.FailedDocuments != 1 {
+		t.Fatalf("status = %+v, want completed with one failure", status)
+	}
+	if chunks := chunksOf(env.vectorStore, "b"); len(chunks) != 1 || chunks[0].ID != "b-old" {
+		t.Errorf("failed document chunks = %+v, want its old chunk kept", chunks)
+	}
+	if meta, _ := env.metadataStore.Get("b"); meta.ReindexError != "" || meta.ChunkCount != 1 {
+		t.Errorf("failed document metadata = %+v, want it unchanged", meta)
+	}
+}
+
+func TestReindexDropsFailedDocumentsOfOldDimension(t *testing.T) {
+	_, server := newOllamaStub(t, 4)
+	env := newTestEnv(t, server.URL)
+	env.addDocument(t, "a", "Reindexed content of document a.")
+	env.addDocument(t, "b", "")
+
+	env.svc.Start()
+	status := env.wait(t)
+
+	// The 3-dimensional chunks of b cannot share the store with the new 4-dimensional ones
+	if status.State != StateCompleted || status.FailedDocuments != 1 {
+		t.Fatalf("status = %+v, want completed with one failure", status)
+	}
+	if env.vectorStore.Dimensions() != 4 {
+		t.Errorf("store dimension = %d, want 4", env.vectorStore.Dimensions())
+	}
+	if chunks := chunksOf(env.vectorStore, "b"); len(chunks) != 0 {
+		t.Errorf("failed document kept %d chunks of the old dimension", len(chunks))
+	}
+
+	meta, err := env.metadataStore.Get("b")
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	if meta.ReindexError == "" || meta.ChunkCount != 0 {
+		t.Errorf("dropped document metadata = %+v, want it flagged with no chunks", meta)
+	}
+}
+
+func TestReindexKeepsConcurrentDelete(t *testing.T) {
+	stub, server := newOllamaStub(t, 3)
+	env := newTestEnv(t, server.URL)
+	env.addDocument(t, "a", "Reindexed content of document a.")
+
+	stub.hold(t)
+	env.svc.Start()
+	<-stub.entered
+
+	// A soft delete updates the metadata right away and its chunks once the swap is done
+	now := time.Now()
+	if _, err := env.metadataStore.SetDeleted("a", &now); err != nil {
+		t.Fatalf("SetDeleted failed: %v", err)
+	}
+	deleted := make(chan error, 1)
+	go func() { deleted <- env.vectorStore.DeleteByDocID("a") }()
+
+	stub.release()
+	if err := <-deleted; err != nil {
+		t.Fatalf("DeleteByDocID failed: %v", err)
+	}
+	env.wait(t)
+
+	if meta, _ := env.metadataStore.Get("a"); meta.DeletedAt == nil {
+		t.Error("the reindex reverted a concurrent delete in the metadata")
+	}
+	if env.vectorStore.Count() != 0 {
+		t.Errorf("%d chunks searchable after a concurrent delete, want 0", env.vectorStore.Count())
+	}
+}
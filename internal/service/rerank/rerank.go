@@ -0,0 +1,163 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/pkg/httpdebug"
+	"go.uber.org/zap"
+)
+
+// maxConcurrency bounds the scoring calls in flight for one rerank
+const maxConcurrency = 4
+
+// scorePrompt asks the model for a single relevance score
+const scorePrompt = `Rate how relevant the passage is to the query on a scale from 0 (unrelated) to 10 (directly answers it). Reply with the number only.
+
+Query: %s
+
+Passage:
+%s
+
+Score:`
+
+// scorePattern finds the first number in a model reply
+var scorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// Reranker scores candidate passages against a query with a model served by Ollama
+// (RERANK_PROVIDER=ollama), prompting it for a 0-10 relevance score per passage. A nil
+// Reranker is disabled.
+type Reranker struct {
+	cfg        *config.Config
+	logger     *zap.Logger
+	httpClient *http.Client
+}
+
+// New creates a reranker for RERANK_PROVIDER, or returns nil when reranking is off
+func New(cfg *config.Config, logger *zap.Logger) *Reranker {
+	if cfg.RAG.RerankProvider != "ollama" {
+		return nil
+	}
+
+	return &Reranker{
+		cfg:        cfg,
+		logger:     logger,
+		httpClient: httpdebug.NewClient(cfg.LLM.DebugRaw, logger, "rerank"),
+	}
+}
+
+// Enabled reports whether results are reranked
+func (r *Reranker) Enabled() bool {
+	return r != nil
+}
+
+// Score returns the relevance of each passage to query, in [0, 1] and in passage order.
+// It fails if any passage cannot be scored, so callers never mix scored and unscored results.
+func (r *Reranker) Score(ctx context.Context, query string, passages []string) ([]float64, error) {
+	scores := make([]float64, len(passages))
+	errs := make([]error, len(passages))
+
+	slots := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+
+	for i, passage := range passages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			scores[i], errs[i] = r.score(ctx, query, passage)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to score passage %d: %w", i, err)
+		}
+	}
+
+	return scores, nil
+}
+
+// ollamaGenerateRequest represents an Ollama generate API request
+type ollamaGenerateRequest struct {
+	Model   string                 `json:"model"`
+	Prompt  string                 `json:"prompt"`
+	Stream  bool                   `json:"stream"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaGenerateResponse represents an Ollama generate API response
+type ollamaGenerateResponse struct {
+	Response string `json:"response"`
+	Error    string `json:"error,omitempty"`
+}
+
+// score asks the model for the relevance of one passage
+func (r *Reranker) score(ctx context.Context, query, passage string) (float64, error) {
+	jsonData, err := json.Marshal(ollamaGenerateRequest{
+		Model:   r.cfg.RAG.RerankModel,
+		Prompt:  fmt.Sprintf(scorePrompt, query, passage),
+		Stream:  false,
+		Options: map[string]interface{}{"temperature": 0},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.cfg.Ollama.BaseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Ollama API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response ollamaGenerateResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.Error != "" {
+		return 0, fmt.Errorf("Ollama API error: %s", response.Error)
+	}
+
+	return parseScore(response.Response)
+}
+
+// parseScore reads the first number of a reply as a 0-10 score and scales it to [0, 1]
+func parseScore(reply string) (float64, error) {
+	match := scorePattern.FindString(reply)
+	if match == "" {
+		return 0, fmt.Errorf("no score in reply %q", reply)
+	}
+
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid score %q: %w", match, err)
+	}
+
+	return min(max(score, 0), 10) / 10, nil
+}
@@ -0,0 +1,147 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"go.uber.org/zap"
+)
+
+// generateStub serves Ollama /api/generate, replying to each prompt with the reply set for
+// the first passage it contains
+type generateStub struct {
+	mu       sync.Mutex
+	replies  map[string]string
+	requests []ollamaGenerateRequest
+}
+
+// newReranker creates an Ollama reranker against a stub replying with replies
+func newReranker(t *testing.T, replies map[string]string) (*Reranker, *generateStub) {
+	t.Helper()
+
+	stub := &generateStub{replies: replies}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var req ollamaGenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, req)
+		stub.mu.Unlock()
+
+		for passage, reply := range stub.replies {
+			if strings.Contains(req.Prompt, "Passage:\n"+passage+"\n") {
+				json.NewEncoder(w).Encode(ollamaGenerateResponse{Response: reply})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ollamaGenerateResponse{Error: "unexpected prompt"})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.Ollama.BaseURL = server.URL
+	cfg.RAG.RerankProvider = "ollama"
+	cfg.RAG.RerankModel = "scorer"
+
+	return New(cfg, zap.NewNop()), stub
+}
+
+func TestNewDisabled(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RAG.RerankProvider = "none"
+
+	reranker := New(cfg, zap.NewNop())
+	if reranker.Enabled() {
+		t.Fatal("reranker enabled with RERANK_PROVIDER=none")
+	}
+}
+
+func TestScore(t *testing.T) {
+	reranker, stub := newReranker(t, map[string]string{
+		"alpha": "3",
+		"beta":  " 8.5\n",
+		"gamma": "I'd rate it 10/10",
+		"delta": "0",
+	})
+
+	scores, err := reranker.Score(context.Background(), "which one?", []string{"alpha", "beta", "gamma", "delta"})
+	if err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+
+	want := []float64{0.3, 0.85, 1, 0}
+	for i := range want {
+		if scores[i] != want[i] {
+			t.Errorf("score %d = %g, want %g", i, scores[i], want[i])
+		}
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.requests) != 4 {
+		t.Fatalf("requests = %d, want one per passage", len(stub.requests))
+	}
+	for _, req := range stub.requests {
+		if req.Model != "scorer" || req.Stream || req.Options["temperature"] != float64(0) {
+			t.Errorf("request = %+v, want a non-streaming call to RERANK_MODEL at temperature 0", req)
+		}
+		if !strings.Contains(req.Prompt, "Query: which one?\n") {
+			t.Errorf("prompt = %q, want it to hold the query", req.Prompt)
+		}
+	}
+}
+
+func TestScoreFailsOnAnyPassage(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies map[string]string
+	}{
+		{name: "no number", replies: map[string]string{"alpha": "3", "beta": "relevant"}},
+		{name: "provider error", replies: map[string]string{"alpha": "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reranker, _ := newReranker(t, tt.replies)
+
+			if scores, err := reranker.Score(context.Background(), "q", []string{"alpha", "beta"}); err == nil {
+				t.Fatalf("Score = %v, want an error when a passage cannot be scored", scores)
+			}
+		})
+	}
+}
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  float64
+	}{
+		{"7", 0.7},
+		{"Score: 4.5", 0.45},
+		{"12", 1},
+		{"10. Directly answers it.", 1},
+	}
+
+	for _, tt := range tests {
+		got, err := parseScore(tt.reply)
+		if err != nil || got != tt.want {
+			t.Errorf("parseScore(%q) = %g, %v; want %g", tt.reply, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseScore("not relevant"); err == nil {
+		t.Error("parseScore without a number succeeded")
+	}
+}
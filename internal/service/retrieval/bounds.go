@@ -0,0 +1,61 @@
+package retrieval
+
+import (
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"go.uber.org/zap"
+)
+
+// BoundResults applies SEARCH_MIN_SCORE to results ordered by score, keeping those that
+// score at least the minimum. When fewer than SEARCH_MIN_RESULTS remain, the best results
+// below the minimum fill the gap. confident is the number of leading results that cleared
+// the minimum; the rest are low confidence.
+func (s *Service) BoundResults(results []vector.SimilarityResult) (bounded []vector.SimilarityResult, confident int) {
+	minScore := s.cfg.RAG.SearchMinScore
+	if minScore <= 0 {
+		return results, len(results)
+	}
+
+	for confident < len(results) && results[confident].Similarity >= minScore {
+		confident++
+	}
+
+	keep := max(confident, min(s.cfg.RAG.SearchMinResults, len(results)))
+	return results[:keep], confident
+}
+
+// ThresholdContext applies CONTEXT_MIN_SCORE to chat context results ordered by score. When
+// no result clears it, the best MIN_RESULTS results are kept anyway, flagged low confidence,
+// so the LLM still has some grounding; with MIN_RESULTS=0 the context is left empty.
+func (s *Service) ThresholdContext(results []vector.SimilarityResult) []vector.SimilarityResult {
+	minScore := s.cfg.RAG.ContextMinScore
+	if minScore <= 0 {
+		return results
+	}
+
+	kept := 0
+	for kept < len(results) && results[kept].Similarity >= minScore {
+		kept++
+	}
+	if kept > 0 || len(results) == 0 {
+		return results[:kept]
+	}
+
+	fallback := results[:min(s.cfg.RAG.MinResults, len(results))]
+	if len(fallback) > 0 {
+		s.logger.Info("no context cleared CONTEXT_MIN_SCORE, keeping the best chunks as low confidence",
+			zap.Int("kept", len(fallback)),
+			zap.Float64("best_score", results[0].Similarity),
+			zap.Float64("min_score", minScore),
+		)
+	}
+	for i := range fallback {
+		fallback[i].LowConfidence = true
+	}
+	return fallback
+}
+
+// LowConfidence reports whether the results are the MIN_RESULTS fallback rather than chunks
+// that cleared CONTEXT_MIN_SCORE
+func LowConfidence(results []vector.SimilarityResult) bool {
+	return len(results) > 0 && results[0].LowConfidence
+}
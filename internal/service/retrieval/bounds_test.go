@@ -0,0 +1,91 @@
+package retrieval
+
+import (
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestBoundResults(t *testing.T) {
+	tests := []struct {
+		name          string
+		minScore      float64
+		minResults    int
+		similarities  []float64
+		wantKept      int
+		wantConfident int
+	}{
+		{name: "no minimum score", minResults: 1, similarities: []float64{0.9, 0.1}, wantKept: 2, wantConfident: 2},
+		{name: "above the minimum", minScore: 0.5, similarities: []float64{0.9, 0.6, 0.4}, wantKept: 2, wantConfident: 2},
+		{name: "all filtered", minScore: 0.5, similarities: []float64{0.4, 0.3}, wantKept: 0, wantConfident: 0},
+		{name: "all filtered keeps the best with min 1", minScore: 0.5, minResults: 1, similarities: []float64{0.4, 0.3}, wantKept: 1, wantConfident: 0},
+		{name: "min fills the gap", minScore: 0.5, minResults: 3, similarities: []float64{0.8, 0.4, 0.3, 0.2}, wantKept: 3, wantConfident: 1},
+		{name: "min already met", minScore: 0.5, minResults: 1, similarities: []float64{0.8, 0.7, 0.3}, wantKept: 2, wantConfident: 2},
+		{name: "min above the results", minScore: 0.5, minResults: 5, similarities: []float64{0.4, 0.3}, wantKept: 2, wantConfident: 0},
+		{name: "no results", minScore: 0.5, minResults: 1, wantKept: 0, wantConfident: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.RAG.SearchMinScore = tt.minScore
+			cfg.RAG.SearchMinResults = tt.minResults
+			svc := &Service{cfg: cfg}
+
+			results := scored(tt.similarities...)
+			kept, confident := svc.BoundResults(results)
+			if len(kept) != tt.wantKept || confident != tt.wantConfident {
+				t.Fatalf("kept %d (%d confident), want %d (%d confident)", len(kept), confident, tt.wantKept, tt.wantConfident)
+			}
+			for i := range kept {
+				if kept[i].Similarity != results[i].Similarity {
+					t.Errorf("result %d = %g, want the best results in order", i, kept[i].Similarity)
+				}
+			}
+		})
+	}
+}
+
+func TestThresholdContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		minScore      float64
+		minResults    int
+		similarities  []float64
+		wantKept      int
+		lowConfidence bool
+	}{
+		{name: "no minimum score", minResults: 1, similarities: []float64{0.9, 0.1}, wantKept: 2},
+		{name: "above the minimum", minScore: 0.5, minResults: 1, similarities: []float64{0.9, 0.6, 0.4}, wantKept: 2},
+		{name: "one cleared, no fallback", minScore: 0.5, minResults: 3, similarities: []float64{0.6, 0.4, 0.3}, wantKept: 1},
+		{name: "all filtered without MIN_RESULTS", minScore: 0.5, similarities: []float64{0.4, 0.3}, wantKept: 0},
+		{name: "all filtered keeps the best", minScore: 2, minResults: 1, similarities: []float64{0.9, 0.8}, wantKept: 1, lowConfidence: true},
+		{name: "all filtered keeps MIN_RESULTS", minScore: 2, minResults: 2, similarities: []float64{0.9, 0.8, 0.7}, wantKept: 2, lowConfidence: true},
+		{name: "MIN_RESULTS above the results", minScore: 2, minResults: 5, similarities: []float64{0.9}, wantKept: 1, lowConfidence: true},
+		{name: "no results", minScore: 0.5, minResults: 1, wantKept: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.RAG.ContextMinScore = tt.minScore
+			cfg.RAG.MinResults = tt.minResults
+			svc := &Service{cfg: cfg, logger: zap.NewNop()}
+
+			results := scored(tt.similarities...)
+			kept := svc.ThresholdContext(results)
+			if len(kept) != tt.wantKept {
+				t.Fatalf("kept %d, want %d", len(kept), tt.wantKept)
+			}
+			if LowConfidence(kept) != tt.lowConfidence {
+				t.Errorf("LowConfidence = %v, want %v", LowConfidence(kept), tt.lowConfidence)
+			}
+			for i := range kept {
+				if kept[i].Similarity != tt.similarities[i] || kept[i].LowConfidence != tt.lowConfidence {
+					t.Errorf("result %d = %g (low confidence %v), want the best results in order", i, kept[i].Similarity, kept[i].LowConfidence)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package retrieval
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// assertAppError checks that err is an AppError with the given status and code
+func assertAppError(t *testing.T, err error, status int, code string) {
+	t.Helper()
+
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		t.Fatalf("error = %v (%T), want an AppError", err, err)
+	}
+	if appErr.Code != status || appErr.ErrorCode != code {
+		t.Errorf("error = %d %s, want %d %s", appErr.Code, appErr.ErrorCode, status, code)
+	}
+}
+
+func TestRetrieveMultiPassesValidationErrorsThrough(t *testing.T) {
+	cfg, _ := testConfig(t)
+	cfg.Embeddings.MaxInputTokens = 5
+	cfg.Embeddings.QueryTruncation = "error"
+	env := newTestEnv(t, cfg)
+
+	long := strings.Repeat("a very long question ", 10)
+	_, err := env.svc.RetrieveMulti(context.Background(), []string{long, long + "?"}, "", 5, Scope{})
+	assertAppError(t, err, http.StatusBadRequest, errors.CodeInvalidRequest)
+}
+
+func TestRetrieveMultiReportsEmbeddingFailure(t *testing.T) {
+	cfg, ollama := testConfig(t)
+	env := newTestEnv(t, cfg)
+	env.addDocument(t, "doc", time.Now(), []float64{1, 0, 0})
+	// A zero vector is rejected without the retries and backoff of a failing provider
+	ollama.respond([]float64{0, 0, 0})
+
+	_, err := env.svc.RetrieveMulti(context.Background(), []string{"question", "variant"}, "", 5, Scope{})
+	assertAppError(t, err, http.StatusInternalServerError, errors.CodeEmbeddingFailed)
+
+	// A single query fails the same way
+	_, err = env.svc.RetrieveMulti(context.Background(), []string{"question"}, "", 5, Scope{})
+	assertAppError(t, err, http.StatusInternalServerError, errors.CodeEmbeddingFailed)
+}
@@ -0,0 +1,44 @@
+package retrieval
+
+import (
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+)
+
+// Retrieval methods reported in chunk explanations
+const (
+	MethodVector  = "vector"
+	MethodKeyword = "keyword"
+)
+
+// explainResult describes how a result was scored and ranked. rank is 1-based.
+func explainResult(result vector.SimilarityResult, rank int) *models.ChunkDebug {
+	debug := &models.ChunkDebug{
+		Rank:       rank,
+		Method:     MethodVector,
+		FinalScore: result.Similarity,
+		Tokens:     tokenizer.EstimateTokens(result.Chunk.Content),
+	}
+
+	if result.KeywordScore > 0 {
+		debug.Method = MethodKeyword
+		score := result.KeywordScore
+		debug.KeywordScore = &score
+	} else {
+		score := result.VectorScore
+		debug.VectorScore = &score
+	}
+
+	if result.RecencyFactor > 0 {
+		factor := result.RecencyFactor
+		debug.RecencyFactor = &factor
+	}
+
+	if result.RerankScore > 0 {
+		score := result.RerankScore
+		debug.RerankScore = &score
+	}
+
+	return debug
+}
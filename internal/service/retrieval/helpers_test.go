@@ -0,0 +1,185 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"go.uber.org/zap"
+)
+
+// queryEmbedding is the embedding the Ollama stub returns for every query by default
+var queryEmbedding = []float64{1, 0, 0}
+
+// ollamaStub serves Ollama embeddings: the one set for a text with respondTo, otherwise
+// the same embedding for every text. Rerank prompts get the reply set for their passage
+// with scoreReply, or fail without one.
+type ollamaStub struct {
+	mu        sync.Mutex
+	embedding []float64
+	byText    map[string][]float64
+	scores    map[string]string
+	generates int
+}
+
+// scoreReply makes rerank prompts for passage return reply
+func (s *ollamaStub) scoreReply(passage, reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scores == nil {
+		s.scores = make(map[string]string)
+	}
+	s.scores[passage] = reply
+}
+
+// respondTo makes requests for text return embedding
+func (s *ollamaStub) respondTo(text string, embedding []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byText == nil {
+		s.byText = make(map[string][]float64)
+	}
+	s.byText[text] = embedding
+}
+
+// respond makes every following request return embedding
+func (s *ollamaStub) respond(embedding []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedding = embedding
+}
+
+// scoringCalls returns the number of rerank prompts served
+func (s *ollamaStub) scoringCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generates
+}
+
+// testConfig loads the default configuration with storage under a temporary directory
+// and embeddings from an Ollama stub
+func testConfig(t *testing.T) (*config.Config, *ollamaStub) {
+	t.Helper()
+
+	stub := &ollamaStub{embedding: queryEmbedding}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Prompt string `json:"prompt"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if r.URL.Path == "/api/generate" {
+			_, passage, _ := strings.Cut(req.Prompt, "Passage:\n")
+			passage, _, _ = strings.Cut(passage, "\n\nScore:")
+
+			stub.mu.Lock()
+			stub.generates++
+			reply, ok := stub.scores[passage]
+			stub.mu.Unlock()
+
+			if !ok {
+				http.Error(w, `{"error": "model not found"}`, http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"response": reply})
+			return
+		}
+
+		stub.mu.Lock()
+		embedding, ok := stub.byText[req.Prompt]
+		if !ok {
+			embedding = stub.embedding
+		}
+		stub.mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"embedding": embedding})
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	dir := t.TempDir()
+	cfg.Storage.UploadDir = filepath.Join(dir, "uploads")
+	cfg.Storage.VectorStorePath = filepath.Join(dir, "vectors")
+	cfg.Embeddings.Provider = "ollama"
+	cfg.Ollama.BaseURL = server.URL
+
+	return cfg, stub
+}
+
+// testEnv holds a retrieval service and the stores behind it
+type testEnv struct {
+	cfg           *config.Config
+	vectorStore   *vector.Store
+	metadataStore *document.MetadataStore
+	svc           *Service
+}
+
+// newTestEnv creates a retrieval service over fresh stores
+func newTestEnv(t *testing.T, cfg *config.Config) *testEnv {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	vectorStore, err := vector.New(cfg, db)
+	if err != nil {
+		t.Fatalf("failed to create vector store: %v", err)
+	}
+
+	env := &testEnv{cfg: cfg, vectorStore: vectorStore, metadataStore: document.NewMetadataStore(db)}
+	env.svc = New(cfg, zap.NewNop(), embeddings.New(cfg, zap.NewNop()), vectorStore, env.metadataStore, nil, nil)
+
+	return env
+}
+
+// addDocument indexes a document uploaded at uploadedAt with one chunk per embedding; chunk
+// IDs are the document ID followed by the chunk's index
+func (env *testEnv) addDocument(t *testing.T, docID string, uploadedAt time.Time, chunkEmbeddings ...[]float64) {
+	t.Helper()
+
+	if err := env.metadataStore.Add(document.DocumentMetadata{ID: docID, FileName: docID + ".txt", UploadedAt: uploadedAt}); err != nil {
+		t.Fatalf("failed to add metadata: %v", err)
+	}
+
+	chunks := make([]models.Chunk, len(chunkEmbeddings))
+	for i, embedding := range chunkEmbeddings {
+		chunks[i] = models.Chunk{
+			ID:        docID + "-" + string(rune('0'+i)),
+			DocID:     docID,
+			Content:   "content of " + docID,
+			Index:     i,
+			Embedding: embedding,
+		}
+	}
+	if err := env.vectorStore.Add(chunks); err != nil {
+		t.Fatalf("failed to add chunks: %v", err)
+	}
+}
+
+// resultIDs returns the chunk IDs of results in order
+func resultIDs(results []vector.SimilarityResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.Chunk.ID
+	}
+	return ids
+}
@@ -0,0 +1,103 @@
+package retrieval
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/pkg/errors"
+)
+
+// addPassages indexes one document per passage in namespace, keyed by chunk ID, all with
+// the same embedding
+func (env *testEnv) addPassages(t *testing.T, namespace string, embedding []float64, passages map[string]string) {
+	t.Helper()
+
+	var chunks []models.Chunk
+	for id, content := range passages {
+		if err := env.metadataStore.Add(document.DocumentMetadata{ID: "doc-" + id, FileName: id + ".txt"}); err != nil {
+			t.Fatalf("failed to add metadata: %v", err)
+		}
+		chunks = append(chunks, models.Chunk{ID: id, DocID: "doc-" + id, Content: content, Namespace: namespace, Embedding: embedding})
+	}
+	if err := env.vectorStore.Add(chunks); err != nil {
+		t.Fatalf("failed to add chunks: %v", err)
+	}
+}
+
+var keywordPassages = map[string]string{
+	"billing":  "Invoices are sent on the first day of every month.",
+	"refunds":  "Refunds are issued within five business days of a request.",
+	"security": "All data is encrypted at rest and in transit.",
+}
+
+func TestKeywordFallbackOnEmbeddingFailure(t *testing.T) {
+	cfg, ollama := testConfig(t)
+	cfg.RAG.EmbedFallback = "keyword"
+	env := newTestEnv(t, cfg)
+	env.addPassages(t, "default", []float64{1, 0, 0}, keywordPassages)
+
+	// A zero vector is unusable, so embedding the query fails
+	ollama.respond([]float64{0, 0, 0})
+
+	results, err := env.svc.Retrieve(context.Background(), "how long do refunds take", "", 2, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed despite the keyword fallback: %v", err)
+	}
+	if len(results) == 0 || results[0].Chunk.ID != "refunds" {
+		t.Fatalf("results = %v, want the refunds passage first", resultIDs(results))
+	}
+	for _, result := range results {
+		if result.Chunk.ID == "security" {
+			t.Errorf("results = %v include a passage sharing no query terms", resultIDs(results))
+		}
+	}
+	if !Degraded(results) {
+		t.Error("keyword fallback results are not reported as degraded")
+	}
+}
+
+func TestKeywordFallbackRespectsScope(t *testing.T) {
+	cfg, ollama := testConfig(t)
+	cfg.RAG.EmbedFallback = "keyword"
+	env := newTestEnv(t, cfg)
+	env.addPassages(t, "default", []float64{1, 0, 0}, keywordPassages)
+	env.addPassages(t, "finance", []float64{1, 0, 0}, map[string]string{"invoices": "Refunds and invoices are handled by finance."})
+	ollama.respond([]float64{0, 0, 0})
+
+	results, err := env.svc.Retrieve(context.Background(), "refunds invoices", "", 5, Scope{Namespaces: []string{"finance"}})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if ids := resultIDs(results); len(ids) != 1 || ids[0] != "invoices" {
+		t.Errorf("results = %v, want only the passage in scope", ids)
+	}
+}
+
+func TestNoFallbackReportsEmbeddingFailure(t *testing.T) {
+	cfg, ollama := testConfig(t)
+	cfg.RAG.EmbedFallback = "none"
+	env := newTestEnv(t, cfg)
+	env.addPassages(t, "default", []float64{1, 0, 0}, keywordPassages)
+	ollama.respond([]float64{0, 0, 0})
+
+	_, err := env.svc.Retrieve(context.Background(), "how long do refunds take", "", 2, Scope{})
+	assertAppError(t, err, http.StatusInternalServerError, errors.CodeEmbeddingFailed)
+}
+
+func TestVectorResultsAreNotDegraded(t *testing.T) {
+	cfg, _ := testConfig(t)
+	cfg.RAG.EmbedFallback = "keyword"
+	env := newTestEnv(t, cfg)
+	env.addPassages(t, "default", []float64{1, 0, 0}, keywordPassages)
+
+	results, err := env.svc.Retrieve(context.Background(), "how long do refunds take", "", 2, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(results) == 0 || Degraded(results) {
+		t.Errorf("vector search results = %v, degraded = %v; want results that are not degraded", resultIDs(results), Degraded(results))
+	}
+}
@@ -0,0 +1,66 @@
+package retrieval
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/vector"
+)
+
+func TestLimitPerDocument(t *testing.T) {
+	results := make([]vector.SimilarityResult, 0, 6)
+	for _, id := range []string{"a-0", "a-1", "a-2", "b-0", "a-3", "c-0"} {
+		results = append(results, vector.SimilarityResult{Chunk: models.Chunk{ID: id, DocID: id[:1]}})
+	}
+
+	tests := []struct {
+		name      string
+		maxPerDoc int
+		topK      int
+		want      []string
+	}{
+		{"cap fills from other documents", 2, 4, []string{"a-0", "a-1", "b-0", "c-0"}},
+		{"one per document", 1, 5, []string{"a-0", "b-0", "c-0"}},
+		{"topK reached first", 2, 2, []string{"a-0", "a-1"}},
+		{"cap above any document", 10, 6, []string{"a-0", "a-1", "a-2", "b-0", "a-3", "c-0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resultIDs(LimitPerDocument(results, tt.maxPerDoc, tt.topK)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LimitPerDocument = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrieveContextSpreadsAcrossDocuments(t *testing.T) {
+	cfg, _ := testConfig(t)
+	env := newTestEnv(t, cfg)
+
+	// Every chunk of the dominant document is closer to the query than any other chunk
+	env.addDocument(t, "dominant", time.Now(),
+		[]float64{1, 0, 0}, []float64{1, 0.01, 0}, []float64{1, 0.02, 0}, []float64{1, 0.03, 0}, []float64{1, 0.04, 0})
+	env.addDocument(t, "second", time.Now(), []float64{1, 0.5, 0})
+	env.addDocument(t, "third", time.Now(), []float64{1, 0.8, 0})
+
+	monopolized, err := env.svc.RetrieveContext(context.Background(), []string{"query"}, "", 4, Scope{})
+	if err != nil {
+		t.Fatalf("RetrieveContext failed: %v", err)
+	}
+	if want := []string{"dominant-0", "dominant-1", "dominant-2", "dominant-3"}; !reflect.DeepEqual(resultIDs(monopolized), want) {
+		t.Fatalf("uncapped context = %v, want %v", resultIDs(monopolized), want)
+	}
+
+	cfg.RAG.MaxChunksPerDocument = 2
+	capped, err := env.svc.RetrieveContext(context.Background(), []string{"query"}, "", 4, Scope{})
+	if err != nil {
+		t.Fatalf("RetrieveContext failed: %v", err)
+	}
+	if want := []string{"dominant-0", "dominant-1", "second-0", "third-0"}; !reflect.DeepEqual(resultIDs(capped), want) {
+		t.Errorf("capped context = %v, want %v", resultIDs(capped), want)
+	}
+}
@@ -0,0 +1,53 @@
+package retrieval
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRetrieveMultiMergesDespiteFailedSubQuery(t *testing.T) {
+	cfg, ollama := testConfig(t)
+	env := newTestEnv(t, cfg)
+	env.addDocument(t, "install", time.Now(), []float64{1, 0, 0})
+	env.addDocument(t, "upgrade", time.Now(), []float64{0, 1, 0})
+	env.addDocument(t, "unrelated", time.Now(), []float64{-1, -1, 0})
+
+	ollama.respondTo("how to install", []float64{1, 0, 0})
+	ollama.respondTo("installation steps", []float64{0, 0, 0}) // unusable, so this sub-query fails
+	ollama.respondTo("how to upgrade", []float64{0, 1, 0})
+
+	queries := []string{"how to install", "installation steps", "how to upgrade"}
+	results, err := env.svc.RetrieveMulti(context.Background(), queries, "", 2, Scope{})
+	if err != nil {
+		t.Fatalf("RetrieveMulti failed with one of three sub-queries failing: %v", err)
+	}
+
+	ids := resultIDs(results)
+	sort.Strings(ids)
+	if want := []string{"install-0", "upgrade-0"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("results = %v, want the best chunk of each successful sub-query %v", ids, want)
+	}
+}
+
+func TestRetrieveMultiKeepsBestScorePerChunk(t *testing.T) {
+	cfg, ollama := testConfig(t)
+	env := newTestEnv(t, cfg)
+	env.addDocument(t, "doc", time.Now(), []float64{1, 0, 0})
+
+	ollama.respondTo("exact", []float64{1, 0, 0})
+	ollama.respondTo("loose", []float64{1, 1, 0})
+
+	results, err := env.svc.RetrieveMulti(context.Background(), []string{"loose", "exact"}, "", 5, Scope{})
+	if err != nil {
+		t.Fatalf("RetrieveMulti failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results = %v, want the chunk once", resultIDs(results))
+	}
+	if results[0].Similarity < 0.999 {
+		t.Errorf("similarity = %g, want the best of the sub-queries' scores", results[0].Similarity)
+	}
+}
@@ -0,0 +1,60 @@
+package retrieval
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/service/vector"
+)
+
+// scored returns vector search results with the given similarities
+func scored(similarities ...float64) []vector.SimilarityResult {
+	results := make([]vector.SimilarityResult, len(similarities))
+	for i, similarity := range similarities {
+		results[i] = vector.SimilarityResult{Similarity: similarity, VectorScore: similarity}
+	}
+	return results
+}
+
+// assertRelevance compares relevance values within a small tolerance
+func assertRelevance(t *testing.T, mode string, got, want []float64) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: relevance = %v, want %v", mode, got, want)
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("%s: relevance = %v, want %v", mode, got, want)
+			return
+		}
+	}
+}
+
+func TestNormalizeModes(t *testing.T) {
+	results := scored(0.83, 0.5, 0.2, -0.1)
+
+	assertRelevance(t, NormalizationRaw, Normalize(results, NormalizationRaw), []float64{0.83, 0.5, 0.2, -0.1})
+	assertRelevance(t, NormalizationMinMax, Normalize(results, NormalizationMinMax), []float64{100, 60 / 0.93, 30 / 0.93, 0})
+	assertRelevance(t, NormalizationPercent, Normalize(results, NormalizationPercent), []float64{83, 50, 20, 0})
+}
+
+func TestNormalizeMinMaxEqualScores(t *testing.T) {
+	assertRelevance(t, NormalizationMinMax, Normalize(scored(0.4, 0.4), NormalizationMinMax), []float64{100, 100})
+	assertRelevance(t, NormalizationMinMax, Normalize(nil, NormalizationMinMax), []float64{})
+}
+
+func TestNormalizePercentKeywordScores(t *testing.T) {
+	results := []vector.SimilarityResult{
+		{Similarity: 15, KeywordScore: 15},
+		{Similarity: 5, KeywordScore: 5},
+		{Similarity: 1.25, KeywordScore: 1.25},
+	}
+
+	// BM25 scores are squashed rather than all clamped to 100, keeping their order
+	assertRelevance(t, NormalizationPercent, Normalize(results, NormalizationPercent), []float64{75, 50, 20})
+
+	// Once reranked, the score is the reranker's 0-1 relevance
+	reranked := []vector.SimilarityResult{{Similarity: 0.9, KeywordScore: 15, RerankScore: 0.9}}
+	assertRelevance(t, NormalizationPercent, Normalize(reranked, NormalizationPercent), []float64{90})
+}
@@ -0,0 +1,76 @@
+package retrieval
+
+import (
+	"math"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"go.uber.org/zap"
+)
+
+// recencyCandidateFactor controls how many extra candidates are fetched when the recency
+// boost is on, so recent chunks just outside the topK by similarity can move up
+const recencyCandidateFactor = 4
+
+// candidateCount returns how many results to fetch for topK final results
+func (s *Service) candidateCount(topK int) int {
+	count := topK
+	if s.cfg.RAG.RecencyBoost > 0 {
+		count = topK * recencyCandidateFactor
+	}
+	if s.reranker.Enabled() {
+		count = max(count, s.cfg.RAG.RerankCandidates)
+	}
+	return count
+}
+
+// boostRecency scales each score by (1 - w) + w * 0.5^(age / RECENCY_HALFLIFE), where w is
+// RECENCY_BOOST and age is the time since the chunk's document was uploaded, then re-ranks
+// and keeps the topK results. A brand-new document keeps its full score; one a half-life
+// old loses w/2. Chunks whose document metadata is missing are treated as infinitely old.
+func (s *Service) boostRecency(results []vector.SimilarityResult, topK int) []vector.SimilarityResult {
+	weight := s.cfg.RAG.RecencyBoost
+	if weight <= 0 || len(results) == 0 {
+		return results
+	}
+
+	docs, err := s.metadataStore.List()
+	if err != nil {
+		// Ranking by similarity alone is still a useful answer
+		s.logger.Warn("failed to list documents for recency boost", zap.Error(err))
+		return truncate(results, topK)
+	}
+
+	uploadedAt := make(map[string]time.Time, len(docs))
+	for _, doc := range docs {
+		uploadedAt[doc.ID] = doc.UploadedAt
+	}
+
+	now := time.Now()
+	halfLife := s.cfg.RAG.RecencyHalfLife.Hours()
+
+	boosted := make([]vector.SimilarityResult, len(results))
+	for i, result := range results {
+		decay := 0.0
+		if at, ok := uploadedAt[result.Chunk.DocID]; ok {
+			age := max(now.Sub(at).Hours(), 0)
+			decay = math.Pow(0.5, age/halfLife)
+		}
+
+		result.RecencyFactor = (1 - weight) + weight*decay
+		result.Similarity *= result.RecencyFactor
+		boosted[i] = result
+	}
+
+	vector.SortResults(boosted)
+
+	return truncate(boosted, topK)
+}
+
+// truncate returns at most topK results
+func truncate(results []vector.SimilarityResult, topK int) []vector.SimilarityResult {
+	if topK < len(results) {
+		return results[:topK]
+	}
+	return results
+}
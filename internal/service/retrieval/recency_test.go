@@ -0,0 +1,63 @@
+package retrieval
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecencyBoostRanksEqualChunksByAge(t *testing.T) {
+	cfg, _ := testConfig(t)
+	env := newTestEnv(t, cfg)
+
+	// Equally similar to the query; the older one wins the chunk ID tie-break
+	env.addDocument(t, "archived", time.Now().Add(-60*24*time.Hour), []float64{1, 0, 0})
+	env.addDocument(t, "current", time.Now(), []float64{1, 0, 0})
+
+	results, err := env.svc.Retrieve(context.Background(), "query", "", 2, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if want := []string{"archived-0", "current-0"}; !reflect.DeepEqual(resultIDs(results), want) {
+		t.Fatalf("without boost = %v, want %v", resultIDs(results), want)
+	}
+
+	cfg.RAG.RecencyBoost = 0.5
+	cfg.RAG.RecencyHalfLife = 30 * 24 * time.Hour
+	results, err = env.svc.Retrieve(context.Background(), "query", "", 2, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if want := []string{"current-0", "archived-0"}; !reflect.DeepEqual(resultIDs(results), want) {
+		t.Fatalf("with boost = %v, want the newer document first %v", resultIDs(results), want)
+	}
+
+	// Two half-lives old: 0.5 + 0.5*0.25
+	if factor := results[1].RecencyFactor; math.Abs(factor-0.625) > 0.001 {
+		t.Errorf("recency factor two half-lives old = %g, want 0.625", factor)
+	}
+	if factor := results[0].RecencyFactor; factor < 0.999 {
+		t.Errorf("recency factor of a new document = %g, want 1", factor)
+	}
+}
+
+func TestRecencyBoostPromotesRecentCandidates(t *testing.T) {
+	cfg, _ := testConfig(t)
+	cfg.RAG.RecencyBoost = 0.8
+	cfg.RAG.RecencyHalfLife = 24 * time.Hour
+	env := newTestEnv(t, cfg)
+
+	// The recent chunk is slightly less similar, so it is outside the top 1 by similarity alone
+	env.addDocument(t, "stale", time.Now().Add(-30*24*time.Hour), []float64{1, 0, 0})
+	env.addDocument(t, "fresh", time.Now(), []float64{1, 0.1, 0})
+
+	results, err := env.svc.Retrieve(context.Background(), "query", "", 1, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if want := []string{"fresh-0"}; !reflect.DeepEqual(resultIDs(results), want) {
+		t.Errorf("results = %v, want the recent chunk promoted %v", resultIDs(results), want)
+	}
+}
@@ -0,0 +1,52 @@
+package retrieval
+
+import (
+	"context"
+
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"go.uber.org/zap"
+)
+
+// rank orders candidates and keeps the topK: by recency-boosted similarity, then by the
+// reranker's relevance scores when RERANK_PROVIDER is set
+func (s *Service) rank(ctx context.Context, query string, results []vector.SimilarityResult, topK int) []vector.SimilarityResult {
+	if !s.reranker.Enabled() {
+		return s.boostRecency(results, topK)
+	}
+	return s.rerank(ctx, query, s.boostRecency(results, len(results)), topK)
+}
+
+// rerank replaces each result's score with the reranker's relevance score, re-sorts and
+// keeps the topK. If scoring fails the results keep their order, since ranking by
+// similarity is still a useful answer.
+func (s *Service) rerank(ctx context.Context, query string, results []vector.SimilarityResult, topK int) []vector.SimilarityResult {
+	if len(results) == 0 {
+		return results
+	}
+
+	passages := make([]string, len(results))
+	for i, result := range results {
+		passages[i] = result.Chunk.Content
+	}
+
+	scores, err := s.reranker.Score(ctx, query, passages)
+	if err != nil {
+		s.logger.Warn("failed to rerank results, keeping similarity order",
+			zap.Int("candidates", len(results)),
+			zap.Error(err),
+		)
+		vector.SortResults(results)
+		return truncate(results, topK)
+	}
+
+	reranked := make([]vector.SimilarityResult, len(results))
+	for i, result := range results {
+		result.RerankScore = scores[i]
+		result.Similarity = scores[i]
+		reranked[i] = result
+	}
+
+	vector.SortResults(reranked)
+
+	return truncate(reranked, topK)
+}
@@ -0,0 +1,119 @@
+package retrieval
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/rerank"
+	"go.uber.org/zap"
+)
+
+// newRerankEnv creates a retrieval service reranking with RERANK_PROVIDER=ollama against the
+// stub, over three documents ranked a, b, c by similarity
+func newRerankEnv(t *testing.T, candidates int) (*testEnv, *ollamaStub) {
+	t.Helper()
+
+	cfg, stub := testConfig(t)
+	cfg.RAG.RerankProvider = "ollama"
+	cfg.RAG.RerankCandidates = candidates
+	env := newTestEnv(t, cfg)
+	env.svc = New(cfg, zap.NewNop(), embeddings.New(cfg, zap.NewNop()), env.vectorStore, env.metadataStore, rerank.New(cfg, zap.NewNop()), nil)
+
+	env.addDocument(t, "a", time.Now(), []float64{1, 0, 0})
+	env.addDocument(t, "b", time.Now(), []float64{1, 0.2, 0})
+	env.addDocument(t, "c", time.Now(), []float64{1, 0.5, 0})
+
+	return env, stub
+}
+
+func TestRerankReordersResults(t *testing.T) {
+	env, stub := newRerankEnv(t, 20)
+	stub.scoreReply("content of a", "2")
+	stub.scoreReply("content of b", "9")
+	stub.scoreReply("content of c", "Score: 7.5 out of 10")
+
+	results, err := env.svc.Retrieve(context.Background(), "query", "", 2, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if want := []string{"b-0", "c-0"}; !reflect.DeepEqual(resultIDs(results), want) {
+		t.Fatalf("results = %v, want the top 2 by rerank score %v", resultIDs(results), want)
+	}
+
+	for i, want := range []float64{0.9, 0.75} {
+		if math.Abs(results[i].RerankScore-want) > 1e-9 || results[i].Similarity != results[i].RerankScore {
+			t.Errorf("result %s rerank score = %g, similarity = %g; want both %g",
+				results[i].Chunk.ID, results[i].RerankScore, results[i].Similarity, want)
+		}
+	}
+	if calls := stub.scoringCalls(); calls != 3 {
+		t.Errorf("scoring calls = %d, want one per candidate", calls)
+	}
+}
+
+func TestRerankScoresAreClamped(t *testing.T) {
+	env, stub := newRerankEnv(t, 20)
+	stub.scoreReply("content of a", "42")
+	stub.scoreReply("content of b", "-3")
+	stub.scoreReply("content of c", "5")
+
+	results, err := env.svc.Retrieve(context.Background(), "query", "", 3, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+
+	// "-3" reads as 3: the minus sign is not part of the score
+	want := map[string]float64{"a-0": 1, "c-0": 0.5, "b-0": 0.3}
+	for _, result := range results {
+		if math.Abs(result.RerankScore-want[result.Chunk.ID]) > 1e-9 {
+			t.Errorf("result %s rerank score = %g, want %g", result.Chunk.ID, result.RerankScore, want[result.Chunk.ID])
+		}
+	}
+	if ids := resultIDs(results); !reflect.DeepEqual(ids, []string{"a-0", "c-0", "b-0"}) {
+		t.Errorf("results = %v, want a, c, b", ids)
+	}
+}
+
+func TestRerankFailureKeepsSimilarityOrder(t *testing.T) {
+	env, stub := newRerankEnv(t, 20)
+	stub.scoreReply("content of a", "1")
+	stub.scoreReply("content of b", "no idea")
+	stub.scoreReply("content of c", "10")
+
+	results, err := env.svc.Retrieve(context.Background(), "query", "", 2, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if want := []string{"a-0", "b-0"}; !reflect.DeepEqual(resultIDs(results), want) {
+		t.Fatalf("results = %v, want the similarity order %v", resultIDs(results), want)
+	}
+	for _, result := range results {
+		if result.RerankScore != 0 {
+			t.Errorf("result %s rerank score = %g, want none after a failed rerank", result.Chunk.ID, result.RerankScore)
+		}
+	}
+}
+
+func TestRerankCandidatesBoundScoring(t *testing.T) {
+	env, stub := newRerankEnv(t, 2)
+	stub.scoreReply("content of a", "3")
+	stub.scoreReply("content of b", "6")
+	stub.scoreReply("content of c", "10")
+
+	results, err := env.svc.Retrieve(context.Background(), "query", "", 1, Scope{})
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+
+	// c is the least similar, so it is not among the 2 candidates however well it would score
+	if want := []string{"b-0"}; !reflect.DeepEqual(resultIDs(results), want) {
+		t.Fatalf("results = %v, want %v", resultIDs(results), want)
+	}
+	if calls := stub.scoringCalls(); calls != 2 {
+		t.Errorf("scoring calls = %d, want RERANK_CANDIDATES", calls)
+	}
+}
@@ -0,0 +1,396 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/document"
+	"github.com/mrkaynak/rag/internal/service/embeddings"
+	"github.com/mrkaynak/rag/internal/service/querylog"
+	"github.com/mrkaynak/rag/internal/service/rerank"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/pkg/errors"
+	"github.com/mrkaynak/rag/pkg/tokenizer"
+	"go.uber.org/zap"
+)
+
+// Score normalization modes
+const (
+	NormalizationRaw     = "raw"
+	NormalizationMinMax  = "minmax"
+	NormalizationPercent = "percent"
+)
+
+// Service retrieves relevant chunks for a query
+type Service struct {
+	cfg           *config.Config
+	logger        *zap.Logger
+	embeddingsSvc *embeddings.Service
+	vectorStore   *vector.Store
+	metadataStore *document.MetadataStore
+	reranker      *rerank.Reranker
+	queryLog      *querylog.Log
+}
+
+// New creates a new retrieval service
+func New(cfg *config.Config, logger *zap.Logger, embeddingsSvc *embeddings.Service, vectorStore *vector.Store, metadataStore *document.MetadataStore, reranker *rerank.Reranker, queryLog *querylog.Log) *Service {
+	return &Service{
+		cfg:           cfg,
+		logger:        logger,
+		embeddingsSvc: embeddingsSvc,
+		vectorStore:   vectorStore,
+		metadataStore: metadataStore,
+		reranker:      reranker,
+		queryLog:      queryLog,
+	}
+}
+
+// Retrieve embeds the query and returns the topK most similar chunks within scope, optionally
+// re-ranked by document recency (RECENCY_BOOST) and by a relevance model (RERANK_PROVIDER).
+// If embedding fails, or the vector search finds nothing, and EMBED_FALLBACK=keyword, a BM25
+// keyword search is used instead; Degraded reports such results.
+func (s *Service) Retrieve(ctx context.Context, query, apiKey string, topK int, scope Scope) ([]vector.SimilarityResult, error) {
+	return s.retrieve(ctx, query, apiKey, topK, scope, true)
+}
+
+// retrieve implements Retrieve; record adds the query embedding to the query log
+// (QUERY_LOGGING), which is only wanted for the caller's own query, not for
+// generated variants
+func (s *Service) retrieve(ctx context.Context, query, apiKey string, topK int, scope Scope, record bool) ([]vector.SimilarityResult, error) {
+	embedQuery, err := s.fitQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err = s.resolveScope(ctx, scope)
+	if err != nil {
+		s.logger.Error("failed to resolve retrieval scope", zap.Error(err))
+		return nil, errors.InternalWrap(err, "failed to resolve search scope")
+	}
+
+	chunks, err := s.embedQuery(ctx, embedQuery, apiKey)
+	if err == nil && len(chunks) == 0 {
+		err = fmt.Errorf("provider returned an unusable embedding for the query")
+	}
+	if err != nil {
+		if s.cfg.RAG.EmbedFallback == "keyword" {
+			s.logger.Warn("query embedding failed, falling back to keyword search", zap.Error(err))
+			return s.rank(ctx, query, s.keywordSearch(ctx, query, s.candidateCount(topK), scope), topK), nil
+		}
+
+		s.logger.Error("failed to generate query embedding", zap.Error(err))
+		return nil, errors.InternalWrap(err, "failed to generate query embedding").WithCode(errors.CodeEmbeddingFailed)
+	}
+
+	if record {
+		s.queryLog.RecordEmbedding(query, chunks[0].Embedding)
+	}
+
+	results, err := s.search(ctx, chunks[0].Embedding, s.candidateCount(topK), scope)
+	if err != nil {
+		s.logger.Error("failed to search vector store", zap.Error(err))
+		return nil, errors.InternalWrap(err, "failed to search context")
+	}
+
+	if len(results) == 0 && s.cfg.RAG.EmbedFallback == "keyword" {
+		s.logger.Warn("vector search found nothing, falling back to keyword search")
+		return s.rank(ctx, query, s.keywordSearch(ctx, query, s.candidateCount(topK), scope), topK), nil
+	}
+
+	return s.rank(ctx, query, results, topK), nil
+}
+
+// Degraded reports whether any of the results came from the EMBED_FALLBACK keyword search
+// rather than the vector search
+func Degraded(results []vector.SimilarityResult) bool {
+	for _, result := range results {
+		if result.KeywordScore > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// embedQuery embeds the query with the model the vector store was built with. A store
+// stamped with a different provider or model than the configured one would otherwise be
+// searched with incompatible vectors; EMBEDDING_QUERY_MODEL=config opts out.
+func (s *Service) embedQuery(ctx context.Context, query, apiKey string) ([]models.Chunk, error) {
+	chunks := []models.Chunk{{Content: query}}
+
+	stamp := s.vectorStore.Model()
+	configured := vector.ModelStamp{Provider: s.cfg.Embeddings.Provider, Model: s.cfg.Embeddings.Model}
+	if stamp.IsZero() || stamp == configured || s.cfg.Embeddings.QueryModel == "config" {
+		return s.embeddingsSvc.GenerateEmbeddings(ctx, chunks, apiKey)
+	}
+
+	provider, err := s.embeddingsSvc.ResolveProvider(stamp.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("the index was built with %s/%s, which cannot be used for queries: %w", stamp.Provider, stamp.Model, err)
+	}
+
+	return s.embeddingsSvc.GenerateEmbeddingsWithModel(ctx, provider, stamp.Model, chunks, s.embeddingsSvc.APIKeyFor(provider))
+}
+
+// fitQuery applies EMBEDDING_QUERY_TRUNCATION to queries exceeding the embedding model's
+// input limit: truncate_head drops the beginning, truncate_tail drops the end, error rejects.
+func (s *Service) fitQuery(query string) (string, error) {
+	limit := s.cfg.Embeddings.MaxInputTokens
+	tokens := tokenizer.EstimateTokens(query)
+	if tokens <= limit {
+		return query, nil
+	}
+
+	strategy := s.cfg.Embeddings.QueryTruncation
+	if strategy == "error" {
+		return "", errors.BadRequest(fmt.Sprintf(
+			"message is too long to embed (~%d tokens, limit %d)", tokens, limit))
+	}
+
+	truncated := tokenizer.TruncateTokens(query, limit, strategy == "truncate_head")
+
+	s.logger.Info("truncated query for embedding",
+		zap.String("strategy", strategy),
+		zap.Int("tokens", tokens),
+		zap.Int("limit", limit),
+	)
+
+	return truncated, nil
+}
+
+// RetrieveMulti runs Retrieve for every query and merges the results by chunk ID,
+// keeping each chunk's best score. The first query is the caller's own; the rest are
+// variants of it. Sub-queries that fail are logged and skipped; an error is only returned
+// when all of them fail, and is then the caller's query's error.
+func (s *Service) RetrieveMulti(ctx context.Context, queries []string, apiKey string, topK int, scope Scope) ([]vector.SimilarityResult, error) {
+	if len(queries) == 1 {
+		return s.Retrieve(ctx, queries[0], apiKey, topK, scope)
+	}
+
+	// Resolve once rather than per sub-query
+	scope, err := s.resolveScope(ctx, scope)
+	if err != nil {
+		s.logger.Error("failed to resolve retrieval scope", zap.Error(err))
+		return nil, errors.InternalWrap(err, "failed to resolve search scope")
+	}
+
+	resultSets := make([][]vector.SimilarityResult, len(queries))
+	errs := make([]error, len(queries))
+
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resultSets[i], errs[i] = s.retrieve(ctx, query, apiKey, topK, scope, i == 0)
+		}()
+	}
+	wg.Wait()
+
+	best := make(map[string]vector.SimilarityResult)
+	failed := 0
+
+	for i, results := range resultSets {
+		if errs[i] != nil {
+			failed++
+			s.logger.Warn("sub-query retrieval failed",
+				zap.Int("query_index", i),
+				zap.String("query", queries[i]),
+				zap.Error(errs[i]),
+			)
+			continue
+		}
+
+		for _, result := range results {
+			if existing, ok := best[result.Chunk.ID]; !ok || result.Similarity > existing.Similarity {
+				best[result.Chunk.ID] = result
+			}
+		}
+	}
+
+	if failed == len(queries) {
+		// Report the caller's own query's error with its status and code, e.g. a 400 for a
+		// message too long to embed, rather than a generic failure
+		if appErr, ok := errs[0].(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.InternalWrap(errs[0], fmt.Sprintf("all %d sub-queries failed", len(queries)))
+	}
+
+	merged := make([]vector.SimilarityResult, 0, len(best))
+	for _, result := range best {
+		merged = append(merged, result)
+	}
+
+	vector.SortResults(merged)
+
+	if topK < len(merged) {
+		merged = merged[:topK]
+	}
+
+	return merged, nil
+}
+
+// perDocumentCandidateFactor controls how many extra candidates are fetched when chunks per
+// document are capped, so other documents can fill the slots a dominant one gives up
+const perDocumentCandidateFactor = 4
+
+// RetrieveContext retrieves the chunks used as chat context. When MAX_CHUNKS_PER_DOCUMENT
+// is set, a wider candidate set is retrieved and no document contributes more than that
+// many chunks to the topK results. CONTEXT_MIN_SCORE is applied last (see ThresholdContext).
+func (s *Service) RetrieveContext(ctx context.Context, queries []string, apiKey string, topK int, scope Scope) ([]vector.SimilarityResult, error) {
+	maxPerDoc := s.cfg.RAG.MaxChunksPerDocument
+	if maxPerDoc <= 0 {
+		results, err := s.RetrieveMulti(ctx, queries, apiKey, topK, scope)
+		if err != nil {
+			return nil, err
+		}
+		return s.ThresholdContext(results), nil
+	}
+
+	candidates, err := s.RetrieveMulti(ctx, queries, apiKey, topK*perDocumentCandidateFactor, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ThresholdContext(LimitPerDocument(candidates, maxPerDoc, topK)), nil
+}
+
+// LimitPerDocument keeps results in order, skipping chunks from documents that already
+// contributed maxPerDoc chunks, until topK results are selected
+func LimitPerDocument(results []vector.SimilarityResult, maxPerDoc, topK int) []vector.SimilarityResult {
+	counts := make(map[string]int)
+	limited := make([]vector.SimilarityResult, 0, min(topK, len(results)))
+
+	for _, result := range results {
+		if len(limited) == topK {
+			break
+		}
+		if counts[result.Chunk.DocID] >= maxPerDoc {
+			continue
+		}
+
+		counts[result.Chunk.DocID]++
+		limited = append(limited, result)
+	}
+
+	return limited
+}
+
+// ToRetrievedChunks converts search results into response DTOs with a relevance score
+// computed using the configured SCORE_NORMALIZATION mode. Raw embeddings are left out
+// unless includeEmbeddings is set, so API responses don't ship large float arrays. With
+// explain set, each chunk carries the scoring details behind its rank.
+func (s *Service) ToRetrievedChunks(results []vector.SimilarityResult, includeEmbeddings, explain bool) []models.RetrievedChunk {
+	relevance := Normalize(results, s.cfg.RAG.ScoreNormalization)
+
+	chunks := make([]models.RetrievedChunk, 0, len(results))
+	for i, result := range results {
+		chunk := models.RetrievedChunk{
+			ID:        result.Chunk.ID,
+			DocID:     result.Chunk.DocID,
+			Index:     result.Chunk.Index,
+			Heading:   result.Chunk.Heading,
+			Namespace: chunkNamespace(result.Chunk),
+			Content:   result.Chunk.Content,
+			Score:     result.Similarity,
+			Relevance: relevance[i],
+
+			LowConfidence: result.LowConfidence,
+		}
+		if includeEmbeddings {
+			chunk.Embedding = result.Chunk.Embedding
+		}
+		if explain {
+			chunk.Debug = explainResult(result, i+1)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// ToSources describes results as sources, in order, with their document's file name
+// (omitted when the metadata cannot be read) and a SCORE_NORMALIZATION relevance
+func (s *Service) ToSources(results []vector.SimilarityResult) []models.Source {
+	relevance := Normalize(results, s.cfg.RAG.ScoreNormalization)
+	fileNames := make(map[string]string)
+
+	sources := make([]models.Source, 0, len(results))
+	for i, result := range results {
+		docID := result.Chunk.DocID
+		fileName, ok := fileNames[docID]
+		if !ok {
+			if doc, err := s.metadataStore.Get(docID); err == nil {
+				fileName = doc.FileName
+			} else {
+				s.logger.Debug("no metadata for source document", zap.String("doc_id", docID), zap.Error(err))
+			}
+			fileNames[docID] = fileName
+		}
+
+		sources = append(sources, models.Source{
+			DocID:     docID,
+			FileName:  fileName,
+			ChunkID:   result.Chunk.ID,
+			Index:     result.Chunk.Index,
+			Heading:   result.Chunk.Heading,
+			Namespace: chunkNamespace(result.Chunk),
+			Score:     result.Similarity,
+			Relevance: relevance[i],
+		})
+	}
+
+	return sources
+}
+
+// keywordPercentPivot is the BM25 score that maps to 50 in percent mode. BM25 scores are
+// unbounded, so keyword fallback results are squashed into [0, 1) as score/(score+pivot).
+const keywordPercentPivot = 5.0
+
+// Normalize maps raw similarity scores to relevance values:
+//   - raw: the cosine similarity unchanged
+//   - minmax: 0-100 scaled between the lowest and highest score of the result set
+//   - percent: cosine similarity clamped to [0, 1] and mapped to 0-100; BM25 scores of
+//     keyword fallback results are first squashed into [0, 1) (see keywordPercentPivot)
+func Normalize(results []vector.SimilarityResult, mode string) []float64 {
+	relevance := make([]float64, len(results))
+
+	switch mode {
+	case NormalizationMinMax:
+		if len(results) == 0 {
+			return relevance
+		}
+
+		minScore, maxScore := results[0].Similarity, results[0].Similarity
+		for _, result := range results {
+			minScore = min(minScore, result.Similarity)
+			maxScore = max(maxScore, result.Similarity)
+		}
+
+		for i, result := range results {
+			if maxScore == minScore {
+				relevance[i] = 100
+				continue
+			}
+			relevance[i] = (result.Similarity - minScore) / (maxScore - minScore) * 100
+		}
+	case NormalizationPercent:
+		for i, result := range results {
+			score := result.Similarity
+			// Reranked results already hold a 0-1 relevance score
+			if result.KeywordScore > 0 && result.RerankScore == 0 && score > 0 {
+				score /= score + keywordPercentPivot
+			}
+			relevance[i] = min(max(score, 0), 1) * 100
+		}
+	default:
+		for i, result := range results {
+			relevance[i] = result.Similarity
+		}
+	}
+
+	return relevance
+}
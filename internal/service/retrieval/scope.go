@@ -0,0 +1,162 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrkaynak/rag/internal/models"
+	"github.com/mrkaynak/rag/internal/service/tracing"
+	"github.com/mrkaynak/rag/internal/service/vector"
+	"github.com/mrkaynak/rag/internal/tenant"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Scope restricts which chunks a retrieval considers. The zero value searches everything.
+type Scope struct {
+	// Namespaces lists the namespaces to search; empty means all namespaces
+	Namespaces []string
+	// UploadedAfter and UploadedBefore bound the upload time of the chunk's document
+	// (after is inclusive, before is exclusive); zero values leave that side open
+	UploadedAfter  time.Time
+	UploadedBefore time.Time
+
+	// tenantID is the caller's tenant once resolved; it is taken from the request context
+	// rather than set by callers so tenant isolation cannot be bypassed
+	tenantID string
+	// docIDs holds the documents within the upload range once resolved (nil when unbounded)
+	docIDs map[string]bool
+}
+
+// dateBounded reports whether the scope restricts documents by upload time
+func (sc Scope) dateBounded() bool {
+	return !sc.UploadedAfter.IsZero() || !sc.UploadedBefore.IsZero()
+}
+
+// resolveScope binds the scope to the caller's tenant when TENANT_HEADER is set and joins
+// the scope's upload range against document metadata, recording the documents it admits.
+// Without a tenant in ctx, tenant isolation fails closed.
+func (s *Service) resolveScope(ctx context.Context, scope Scope) (Scope, error) {
+	if s.cfg.Server.TenantHeader != "" {
+		scope.tenantID = tenant.FromContext(ctx)
+		if scope.tenantID == "" {
+			return scope, fmt.Errorf("no tenant in request context")
+		}
+	}
+
+	if !scope.dateBounded() || scope.docIDs != nil {
+		return scope, nil
+	}
+
+	docs, err := s.metadataStore.List()
+	if err != nil {
+		return scope, fmt.Errorf("failed to list document metadata: %w", err)
+	}
+
+	scope.docIDs = make(map[string]bool)
+	for _, doc := range docs {
+		if !scope.UploadedAfter.IsZero() && doc.UploadedAt.Before(scope.UploadedAfter) {
+			continue
+		}
+		if !scope.UploadedBefore.IsZero() && !doc.UploadedAt.Before(scope.UploadedBefore) {
+			continue
+		}
+		scope.docIDs[doc.ID] = true
+	}
+
+	return scope, nil
+}
+
+// filter returns the vector filter for the scope, or nil if it matches every chunk
+func (sc Scope) filter() vector.Filter {
+	if len(sc.Namespaces) == 0 && sc.docIDs == nil && sc.tenantID == "" {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(sc.Namespaces))
+	for _, ns := range sc.Namespaces {
+		allowed[ns] = true
+	}
+
+	return func(chunk models.Chunk) bool {
+		// Chunks indexed before tenant isolation was turned on belong to no tenant
+		if sc.tenantID != "" && chunk.TenantID != sc.tenantID {
+			return false
+		}
+		if len(allowed) > 0 && !allowed[chunkNamespace(chunk)] {
+			return false
+		}
+		return sc.docIDs == nil || sc.docIDs[chunk.DocID]
+	}
+}
+
+// withNamespace returns a copy of the scope limited to a single namespace
+func (sc Scope) withNamespace(ns string) Scope {
+	sc.Namespaces = []string{ns}
+	return sc
+}
+
+// chunkNamespace returns the namespace of a chunk, treating chunks indexed before
+// namespaces existed as part of the default namespace
+func chunkNamespace(chunk models.Chunk) string {
+	if chunk.Namespace == "" {
+		return models.DefaultNamespace
+	}
+	return chunk.Namespace
+}
+
+// search runs the vector search for a scope. With several namespaces each one is searched
+// separately and the results are merged by score, deduplicated by chunk ID.
+func (s *Service) search(ctx context.Context, embedding []float64, topK int, scope Scope) (merged []vector.SimilarityResult, err error) {
+	_, span := tracing.Start(ctx, "vector.search",
+		attribute.Int("search.top_k", topK),
+		attribute.StringSlice("search.namespaces", scope.Namespaces),
+	)
+	defer func() {
+		span.SetAttributes(attribute.Int("search.results", len(merged)))
+		tracing.End(span, err)
+	}()
+
+	if len(scope.Namespaces) <= 1 {
+		return s.vectorStore.SearchFiltered(embedding, topK, scope.filter())
+	}
+
+	seen := make(map[string]bool)
+
+	for _, ns := range scope.Namespaces {
+		results, err := s.vectorStore.SearchFiltered(embedding, topK, scope.withNamespace(ns).filter())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			if seen[result.Chunk.ID] {
+				continue
+			}
+			seen[result.Chunk.ID] = true
+			merged = append(merged, result)
+		}
+	}
+
+	vector.SortResults(merged)
+
+	if topK < len(merged) {
+		merged = merged[:topK]
+	}
+
+	return merged, nil
+}
+
+// keywordSearch runs the BM25 keyword search for a scope
+func (s *Service) keywordSearch(ctx context.Context, query string, topK int, scope Scope) []vector.SimilarityResult {
+	_, span := tracing.Start(ctx, "vector.keyword_search",
+		attribute.Int("search.top_k", topK),
+		attribute.StringSlice("search.namespaces", scope.Namespaces),
+	)
+	defer span.End()
+
+	results := s.vectorStore.KeywordSearch(query, topK, scope.filter())
+	span.SetAttributes(attribute.Int("search.results", len(results)))
+
+	return results
+}
@@ -0,0 +1,50 @@
+package retrieval
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRetrieveUploadDateRange(t *testing.T) {
+	cfg, _ := testConfig(t)
+	env := newTestEnv(t, cfg)
+
+	now := time.Now()
+	env.addDocument(t, "last-month", now.AddDate(0, 0, -30), []float64{1, 0, 0})
+	env.addDocument(t, "last-week", now.AddDate(0, 0, -5), []float64{1, 0.1, 0})
+	env.addDocument(t, "today", now, []float64{1, 0.2, 0})
+
+	weekAgo := now.AddDate(0, 0, -7)
+	yesterday := now.AddDate(0, 0, -1)
+
+	tests := []struct {
+		name  string
+		scope Scope
+		want  []string
+	}{
+		{"unbounded", Scope{}, []string{"last-month-0", "last-week-0", "today-0"}},
+		{"after excludes older documents", Scope{UploadedAfter: weekAgo}, []string{"last-week-0", "today-0"}},
+		{"before excludes newer documents", Scope{UploadedBefore: weekAgo}, []string{"last-month-0"}},
+		{"bounded on both sides", Scope{UploadedAfter: weekAgo, UploadedBefore: yesterday}, []string{"last-week-0"}},
+		{"after is inclusive", Scope{UploadedAfter: now}, []string{"today-0"}},
+		{"no documents in range", Scope{UploadedAfter: now.Add(time.Hour)}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := env.svc.Retrieve(context.Background(), "what changed this week?", "", 10, tt.scope)
+			if err != nil {
+				t.Fatalf("Retrieve failed: %v", err)
+			}
+
+			ids := resultIDs(results)
+			sort.Strings(ids)
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("results = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -27,27 +27,43 @@ type APIKeys struct {
 // ModelConfig represents a model configuration
 type ModelConfig struct {
 	ID          string  `json:"id"`
-	Provider    string  `json:"provider"`
-	ModelID     string  `json:"model_id"`
-	DisplayName string  `json:"display_name"`
+	Provider    string  `json:"provider" validate:"required"`
+	ModelID     string  `json:"model_id" validate:"required"`
+	DisplayName string  `json:"display_name" validate:"required"`
 	MaxTokens   int     `json:"max_tokens,omitempty"`
 	Temperature float64 `json:"temperature,omitempty"`
+	// Stop is the default stop sequences for chats with this model
+	Stop []string `json:"stop,omitempty"`
 }
 
 // SystemPrompt represents a system prompt configuration
 type SystemPrompt struct {
 	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Prompt  string `json:"prompt"`
+	Name    string `json:"name" validate:"required"`
+	Prompt  string `json:"prompt" validate:"required"`
 	Default bool   `json:"default"`
 }
 
+// DefaultModel points a provider at the saved model its chats use when none is requested
+type DefaultModel struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id" validate:"required"`
+}
+
+// NamespacePrompt is the default system prompt for chats scoped to a namespace
+type NamespacePrompt struct {
+	Namespace string `json:"namespace"`
+	Prompt    string `json:"prompt" validate:"required"`
+}
+
 // BadgerDB key prefixes
 const (
-	prefixAPIKeys       = "apikeys:"
-	prefixModel         = "model:"
-	prefixSystemPrompt  = "prompt:"
-	prefixDefaultPrompt = "default_prompt"
+	prefixAPIKeys         = "apikeys:"
+	prefixModel           = "model:"
+	prefixSystemPrompt    = "prompt:"
+	prefixDefaultPrompt   = "default_prompt"
+	prefixDefaultModel    = "default_model:"
+	prefixNamespacePrompt = "namespace_prompt:"
 )
 
 // New creates a new settings store (opens its own DB)
@@ -228,6 +244,53 @@ func (s *Store) DeleteModel(id string) error {
 	})
 }
 
+// SetDefaultModel makes a saved model the default for its provider
+func (s *Store) SetDefaultModel(provider, id string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(prefixDefaultModel+provider), []byte(id))
+	})
+}
+
+// GetDefaultModel retrieves the default model of a provider. An empty model is returned if
+// none is set or the model it pointed to was deleted.
+func (s *Store) GetDefaultModel(provider string) (ModelConfig, error) {
+	var modelID string
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(prefixDefaultModel + provider))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			modelID = string(val)
+			return nil
+		})
+	})
+
+	if err == badger.ErrKeyNotFound {
+		return ModelConfig{}, nil
+	}
+
+	if err != nil {
+		return ModelConfig{}, err
+	}
+
+	model, err := s.GetModel(modelID)
+	if err == badger.ErrKeyNotFound {
+		return ModelConfig{}, nil
+	}
+
+	return model, err
+}
+
+// ClearDefaultModel removes the default model of a provider
+func (s *Store) ClearDefaultModel(provider string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(prefixDefaultModel + provider))
+	})
+}
+
 // === System Prompts ===
 
 // SaveSystemPrompt saves a system prompt
@@ -354,6 +417,50 @@ func (s *Store) DeleteSystemPrompt(id string) error {
 	})
 }
 
+// === Namespace Prompts ===
+
+// SaveNamespacePrompt sets the default system prompt of a namespace, replacing any existing one
+func (s *Store) SaveNamespacePrompt(prompt NamespacePrompt) error {
+	data, err := json.Marshal(prompt)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(prefixNamespacePrompt+prompt.Namespace), data)
+	})
+}
+
+// GetNamespacePrompt retrieves the default system prompt of a namespace.
+// An empty prompt is returned if none is set.
+func (s *Store) GetNamespacePrompt(namespace string) (NamespacePrompt, error) {
+	var prompt NamespacePrompt
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(prefixNamespacePrompt + namespace))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &prompt)
+		})
+	})
+
+	if err == badger.ErrKeyNotFound {
+		return NamespacePrompt{Namespace: namespace}, nil
+	}
+
+	return prompt, err
+}
+
+// DeleteNamespacePrompt removes the default system prompt of a namespace
+func (s *Store) DeleteNamespacePrompt(namespace string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(prefixNamespacePrompt + namespace))
+	})
+}
+
 // === Encryption Helpers ===
 
 func (s *Store) encrypt(data []byte) []byte {
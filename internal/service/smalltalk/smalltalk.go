@@ -0,0 +1,92 @@
+package smalltalk
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule maps a small-talk pattern to a canned reply
+type Rule struct {
+	Pattern string `json:"pattern"`
+	Reply   string `json:"reply"`
+}
+
+// DefaultRules cover common greetings, thanks and goodbyes
+var DefaultRules = []Rule{
+	{
+		Pattern: `^(hi|hello|hey|hiya|good (morning|afternoon|evening))( there)?[\s!.]*$`,
+		Reply:   "Hello! Ask me anything about your documents.",
+	},
+	{
+		Pattern: `^(thanks|thank you|thx|ty|cheers)( so much| a lot| very much)?[\s!.]*$`,
+		Reply:   "You're welcome! Let me know if you have any other questions.",
+	},
+	{
+		Pattern: `^(bye|goodbye|see you|see ya)( later)?[\s!.]*$`,
+		Reply:   "Goodbye!",
+	},
+}
+
+// compiledRule is a Rule with its pattern compiled
+type compiledRule struct {
+	pattern *regexp.Regexp
+	reply   string
+}
+
+// Classifier detects small-talk messages that can be answered without retrieval or an LLM call
+type Classifier struct {
+	rules []compiledRule
+}
+
+// New creates a classifier from a JSON array of rules ([{"pattern": "...", "reply": "..."}]).
+// An empty rulesJSON uses DefaultRules. Patterns are matched case-insensitively against the
+// trimmed message.
+func New(rulesJSON string) (*Classifier, error) {
+	rules := DefaultRules
+	if strings.TrimSpace(rulesJSON) != "" {
+		// Decoded into a fresh slice: decoding into DefaultRules would overwrite its
+		// elements and fill missing fields from them
+		rules = nil
+		if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+			return nil, fmt.Errorf("invalid SMALLTALK_RULES: %w", err)
+		}
+	}
+
+	c := &Classifier{}
+	for _, rule := range rules {
+		if rule.Reply == "" {
+			return nil, fmt.Errorf("invalid SMALLTALK_RULES: pattern %q has no reply", rule.Pattern)
+		}
+
+		pattern, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMALLTALK_RULES pattern %q: %w", rule.Pattern, err)
+		}
+
+		c.rules = append(c.rules, compiledRule{pattern: pattern, reply: rule.Reply})
+	}
+
+	return c, nil
+}
+
+// Match returns the canned reply for a small-talk message. A nil classifier never matches.
+func (c *Classifier) Match(message string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", false
+	}
+
+	for _, rule := range c.rules {
+		if rule.pattern.MatchString(message) {
+			return rule.reply, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,64 @@
+package smalltalk
+
+import "testing"
+
+func TestDefaultRules(t *testing.T) {
+	c, err := New("")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		message string
+		match   bool
+	}{
+		{"hi", true},
+		{"  Hello there! ", true},
+		{"Good morning.", true},
+		{"thanks a lot!", true},
+		{"bye", true},
+		{"hi, how do I configure the reranker?", false},
+		{"thanks, but what does CHUNK_SIZE do?", false},
+		{"What is RAG?", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := c.Match(tt.message); ok != tt.match {
+			t.Errorf("Match(%q) = %v, want %v", tt.message, ok, tt.match)
+		}
+	}
+}
+
+func TestCustomRules(t *testing.T) {
+	c, err := New(`[{"pattern": "^merhaba$", "reply": "Merhaba!"}]`)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if reply, ok := c.Match("MERHABA"); !ok || reply != "Merhaba!" {
+		t.Errorf("Match(MERHABA) = %q, %v; want the custom reply", reply, ok)
+	}
+	if _, ok := c.Match("hi"); ok {
+		t.Error("custom rules still match the default greetings")
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	for _, rules := range []string{
+		`not json`,
+		`[{"pattern": "^hi$"}]`,
+		`[{"pattern": "(", "reply": "x"}]`,
+	} {
+		if _, err := New(rules); err == nil {
+			t.Errorf("New(%s) succeeded, want an error", rules)
+		}
+	}
+}
+
+func TestNilClassifierNeverMatches(t *testing.T) {
+	var c *Classifier
+	if _, ok := c.Match("hi"); ok {
+		t.Error("nil classifier matched")
+	}
+}
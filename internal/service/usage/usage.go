@@ -0,0 +1,194 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// keyTotals is the BadgerDB key of the persisted usage counters
+const keyTotals = "usage:totals"
+
+// Price is the USD cost per million input and output tokens of a model
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Tracker accumulates LLM token usage and estimated cost per provider and model. It is safe
+// for concurrent use.
+type Tracker struct {
+	db     *badger.DB // nil keeps the counters in memory only
+	prices map[string]Price
+
+	mu     sync.Mutex
+	since  time.Time
+	totals map[string]*models.ModelUsage
+}
+
+// persistedTotals is the stored form of the counters
+type persistedTotals struct {
+	Since  time.Time            `json:"since"`
+	Models []*models.ModelUsage `json:"models"`
+}
+
+// New creates a usage tracker. pricesJSON maps model IDs to per-million-token prices
+// ({"gpt-4o": {"input": 2.5, "output": 10}}); the "*" entry prices any unlisted model.
+// When db is set, the counters are persisted and survive restarts.
+func New(pricesJSON string, db *badger.DB) (*Tracker, error) {
+	prices := make(map[string]Price)
+	if strings.TrimSpace(pricesJSON) != "" {
+		if err := json.Unmarshal([]byte(pricesJSON), &prices); err != nil {
+			return nil, fmt.Errorf("invalid USAGE_PRICES: %w", err)
+		}
+	}
+
+	for model, price := range prices {
+		if price.Input < 0 || price.Output < 0 {
+			return nil, fmt.Errorf("invalid USAGE_PRICES: negative price for model %q", model)
+		}
+	}
+
+	t := &Tracker{
+		db:     db,
+		prices: prices,
+		since:  time.Now(),
+		totals: make(map[string]*models.ModelUsage),
+	}
+
+	if err := t.load(); err != nil {
+		return nil, fmt.Errorf("failed to load usage counters: %w", err)
+	}
+
+	return t, nil
+}
+
+// Record adds the tokens of one LLM call. A nil tracker records nothing.
+func (t *Tracker) Record(provider, model string, inputTokens, outputTokens int) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := provider + "\x00" + model
+	entry, ok := t.totals[key]
+	if !ok {
+		entry = &models.ModelUsage{Provider: provider, Model: model}
+		t.totals[key] = entry
+	}
+
+	entry.Requests++
+	entry.InputTokens += int64(inputTokens)
+	entry.OutputTokens += int64(outputTokens)
+	entry.TotalTokens = entry.InputTokens + entry.OutputTokens
+
+	// Persistence is best effort; the in-memory counters stay authoritative
+	_ = t.save()
+}
+
+// Snapshot returns the accumulated usage with costs computed from the configured prices
+func (t *Tracker) Snapshot() models.UsageResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	resp := models.UsageResponse{
+		Since:     t.since,
+		Persisted: t.db != nil,
+		Models:    make([]models.ModelUsage, 0, len(t.totals)),
+	}
+
+	for _, entry := range t.totals {
+		modelUsage := *entry
+		if price, ok := t.priceFor(entry.Model); ok {
+			modelUsage.Priced = true
+			modelUsage.Cost = (float64(entry.InputTokens)*price.Input + float64(entry.OutputTokens)*price.Output) / 1e6
+		}
+
+		resp.Models = append(resp.Models, modelUsage)
+		resp.Requests += modelUsage.Requests
+		resp.InputTokens += modelUsage.InputTokens
+		resp.OutputTokens += modelUsage.OutputTokens
+		resp.Cost += modelUsage.Cost
+	}
+	resp.TotalTokens = resp.InputTokens + resp.OutputTokens
+
+	sort.Slice(resp.Models, func(i, j int) bool {
+		if resp.Models[i].Provider != resp.Models[j].Provider {
+			return resp.Models[i].Provider < resp.Models[j].Provider
+		}
+		return resp.Models[i].Model < resp.Models[j].Model
+	})
+
+	return resp
+}
+
+// priceFor returns the price of a model, falling back to the "*" entry
+func (t *Tracker) priceFor(model string) (Price, bool) {
+	if price, ok := t.prices[model]; ok {
+		return price, true
+	}
+	price, ok := t.prices["*"]
+	return price, ok
+}
+
+// load restores persisted counters
+func (t *Tracker) load() error {
+	if t.db == nil {
+		return nil
+	}
+
+	var stored persistedTotals
+	err := t.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyTotals))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &stored)
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !stored.Since.IsZero() {
+		t.since = stored.Since
+	}
+	for _, entry := range stored.Models {
+		t.totals[entry.Provider+"\x00"+entry.Model] = entry
+	}
+
+	return nil
+}
+
+// save persists the counters; the caller must hold t.mu
+func (t *Tracker) save() error {
+	if t.db == nil {
+		return nil
+	}
+
+	stored := persistedTotals{Since: t.since}
+	for _, entry := range t.totals {
+		stored.Models = append(stored.Models, entry)
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	return t.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyTotals), data)
+	})
+}
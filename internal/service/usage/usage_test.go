@@ -0,0 +1,110 @@
+package usage
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+func TestRecordIsConcurrencySafe(t *testing.T) {
+	tracker, err := New("", nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker.Record("openrouter", "model-a", 10, 5)
+		}()
+	}
+	wg.Wait()
+
+	snapshot := tracker.Snapshot()
+	if snapshot.Requests != 50 || snapshot.InputTokens != 500 || snapshot.OutputTokens != 250 || snapshot.TotalTokens != 750 {
+		t.Errorf("snapshot = %+v, want 50 requests of 10+5 tokens", snapshot)
+	}
+}
+
+func TestSnapshotCost(t *testing.T) {
+	tracker, err := New(`{"model-a": {"input": 2, "output": 10}, "*": {"input": 1, "output": 1}}`, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tracker.Record("openrouter", "model-a", 1_000_000, 500_000)
+	tracker.Record("bedrock", "model-b", 2_000_000, 0)
+
+	snapshot := tracker.Snapshot()
+	if len(snapshot.Models) != 2 {
+		t.Fatalf("models = %+v, want one entry per provider and model", snapshot.Models)
+	}
+
+	// Sorted by provider: bedrock first, priced by the "*" fallback
+	if m := snapshot.Models[0]; m.Model != "model-b" || !m.Priced || math.Abs(m.Cost-2) > 1e-9 {
+		t.Errorf("model-b = %+v, want a cost of 2 from the fallback price", m)
+	}
+	if m := snapshot.Models[1]; m.Model != "model-a" || !m.Priced || math.Abs(m.Cost-7) > 1e-9 {
+		t.Errorf("model-a = %+v, want a cost of 2 + 5", m)
+	}
+	if math.Abs(snapshot.Cost-9) > 1e-9 {
+		t.Errorf("total cost = %g, want 9", snapshot.Cost)
+	}
+}
+
+func TestUnpricedModel(t *testing.T) {
+	tracker, err := New(`{"model-a": {"input": 2, "output": 10}}`, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tracker.Record("openrouter", "model-b", 100, 100)
+	if m := tracker.Snapshot().Models[0]; m.Priced || m.Cost != 0 {
+		t.Errorf("unpriced model = %+v, want no cost", m)
+	}
+}
+
+func TestCountersPersist(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	first, err := New("", db)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	first.Record("openrouter", "model-a", 10, 5)
+
+	restarted, err := New("", db)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	restarted.Record("openrouter", "model-a", 10, 5)
+
+	snapshot := restarted.Snapshot()
+	if !snapshot.Persisted || snapshot.Requests != 2 || snapshot.TotalTokens != 30 {
+		t.Errorf("snapshot after restart = %+v, want both requests counted", snapshot)
+	}
+	if !snapshot.Since.Equal(first.Snapshot().Since) {
+		t.Errorf("since = %v, want the first start %v", snapshot.Since, first.Snapshot().Since)
+	}
+}
+
+func TestInvalidPrices(t *testing.T) {
+	for _, prices := range []string{`not json`, `{"model-a": {"input": -1, "output": 1}}`} {
+		if _, err := New(prices, nil); err == nil {
+			t.Errorf("New(%s) succeeded, want an error", prices)
+		}
+	}
+}
+
+func TestNilTrackerRecordsNothing(t *testing.T) {
+	var tracker *Tracker
+	tracker.Record("openrouter", "model-a", 10, 5)
+}
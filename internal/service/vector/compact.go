@@ -0,0 +1,147 @@
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CompactResult reports the outcome of a vector store compaction
+type CompactResult struct {
+	Chunks         int   `json:"chunks"`
+	BytesBefore    int64 `json:"bytes_before"`
+	BytesAfter     int64 `json:"bytes_after"`
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+	// PurgedChunks counts the soft-deleted chunks dropped by the compaction and
+	// PurgedDocIDs lists the documents they belonged to
+	PurgedChunks int      `json:"purged_chunks"`
+	PurgedDocIDs []string `json:"purged_doc_ids,omitempty"`
+}
+
+// CompactionStats describes how much of the vector store file a compaction would reclaim
+type CompactionStats struct {
+	// FileBytes is the current size of the vector store file
+	FileBytes     int64 `json:"file_bytes"`
+	LiveChunks    int   `json:"live_chunks"`
+	DeletedChunks int   `json:"deleted_chunks"`
+	// StaleChunks counts live chunks the file still holds in an older encoding
+	StaleChunks int `json:"stale_chunks"`
+	// StaleRatio is the share of the file's entries a compaction would drop or re-encode
+	StaleRatio float64 `json:"stale_ratio"`
+}
+
+// CompactionStats reports the vector store file size and the number of live, soft-deleted
+// and stale chunks. It only counts entries, so it is cheap enough to poll.
+func (s *Store) CompactionStats() (CompactionStats, error) {
+	filePath := filepath.Join(s.cfg.Storage.VectorStorePath, "vectors.json")
+
+	var stats CompactionStats
+
+	s.mu.RLock()
+	for _, chunk := range s.chunks {
+		if chunk.Deleted {
+			stats.DeletedChunks++
+		} else {
+			stats.LiveChunks++
+		}
+	}
+	s.mu.RUnlock()
+
+	stats.StaleChunks = int(s.staleChunks.Load())
+
+	if info, err := os.Stat(filePath); err == nil {
+		stats.FileBytes = info.Size()
+	} else if !os.IsNotExist(err) {
+		return stats, fmt.Errorf("failed to stat vector store: %w", err)
+	}
+
+	if total := stats.LiveChunks + stats.DeletedChunks; total > 0 {
+		stats.StaleRatio = float64(stats.DeletedChunks+stats.StaleChunks) / float64(total)
+	}
+
+	return stats, nil
+}
+
+// Compact permanently drops the chunks of soft-deleted documents and rewrites the vector
+// store file from the remaining chunks. The file is normally rewritten on every mutation, but
+// it keeps the encoding it was written with until then, so compaction also reclaims the space
+// of entries persisted under older VECTOR_QUANTIZATION or COMPRESS_CHUNK_TEXT settings. The
+// new file is written next to the old one and renamed over it, so a crash never leaves a
+// partial store. Searches keep running from memory; writes wait until the rewrite is done.
+// Deleted chunks are excluded from searches already, so search results are unchanged.
+func (s *Store) Compact() (CompactResult, error) {
+	filePath := filepath.Join(s.cfg.Storage.VectorStorePath, "vectors.json")
+
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
+	var result CompactResult
+
+	s.mu.Lock()
+	purged := make(map[string]bool)
+	for id, chunk := range s.chunks {
+		if chunk.Deleted {
+			delete(s.chunks, id)
+			purged[chunk.DocID] = true
+			result.PurgedChunks++
+		}
+	}
+	if result.PurgedChunks > 0 {
+		s.version.Add(1)
+	}
+	s.mu.Unlock()
+
+	for docID := range purged {
+		result.PurgedDocIDs = append(result.PurgedDocIDs, docID)
+	}
+	sort.Strings(result.PurgedDocIDs)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result.Chunks = len(s.chunks)
+
+	if info, err := os.Stat(filePath); err == nil {
+		result.BytesBefore = info.Size()
+	} else if !os.IsNotExist(err) {
+		return result, fmt.Errorf("failed to stat vector store: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s.chunks, "", "  ")
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal chunks: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.cfg.Storage.VectorStorePath, ".vectors-compact-*")
+	if err != nil {
+		return result, fmt.Errorf("failed to create compacted vector store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return result, fmt.Errorf("failed to write compacted vector store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return result, fmt.Errorf("failed to sync compacted vector store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return result, fmt.Errorf("failed to close compacted vector store: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return result, fmt.Errorf("failed to set vector store permissions: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return result, fmt.Errorf("failed to replace vector store: %w", err)
+	}
+
+	s.staleChunks.Store(0)
+	result.BytesAfter = int64(len(data))
+	result.ReclaimedBytes = max(result.BytesBefore-result.BytesAfter, 0)
+
+	return result, nil
+}
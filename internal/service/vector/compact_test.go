@@ -0,0 +1,147 @@
+package vector
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func fileSize(t testing.TB, store *Store) int64 {
+	t.Helper()
+
+	info, err := os.Stat(filepath.Join(store.cfg.Storage.VectorStorePath, "vectors.json"))
+	if err != nil {
+		t.Fatalf("failed to stat vector store: %v", err)
+	}
+	return info.Size()
+}
+
+func TestCompactDropsDeletedChunks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	store := newTestStore(t, testConfig(t))
+
+	if err := store.Add(randomChunks(rng, 200, 20, 16)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	for i := 0; i < 15; i++ {
+		if err := store.DeleteByDocID(fmt.Sprintf("doc-%d", i)); err != nil {
+			t.Fatalf("DeleteByDocID failed: %v", err)
+		}
+	}
+
+	query := randomEmbedding(rng, 16)
+	before, err := store.Search(query, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	sizeBefore := fileSize(t, store)
+
+	result, err := store.Compact()
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	if result.PurgedChunks != 150 || len(result.PurgedDocIDs) != 15 {
+		t.Errorf("purged %d chunks of %d documents, want 150 of 15", result.PurgedChunks, len(result.PurgedDocIDs))
+	}
+	if result.Chunks != 50 || len(store.GetAllIncludingDeleted()) != 50 {
+		t.Errorf("%d chunks left, want 50", result.Chunks)
+	}
+
+	sizeAfter := fileSize(t, store)
+	if sizeAfter >= sizeBefore/2 {
+		t.Errorf("file shrank from %d to %d bytes, want below half", sizeBefore, sizeAfter)
+	}
+	if result.BytesBefore != sizeBefore || result.BytesAfter != sizeAfter || result.ReclaimedBytes != sizeBefore-sizeAfter {
+		t.Errorf("reported %+v, file went from %d to %d bytes", result, sizeBefore, sizeAfter)
+	}
+
+	after, err := store.Search(query, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if !reflect.DeepEqual(resultIDs(before), resultIDs(after)) {
+		t.Errorf("results changed by compaction: %v, then %v", resultIDs(before), resultIDs(after))
+	}
+
+	// The compacted file reloads to the same contents
+	reloaded := newTestStore(t, store.cfg)
+	if reloaded.Count() != 50 || len(reloaded.GetAllIncludingDeleted()) != 50 {
+		t.Errorf("reloaded store has %d chunks, want 50", len(reloaded.GetAllIncludingDeleted()))
+	}
+}
+
+func TestCompactWithoutDeletesKeepsVersion(t *testing.T) {
+	store := newTestStore(t, testConfig(t))
+	if err := store.Add(randomChunks(rand.New(rand.NewSource(1)), 10, 2, 4)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	version := store.Version()
+	result, err := store.Compact()
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	if result.PurgedChunks != 0 || result.Chunks != 10 {
+		t.Errorf("compaction of a store without deletes = %+v", result)
+	}
+	if store.Version() != version {
+		t.Error("a compaction that dropped nothing changed the store version")
+	}
+}
+
+func TestCompactionStats(t *testing.T) {
+	store := newTestStore(t, testConfig(t))
+	if err := store.Add(randomChunks(rand.New(rand.NewSource(1)), 10, 5, 4)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.DeleteByDocID("doc-0"); err != nil {
+		t.Fatalf("DeleteByDocID failed: %v", err)
+	}
+
+	stats, err := store.CompactionStats()
+	if err != nil {
+		t.Fatalf("CompactionStats failed: %v", err)
+	}
+	if stats.LiveChunks != 8 || stats.DeletedChunks != 2 || stats.StaleChunks != 0 || stats.StaleRatio != 0.2 {
+		t.Errorf("stats = %+v, want 8 live, 2 deleted and a 0.2 ratio", stats)
+	}
+	if stats.FileBytes != fileSize(t, store) {
+		t.Errorf("file_bytes = %d, want %d", stats.FileBytes, fileSize(t, store))
+	}
+}
+
+func TestCompactionStatsCountsStaleEncoding(t *testing.T) {
+	cfg := testConfig(t)
+	store := newTestStore(t, cfg)
+	if err := store.Add(randomChunks(rand.New(rand.NewSource(1)), 10, 5, 4)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.DeleteByDocID("doc-0"); err != nil {
+		t.Fatalf("DeleteByDocID failed: %v", err)
+	}
+
+	// Reopening with another encoding leaves the file in the old one
+	cfg.Storage.VectorQuantization = QuantizationFloat32
+	reopened := newTestStore(t, cfg)
+
+	stats, err := reopened.CompactionStats()
+	if err != nil {
+		t.Fatalf("CompactionStats failed: %v", err)
+	}
+	if stats.StaleChunks != 8 || stats.StaleRatio != 1 {
+		t.Errorf("stats after an encoding change = %+v, want 8 stale chunks and a ratio of 1", stats)
+	}
+
+	if _, err := reopened.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if stats, _ := reopened.CompactionStats(); stats.StaleChunks != 0 || stats.StaleRatio != 0 {
+		t.Errorf("stats after compaction = %+v, want nothing stale", stats)
+	}
+}
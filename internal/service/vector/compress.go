@@ -0,0 +1,60 @@
+package vector
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// compressText deflates chunk text for in-memory storage
+func compressText(text string) []byte {
+	var buf bytes.Buffer
+
+	// Writing to a bytes.Buffer cannot fail and the level is valid
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	w.Write([]byte(text))
+	w.Close()
+
+	return buf.Bytes()
+}
+
+// decompressText inflates text produced by compressText
+func decompressText(data []byte) (string, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress chunk text: %w", err)
+	}
+
+	return string(text), nil
+}
+
+// chunkContent returns a chunk's text, inflating it if compressed. Compressed text is either
+// produced in-process or verified by checkCompressedContent on load, so it always inflates.
+func chunkContent(chunk models.Chunk) string {
+	if chunk.CompressedContent == nil {
+		return chunk.Content
+	}
+
+	text, _ := decompressText(chunk.CompressedContent)
+	return text
+}
+
+// checkCompressedContent verifies that every compressed chunk text read from disk inflates
+func checkCompressedContent(chunks map[string]models.Chunk) error {
+	for id, chunk := range chunks {
+		if chunk.CompressedContent == nil {
+			continue
+		}
+		if _, err := decompressText(chunk.CompressedContent); err != nil {
+			return fmt.Errorf("chunk %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
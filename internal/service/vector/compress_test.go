@@ -0,0 +1,131 @@
+package vector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// compressedChunks returns chunks with texts that exercise deflate: long and repetitive,
+// multi-byte and very short
+func compressedChunks() []models.Chunk {
+	texts := []string{
+		strings.Repeat("Retrieval augmented generation grounds answers in documents. ", 40),
+		"Ünïcödé — 日本語のテキスト, emoji 🚀 and\ttabs\nacross lines",
+		"x",
+	}
+
+	chunks := make([]models.Chunk, len(texts))
+	for i, text := range texts {
+		embedding := []float64{0, 0, 0}
+		embedding[i] = 1
+		chunks[i] = models.Chunk{ID: "chunk-" + string(rune('a'+i)), DocID: "doc", Index: i, Content: text, Embedding: embedding}
+	}
+	return chunks
+}
+
+// assertContents checks that chunks hold the texts of want by ID
+func assertContents(t *testing.T, label string, chunks []models.Chunk, want []models.Chunk) {
+	t.Helper()
+
+	texts := make(map[string]string, len(want))
+	for _, chunk := range want {
+		texts[chunk.ID] = chunk.Content
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("%s returned %d chunks, want %d", label, len(chunks), len(want))
+	}
+	for _, chunk := range chunks {
+		if chunk.Content != texts[chunk.ID] {
+			t.Errorf("%s content of %s = %q, want %q", label, chunk.ID, chunk.Content, texts[chunk.ID])
+		}
+		if chunk.CompressedContent != nil {
+			t.Errorf("%s chunk %s still holds compressed content", label, chunk.ID)
+		}
+	}
+}
+
+// searchAll returns every chunk of store through Search, one query per embedding axis
+func searchAll(t *testing.T, store *Store, chunks []models.Chunk) []models.Chunk {
+	t.Helper()
+
+	found := make([]models.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		results, err := store.Search(chunk.Embedding, 1)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || results[0].Chunk.ID != chunk.ID {
+			t.Fatalf("Search for %s = %v", chunk.ID, resultIDs(results))
+		}
+		found = append(found, results[0].Chunk)
+	}
+	return found
+}
+
+func TestCompressedContentRoundTrip(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Storage.CompressChunkText = true
+	db := newTestDB(t)
+	store := openTestStore(t, cfg, db)
+
+	chunks := compressedChunks()
+	if err := store.Add(chunks); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	// Held deflated in memory and on disk
+	for id, chunk := range store.chunks {
+		if chunk.Content != "" || chunk.CompressedContent == nil {
+			t.Errorf("stored chunk %s has content %q, want it compressed", id, chunk.Content)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.Storage.VectorStorePath, "vectors.json"))
+	if err != nil {
+		t.Fatalf("failed to read vector store: %v", err)
+	}
+	if strings.Contains(string(data), "Retrieval augmented generation") {
+		t.Error("vector store file holds the chunk text uncompressed")
+	}
+
+	assertContents(t, "Search", searchAll(t, store, chunks), chunks)
+	assertContents(t, "GetAll", store.GetAll(), chunks)
+
+	reloaded := openTestStore(t, cfg, db)
+	assertContents(t, "Search after reload", searchAll(t, reloaded, chunks), chunks)
+	assertContents(t, "GetAll after reload", reloaded.GetAll(), chunks)
+
+	// Turning compression off inflates the stored texts on load
+	cfg.Storage.CompressChunkText = false
+	plain := openTestStore(t, cfg, db)
+	for id, chunk := range plain.chunks {
+		if chunk.CompressedContent != nil {
+			t.Errorf("chunk %s still compressed with COMPRESS_CHUNK_TEXT off", id)
+		}
+	}
+	assertContents(t, "GetAll uncompressed", plain.GetAll(), chunks)
+}
+
+func TestCorruptCompressedContentFailsLoad(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Storage.CompressChunkText = true
+
+	corrupt := map[string]models.Chunk{
+		"bad": {ID: "bad", DocID: "doc", CompressedContent: []byte{0xff, 0xff, 0xff}, Embedding: []float64{1}},
+	}
+	data, err := json.Marshal(corrupt)
+	if err != nil {
+		t.Fatalf("failed to marshal store: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.Storage.VectorStorePath, "vectors.json"), data, 0644); err != nil {
+		t.Fatalf("failed to write store: %v", err)
+	}
+
+	if _, err := New(cfg, newTestDB(t)); err == nil || !strings.Contains(err.Error(), "chunk bad") {
+		t.Fatalf("New over corrupt compressed text = %v, want an error naming the chunk", err)
+	}
+}
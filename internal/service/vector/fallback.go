@@ -0,0 +1,54 @@
+package vector
+
+import "github.com/mrkaynak/rag/internal/models"
+
+// embeddingLen returns the dimension of a chunk's embedding in whichever representation it has
+func embeddingLen(chunk models.Chunk) int {
+	switch {
+	case len(chunk.Embedding) > 0:
+		return len(chunk.Embedding)
+	case chunk.Embedding32 != nil:
+		return len(chunk.Embedding32)
+	case chunk.Quantized != nil:
+		return len(chunk.Quantized.Values)
+	default:
+		return 0
+	}
+}
+
+// fitDimensions pads embedding with zeros or truncates it to n dimensions. Scores against
+// a fitted vector are only an approximation: the two models' dimensions don't correspond.
+func fitDimensions(embedding []float64, n int) []float64 {
+	if len(embedding) >= n {
+		return embedding[:n]
+	}
+	fitted := make([]float64, n)
+	copy(fitted, embedding)
+	return fitted
+}
+
+// fallbackSimilarity scores a query against a chunk, fitting legacy vectors of another
+// dimension to the query's when VECTOR_DIMENSION_FALLBACK is on
+func (s *Store) fallbackSimilarity(query []float64, query32 []float32, chunk models.Chunk) float64 {
+	if !s.cfg.Storage.DimensionFallback || embeddingLen(chunk) == len(query) {
+		return similarity(query, query32, chunk)
+	}
+	return cosineSimilarity(query, fitDimensions(floatEmbedding(chunk), len(query)))
+}
+
+// LegacyChunks counts live chunks whose embedding dimension differs from the stamped one.
+// Such chunks only exist while VECTOR_DIMENSION_FALLBACK is on and are scored approximately
+// until a reindex rebuilds them.
+func (s *Store) LegacyChunks() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expected := s.Dimensions()
+	count := 0
+	for _, chunk := range s.chunks {
+		if n := embeddingLen(chunk); !chunk.Deleted && n > 0 && n != expected {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,148 @@
+package vector
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+func TestFloat32TopKOverlap(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	exact := newTestStore(t, testConfig(t))
+
+	cfg := testConfig(t)
+	cfg.Storage.VectorQuantization = QuantizationFloat32
+	narrowed := newTestStore(t, cfg)
+
+	chunks := randomChunks(rng, 1000, 50, 384)
+	if err := exact.Add(chunks); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := narrowed.Add(chunks); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	const queries, topK = 50, 10
+	total := 0.0
+	for q := 0; q < queries; q++ {
+		query := randomEmbedding(rng, 384)
+		want, err := exact.Search(query, topK)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		got, err := narrowed.Search(query, topK)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		total += topKOverlap(want, got)
+
+		// Scores differ by around 1e-6, so only exact near-ties can swap places
+		for i := range want {
+			if got[i].Chunk.ID == want[i].Chunk.ID && !approxEqual(got[i].Similarity, want[i].Similarity, 1e-5) {
+				t.Fatalf("float32 score of %s = %g, want %g within 1e-5", want[i].Chunk.ID, got[i].Similarity, want[i].Similarity)
+			}
+		}
+	}
+
+	overlap := total / queries
+	t.Logf("float32 top-%d overlap with float64: %.3f", topK, overlap)
+	if overlap < 0.99 {
+		t.Errorf("float32 top-%d overlap = %.3f, want at least 0.99", topK, overlap)
+	}
+}
+
+// BenchmarkQuantizationSearch compares search time and vector file size of each
+// VECTOR_QUANTIZATION mode for 10k 384-dimensional chunks
+func BenchmarkQuantizationSearch(b *testing.B) {
+	rng := rand.New(rand.NewSource(6))
+	chunks := randomChunks(rng, 10000, 100, 384)
+	query := randomEmbedding(rng, 384)
+
+	for _, mode := range []string{QuantizationNone, QuantizationFloat32, QuantizationInt8} {
+		b.Run(fmt.Sprintf("mode=%s", mode), func(b *testing.B) {
+			cfg := testConfig(b)
+			cfg.Storage.VectorQuantization = mode
+			store := newTestStore(b, cfg)
+			if err := store.Add(chunks); err != nil {
+				b.Fatalf("Add failed: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.Search(query, 10); err != nil {
+					b.Fatalf("Search failed: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(fileSize(b, store)), "file-bytes")
+		})
+	}
+}
+
+// proseWords make up the chunk texts of BenchmarkStoreMemory, so they compress like text
+var proseWords = strings.Fields("the retrieval service embeds each query and ranks document chunks by cosine similarity before the model writes an answer grounded in the passages it was given")
+
+// proseChunks returns n chunks of about 1000 characters of text with random embeddings
+func proseChunks(rng *rand.Rand, n, dims int) []models.Chunk {
+	chunks := randomChunks(rng, n, 100, dims)
+	for i := range chunks {
+		var text strings.Builder
+		for text.Len() < 1000 {
+			text.WriteString(proseWords[rng.Intn(len(proseWords))])
+			text.WriteByte(' ')
+		}
+		chunks[i].Content = text.String()
+	}
+	return chunks
+}
+
+// heapInUse returns the live heap after a garbage collection
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// BenchmarkStoreMemory reports the heap held by a store of 50k 384-dimensional chunks of
+// about 1000 characters each, with float64 embeddings, float32 embeddings, and float64
+// embeddings with COMPRESS_CHUNK_TEXT. Run with -benchtime=1x.
+func BenchmarkStoreMemory(b *testing.B) {
+	modes := []struct {
+		name         string
+		quantization string
+		compress     bool
+	}{
+		{"none", QuantizationNone, false},
+		{"float32", QuantizationFloat32, false},
+		{"compressed-text", QuantizationNone, true},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cfg := testConfig(b)
+				cfg.Storage.VectorQuantization = mode.quantization
+				cfg.Storage.CompressChunkText = mode.compress
+				store := newTestStore(b, cfg)
+
+				// The input chunks are dropped before measuring, so only what the store
+				// keeps is counted
+				before := heapInUse()
+				if err := store.Add(proseChunks(rand.New(rand.NewSource(7)), 50000, 384)); err != nil {
+					b.Fatalf("Add failed: %v", err)
+				}
+				after := heapInUse()
+
+				b.ReportMetric(float64(after-before), "heap-bytes")
+				b.ReportMetric(float64(after-before)/50000, "heap-bytes/chunk")
+				runtime.KeepAlive(store)
+			}
+		})
+	}
+}
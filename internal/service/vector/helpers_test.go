@@ -0,0 +1,106 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// testConfig returns a configuration with the vector store under a temporary directory
+func testConfig(t testing.TB) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Storage.VectorStorePath = t.TempDir()
+	return cfg
+}
+
+// newTestStore opens a vector store with its stamps in an in-memory BadgerDB
+func newTestStore(t testing.TB, cfg *config.Config) *Store {
+	t.Helper()
+
+	return openTestStore(t, cfg, newTestDB(t))
+}
+
+// newTestDB opens an in-memory BadgerDB closed at the end of the test
+func newTestDB(t testing.TB) *badger.DB {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// openTestStore opens a vector store over db, e.g. to reload one written earlier in the test
+func openTestStore(t testing.TB, cfg *config.Config, db *badger.DB) *Store {
+	t.Helper()
+
+	store, err := New(cfg, db)
+	if err != nil {
+		t.Fatalf("failed to create vector store: %v", err)
+	}
+	return store
+}
+
+// randomEmbedding returns a deterministic pseudo-random embedding
+func randomEmbedding(rng *rand.Rand, dims int) []float64 {
+	embedding := make([]float64, dims)
+	for i := range embedding {
+		embedding[i] = rng.NormFloat64()
+	}
+	return embedding
+}
+
+// randomChunks returns n chunks of docs documents with random embeddings
+func randomChunks(rng *rand.Rand, n, docs, dims int) []models.Chunk {
+	chunks := make([]models.Chunk, n)
+	for i := range chunks {
+		chunks[i] = models.Chunk{
+			ID:        fmt.Sprintf("chunk-%d", i),
+			DocID:     fmt.Sprintf("doc-%d", i%docs),
+			Index:     i / docs,
+			Content:   fmt.Sprintf("content of chunk %d", i),
+			Embedding: randomEmbedding(rng, dims),
+		}
+	}
+	return chunks
+}
+
+// resultIDs returns the chunk IDs of search results in order
+func resultIDs(results []SimilarityResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.Chunk.ID
+	}
+	return ids
+}
+
+// approxEqual reports whether two scores agree within tolerance
+func approxEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// topKOverlap returns the share of want's IDs that also appear in got
+func topKOverlap(want, got []SimilarityResult) float64 {
+	found := make(map[string]bool, len(got))
+	for _, result := range got {
+		found[result.Chunk.ID] = true
+	}
+
+	shared := 0
+	for _, result := range want {
+		if found[result.Chunk.ID] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(want))
+}
@@ -0,0 +1,108 @@
+package vector
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// BM25 parameters (standard defaults)
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// KeywordSearch ranks chunks by BM25 relevance of their content to the query.
+// It needs no embeddings, so it can serve as a fallback when the embeddings provider is down.
+// The Similarity field of each result holds the (unbounded) BM25 score rather than a cosine value.
+// Only chunks accepted by filter (and not soft-deleted) are scored and count towards corpus
+// statistics.
+func (s *Store) KeywordSearch(query string, topK int, filter Filter) []SimilarityResult {
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return []SimilarityResult{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.chunks) == 0 {
+		return []SimilarityResult{}
+	}
+
+	// Term frequencies per chunk and document frequencies across the corpus
+	type scoredDoc struct {
+		chunk  models.Chunk
+		tf     map[string]int
+		length int
+	}
+
+	docs := make([]scoredDoc, 0, len(s.chunks))
+	df := make(map[string]int)
+	totalLength := 0
+
+	for _, chunk := range s.chunks {
+		if chunk.Deleted || (filter != nil && !filter(chunk)) {
+			continue
+		}
+
+		terms := tokenize(chunkContent(chunk))
+		tf := make(map[string]int, len(terms))
+		for _, term := range terms {
+			tf[term]++
+		}
+		for term := range tf {
+			df[term]++
+		}
+
+		docs = append(docs, scoredDoc{chunk: chunk, tf: tf, length: len(terms)})
+		totalLength += len(terms)
+	}
+
+	if len(docs) == 0 {
+		return []SimilarityResult{}
+	}
+
+	n := float64(len(docs))
+	avgLength := float64(totalLength) / n
+
+	results := make([]SimilarityResult, 0, len(docs))
+	for _, doc := range docs {
+		score := 0.0
+		for _, term := range queryTerms {
+			freq := float64(doc.tf[term])
+			if freq == 0 {
+				continue
+			}
+
+			idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+			norm := freq + bm25K1*(1-bm25B+bm25B*float64(doc.length)/avgLength)
+			score += idf * freq * (bm25K1 + 1) / norm
+		}
+
+		if score > 0 {
+			results = append(results, SimilarityResult{Chunk: doc.chunk, Similarity: score, KeywordScore: score})
+		}
+	}
+
+	SortResults(results)
+
+	if topK < len(results) {
+		results = results[:topK]
+	}
+
+	for i := range results {
+		results[i].Chunk = decode(results[i].Chunk)
+	}
+
+	return results
+}
+
+// tokenize lowercases text and splits it into letter/digit terms
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
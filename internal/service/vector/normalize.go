@@ -0,0 +1,93 @@
+package vector
+
+import (
+	"math"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+const (
+	// normSampleSize is how many stored vectors are inspected when reporting norm statistics
+	normSampleSize = 200
+	// normTolerance is how far a norm may stray from 1 and still count as normalized;
+	// loose enough to absorb int8 quantization error
+	normTolerance = 0.01
+)
+
+// norm returns the Euclidean length of a vector
+func norm(embedding []float64) float64 {
+	var sum float64
+	for _, v := range embedding {
+		sum += v * v
+	}
+	return math.Sqrt(sum)
+}
+
+// normalize returns a unit-length copy of embedding (a zero vector is returned unchanged)
+func normalize(embedding []float64) []float64 {
+	n := norm(embedding)
+	if n == 0 {
+		return embedding
+	}
+
+	normalized := make([]float64, len(embedding))
+	for i, v := range embedding {
+		normalized[i] = v / n
+	}
+	return normalized
+}
+
+// normalizeChunks scales chunk embeddings to unit length when EMBEDDING_NORMALIZE is on
+func (s *Store) normalizeChunks(chunks []models.Chunk) {
+	if !s.cfg.Embeddings.Normalize {
+		return
+	}
+
+	for i := range chunks {
+		chunks[i].Embedding = normalize(chunks[i].Embedding)
+	}
+}
+
+// NormStats samples stored vectors and reports their lengths, showing whether the store holds
+// unit-length embeddings. Cosine ranking is unaffected either way, but dot-product shortcuts
+// and absolute thresholds assume normalized vectors. Returns nil for an empty store.
+func (s *Store) NormStats() *models.EmbeddingNormStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var stats models.EmbeddingNormStats
+	var total float64
+
+	for _, chunk := range s.chunks {
+		if stats.Sampled == normSampleSize {
+			break
+		}
+
+		embedding := floatEmbedding(chunk)
+		if len(embedding) == 0 {
+			continue
+		}
+
+		n := norm(embedding)
+		if stats.Sampled == 0 || n < stats.MinNorm {
+			stats.MinNorm = n
+		}
+		if n > stats.MaxNorm {
+			stats.MaxNorm = n
+		}
+		if math.Abs(n-1) <= normTolerance {
+			stats.Normalized++
+		}
+		total += n
+		stats.Sampled++
+	}
+
+	if stats.Sampled == 0 {
+		return nil
+	}
+
+	stats.MeanNorm = total / float64(stats.Sampled)
+	stats.AllNormalized = stats.Normalized == stats.Sampled
+
+	return &stats
+}
@@ -0,0 +1,26 @@
+package vector
+
+import "sort"
+
+// SortResults orders results by similarity, highest first. Ties, common with quantized
+// vectors or duplicate content, are broken by chunk ID and then document ID and index, so
+// equal scores come back in the same order on every run rather than in map order.
+func SortResults(results []SimilarityResult) {
+	sort.Slice(results, func(i, j int) bool {
+		return resultLess(results[i], results[j])
+	})
+}
+
+// resultLess reports whether a ranks before b
+func resultLess(a, b SimilarityResult) bool {
+	if a.Similarity != b.Similarity {
+		return a.Similarity > b.Similarity
+	}
+	if a.Chunk.ID != b.Chunk.ID {
+		return a.Chunk.ID < b.Chunk.ID
+	}
+	if a.Chunk.DocID != b.Chunk.DocID {
+		return a.Chunk.DocID < b.Chunk.DocID
+	}
+	return a.Chunk.Index < b.Chunk.Index
+}
@@ -0,0 +1,76 @@
+package vector
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+func TestSortResultsBreaksTies(t *testing.T) {
+	result := func(similarity float64, id, docID string, index int) SimilarityResult {
+		return SimilarityResult{Similarity: similarity, Chunk: models.Chunk{ID: id, DocID: docID, Index: index}}
+	}
+	want := []SimilarityResult{
+		result(0.9, "z", "doc-z", 0),
+		result(0.5, "a", "doc-b", 3),
+		result(0.5, "b", "doc-a", 0),
+		// Same chunk ID from two documents, as after a re-upload under a new document ID
+		result(0.5, "c", "doc-a", 1),
+		result(0.5, "c", "doc-b", 0),
+		result(0.5, "d", "doc-a", 0),
+		result(0.5, "d", "doc-a", 2),
+		result(0.1, "a", "doc-a", 0),
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for run := 0; run < 20; run++ {
+		got := append([]SimilarityResult(nil), want...)
+		rng.Shuffle(len(got), func(i, j int) { got[i], got[j] = got[j], got[i] })
+
+		SortResults(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: order = %v, want %v", run, resultIDs(got), resultIDs(want))
+		}
+	}
+}
+
+func TestSearchOrdersTiedScoresStably(t *testing.T) {
+	cfg := testConfig(t)
+	db := newTestDB(t)
+	store := openTestStore(t, cfg, db)
+
+	// Duplicate content: every chunk scores the same against any query
+	chunks := make([]models.Chunk, 30)
+	for i := range chunks {
+		chunks[i] = models.Chunk{
+			ID:        fmt.Sprintf("chunk-%02d", (i*7)%30),
+			DocID:     fmt.Sprintf("doc-%d", i%3),
+			Index:     i / 3,
+			Content:   "the same paragraph",
+			Embedding: []float64{0.6, 0.8, 0},
+		}
+	}
+	if err := store.Add(chunks); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	var want []string
+	for i := 0; i < 10; i++ {
+		want = append(want, fmt.Sprintf("chunk-%02d", i))
+	}
+
+	query := []float64{1, 1, 0}
+	for run := 0; run < 10; run++ {
+		// Reloading rebuilds the store's map, so iteration order changes between runs
+		results, err := openTestStore(t, cfg, db).Search(query, 10)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if got := resultIDs(results); !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: results = %v, want the lowest chunk IDs in order %v", run, got, want)
+		}
+	}
+}
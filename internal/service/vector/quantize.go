@@ -0,0 +1,219 @@
+package vector
+
+import (
+	"math"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// Vector quantization modes
+const (
+	QuantizationNone    = "none"
+	QuantizationFloat32 = "float32"
+	QuantizationInt8    = "int8"
+)
+
+// quantize scales each dimension of an embedding into an int8 using that dimension's
+// min/max range in scale, clamping values outside it. The int8 values are stored as bytes
+// (two's complement) so they persist compactly as base64 in vectors.json.
+func quantize(embedding []float64, scale *models.QuantizationScale) *models.QuantizedEmbedding {
+	values := make([]byte, len(embedding))
+	for i, v := range embedding {
+		lo, hi := scale.Min[i], scale.Max[i]
+		if hi > lo {
+			level := math.Max(0, math.Min(255, math.Round((v-lo)/(hi-lo)*255)))
+			values[i] = byte(int8(level - 128))
+		}
+	}
+
+	return &models.QuantizedEmbedding{ScaleID: scale.ID, Values: values, Scale: scale}
+}
+
+// dequantizedValue reconstructs dimension i of an int8 embedding
+func dequantizedValue(q *models.QuantizedEmbedding, i int) float64 {
+	lo, hi := q.Min, q.Max
+	if q.Scale != nil {
+		lo, hi = q.Scale.Min[i], q.Scale.Max[i]
+	}
+	return lo + (float64(int8(q.Values[i]))+128)*(hi-lo)/255
+}
+
+// dequantize reconstructs an approximate float embedding from its int8 form
+func dequantize(q *models.QuantizedEmbedding) []float64 {
+	embedding := make([]float64, len(q.Values))
+	for i := range q.Values {
+		embedding[i] = dequantizedValue(q, i)
+	}
+
+	return embedding
+}
+
+// quantizedCosineSimilarity scores a float query against an int8 embedding without
+// materializing the dequantized vector
+func quantizedCosineSimilarity(query []float64, q *models.QuantizedEmbedding) float64 {
+	if len(query) != len(q.Values) {
+		return 0.0
+	}
+
+	var dotProduct, normA, normB float64
+	for i := range q.Values {
+		v := dequantizedValue(q, i)
+		dotProduct += query[i] * v
+		normA += query[i] * query[i]
+		normB += v * v
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0.0
+	}
+
+	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// toFloat32 narrows an embedding to float32
+func toFloat32(embedding []float64) []float32 {
+	narrowed := make([]float32, len(embedding))
+	for i, v := range embedding {
+		narrowed[i] = float32(v)
+	}
+	return narrowed
+}
+
+// toFloat64 widens a float32 embedding
+func toFloat64(embedding []float32) []float64 {
+	widened := make([]float64, len(embedding))
+	for i, v := range embedding {
+		widened[i] = float64(v)
+	}
+	return widened
+}
+
+// cosineSimilarity32 calculates cosine similarity in float32. Components carry ~7
+// significant digits, so scores differ from the float64 result by around 1e-6, which can
+// only reorder near-ties.
+func cosineSimilarity32(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0.0
+	}
+
+	var dotProduct, normA, normB float32
+	for i := range a {
+		dotProduct += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0.0
+	}
+
+	return float64(dotProduct) / (math.Sqrt(float64(normA)) * math.Sqrt(float64(normB)))
+}
+
+// floatEmbedding returns a chunk's embedding as float64 from whichever representation it has
+func floatEmbedding(chunk models.Chunk) []float64 {
+	switch {
+	case len(chunk.Embedding) > 0:
+		return chunk.Embedding
+	case chunk.Embedding32 != nil:
+		return toFloat64(chunk.Embedding32)
+	case chunk.Quantized != nil:
+		return dequantize(chunk.Quantized)
+	default:
+		return nil
+	}
+}
+
+// encode converts a chunk to the store's configured representation: the embedding is kept
+// as float64, float32 or int8 per VECTOR_QUANTIZATION and the text is deflated when
+// COMPRESS_CHUNK_TEXT is on. Chunks in another representation (e.g. persisted before the
+// setting changed) are converted. int8 embeddings are scaled with the current scale for
+// their dimension, which fitScales must have widened to cover them (requires s.mu).
+func (s *Store) encode(chunk models.Chunk) models.Chunk {
+	return s.encodeWith(chunk, s.scaleFor[embeddingLen(chunk)])
+}
+
+// encodeWith is encode with the int8 scale given rather than the store's current one
+func (s *Store) encodeWith(chunk models.Chunk, scale *models.QuantizationScale) models.Chunk {
+	switch s.cfg.Storage.VectorQuantization {
+	case QuantizationInt8:
+		if needsQuantizing(chunk) {
+			if embedding := floatEmbedding(chunk); len(embedding) > 0 {
+				chunk.Quantized = quantize(embedding, scale)
+			}
+		}
+		chunk.Embedding, chunk.Embedding32 = nil, nil
+	case QuantizationFloat32:
+		if chunk.Embedding32 == nil {
+			if embedding := floatEmbedding(chunk); len(embedding) > 0 {
+				chunk.Embedding32 = toFloat32(embedding)
+			}
+		}
+		chunk.Embedding, chunk.Quantized = nil, nil
+	default:
+		chunk.Embedding = floatEmbedding(chunk)
+		chunk.Embedding32, chunk.Quantized = nil, nil
+	}
+
+	if s.cfg.Storage.CompressChunkText {
+		if chunk.CompressedContent == nil && chunk.Content != "" {
+			chunk.CompressedContent = compressText(chunk.Content)
+			chunk.Content = ""
+		}
+	} else if chunk.CompressedContent != nil {
+		chunk.Content = chunkContent(chunk)
+		chunk.CompressedContent = nil
+	}
+
+	return chunk
+}
+
+// needsQuantizing reports whether a chunk's embedding is not yet int8 with a per-dimension
+// scale
+func needsQuantizing(chunk models.Chunk) bool {
+	return chunk.Quantized == nil || chunk.Quantized.Scale == nil
+}
+
+// chunkRepresentation records which embedding and text forms a chunk holds
+type chunkRepresentation struct {
+	floats, floats32, quantized, perVector, compressed bool
+}
+
+// representation returns the forms a chunk's embedding and text are stored in
+func representation(chunk models.Chunk) chunkRepresentation {
+	return chunkRepresentation{
+		floats:     len(chunk.Embedding) > 0,
+		floats32:   chunk.Embedding32 != nil,
+		quantized:  chunk.Quantized != nil,
+		perVector:  chunk.Quantized != nil && chunk.Quantized.Scale == nil,
+		compressed: chunk.CompressedContent != nil,
+	}
+}
+
+// decode returns a chunk with a float64 embedding and plain text content
+func decode(chunk models.Chunk) models.Chunk {
+	if chunk.Quantized != nil || chunk.Embedding32 != nil {
+		chunk.Embedding = floatEmbedding(chunk)
+		chunk.Quantized, chunk.Embedding32 = nil, nil
+	}
+
+	if chunk.CompressedContent != nil {
+		chunk.Content = chunkContent(chunk)
+		chunk.CompressedContent = nil
+	}
+
+	return chunk
+}
+
+// similarity scores a query against a stored chunk in whichever representation it has.
+// query32 is the float32 form of query, only needed for float32 stores.
+func similarity(query []float64, query32 []float32, chunk models.Chunk) float64 {
+	switch {
+	case chunk.Quantized != nil:
+		return quantizedCosineSimilarity(query, chunk.Quantized)
+	case chunk.Embedding32 != nil:
+		return cosineSimilarity32(query32, chunk.Embedding32)
+	default:
+		return cosineSimilarity(query, chunk.Embedding)
+	}
+}
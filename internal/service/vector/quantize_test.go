@@ -0,0 +1,205 @@
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/config"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// int8Config returns a test configuration with VECTOR_QUANTIZATION=int8
+func int8Config(t testing.TB) *config.Config {
+	t.Helper()
+
+	cfg := testConfig(t)
+	cfg.Storage.VectorQuantization = QuantizationInt8
+	return cfg
+}
+
+// skewedChunks returns chunks whose dimensions have very different ranges, from ±0.01 to
+// ±100, where a single per-vector range would drown the narrow dimensions
+func skewedChunks(rng *rand.Rand, from, n, dims int) []models.Chunk {
+	chunks := make([]models.Chunk, n)
+	for i := range chunks {
+		embedding := make([]float64, dims)
+		for d := range embedding {
+			embedding[d] = rng.NormFloat64() * math.Pow(10, float64(d%5)-2)
+		}
+		chunks[i] = models.Chunk{
+			ID:        fmt.Sprintf("chunk-%d", from+i),
+			DocID:     fmt.Sprintf("doc-%d", (from+i)%10),
+			Content:   fmt.Sprintf("content of chunk %d", from+i),
+			Embedding: embedding,
+		}
+	}
+	return chunks
+}
+
+func TestInt8QuantizationPerDimensionAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	store := newTestStore(t, int8Config(t))
+
+	// Added in batches so the scale is widened and stored vectors are re-quantized
+	var originals []models.Chunk
+	for batch := 0; batch < 5; batch++ {
+		chunks := skewedChunks(rng, batch*100, 100, 20)
+		originals = append(originals, chunks...)
+		if err := store.Add(chunks); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	scale := store.scaleFor[20]
+	if scale == nil {
+		t.Fatal("no quantization scale for 20 dimensions")
+	}
+
+	stored := make(map[string][]float64)
+	for _, chunk := range store.GetAll() {
+		stored[chunk.ID] = chunk.Embedding
+	}
+
+	// Rounding costs half a step of a dimension's own range on average at most; each
+	// re-quantization after a widening can add up to half a step more for older vectors
+	var sumSteps float64
+	for _, original := range originals {
+		got := stored[original.ID]
+		for d, want := range original.Embedding {
+			step := (scale.Max[d] - scale.Min[d]) / 255
+			steps := math.Abs(got[d]-want) / step
+			if steps > 2 {
+				t.Fatalf("chunk %s dimension %d: error of %.2f steps of the dimension's range", original.ID, d, steps)
+			}
+			sumSteps += steps
+		}
+	}
+	if mean := sumSteps / float64(len(originals)*20); mean > 0.5 {
+		t.Errorf("mean error = %.2f steps, want at most half a step", mean)
+	}
+
+	// The narrowest dimensions (±0.01) keep their precision next to ±100 ones
+	if step := (scale.Max[0] - scale.Min[0]) / 255; step > 0.001 {
+		t.Errorf("dimension 0 step = %g, want it scaled to its own range", step)
+	}
+}
+
+func TestInt8QuantizationTopKOverlap(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	exact := newTestStore(t, testConfig(t))
+	quantized := newTestStore(t, int8Config(t))
+
+	for batch := 0; batch < 4; batch++ {
+		chunks := randomChunks(rng, 250, 50, 64)
+		for i := range chunks {
+			chunks[i].ID = fmt.Sprintf("chunk-%d-%d", batch, i)
+		}
+		if err := exact.Add(chunks); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if err := quantized.Add(chunks); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	const queries, topK = 50, 10
+	total := 0.0
+	for q := 0; q < queries; q++ {
+		query := randomEmbedding(rng, 64)
+		want, err := exact.Search(query, topK)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		got, err := quantized.Search(query, topK)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		total += topKOverlap(want, got)
+	}
+
+	// int8 keeps ~98% of the exact top-10 on random 64-dimensional vectors; near-ties
+	// account for the rest
+	overlap := total / queries
+	t.Logf("int8 top-%d overlap with float64: %.3f", topK, overlap)
+	if overlap < 0.9 {
+		t.Errorf("int8 top-%d overlap = %.3f, want at least 0.9", topK, overlap)
+	}
+}
+
+func TestInt8ScalesSurviveReload(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	cfg := int8Config(t)
+	db := newTestDB(t)
+	store := openTestStore(t, cfg, db)
+
+	for batch := 0; batch < 3; batch++ {
+		if err := store.Add(skewedChunks(rng, batch*50, 50, 8)); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	query := randomEmbedding(rng, 8)
+	before, err := store.Search(query, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	reloaded := openTestStore(t, cfg, db)
+	after, err := reloaded.Search(query, 10)
+	if err != nil {
+		t.Fatalf("Search after reload failed: %v", err)
+	}
+	for i := range before {
+		if before[i].Chunk.ID != after[i].Chunk.ID || before[i].Similarity != after[i].Similarity {
+			t.Fatalf("result %d after reload = %s %g, want %s %g", i,
+				after[i].Chunk.ID, after[i].Similarity, before[i].Chunk.ID, before[i].Similarity)
+		}
+	}
+
+	// Scales replaced by wider ones are dropped once nothing refers to them
+	if len(reloaded.scales) != 1 {
+		t.Errorf("reloaded store keeps %d scales, want only the current one", len(reloaded.scales))
+	}
+	if stats, _ := reloaded.CompactionStats(); stats.StaleChunks != 0 {
+		t.Errorf("stale chunks after reload = %d, want 0", stats.StaleChunks)
+	}
+}
+
+func TestInt8PerVectorChunksAreRequantized(t *testing.T) {
+	cfg := int8Config(t)
+
+	// A store written before per-dimension scales: one min/max per vector
+	legacy := map[string]models.Chunk{
+		"a": {ID: "a", DocID: "doc", Content: "a", Quantized: &models.QuantizedEmbedding{Min: -1, Max: 1, Values: []byte{0x7f, 0x80, 0x00}}},
+		"b": {ID: "b", DocID: "doc", Content: "b", Quantized: &models.QuantizedEmbedding{Min: 0, Max: 2, Values: []byte{0x80, 0x7f, 0x00}}},
+	}
+	data, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatalf("failed to marshal legacy store: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.Storage.VectorStorePath, "vectors.json"), data, 0644); err != nil {
+		t.Fatalf("failed to write legacy store: %v", err)
+	}
+
+	store := newTestStore(t, cfg)
+	if stats, _ := store.CompactionStats(); stats.StaleChunks != 2 {
+		t.Errorf("stale chunks = %d, want the 2 per-vector chunks", stats.StaleChunks)
+	}
+
+	wants := map[string][]float64{
+		"a": dequantize(legacy["a"].Quantized),
+		"b": dequantize(legacy["b"].Quantized),
+	}
+	for _, chunk := range store.GetAll() {
+		for d, want := range wants[chunk.ID] {
+			if !approxEqual(chunk.Embedding[d], want, 0.02) {
+				t.Errorf("chunk %s dimension %d = %g, want %g", chunk.ID, d, chunk.Embedding[d], want)
+			}
+		}
+	}
+}
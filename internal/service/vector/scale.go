@@ -0,0 +1,225 @@
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+// prefixScale prefixes the stored int8 quantization scales, keyed by their ID
+const prefixScale = "vector:scale:"
+
+// scaleMargin extends a dimension's range by this share of its width when new values fall
+// outside it, so a growing corpus rarely needs its stored vectors re-quantized
+const scaleMargin = 0.1
+
+func scaleKey(id uint64) []byte {
+	return []byte(prefixScale + strconv.FormatUint(id, 10))
+}
+
+// loadScales reads the stored quantization scales. The newest scale of each dimension is
+// the one new embeddings are quantized with.
+func (s *Store) loadScales() error {
+	s.scales = make(map[uint64]*models.QuantizationScale)
+	s.scaleFor = make(map[int]*models.QuantizationScale)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(prefixScale)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var scale models.QuantizationScale
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &scale)
+			}); err != nil {
+				return fmt.Errorf("invalid quantization scale %s: %w", it.Item().Key(), err)
+			}
+
+			s.scales[scale.ID] = &scale
+			if current := s.scaleFor[len(scale.Min)]; current == nil || scale.ID > current.ID {
+				s.scaleFor[len(scale.Min)] = &scale
+			}
+			if scale.ID > s.lastScaleID.Load() {
+				s.lastScaleID.Store(scale.ID)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read quantization scales: %w", err)
+	}
+
+	return nil
+}
+
+// attachScales links the loaded int8 embeddings to the scales they were quantized with
+func (s *Store) attachScales() error {
+	for id, chunk := range s.chunks {
+		if chunk.Quantized == nil || chunk.Quantized.ScaleID == 0 {
+			continue
+		}
+
+		scale := s.scales[chunk.Quantized.ScaleID]
+		if scale == nil || len(scale.Min) != len(chunk.Quantized.Values) {
+			return fmt.Errorf("chunk %s refers to missing quantization scale %d", id, chunk.Quantized.ScaleID)
+		}
+		chunk.Quantized.Scale = scale
+	}
+
+	return nil
+}
+
+// referencedScales adds the IDs of the scales the stored chunks use to into (requires s.mu)
+func (s *Store) referencedScales(into map[uint64]bool) {
+	for _, chunk := range s.chunks {
+		if chunk.Quantized != nil && chunk.Quantized.ScaleID != 0 {
+			into[chunk.Quantized.ScaleID] = true
+		}
+	}
+}
+
+// pruneScales deletes the scales that are neither referenced nor current. It runs at load,
+// where the file on disk is known, so a snapshot still being written never loses its scale.
+func (s *Store) pruneScales(referenced map[uint64]bool) error {
+	var unused []uint64
+	for id, scale := range s.scales {
+		if !referenced[id] && s.scaleFor[len(scale.Min)] != scale {
+			unused = append(unused, id)
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		for _, id := range unused {
+			if err := txn.Delete(scaleKey(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete unused quantization scales: %w", err)
+	}
+
+	for _, id := range unused {
+		delete(s.scales, id)
+	}
+	return nil
+}
+
+// fitScales makes sure the current scale of each dimension covers the embeddings of chunks
+// that are about to be quantized. A scale that is too narrow is replaced by a wider one and
+// the stored vectors using it are re-quantized, which adds at most half a quantization step
+// of error to them. Only applies with VECTOR_QUANTIZATION=int8 (requires s.mu).
+func (s *Store) fitScales(chunks []models.Chunk) error {
+	if s.cfg.Storage.VectorQuantization != QuantizationInt8 {
+		return nil
+	}
+
+	byDimension := make(map[int][][]float64)
+	for _, chunk := range chunks {
+		if !needsQuantizing(chunk) {
+			continue
+		}
+		if embedding := floatEmbedding(chunk); len(embedding) > 0 {
+			byDimension[len(embedding)] = append(byDimension[len(embedding)], embedding)
+		}
+	}
+
+	for n, embeddings := range byDimension {
+		current := s.scaleFor[n]
+		lo, hi, widened := fitRange(current, embeddings)
+		if !widened {
+			continue
+		}
+
+		scale, err := s.saveScale(lo, hi)
+		if err != nil {
+			return err
+		}
+		s.scales[scale.ID] = scale
+		s.scaleFor[n] = scale
+
+		if current == nil {
+			continue
+		}
+		for id, chunk := range s.chunks {
+			if chunk.Quantized != nil && chunk.Quantized.Scale == current {
+				chunk.Quantized = quantize(dequantize(chunk.Quantized), scale)
+				s.chunks[id] = chunk
+			}
+		}
+	}
+
+	return nil
+}
+
+// fitRange returns per-dimension ranges covering current (if any) and embeddings, and
+// whether they are wider than current. Dimensions that had to grow get scaleMargin of slack.
+func fitRange(current *models.QuantizationScale, embeddings [][]float64) (lo, hi []float64, widened bool) {
+	n := len(embeddings[0])
+	lo, hi = make([]float64, n), make([]float64, n)
+	if current != nil {
+		copy(lo, current.Min)
+		copy(hi, current.Max)
+	} else {
+		copy(lo, embeddings[0])
+		copy(hi, embeddings[0])
+		widened = true
+	}
+
+	grew := make([]bool, n)
+	for _, embedding := range embeddings {
+		for i, v := range embedding {
+			if v < lo[i] {
+				lo[i], grew[i] = v, true
+			}
+			if v > hi[i] {
+				hi[i], grew[i] = v, true
+			}
+		}
+	}
+
+	for i := range grew {
+		if grew[i] {
+			margin := (hi[i] - lo[i]) * scaleMargin
+			lo[i] -= margin
+			hi[i] += margin
+			widened = true
+		}
+	}
+
+	return lo, hi, widened
+}
+
+// newScale persists a scale fitted to embeddings alone, as for a full replacement
+func (s *Store) newScale(embeddings [][]float64) (*models.QuantizationScale, error) {
+	lo, hi, _ := fitRange(nil, embeddings)
+	return s.saveScale(lo, hi)
+}
+
+// saveScale stores a new scale under the next ID
+func (s *Store) saveScale(lo, hi []float64) (*models.QuantizationScale, error) {
+	scale := &models.QuantizationScale{ID: s.lastScaleID.Add(1), Min: lo, Max: hi}
+
+	data, err := json.Marshal(scale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal quantization scale: %w", err)
+	}
+	if err := s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(scaleKey(scale.ID), data)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write quantization scale: %w", err)
+	}
+
+	return scale, nil
+}
@@ -0,0 +1,174 @@
+package vector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/mrkaynak/rag/internal/models"
+	apperrors "github.com/mrkaynak/rag/pkg/errors"
+)
+
+// keyDimensions stores the embedding dimension of the indexed vectors
+const keyDimensions = "vector:dimensions"
+
+// keyModel stores the embedding provider and model the indexed vectors were built with
+const keyModel = "vector:model"
+
+// ModelStamp identifies the embedding provider and model of the indexed vectors
+type ModelStamp struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+// IsZero reports whether the stamp is unset
+func (m ModelStamp) IsZero() bool {
+	return m == ModelStamp{}
+}
+
+// Dimensions returns the embedding dimension stamped on the store (0 while it is empty)
+func (s *Store) Dimensions() int {
+	return int(s.dimensions.Load())
+}
+
+// loadDimensions reads the stamped dimension. Stores indexed before stamping existed are
+// stamped from their first chunk.
+func (s *Store) loadDimensions() error {
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyDimensions))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			n, err := strconv.Atoi(string(val))
+			if err != nil {
+				return fmt.Errorf("invalid dimension stamp %q: %w", val, err)
+			}
+			s.dimensions.Store(int64(n))
+			return nil
+		})
+	})
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, badger.ErrKeyNotFound) {
+		return fmt.Errorf("failed to read dimension stamp: %w", err)
+	}
+
+	for _, chunk := range s.chunks {
+		if n := len(floatEmbedding(chunk)); n > 0 {
+			return s.stampDimensions(n)
+		}
+	}
+
+	return nil
+}
+
+// stampDimensions records the embedding dimension of the store (0 clears it)
+func (s *Store) stampDimensions(n int) error {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		if n == 0 {
+			return txn.Delete([]byte(keyDimensions))
+		}
+		return txn.Set([]byte(keyDimensions), []byte(strconv.Itoa(n)))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write dimension stamp: %w", err)
+	}
+
+	s.dimensions.Store(int64(n))
+	return nil
+}
+
+// chunkDimensions returns the common embedding length of chunks, rejecting mixed lengths
+func chunkDimensions(chunks []models.Chunk) (int, error) {
+	n := 0
+	for _, chunk := range chunks {
+		size := len(floatEmbedding(chunk))
+		if n == 0 {
+			n = size
+		} else if size != n {
+			return 0, apperrors.Internal(fmt.Sprintf(
+				"chunk %s has %d dimensions but other chunks have %d", chunk.ID, size, n)).WithCode(apperrors.CodeDimensionMismatch)
+		}
+	}
+	return n, nil
+}
+
+// Model returns the embedding model stamped on the store. It is zero for stores indexed
+// before model stamping existed, until their next upload or reindex.
+func (s *Store) Model() ModelStamp {
+	if m := s.model.Load(); m != nil {
+		return *m
+	}
+	return ModelStamp{}
+}
+
+// StampModel records the embedding model of the indexed vectors. Unless replace is set, an
+// existing stamp is kept, so the first indexed vectors decide it as with the dimension.
+func (s *Store) StampModel(stamp ModelStamp, replace bool) error {
+	s.modelMu.Lock()
+	defer s.modelMu.Unlock()
+
+	if !replace && !s.Model().IsZero() {
+		return nil
+	}
+
+	data, err := json.Marshal(stamp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal model stamp: %w", err)
+	}
+
+	if err := s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(keyModel), data)
+	}); err != nil {
+		return fmt.Errorf("failed to write model stamp: %w", err)
+	}
+
+	s.model.Store(&stamp)
+	return nil
+}
+
+// loadModel reads the stamped embedding model, if any
+func (s *Store) loadModel() error {
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(keyModel))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			var stamp ModelStamp
+			if err := json.Unmarshal(val, &stamp); err != nil {
+				return fmt.Errorf("invalid model stamp %q: %w", val, err)
+			}
+			s.model.Store(&stamp)
+			return nil
+		})
+	})
+	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return fmt.Errorf("failed to read model stamp: %w", err)
+	}
+
+	return nil
+}
+
+// checkMaxDimensions rejects vectors longer than MAX_EMBEDDING_DIM, which point to a
+// misconfigured or runaway embedding provider
+func (s *Store) checkMaxDimensions(n int) error {
+	if limit := s.cfg.Embeddings.MaxDimensions; limit > 0 && n > limit {
+		return apperrors.Internal(fmt.Sprintf(
+			"embeddings have %d dimensions, more than MAX_EMBEDDING_DIM=%d", n, limit)).WithCode(apperrors.CodeDimensionMismatch)
+	}
+	return nil
+}
+
+// dimensionMismatch reports vectors whose length differs from the stamped dimension
+func dimensionMismatch(expected, got int) error {
+	return apperrors.Internal(fmt.Sprintf(
+		"embedding dimension mismatch: the vector store holds %d-dimensional vectors but got %d (reindex after changing the embedding model)",
+		expected, got)).WithCode(apperrors.CodeDimensionMismatch)
+}
@@ -6,9 +6,10 @@ import (
 	"math"
 	"os"
 	"path/filepath"
-	"sort"
 	"sync"
+	"sync/atomic"
 
+	badger "github.com/dgraph-io/badger/v4"
 	"github.com/mrkaynak/rag/internal/config"
 	"github.com/mrkaynak/rag/internal/models"
 	"github.com/mrkaynak/rag/pkg/errors"
@@ -16,19 +17,51 @@ import (
 
 // Store handles vector storage and similarity search
 type Store struct {
-	cfg    *config.Config
-	mu     sync.RWMutex
-	chunks map[string]models.Chunk // chunkID -> Chunk
+	cfg     *config.Config
+	db      *badger.DB
+	mu      sync.RWMutex
+	writes  sync.RWMutex            // shared by each mutation, held exclusively by HoldWrites
+	chunks  map[string]models.Chunk // chunkID -> Chunk
+	version atomic.Uint64           // bumped on every mutation
+	// staleChunks counts live chunks the file holds in an encoding other than the configured
+	// one; they are re-encoded on load and the count is cleared once the file is rewritten
+	staleChunks atomic.Int64
+	dimensions  atomic.Int64 // stamped embedding dimension, 0 while empty
+	model       atomic.Pointer[ModelStamp]
+	modelMu     sync.Mutex // serializes model stamp writes
+
+	// scales are the int8 quantization scales by ID and scaleFor the one new embeddings of
+	// each dimension are quantized with (guarded by mu)
+	scales      map[uint64]*models.QuantizationScale
+	scaleFor    map[int]*models.QuantizationScale
+	lastScaleID atomic.Uint64
 }
 
 // SimilarityResult represents a similarity search result
 type SimilarityResult struct {
 	Chunk      models.Chunk
 	Similarity float64
+
+	// Scoring details kept for retrieval explanations. VectorScore is the cosine similarity
+	// and KeywordScore the BM25 score of the search that found the chunk; RecencyFactor is
+	// the recency boost multiplier applied to Similarity (0 when not applied). RerankScore is
+	// the relevance score that replaced Similarity when the results were reranked.
+	VectorScore   float64
+	KeywordScore  float64
+	RecencyFactor float64
+	RerankScore   float64
+
+	// LowConfidence marks a result kept as context although it scored below CONTEXT_MIN_SCORE
+	LowConfidence bool
 }
 
-// New creates a new vector store
-func New(cfg *config.Config) (*Store, error) {
+// Filter reports whether a chunk should be considered by a search. A nil Filter matches all chunks.
+// Filters see the stored form of a chunk, whose Content and Embedding may be empty when the
+// store keeps them compressed or quantized, so they should only inspect metadata fields.
+type Filter func(chunk models.Chunk) bool
+
+// New creates a new vector store. db holds the store's dimension stamp.
+func New(cfg *config.Config, db *badger.DB) (*Store, error) {
 	// Ensure vector store directory exists
 	if err := os.MkdirAll(cfg.Storage.VectorStorePath, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create vector store directory: %w", err)
@@ -36,31 +69,97 @@ func New(cfg *config.Config) (*Store, error) {
 
 	store := &Store{
 		cfg:    cfg,
+		db:     db,
 		chunks: make(map[string]models.Chunk),
 	}
 
+	if err := store.loadScales(); err != nil {
+		return nil, err
+	}
+
 	// Load existing vectors
 	if err := store.load(); err != nil {
 		return nil, fmt.Errorf("failed to load vector store: %w", err)
 	}
 
+	if err := store.loadDimensions(); err != nil {
+		return nil, err
+	}
+
+	if err := store.loadModel(); err != nil {
+		return nil, err
+	}
+
 	return store, nil
 }
 
 // Add adds chunks to the vector store
 func (s *Store) Add(chunks []models.Chunk) error {
+	return s.add(chunks, "")
+}
+
+// ReplaceDocument swaps all chunks of a document for the given ones in a single mutation,
+// so searches never see the document half replaced and a rejected batch keeps the old
+// chunks
+func (s *Store) ReplaceDocument(docID string, chunks []models.Chunk) error {
+	return s.add(chunks, docID)
+}
+
+// add adds chunks to the vector store, first dropping the chunks of replaceDocID if set
+func (s *Store) add(chunks []models.Chunk, replaceDocID string) error {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	// Validate first (no lock needed)
-	for _, chunk := range chunks {
-		if len(chunk.Embedding) == 0 {
-			return errors.BadRequest(fmt.Sprintf("chunk %s has no embedding", chunk.ID))
-		}
+	if err := validateChunks(chunks); err != nil {
+		return err
 	}
 
+	dims, err := chunkDimensions(chunks)
+	if err != nil {
+		return err
+	}
+	if err := s.checkMaxDimensions(dims); err != nil {
+		return err
+	}
+
+	s.normalizeChunks(chunks)
+
 	// Short lock for memory update
 	s.mu.Lock()
+	if expected := s.Dimensions(); expected == 0 && dims > 0 {
+		// First vectors decide the store's dimension
+		if err := s.stampDimensions(dims); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	} else if dims > 0 && dims != expected {
+		// With VECTOR_DIMENSION_FALLBACK new vectors restamp the store and the existing
+		// ones become legacy vectors, scored approximately until a reindex
+		if !s.cfg.Storage.DimensionFallback {
+			s.mu.Unlock()
+			return dimensionMismatch(expected, dims)
+		}
+		if err := s.stampDimensions(dims); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	if err := s.fitScales(chunks); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if replaceDocID != "" {
+		for id, chunk := range s.chunks {
+			if chunk.DocID == replaceDocID {
+				delete(s.chunks, id)
+			}
+		}
+	}
 	for _, chunk := range chunks {
-		s.chunks[chunk.ID] = chunk
+		s.chunks[chunk.ID] = s.encode(chunk)
 	}
+	s.version.Add(1)
 	// Create snapshot for persistence
 	snapshot := s.cloneChunks()
 	s.mu.Unlock()
@@ -71,6 +170,11 @@ func (s *Store) Add(chunks []models.Chunk) error {
 
 // Search finds similar chunks using cosine similarity
 func (s *Store) Search(queryEmbedding []float64, topK int) ([]SimilarityResult, error) {
+	return s.SearchFiltered(queryEmbedding, topK, nil)
+}
+
+// SearchFiltered finds similar chunks among those accepted by filter
+func (s *Store) SearchFiltered(queryEmbedding []float64, topK int, filter Filter) ([]SimilarityResult, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -78,58 +182,218 @@ func (s *Store) Search(queryEmbedding []float64, topK int) ([]SimilarityResult,
 		return nil, errors.BadRequest("query embedding is empty")
 	}
 
+	if expected := s.Dimensions(); expected > 0 && len(queryEmbedding) != expected && !s.cfg.Storage.DimensionFallback {
+		return nil, dimensionMismatch(expected, len(queryEmbedding))
+	}
+
 	if len(s.chunks) == 0 {
 		return []SimilarityResult{}, nil
 	}
 
+	// Float32 stores compare against a float32 query
+	var query32 []float32
+	if s.cfg.Storage.VectorQuantization == QuantizationFloat32 {
+		query32 = toFloat32(queryEmbedding)
+	}
+
 	// Calculate similarities
 	results := make([]SimilarityResult, 0, len(s.chunks))
 	for _, chunk := range s.chunks {
-		similarity := cosineSimilarity(queryEmbedding, chunk.Embedding)
+		if chunk.Deleted || (filter != nil && !filter(chunk)) {
+			continue
+		}
+
 		results = append(results, SimilarityResult{
 			Chunk:      chunk,
-			Similarity: similarity,
+			Similarity: s.fallbackSimilarity(queryEmbedding, query32, chunk),
 		})
 	}
 
-	// Sort by similarity (descending)
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Similarity > results[j].Similarity
-	})
+	// Sort by similarity (descending), ties in a stable order
+	SortResults(results)
 
 	// Return top K results
 	if topK < len(results) {
 		results = results[:topK]
 	}
 
+	for i := range results {
+		results[i].Chunk = decode(results[i].Chunk)
+		results[i].VectorScore = results[i].Similarity
+	}
+
 	return results, nil
 }
 
-// GetAll returns all chunks
+// GetAll returns all chunks except those of soft-deleted documents
 func (s *Store) GetAll() []models.Chunk {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	chunks := make([]models.Chunk, 0, len(s.chunks))
 	for _, chunk := range s.chunks {
-		chunks = append(chunks, chunk)
+		if !chunk.Deleted {
+			chunks = append(chunks, decode(chunk))
+		}
+	}
+
+	return chunks
+}
+
+// GetAllIncludingDeleted returns all chunks, including those of soft-deleted documents
+func (s *Store) GetAllIncludingDeleted() []models.Chunk {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	chunks := make([]models.Chunk, 0, len(s.chunks))
+	for _, chunk := range s.chunks {
+		chunks = append(chunks, decode(chunk))
 	}
 
 	return chunks
 }
 
+// Count returns the number of stored chunks, excluding those of soft-deleted documents
+func (s *Store) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, chunk := range s.chunks {
+		if !chunk.Deleted {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Version returns a monotonically increasing counter that changes on every mutation (Add,
+// Replace, Clear, and soft delete, restore or purge of a document), so reindexes and
+// uploads are covered. Callers can compare versions to cheaply detect that the store
+// contents changed; anything caching search results must read the version before searching
+// and key its entries on it, so a stale entry is never served after the corpus changes.
+func (s *Store) Version() uint64 {
+	return s.version.Load()
+}
+
+// HoldWrites waits for the mutations in progress and blocks every further one except
+// Replace until release is called. A reindex holds it from reading the store until it swaps
+// in the result, so no write lands in between and gets discarded by the swap.
+func (s *Store) HoldWrites() (release func()) {
+	s.writes.Lock()
+	return s.writes.Unlock
+}
+
 // Clear removes all chunks
 func (s *Store) Clear() error {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	s.mu.Lock()
+	if err := s.stampDimensions(0); err != nil {
+		s.mu.Unlock()
+		return err
+	}
 	s.chunks = make(map[string]models.Chunk)
+	s.version.Add(1)
+	snapshot := s.cloneChunks()
+	s.mu.Unlock()
+
+	return s.persistSnapshot(snapshot)
+}
+
+// Replace atomically swaps the entire store contents with the given chunks. It is not held
+// off by HoldWrites, so the holder can swap in a store built from what it read.
+func (s *Store) Replace(chunks []models.Chunk) error {
+	if err := validateChunks(chunks); err != nil {
+		return err
+	}
+
+	dims, err := chunkDimensions(chunks)
+	if err != nil {
+		return err
+	}
+	if err := s.checkMaxDimensions(dims); err != nil {
+		return err
+	}
+
+	s.normalizeChunks(chunks)
+
+	// The replacement gets a scale fitted to it alone rather than widening the old one
+	var scale *models.QuantizationScale
+	if s.cfg.Storage.VectorQuantization == QuantizationInt8 {
+		var embeddings [][]float64
+		for _, chunk := range chunks {
+			if embedding := floatEmbedding(chunk); needsQuantizing(chunk) && len(embedding) > 0 {
+				embeddings = append(embeddings, embedding)
+			}
+		}
+		if len(embeddings) > 0 {
+			if scale, err = s.newScale(embeddings); err != nil {
+				return err
+			}
+		}
+	}
+
+	replacement := make(map[string]models.Chunk, len(chunks))
+	for _, chunk := range chunks {
+		replacement[chunk.ID] = s.encodeWith(chunk, scale)
+	}
+
+	s.mu.Lock()
+	if scale != nil {
+		s.scales[scale.ID] = scale
+		s.scaleFor[dims] = scale
+	}
+	// A full replacement (e.g. a reindex with a new model) restamps the dimension
+	if err := s.stampDimensions(dims); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.chunks = replacement
+	s.version.Add(1)
 	snapshot := s.cloneChunks()
 	s.mu.Unlock()
 
 	return s.persistSnapshot(snapshot)
 }
 
-// DeleteByDocID removes all chunks belonging to a document
+// DeleteByDocID soft-deletes all chunks belonging to a document: they are kept (so the
+// document can be restored) but excluded from searches and listings
 func (s *Store) DeleteByDocID(docID string) error {
+	return s.setDeleted(docID, true)
+}
+
+// RestoreByDocID restores the soft-deleted chunks of a document
+func (s *Store) RestoreByDocID(docID string) error {
+	return s.setDeleted(docID, false)
+}
+
+// setDeleted sets the soft-delete flag on all chunks of a document
+func (s *Store) setDeleted(docID string, deleted bool) error {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
+	s.mu.Lock()
+	for id, chunk := range s.chunks {
+		if chunk.DocID == docID {
+			chunk.Deleted = deleted
+			s.chunks[id] = chunk
+		}
+	}
+	s.version.Add(1)
+	snapshot := s.cloneChunks()
+	s.mu.Unlock()
+
+	return s.persistSnapshot(snapshot)
+}
+
+// PurgeByDocID permanently removes all chunks belonging to a document
+func (s *Store) PurgeByDocID(docID string) error {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	s.mu.Lock()
 	// Find and remove chunks with matching DocID
 	for id, chunk := range s.chunks {
@@ -137,6 +401,7 @@ func (s *Store) DeleteByDocID(docID string) error {
 			delete(s.chunks, id)
 		}
 	}
+	s.version.Add(1)
 	snapshot := s.cloneChunks()
 	s.mu.Unlock()
 
@@ -164,6 +429,7 @@ func (s *Store) persistSnapshot(snapshot map[string]models.Chunk) error {
 	if err := os.WriteFile(filePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write vector store: %w", err)
 	}
+	s.staleChunks.Store(0)
 
 	return nil
 }
@@ -195,6 +461,87 @@ func (s *Store) load() error {
 		return fmt.Errorf("failed to unmarshal chunks: %w", err)
 	}
 
+	if err := checkCompressedContent(s.chunks); err != nil {
+		return err
+	}
+
+	if err := s.attachScales(); err != nil {
+		return err
+	}
+	referenced := make(map[uint64]bool)
+	s.referencedScales(referenced)
+
+	// Convert chunks persisted under different VECTOR_QUANTIZATION/COMPRESS_CHUNK_TEXT settings
+	loaded := make([]models.Chunk, 0, len(s.chunks))
+	for _, chunk := range s.chunks {
+		loaded = append(loaded, chunk)
+	}
+	if err := s.fitScales(loaded); err != nil {
+		return err
+	}
+	for id, chunk := range s.chunks {
+		encoded := s.encode(chunk)
+		if !chunk.Deleted && representation(encoded) != representation(chunk) {
+			s.staleChunks.Add(1)
+		}
+		s.chunks[id] = encoded
+	}
+
+	// Scales used neither by the file nor by the re-encoded chunks can go
+	s.referencedScales(referenced)
+	return s.pruneScales(referenced)
+}
+
+// CheckWritable verifies the vector store directory accepts writes by creating and removing
+// a probe file, so a read-only mount is detected before the next upload fails to persist
+func (s *Store) CheckWritable() error {
+	probe, err := os.CreateTemp(s.cfg.Storage.VectorStorePath, ".write-probe-*")
+	if err != nil {
+		return fmt.Errorf("vector store directory %s is not writable: %w", s.cfg.Storage.VectorStorePath, err)
+	}
+
+	name := probe.Name()
+	probe.Close()
+
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("failed to remove write probe from vector store directory: %w", err)
+	}
+
+	return nil
+}
+
+// validateChunks rejects chunks whose embedding is missing or could never match a query
+func validateChunks(chunks []models.Chunk) error {
+	for _, chunk := range chunks {
+		if err := CheckEmbedding(chunk.Embedding); err != nil {
+			return errors.BadRequest(fmt.Sprintf("chunk %s (doc %s, index %d): %v", chunk.ID, chunk.DocID, chunk.Index, err))
+		}
+	}
+
+	return nil
+}
+
+// CheckEmbedding reports why an embedding is unusable: empty, containing NaN/Inf values,
+// or all zeros (cosine similarity against it is always 0, so the chunk is never retrieved)
+func CheckEmbedding(embedding []float64) error {
+	if len(embedding) == 0 {
+		return fmt.Errorf("embedding is empty")
+	}
+
+	nonZero := false
+	for _, v := range embedding {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("embedding contains NaN or Inf values")
+		}
+		if v != 0 {
+			nonZero = true
+		}
+	}
+
+	if !nonZero {
+		return fmt.Errorf("embedding is an all-zero vector")
+	}
+
 	return nil
 }
 
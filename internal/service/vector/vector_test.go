@@ -0,0 +1,48 @@
+package vector
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mrkaynak/rag/internal/models"
+)
+
+func TestMutationsBumpVersion(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	store := newTestStore(t, testConfig(t))
+	chunks := randomChunks(rng, 10, 2, 4)
+
+	mutations := []struct {
+		name   string
+		mutate func() error
+	}{
+		{"Add", func() error { return store.Add(chunks) }},
+		{"DeleteByDocID", func() error { return store.DeleteByDocID("doc-0") }},
+		{"RestoreByDocID", func() error { return store.RestoreByDocID("doc-0") }},
+		{"PurgeByDocID", func() error { return store.PurgeByDocID("doc-1") }},
+		{"ReplaceDocument", func() error {
+			return store.ReplaceDocument("doc-0", []models.Chunk{{ID: "new", DocID: "doc-0", Content: "new", Embedding: randomEmbedding(rng, 4)}})
+		}},
+		{"Replace", func() error { return store.Replace(randomChunks(rng, 5, 1, 4)) }},
+		{"Clear", store.Clear},
+	}
+
+	for _, m := range mutations {
+		before := store.Version()
+		if err := m.mutate(); err != nil {
+			t.Fatalf("%s failed: %v", m.name, err)
+		}
+		if after := store.Version(); after <= before {
+			t.Errorf("%s: Version() = %d, want it to grow past %d", m.name, after, before)
+		}
+	}
+
+	// Reads leave it alone
+	before := store.Version()
+	store.Search(randomEmbedding(rng, 4), 5)
+	store.GetAll()
+	store.Count()
+	if after := store.Version(); after != before {
+		t.Errorf("Version() changed from %d to %d without a mutation", before, after)
+	}
+}
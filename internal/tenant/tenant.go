@@ -0,0 +1,23 @@
+package tenant
+
+import "context"
+
+type contextKey struct{}
+
+// WithID returns a copy of ctx carrying the caller's tenant ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ID carried by ctx, or "" when there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Allows reports whether the caller whose tenant ID is carried by ctx may access a resource
+// owned by owner. Without a caller tenant (TENANT_HEADER unset) everything is accessible.
+func Allows(ctx context.Context, owner string) bool {
+	id := FromContext(ctx)
+	return id == "" || id == owner
+}
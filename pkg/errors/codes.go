@@ -0,0 +1,83 @@
+package errors
+
+import "net/http"
+
+// Machine-readable error codes returned alongside the HTTP status as "error_code", so clients
+// can tell apart failures that share a status
+const (
+	// Generic codes, the defaults for their HTTP status
+	CodeInvalidRequest     = "INVALID_REQUEST"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeNotFound           = "NOT_FOUND"
+	CodeConflict           = "CONFLICT"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeInternal           = "INTERNAL_ERROR"
+	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	CodeDeadlineExceeded   = "DEADLINE_EXCEEDED"
+
+	// Request errors
+	CodeValidationFailed      = "VALIDATION_FAILED"
+	CodeInvalidProvider       = "INVALID_PROVIDER"
+	CodeInvalidModel          = "INVALID_MODEL"
+	CodeInvalidResumeToken    = "INVALID_RESUME_TOKEN"
+	CodeInvalidFile           = "INVALID_FILE"
+	CodeInvalidTenant         = "INVALID_TENANT"
+	CodeInvalidRequestTimeout = "INVALID_REQUEST_TIMEOUT"
+	CodeTenantRequired        = "TENANT_REQUIRED"
+	CodeDocumentNotFound      = "DOCUMENT_NOT_FOUND"
+	CodeModelNotFound         = "MODEL_NOT_FOUND"
+	CodePromptNotFound        = "PROMPT_NOT_FOUND"
+
+	// Configuration and state errors
+	CodeProviderNotConfigured = "PROVIDER_NOT_CONFIGURED"
+	CodeAdminDisabled         = "ADMIN_DISABLED"
+	CodeInvalidAPIKey         = "INVALID_API_KEY"
+	CodeReindexInProgress     = "REINDEX_IN_PROGRESS"
+	CodeTooManyUploads        = "TOO_MANY_UPLOADS"
+	CodeDimensionMismatch     = "DIMENSION_MISMATCH"
+	CodeDuplicateDocument     = "DUPLICATE_DOCUMENT"
+	CodeModelMismatch         = "EMBEDDING_MODEL_MISMATCH"
+
+	// Upstream provider errors
+	CodeEmbeddingFailed              = "EMBEDDING_FAILED"
+	CodeEmbeddingProviderUnavailable = "EMBEDDING_PROVIDER_UNAVAILABLE"
+	CodeProviderError                = "PROVIDER_ERROR"
+	CodeProviderRateLimited          = "PROVIDER_RATE_LIMITED"
+	CodeProviderBusy                 = "PROVIDER_BUSY"
+)
+
+// DefaultCode returns the generic error code for an HTTP status
+func DefaultCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeDeadlineExceeded
+	default:
+		return CodeInternal
+	}
+}
+
+// Provider creates an error for a failed upstream LLM or embeddings call, passing the
+// upstream status through. A 429 is reported as PROVIDER_RATE_LIMITED.
+func Provider(status int, message string) *AppError {
+	err := New(status, message)
+	err.ErrorCode = CodeProviderError
+	if status == http.StatusTooManyRequests {
+		err.ErrorCode = CodeProviderRateLimited
+	}
+	return err
+}
@@ -1,15 +1,28 @@
 package errors
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
-// AppError represents an application error with HTTP status code
+// AppError represents an application error with HTTP status code and a machine-readable
+// error code (see codes.go)
 type AppError struct {
-	Code    int    `json:"code"`
+	Code      int          `json:"code"`
+	ErrorCode string       `json:"error_code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	Err       error        `json:"-"`
+}
+
+// FieldError describes a single request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
 	Message string `json:"message"`
-	Err     error  `json:"-"`
 }
 
 // Error implements the error interface
@@ -25,23 +38,32 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
-// New creates a new AppError
+// New creates a new AppError with the default error code for its status
 func New(code int, message string) *AppError {
 	return &AppError{
-		Code:    code,
-		Message: message,
+		Code:      code,
+		ErrorCode: DefaultCode(code),
+		Message:   message,
 	}
 }
 
 // Wrap wraps an existing error with additional context
 func Wrap(err error, code int, message string) *AppError {
 	return &AppError{
-		Code:    code,
-		Message: message,
-		Err:     err,
+		Code:      code,
+		ErrorCode: DefaultCode(code),
+		Message:   message,
+		Err:       err,
 	}
 }
 
+// WithCode returns a copy of the error with a more specific error code
+func (e *AppError) WithCode(errorCode string) *AppError {
+	copied := *e
+	copied.ErrorCode = errorCode
+	return &copied
+}
+
 // Common error constructors
 func BadRequest(message string) *AppError {
 	return New(http.StatusBadRequest, message)
@@ -55,6 +77,41 @@ func NotFound(message string) *AppError {
 	return New(http.StatusNotFound, message)
 }
 
+func Forbidden(message string) *AppError {
+	return New(http.StatusForbidden, message)
+}
+
+func Conflict(message string) *AppError {
+	return New(http.StatusConflict, message)
+}
+
+func ServiceUnavailable(message string) *AppError {
+	return New(http.StatusServiceUnavailable, message)
+}
+
+func TooManyRequests(message string) *AppError {
+	return New(http.StatusTooManyRequests, message)
+}
+
+func GatewayTimeout(message string) *AppError {
+	return New(http.StatusGatewayTimeout, message)
+}
+
+// Validation creates a 400 error listing every field that failed validation
+func Validation(fields []FieldError) *AppError {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+
+	return &AppError{
+		Code:      http.StatusBadRequest,
+		ErrorCode: CodeValidationFailed,
+		Message:   strings.Join(messages, "; "),
+		Fields:    fields,
+	}
+}
+
 func Internal(message string) *AppError {
 	return New(http.StatusInternalServerError, message)
 }
@@ -62,3 +119,11 @@ func Internal(message string) *AppError {
 func InternalWrap(err error, message string) *AppError {
 	return Wrap(err, http.StatusInternalServerError, message)
 }
+
+// IsDeadlineExceeded reports whether err was caused by an expired request deadline
+func IsDeadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// ErrDeadlineExceeded is returned to clients whose request deadline expired
+var ErrDeadlineExceeded = GatewayTimeout("request deadline exceeded before the response was ready; retry with a longer X-Request-Timeout")
@@ -0,0 +1,192 @@
+package httpdebug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// maxLoggedBody caps how much of each body is logged so huge payloads don't flood the logs
+const maxLoggedBody = 1 << 20
+
+// Transport is an http.RoundTripper that logs raw request and response bodies at debug
+// level. Credential-bearing headers are redacted. Response bodies are logged when closed,
+// so streaming responses are passed through unchanged.
+type Transport struct {
+	Base   http.RoundTripper
+	Logger *zap.Logger
+	// Service names the upstream in log entries (e.g. "openrouter", "embeddings")
+	Service string
+}
+
+// NewClient returns an HTTP client that logs raw traffic when enabled, or a plain client
+func NewClient(enabled bool, logger *zap.Logger, service string) *http.Client {
+	if !enabled {
+		return &http.Client{}
+	}
+
+	return &http.Client{
+		Transport: &Transport{
+			Base:    http.DefaultTransport,
+			Logger:  logger,
+			Service: service,
+		},
+	}
+}
+
+// RoundTrip logs the request, performs it and wraps the response body for logging
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(io.LimitReader(body, maxLoggedBody))
+			body.Close()
+		}
+	}
+
+	t.Logger.Debug("raw upstream request",
+		zap.String("service", t.Service),
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.String()),
+		zap.Any("headers", RedactHeaders(req.Header)),
+		zap.String("body", string(reqBody)),
+	)
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		t.Logger.Debug("raw upstream request failed",
+			zap.String("service", t.Service),
+			zap.String("url", req.URL.String()),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	resp.Body = &loggingBody{
+		ReadCloser: resp.Body,
+		log: func(body string, truncated bool) {
+			t.Logger.Debug("raw upstream response",
+				zap.String("service", t.Service),
+				zap.String("url", req.URL.String()),
+				zap.Int("status", resp.StatusCode),
+				zap.Any("headers", RedactHeaders(resp.Header)),
+				zap.String("body", body),
+				zap.Bool("truncated", truncated),
+			)
+		},
+	}
+
+	return resp, nil
+}
+
+// loggingBody captures what the caller reads from a response body and logs it on Close
+type loggingBody struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	truncated bool
+	log       func(body string, truncated bool)
+	logged    bool
+}
+
+// Read reads from the underlying body, keeping a copy up to maxLoggedBody
+func (b *loggingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if room := maxLoggedBody - b.buf.Len(); room > 0 {
+			b.buf.Write(p[:min(n, room)])
+		}
+		if b.buf.Len() >= maxLoggedBody {
+			b.truncated = true
+		}
+	}
+	return n, err
+}
+
+// Close logs the captured body once and closes the underlying body
+func (b *loggingBody) Close() error {
+	if !b.logged {
+		b.logged = true
+		b.log(b.buf.String(), b.truncated)
+	}
+	return b.ReadCloser.Close()
+}
+
+// credentialNames lists the header and JSON field names whose values are credentials, in
+// lower case with "-" written as "_". Names are matched exactly, so fields like max_tokens
+// or input_tokens stay visible.
+var credentialNames = map[string]bool{
+	"authorization":       true,
+	"proxy_authorization": true,
+	"cookie":              true,
+	"set_cookie":          true,
+	"x_api_key":           true,
+	"api_key":             true,
+	"access_token":        true,
+	"refresh_token":       true,
+	"client_secret":       true,
+	"password":            true,
+}
+
+// RedactHeaders returns a copy of headers with credential values replaced
+func RedactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, values := range headers {
+		value := strings.Join(values, ", ")
+		if isCredential(name) {
+			value = "[REDACTED]"
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// isCredential reports whether a header or JSON field carries credentials
+func isCredential(name string) bool {
+	return credentialNames[strings.ReplaceAll(strings.ToLower(name), "-", "_")]
+}
+
+// RedactBody returns a JSON body as a string for logging, at most limit bytes long, with
+// the values of credential fields replaced. Other bodies (e.g. file uploads) are only
+// described by their size.
+func RedactBody(body []byte, limit int) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return fmt.Sprintf("[%d bytes, not JSON]", len(body))
+	}
+	if redacted, err := json.Marshal(redactValue(decoded)); err == nil {
+		body = redacted
+	}
+
+	if len(body) > limit {
+		return string(body[:limit]) + "...[truncated]"
+	}
+	return string(body)
+}
+
+// redactValue replaces the values of credential keys in decoded JSON
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if isCredential(key) {
+				v[key] = "[REDACTED]"
+				continue
+			}
+			v[key] = redactValue(inner)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner)
+		}
+	}
+	return value
+}
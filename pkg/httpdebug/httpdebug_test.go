@@ -0,0 +1,156 @@
+package httpdebug
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// post sends a JSON request with credentials through a client from NewClient and returns
+// the debug entries logged
+func post(t *testing.T, enabled bool) []observer.LoggedEntry {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		io.WriteString(w, `{"answer": "raw response"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	client := NewClient(enabled, zap.New(core), "openrouter")
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"prompt": "raw request"}`))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("X-Api-Key", "sk-secret")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	return logs.AllUntimed()
+}
+
+func TestRawBodiesLoggedOnlyWhenEnabled(t *testing.T) {
+	if entries := post(t, false); len(entries) != 0 {
+		t.Fatalf("disabled client logged %d entries, want none", len(entries))
+	}
+
+	entries := post(t, true)
+	if len(entries) != 2 {
+		t.Fatalf("enabled client logged %d entries, want the request and the response", len(entries))
+	}
+
+	request, response := entries[0].ContextMap(), entries[1].ContextMap()
+	if entries[0].Level != zapcore.DebugLevel || entries[1].Level != zapcore.DebugLevel {
+		t.Errorf("logged at %v and %v, want debug level", entries[0].Level, entries[1].Level)
+	}
+	if request["body"] != `{"prompt": "raw request"}` {
+		t.Errorf("request body = %v, want the raw body", request["body"])
+	}
+	if response["body"] != `{"answer": "raw response"}` {
+		t.Errorf("response body = %v, want the raw body", response["body"])
+	}
+	if request["service"] != "openrouter" || response["status"] != int64(http.StatusOK) {
+		t.Errorf("entries = %v and %v, want the service and status", request, response)
+	}
+}
+
+func TestCredentialHeadersRedacted(t *testing.T) {
+	entries := post(t, true)
+	if len(entries) != 2 {
+		t.Fatalf("logged %d entries, want 2", len(entries))
+	}
+
+	for _, entry := range entries {
+		for key, value := range entry.ContextMap() {
+			if logged := fmt.Sprint(value); strings.Contains(logged, "sk-secret") || strings.Contains(logged, "session=abc") {
+				t.Errorf("%s logs credentials in %s: %s", entry.Message, key, logged)
+			}
+		}
+	}
+
+	headers := entries[0].ContextMap()["headers"].(map[string]string)
+	if headers["Authorization"] != "[REDACTED]" || headers["X-Api-Key"] != "[REDACTED]" {
+		t.Errorf("request headers = %v, want credentials redacted", headers)
+	}
+	if headers["Content-Type"] != "application/json" {
+		t.Errorf("Content-Type = %q, want it kept", headers["Content-Type"])
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "credential fields",
+			body: `{"api_key": "k", "password": "p", "client_secret": "s", "access_token": "a", "refresh_token": "r"}`,
+			want: `{"access_token":"[REDACTED]","api_key":"[REDACTED]","client_secret":"[REDACTED]","password":"[REDACTED]","refresh_token":"[REDACTED]"}`,
+		},
+		{
+			name: "token counts pass through",
+			body: `{"max_tokens": 256, "usage": {"input_tokens": 10, "output_tokens": 5}, "token_metrics": {"total_tokens": 15}}`,
+			want: `{"max_tokens":256,"token_metrics":{"total_tokens":15},"usage":{"input_tokens":10,"output_tokens":5}}`,
+		},
+		{
+			name: "other names containing key or secret",
+			body: `{"cache_key": "q", "keywords": ["a"], "secret_santa": true}`,
+			want: `{"cache_key":"q","keywords":["a"],"secret_santa":true}`,
+		},
+		{
+			name: "nested and case-insensitive",
+			body: `{"auth": [{"API_KEY": "k", "model": "m"}]}`,
+			want: `{"auth":[{"API_KEY":"[REDACTED]","model":"m"}]}`,
+		},
+		{
+			name: "not JSON",
+			body: "file contents",
+			want: "[13 bytes, not JSON]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactBody([]byte(tt.body), 1024); got != tt.want {
+				t.Errorf("RedactBody = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactHeadersKeepsTokenCounts(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer sk-secret")
+	headers.Set("Proxy-Authorization", "Basic abc")
+	headers.Set("Cookie", "session=abc")
+	headers.Set("X-Api-Key", "sk-secret")
+	headers.Set("X-Max-Tokens", "256")
+	headers.Set("X-Ratelimit-Remaining-Tokens", "9000")
+
+	redacted := RedactHeaders(headers)
+	for _, name := range []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"} {
+		if redacted[name] != "[REDACTED]" {
+			t.Errorf("%s = %q, want it redacted", name, redacted[name])
+		}
+	}
+	if redacted["X-Max-Tokens"] != "256" || redacted["X-Ratelimit-Remaining-Tokens"] != "9000" {
+		t.Errorf("headers = %v, want token counts kept", redacted)
+	}
+}
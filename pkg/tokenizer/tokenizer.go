@@ -1,6 +1,8 @@
 package tokenizer
 
 import (
+	"sort"
+	"strings"
 	"unicode"
 )
 
@@ -80,3 +82,50 @@ func CountTokensForMessages(systemPrompt, userMessage, context string) int {
 
 	return systemTokens + userTokens + contextTokens
 }
+
+// TruncateTokens shortens text so that its estimated token count does not exceed maxTokens.
+// With keepEnd the beginning of the text is dropped, otherwise the end is dropped.
+// Text is cut at word boundaries; whitespace between kept words is normalized to single spaces.
+func TruncateTokens(text string, maxTokens int, keepEnd bool) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	if EstimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	words := strings.Fields(text)
+	window := func(n int) string {
+		if keepEnd {
+			return strings.Join(words[len(words)-n:], " ")
+		}
+		return strings.Join(words[:n], " ")
+	}
+
+	// Largest number of words whose estimate fits the budget (estimates grow with n)
+	n := sort.Search(len(words)+1, func(n int) bool {
+		return EstimateTokens(window(n)) > maxTokens
+	}) - 1
+
+	return window(max(n, 0))
+}
+
+// WordOffsets returns the rune offset at which each whitespace-separated word of text starts
+func WordOffsets(text string) []int {
+	var offsets []int
+	inWord := false
+
+	i := 0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			inWord = false
+		} else if !inWord {
+			offsets = append(offsets, i)
+			inWord = true
+		}
+		i++
+	}
+
+	return offsets
+}